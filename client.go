@@ -0,0 +1,146 @@
+// client.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/scenario"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// runClient plays a scenario file against a language server, started as a child process or already
+// listening on a socket, and prints how its responses differ from the expected ones, for reproducing
+// bugs without an editor
+// Returns the exit code: 0 when every step passed, 1 on differences, 2 on errors
+func runClient(args []string) int {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	server := flags.String("server", "", "command starting the server on stdio (default: this binary)")
+	connect := flags.String("connect", "", "address of a running server to connect to instead, host:port or unix:path")
+	timeout := flags.Duration("timeout", scenario.DefaultTimeout, "how long each step waits for the server")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: lx-lsp client [--server command | --connect address] [--timeout duration] scenario.json")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *server != "" && *connect != "" {
+		flags.Usage()
+		return 2
+	}
+
+	failures, err := playScenario(flags.Arg(0), *server, *connect, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// playScenario runs the scenario at path against the server at connect, or a fresh one when empty,
+// and returns the number of failed steps
+// ${dir} in the scenario is the directory of the scenario file, ${cwd} the working directory
+func playScenario(path, server, connect string, timeout time.Duration) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open scenario: %w", err)
+	}
+	defer file.Close()
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve scenario directory: %w", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	steps, err := scenario.Parse(file, map[string]string{"dir": filepath.ToSlash(dir), "cwd": filepath.ToSlash(cwd)})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var stream io.ReadWriteCloser
+	if connect != "" {
+		network, address := "tcp", connect
+		if socket, ok := strings.CutPrefix(connect, "unix:"); ok {
+			network, address = "unix", socket
+		}
+		if stream, err = net.DialTimeout(network, address, timeout); err != nil {
+			return 0, fmt.Errorf("failed to connect to the server: %w", err)
+		}
+	} else {
+		var stop func()
+		if stream, stop, err = startServer(server); err != nil {
+			return 0, err
+		}
+		defer stop()
+	}
+
+	ctx := context.Background()
+	runner := scenario.NewRunner(os.Stdout, timeout)
+	conn := jsonrpc2.NewConn(jsonrpc2.NewStream(stream))
+	conn.Go(ctx, runner.Handler())
+	defer conn.Close()
+
+	return runner.Run(ctx, conn, steps)
+}
+
+// startServer starts the server command, this binary when empty, and returns its stdio along with
+// a function stopping it
+func startServer(server string) (io.ReadWriteCloser, func(), error) {
+	command := strings.Fields(server)
+	if len(command) == 0 {
+		self, err := os.Executable()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to locate the server: %w", err)
+		}
+		command = []string{self}
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to the server: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to the server: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start the server: %w", err)
+	}
+	stop := func() {
+		// Scenarios usually end with shutdown and exit; give the server a moment before stopping it
+		stdin.Close()
+		done := make(chan struct{})
+		go func() {
+			cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			cmd.Process.Kill()
+			<-done
+		}
+	}
+	stream := struct {
+		io.Reader
+		io.WriteCloser
+	}{stdout, stdin}
+	return stream, stop, nil
+}
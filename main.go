@@ -3,21 +3,73 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/kamal-hamza/lx-lsp/server"
 )
 
 func main() {
+	// The scenario client is a developer tool, kept out of the flag usage
+	if len(os.Args) > 1 && os.Args[1] == "client" {
+		os.Exit(runClient(os.Args[2:]))
+	}
+
+	hook, err := parseFlags(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
 	ctx := context.Background()
 
 	// Create and run the language server
 	srv, err := server.NewLanguageServer()
 	if err != nil {
+		if hook {
+			fmt.Fprintln(os.Stderr, err)
+		}
 		os.Exit(1)
 	}
 
+	if hook {
+		problems, err := srv.RunHook(ctx, os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := srv.Run(ctx); err != nil {
 		os.Exit(1)
 	}
 }
+
+// parseFlags reads the command line of the server and reports whether it runs as a git hook
+// Editors launch language servers with flags of their own, such as --stdio or --clientProcessId=42;
+// flags the server does not define are ignored instead of keeping it from starting
+func parseFlags(args []string, output io.Writer) (bool, error) {
+	flags := flag.NewFlagSet("lx-lsp", flag.ContinueOnError)
+	flags.SetOutput(output)
+	hook := flags.Bool("hook", false, "validate the changed notes listed on stdin and exit, for use in git hooks")
+	flags.Bool("stdio", false, "talk to the editor over stdin and stdout, which the server always does")
+
+	var known []string
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && (flags.Lookup(name) != nil || name == "h" || name == "help") {
+			known = append(known, arg)
+		}
+	}
+	err := flags.Parse(known)
+	return *hook, err
+}
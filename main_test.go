@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// TestParseFlags tests the command lines editors and git hooks start the server with
+func TestParseFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		hook bool
+	}{
+		{nil, false},
+		{[]string{"--stdio"}, false},
+		{[]string{"--stdio", "--clientProcessId=42", "--node-ipc"}, false},
+		{[]string{"--hook"}, true},
+		{[]string{"-hook=false", "--stdio"}, false},
+	} {
+		hook, err := parseFlags(tc.args, io.Discard)
+		if err != nil || hook != tc.hook {
+			t.Errorf("parseFlags(%q) = %v, %v; want %v", tc.args, hook, err, tc.hook)
+		}
+	}
+}
+
+// TestPlayScenarioConnect tests playing a scenario against a server already listening on a socket
+func TestPlayScenarioConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		socket, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn := jsonrpc2.NewConn(jsonrpc2.NewStream(socket))
+		conn.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+			return reply(ctx, req.Params(), nil)
+		})
+		<-conn.Done()
+	}()
+
+	path := filepath.Join(t.TempDir(), "echo.json")
+	os.WriteFile(path, []byte(`[{"request": "echo", "params": {"dir": "${dir}"}, "expect": {"dir": "${dir}"}}]`), 0644)
+	failures, err := playScenario(path, "", listener.Addr().String(), time.Second)
+	if err != nil || failures != 0 {
+		t.Errorf("expected the scenario to pass against the running server, got %d failures, %v", failures, err)
+	}
+}
@@ -6,20 +6,34 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // Metadata represents the structured metadata from a note file
 type Metadata struct {
-	Title string
-	Date  string
-	Tags  []string
+	Title    string
+	Date     string
+	Modified string // Optional date of the last edit, kept up to date by the language server
+	Tags     []string
+	Aliases  []string // Optional alternative names the note can be referenced by
+	Status   string   // Optional review status, e.g. draft, review, final
+
+	Engine      string // Optional TeX engine compiling the note, one of Engines
+	CompileArgs string // Optional extra compiler arguments, space-separated
 }
 
+// Engines are the accepted values of the engine field
+var Engines = []string{"pdflatex", "xelatex", "lualatex", "tectonic"}
+
 // ParseResult contains the parsing outcome with detailed error information
 type ParseResult struct {
 	Metadata *Metadata
 	Errors   []ParseError
 	Warnings []string
+
+	// Legacy lists dates accepted through one of the parser's date formats
+	// Metadata holds them normalized to YYYY-MM-DD
+	Legacy []ParseError
 }
 
 // ParseError represents a metadata parsing error
@@ -27,15 +41,51 @@ type ParseError struct {
 	Line    int
 	Field   string
 	Message string
+
+	// Column and Length locate the offending value within the line (0-based, zero when unknown)
+	Column int
+	Length int
 }
 
 func (e ParseError) Error() string {
 	return fmt.Sprintf("line %d (%s): %s", e.Line, e.Field, e.Message)
 }
 
+// Scope limits where in a file the metadata block is looked for
+type Scope int
+
+const (
+	ScopeDocument Scope = iota // Anywhere in the file (default)
+	ScopePreamble              // Anywhere before \begin{document}
+	ScopeTop                   // Only at the top of the file
+)
+
+// ParseScope converts a scope name ("preamble", "top", "anywhere") to a Scope
+// An empty name is the language server's default, "preamble"
+func ParseScope(name string) (Scope, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "preamble":
+		return ScopePreamble, nil
+	case "top":
+		return ScopeTop, nil
+	case "anywhere":
+		return ScopeDocument, nil
+	default:
+		return ScopePreamble, fmt.Errorf("unknown metadata scope: %s", name)
+	}
+}
+
 // Parser handles metadata extraction from LaTeX files
 type Parser struct {
-	strict bool // If true, fail on any error; if false, try to recover
+	strict      bool         // If true, fail on any error; if false, try to recover
+	scope       Scope        // Where the metadata block may appear
+	dateFormats []dateFormat // Legacy date formats accepted besides YYYY-MM-DD
+}
+
+// dateFormat is an accepted legacy date format, e.g. DD.MM.YYYY, and its Go layout
+type dateFormat struct {
+	name   string
+	layout string
 }
 
 // NewParser creates a new metadata parser
@@ -43,6 +93,92 @@ func NewParser(strict bool) *Parser {
 	return &Parser{strict: strict}
 }
 
+// WithScope sets where the parser looks for the metadata block, the whole file unless set
+func (p *Parser) WithScope(scope Scope) *Parser {
+	p.scope = scope
+	return p
+}
+
+// WithDateFormats makes the parser accept dates in legacy formats such as DD.MM.YYYY
+// Formats are built from YYYY, YY, MM, M, DD and D; accepted dates are normalized to YYYY-MM-DD
+// Returns an error, leaving the parser unchanged, if a format is invalid
+func (p *Parser) WithDateFormats(formats ...string) (*Parser, error) {
+	parsed := make([]dateFormat, 0, len(formats))
+	for _, format := range formats {
+		layout, err := DateLayout(format)
+		if err != nil {
+			return p, err
+		}
+		parsed = append(parsed, dateFormat{name: format, layout: layout})
+	}
+	p.dateFormats = parsed
+	return p, nil
+}
+
+// dateTokens maps the tokens of a date format to Go layout elements, longest first
+var dateTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DD", "02"},
+	{"D", "2"},
+}
+
+// DateLayout converts a date format such as DD.MM.YYYY to a Go time layout
+// Anything but the date tokens must be a separator: no letters or digits
+func DateLayout(format string) (string, error) {
+	var layout strings.Builder
+	hasYear, hasMonth, hasDay := false, false, false
+	for rest := format; rest != ""; {
+		matched := false
+		for _, token := range dateTokens {
+			if strings.HasPrefix(rest, token.token) {
+				layout.WriteString(token.layout)
+				rest = rest[len(token.token):]
+				switch token.token[0] {
+				case 'Y':
+					hasYear = true
+				case 'M':
+					hasMonth = true
+				case 'D':
+					hasDay = true
+				}
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r := []rune(rest)[0]
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return "", fmt.Errorf("invalid date format %q: unexpected %q", format, r)
+		}
+		layout.WriteRune(r)
+		rest = rest[len(string(r)):]
+	}
+	if !hasYear || !hasMonth || !hasDay {
+		return "", fmt.Errorf("invalid date format %q: needs a year, month and day", format)
+	}
+	return layout.String(), nil
+}
+
+// NormalizeDate returns date as YYYY-MM-DD if it is valid or in one of the parser's date formats
+// The format it matched is returned too, empty for dates already in YYYY-MM-DD
+func (p *Parser) NormalizeDate(date string) (string, string, bool) {
+	date = strings.TrimSpace(date)
+	if p.validateDate(date) == nil {
+		return date, "", true
+	}
+	for _, format := range p.dateFormats {
+		if parsed, err := time.Parse(format.layout, date); err == nil {
+			return parsed.Format("2006-01-02"), format.name, true
+		}
+	}
+	return date, "", false
+}
+
 // Parse extracts metadata from file content
 // Returns metadata (possibly partial if not strict) and any errors/warnings
 func (p *Parser) Parse(content string) (*ParseResult, error) {
@@ -109,10 +245,10 @@ func (p *Parser) Parse(content string) (*ParseResult, error) {
 	return result, nil
 }
 
-// extractMetadataBlock finds the metadata comment block at the start of the file
+// extractMetadataBlock finds the metadata comment block within the parser's scope
 // Returns the block content, starting line number, and whether it was found
 func (p *Parser) extractMetadataBlock(content string) (string, int, bool) {
-	lines := strings.Split(content, "\n")
+	lines := strings.Split(Normalize(content), "\n")
 	var metadataLines []string
 	inMetadata := false
 	startLine := 0
@@ -120,6 +256,16 @@ func (p *Parser) extractMetadataBlock(content string) (string, int, bool) {
 	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
+		// Stop looking once the block can no longer appear
+		if !inMetadata {
+			if p.scope == ScopePreamble && strings.HasPrefix(trimmed, "\\begin{document}") {
+				break
+			}
+			if p.scope == ScopeTop && trimmed != "" && !strings.HasPrefix(trimmed, "%") {
+				break
+			}
+		}
+
 		// Start of metadata block - check if line contains "Metadata" after removing % chars
 		if !inMetadata {
 			// Remove all leading % characters and whitespace
@@ -205,11 +351,16 @@ func (p *Parser) parseMetadataLine(line string, lineNum int, result *ParseResult
 			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate date field, using first occurrence", lineNum))
 			return nil
 		}
-		if err := p.validateDate(value); err != nil {
+		if normalized, format, ok := p.NormalizeDate(value); ok && format != "" {
+			result.Legacy = append(result.Legacy, legacyDate(line, lineNum, "date", value, format))
+			result.Metadata.Date = normalized
+		} else if err := p.validateDate(value); err != nil {
 			parseErr := ParseError{
 				Line:    lineNum,
 				Field:   "date",
 				Message: err.Error(),
+				Column:  valueColumn(line, value),
+				Length:  len(value),
 			}
 			result.Errors = append(result.Errors, parseErr)
 			// Still store the date even if invalid format
@@ -221,6 +372,28 @@ func (p *Parser) parseMetadataLine(line string, lineNum int, result *ParseResult
 			result.Metadata.Date = value
 		}
 
+	case "modified":
+		if result.Metadata.Modified != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate modified field, using first occurrence", lineNum))
+			return nil
+		}
+		if normalized, format, ok := p.NormalizeDate(value); ok && format != "" {
+			result.Legacy = append(result.Legacy, legacyDate(line, lineNum, "modified", value, format))
+			value = normalized
+		} else if err := p.validateDate(value); err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Line:    lineNum,
+				Field:   "modified",
+				Message: err.Error(),
+				Column:  valueColumn(line, value),
+				Length:  len(value),
+			})
+			if p.strict {
+				return err
+			}
+		}
+		result.Metadata.Modified = value
+
 	case "tags":
 		if len(result.Metadata.Tags) > 0 {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate tags field, merging values", lineNum))
@@ -236,6 +409,50 @@ func (p *Parser) parseMetadataLine(line string, lineNum int, result *ParseResult
 			}
 		}
 
+	case "aliases":
+		if len(result.Metadata.Aliases) > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate aliases field, merging values", lineNum))
+		}
+		for _, alias := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(alias); trimmed != "" {
+				result.Metadata.Aliases = append(result.Metadata.Aliases, trimmed)
+			}
+		}
+
+	case "status":
+		if result.Metadata.Status != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate status field, using first occurrence", lineNum))
+			return nil
+		}
+		result.Metadata.Status = strings.ToLower(value)
+
+	case "engine":
+		if result.Metadata.Engine != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate engine field, using first occurrence", lineNum))
+			return nil
+		}
+		engine := strings.ToLower(value)
+		if err := validateEngine(engine); err != nil {
+			result.Errors = append(result.Errors, ParseError{
+				Line:    lineNum,
+				Field:   "engine",
+				Message: err.Error(),
+				Column:  valueColumn(line, value),
+				Length:  len(value),
+			})
+			if p.strict {
+				return err
+			}
+		}
+		result.Metadata.Engine = engine
+
+	case "compileargs":
+		if result.Metadata.CompileArgs != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: duplicate compileargs field, using first occurrence", lineNum))
+			return nil
+		}
+		result.Metadata.CompileArgs = value
+
 	default:
 		result.Warnings = append(result.Warnings, fmt.Sprintf("line %d: unknown metadata field '%s', ignoring", lineNum, field))
 	}
@@ -243,6 +460,30 @@ func (p *Parser) parseMetadataLine(line string, lineNum int, result *ParseResult
 	return nil
 }
 
+// valueColumn returns the offset of a field's value within its metadata line
+func valueColumn(line, value string) int {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return 0
+	}
+	offset := strings.Index(line[colon:], value)
+	if offset < 0 {
+		return 0
+	}
+	return colon + offset
+}
+
+// legacyDate records a date value accepted through a legacy format
+func legacyDate(line string, lineNum int, field, value, format string) ParseError {
+	return ParseError{
+		Line:    lineNum,
+		Field:   field,
+		Message: fmt.Sprintf("date in legacy format %s (expected YYYY-MM-DD): %s", format, value),
+		Column:  valueColumn(line, value),
+		Length:  len(value),
+	}
+}
+
 // validateDate checks if a date string is in valid format (YYYY-MM-DD)
 func (p *Parser) validateDate(date string) error {
 	if date == "" {
@@ -258,6 +499,19 @@ func (p *Parser) validateDate(date string) error {
 	return nil
 }
 
+// validateEngine checks an engine is one of Engines
+func validateEngine(engine string) error {
+	if engine == "" {
+		return nil
+	}
+	for _, known := range Engines {
+		if engine == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown engine '%s' (expected %s)", engine, strings.Join(Engines, ", "))
+}
+
 // validateMetadata checks required fields are present
 func (p *Parser) validateMetadata(m *Metadata) error {
 	if m.Title == "" {
@@ -291,6 +545,7 @@ func (p *Parser) normalizeMetadata(m *Metadata) {
 }
 
 // Format generates a standardized metadata block
+// Fields left empty, the date included, are left out; tags are always written
 func Format(m *Metadata) string {
 	var builder strings.Builder
 
@@ -299,8 +554,10 @@ func Format(m *Metadata) string {
 
 	if m.Date != "" {
 		builder.WriteString(fmt.Sprintf("%%%% date: %s\n", m.Date))
-	} else {
-		builder.WriteString(fmt.Sprintf("%%%% date: %s\n", time.Now().Format("2006-01-02")))
+	}
+
+	if m.Modified != "" {
+		builder.WriteString(fmt.Sprintf("%%%% modified: %s\n", m.Modified))
 	}
 
 	if len(m.Tags) > 0 {
@@ -309,12 +566,29 @@ func Format(m *Metadata) string {
 		builder.WriteString("%% tags: \n")
 	}
 
+	if len(m.Aliases) > 0 {
+		builder.WriteString(fmt.Sprintf("%%%% aliases: %s\n", strings.Join(m.Aliases, ", ")))
+	}
+
+	if m.Status != "" {
+		builder.WriteString(fmt.Sprintf("%%%% status: %s\n", m.Status))
+	}
+
+	if m.Engine != "" {
+		builder.WriteString(fmt.Sprintf("%%%% engine: %s\n", m.Engine))
+	}
+
+	if m.CompileArgs != "" {
+		builder.WriteString(fmt.Sprintf("%%%% compileargs: %s\n", m.CompileArgs))
+	}
+
 	return builder.String()
 }
 
 // Update replaces or adds metadata to content
 // If metadata exists, it's replaced; otherwise it's prepended
 func Update(content string, m *Metadata) string {
+	content = Normalize(content)
 	parser := NewParser(false)
 	_, blockStart, found := parser.extractMetadataBlock(content)
 
@@ -379,6 +653,31 @@ func Update(content string, m *Metadata) string {
 	return result.String()
 }
 
+// Normalize strips a leading UTF-8 byte order mark and converts CRLF and CR line endings to LF
+func Normalize(content string) string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.Contains(content, "\r") {
+		return content
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.ReplaceAll(content, "\r", "\n")
+}
+
+// FindBlock locates the metadata block in content
+// Returns the first and last line numbers of the block and whether it was found
+func FindBlock(content string) (int, int, bool) {
+	return NewParser(false).FindBlock(content)
+}
+
+// FindBlock locates the metadata block within the parser's scope
+func (p *Parser) FindBlock(content string) (int, int, bool) {
+	block, blockStart, found := p.extractMetadataBlock(content)
+	if !found {
+		return 0, 0, false
+	}
+	return blockStart, blockStart + strings.Count(block, "\n"), true
+}
+
 // Extract is a convenience function for non-strict parsing
 func Extract(content string) (*Metadata, error) {
 	parser := NewParser(false)
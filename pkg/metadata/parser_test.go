@@ -3,7 +3,6 @@ package metadata
 import (
 	"strings"
 	"testing"
-	"time"
 )
 
 func TestParser_Parse_ValidMetadata(t *testing.T) {
@@ -373,9 +372,7 @@ func TestFormat(t *testing.T) {
 			},
 			contains: []string{
 				"%% Metadata",
-				"%% title: No Date Note",
-				"%% date: ",
-				"%% tags: test",
+				"%% title: No Date Note\n%% tags: test",
 			},
 		},
 	}
@@ -588,7 +585,7 @@ func TestParser_ValidateDate(t *testing.T) {
 	}
 }
 
-func TestFormat_AutoDate(t *testing.T) {
+func TestFormat_NoDate(t *testing.T) {
 	metadata := &Metadata{
 		Title: "Test",
 		Date:  "",
@@ -597,10 +594,9 @@ func TestFormat_AutoDate(t *testing.T) {
 
 	result := Format(metadata)
 
-	// Should contain today's date in YYYY-MM-DD format
-	today := time.Now().Format("2006-01-02")
-	if !strings.Contains(result, "%% date: "+today) {
-		t.Errorf("Expected today's date (%s) to be added, got:\n%s", today, result)
+	// A block without a date keeps none, rather than getting today's
+	if strings.Contains(result, "date:") {
+		t.Errorf("Expected no date line, got:\n%s", result)
 	}
 }
 
@@ -646,3 +642,276 @@ func TestParseError_Error(t *testing.T) {
 		t.Errorf("Expected error message %q, got %q", expected, err.Error())
 	}
 }
+
+func TestFindBlock(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantStart int
+		wantEnd   int
+		wantFound bool
+	}{
+		{
+			name:      "block at top",
+			content:   "%% Metadata\n%% title: Test\n%% date: 2024-01-01\n\n\\begin{document}",
+			wantStart: 0,
+			wantEnd:   2,
+			wantFound: true,
+		},
+		{
+			name:      "block after preamble",
+			content:   "\\documentclass{article}\n% Metadata\n% title: Test\n\\begin{document}",
+			wantStart: 1,
+			wantEnd:   2,
+			wantFound: true,
+		},
+		{
+			name:      "no block",
+			content:   "\\documentclass{article}",
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, found := FindBlock(tt.content)
+			if found != tt.wantFound {
+				t.Fatalf("found = %v, want %v", found, tt.wantFound)
+			}
+			if found && (start != tt.wantStart || end != tt.wantEnd) {
+				t.Errorf("FindBlock() = (%d, %d), want (%d, %d)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+// TestNormalize tests BOM stripping and line ending conversion
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"a\nb":           "a\nb",
+		"a\r\nb\r\n":     "a\nb\n",
+		"a\rb":           "a\nb",
+		"\ufeff%% Title": "%% Title",
+	}
+	for input, expected := range tests {
+		if got := Normalize(input); got != expected {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, expected)
+		}
+	}
+
+	// Metadata behind a BOM and CRLF endings still parses
+	meta, err := Extract("\ufeff%% Metadata\r\n% title: Windows Note\r\n% date: 2024-01-15\r\n% tags: a, b\r\n")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if meta.Title != "Windows Note" || len(meta.Tags) != 2 {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+}
+
+// TestParser_Parse_InvalidDatePosition tests that date errors locate the offending value
+func TestParser_Parse_InvalidDatePosition(t *testing.T) {
+	content := "%% Metadata\n%% title: Test\n%%  date:   01/15/2024\n"
+
+	result, err := NewParser(false).Parse(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %d", len(result.Errors))
+	}
+
+	parseErr := result.Errors[0]
+	if parseErr.Line != 3 || parseErr.Column != 12 || parseErr.Length != 10 {
+		t.Errorf("Expected line 3, column 12, length 10, got %+v", parseErr)
+	}
+}
+
+// TestParser_Scope tests limiting where the metadata block is found
+func TestParser_Scope(t *testing.T) {
+	preamble := "\\documentclass{article}\n%% Metadata\n%% title: Preamble\n\\begin{document}\n"
+	body := "\\documentclass{article}\n\\begin{document}\n%% Metadata\n%% title: Body\n"
+
+	tests := []struct {
+		name      string
+		content   string
+		scope     Scope
+		wantFound bool
+	}{
+		{"preamble block, preamble scope", preamble, ScopePreamble, true},
+		{"preamble block, top scope", preamble, ScopeTop, false},
+		{"body block, preamble scope", body, ScopePreamble, false},
+		{"body block, document scope", body, ScopeDocument, true},
+		{"top block, top scope", "\n% note\n%% Metadata\n%% title: Top\n", ScopeTop, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, found := NewParser(false).WithScope(tt.scope).FindBlock(tt.content)
+			if found != tt.wantFound {
+				t.Errorf("found = %v, want %v", found, tt.wantFound)
+			}
+		})
+	}
+
+	if _, err := ParseScope("sideways"); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+	if scope, _ := ParseScope("Anywhere"); scope != ScopeDocument {
+		t.Errorf("expected ScopeDocument, got %v", scope)
+	}
+
+	// Parsers without a scope search the whole file, as before scopes existed
+	if _, _, found := NewParser(false).FindBlock(body); !found {
+		t.Error("expected the default parser to find a block in the body")
+	}
+	if _, _, found := FindBlock(body); !found {
+		t.Error("expected FindBlock to find a block in the body")
+	}
+}
+
+// TestParser_Parse_Status tests the optional review status field
+func TestParser_Parse_Status(t *testing.T) {
+	content := "%% Metadata\n%% title: Test\n%% status: Draft\n"
+
+	result, err := NewParser(false).Parse(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Metadata.Status != "draft" {
+		t.Errorf("Expected status 'draft', got %q", result.Metadata.Status)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", result.Warnings)
+	}
+
+	if formatted := Format(result.Metadata); !strings.Contains(formatted, "%% status: draft\n") {
+		t.Errorf("Expected status in formatted block, got %q", formatted)
+	}
+}
+
+// TestParser_Parse_Modified tests the optional last-edit date field
+func TestParser_Parse_Modified(t *testing.T) {
+	content := "%% Metadata\n%% title: Test\n%% date: 2024-01-01\n%% modified: 2024-02-03\n"
+
+	result, err := NewParser(false).Parse(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Metadata.Modified != "2024-02-03" {
+		t.Errorf("Expected modified '2024-02-03', got %q", result.Metadata.Modified)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", result.Warnings)
+	}
+
+	if formatted := Format(result.Metadata); !strings.Contains(formatted, "%% date: 2024-01-01\n%% modified: 2024-02-03\n") {
+		t.Errorf("Expected modified after date in formatted block, got %q", formatted)
+	}
+
+	result, _ = NewParser(false).Parse("%% Metadata\n%% title: Test\n%% modified: yesterday\n")
+	if len(result.Errors) != 1 || result.Errors[0].Field != "modified" {
+		t.Errorf("Expected an invalid modified date error, got %v", result.Errors)
+	}
+}
+
+// TestParser_Parse_CompileProfile tests the optional engine and compileargs fields
+func TestParser_Parse_CompileProfile(t *testing.T) {
+	content := "%% Metadata\n%% title: Test\n%% engine: XeLaTeX\n%% compileargs: -shell-escape -synctex=1\n"
+
+	result, err := NewParser(false).Parse(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Metadata.Engine != "xelatex" {
+		t.Errorf("Expected engine 'xelatex', got %q", result.Metadata.Engine)
+	}
+	if result.Metadata.CompileArgs != "-shell-escape -synctex=1" {
+		t.Errorf("Expected compile args, got %q", result.Metadata.CompileArgs)
+	}
+	if len(result.Errors) != 0 || len(result.Warnings) != 0 {
+		t.Errorf("Expected no errors or warnings, got %v, %v", result.Errors, result.Warnings)
+	}
+
+	formatted := Format(result.Metadata)
+	if !strings.Contains(formatted, "%% engine: xelatex\n%% compileargs: -shell-escape -synctex=1\n") {
+		t.Errorf("Expected compile profile in formatted block, got %q", formatted)
+	}
+
+	result, err = NewParser(false).Parse("%% Metadata\n%% title: Test\n%% engine: context\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Field != "engine" || result.Errors[0].Line != 3 || result.Errors[0].Column != 11 || result.Errors[0].Length != 7 {
+		t.Errorf("Expected engine error on the value, got %+v", result.Errors)
+	}
+}
+
+// TestParser_Parse_Aliases tests reading and formatting alternative names of a note
+func TestParser_Parse_Aliases(t *testing.T) {
+	content := "%% Metadata\n%% title: Test\n%% aliases: fourier, FT ,, fourier-series\n"
+
+	result, err := NewParser(false).Parse(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(result.Metadata.Aliases, "|") != "fourier|FT|fourier-series" {
+		t.Errorf("Expected three aliases, got %q", result.Metadata.Aliases)
+	}
+
+	formatted := Format(result.Metadata)
+	if !strings.Contains(formatted, "%% aliases: fourier, FT, fourier-series\n") {
+		t.Errorf("Expected aliases in formatted block, got %q", formatted)
+	}
+	if formatted = Format(&Metadata{Title: "Test"}); strings.Contains(formatted, "aliases") {
+		t.Errorf("Expected no aliases line without aliases, got %q", formatted)
+	}
+}
+
+// TestParser_DateFormats tests accepting and normalizing dates in legacy formats
+func TestParser_DateFormats(t *testing.T) {
+	parser, err := NewParser(false).WithDateFormats("DD.MM.YYYY", "M/D/YY")
+	if err != nil {
+		t.Fatalf("WithDateFormats failed: %v", err)
+	}
+
+	content := "%% Metadata\n%% title: Test\n%% date: 15.01.2024\n%% modified: 2/3/24\n"
+	result, err := parser.Parse(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("Expected no errors, got %+v", result.Errors)
+	}
+	if result.Metadata.Date != "2024-01-15" || result.Metadata.Modified != "2024-02-03" {
+		t.Errorf("Expected normalized dates, got %q and %q", result.Metadata.Date, result.Metadata.Modified)
+	}
+	if len(result.Legacy) != 2 || result.Legacy[0].Line != 3 || result.Legacy[0].Column != 9 || result.Legacy[0].Length != 10 || result.Legacy[1].Field != "modified" {
+		t.Errorf("Unexpected legacy dates %+v", result.Legacy)
+	}
+	if !strings.Contains(Format(result.Metadata), "%% date: 2024-01-15\n") {
+		t.Errorf("Expected Format to write the normalized date, got %q", Format(result.Metadata))
+	}
+
+	// Without the formats the same dates are errors
+	result, _ = NewParser(false).Parse(content)
+	if len(result.Errors) != 2 || len(result.Legacy) != 0 {
+		t.Errorf("Expected 2 errors without date formats, got %+v", result.Errors)
+	}
+
+	if date, format, ok := parser.NormalizeDate("2024-01-15"); !ok || date != "2024-01-15" || format != "" {
+		t.Errorf("Expected ISO dates to pass through, got %q %q %v", date, format, ok)
+	}
+	if _, _, ok := parser.NormalizeDate("15/01/2024"); ok {
+		t.Error("Expected a date in no known format to be rejected")
+	}
+
+	for _, format := range []string{"DD.MM", "DD.MM.YYYY hh", "YYYYMMDDx"} {
+		if _, err := DateLayout(format); err == nil {
+			t.Errorf("Expected %q to be rejected", format)
+		}
+	}
+	if layout, _ := DateLayout("YYYYMMDD"); layout != "20060102" {
+		t.Errorf("Expected 20060102, got %q", layout)
+	}
+}
@@ -0,0 +1,84 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// DiffJSON compares a response against the expected JSON and describes every difference
+// Objects only need the expected keys, so scenarios can pin down just what matters;
+// arrays must have the expected length, and other values must be equal
+func DiffJSON(expected, actual json.RawMessage) ([]string, error) {
+	var want, got interface{}
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return nil, fmt.Errorf("invalid expected JSON: %w", err)
+	}
+	if len(actual) == 0 {
+		actual = json.RawMessage("null")
+	}
+	if err := json.Unmarshal(actual, &got); err != nil {
+		return nil, fmt.Errorf("invalid response JSON: %w", err)
+	}
+	return diffValues("$", want, got), nil
+}
+
+// diffValues compares two decoded JSON values at path
+func diffValues(path string, want, got interface{}) []string {
+	switch want := want.(type) {
+	case map[string]interface{}:
+		object, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{mismatch(path, want, got)}
+		}
+		keys := make([]string, 0, len(want))
+		for key := range want {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var diffs []string
+		for _, key := range keys {
+			value, exists := object[key]
+			if !exists {
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing, expected %s", path, key, encode(want[key])))
+				continue
+			}
+			diffs = append(diffs, diffValues(path+"."+key, want[key], value)...)
+		}
+		return diffs
+
+	case []interface{}:
+		array, ok := got.([]interface{})
+		if !ok {
+			return []string{mismatch(path, want, got)}
+		}
+		if len(array) != len(want) {
+			return []string{fmt.Sprintf("%s: expected %d elements, got %d", path, len(want), len(array))}
+		}
+		var diffs []string
+		for i := range want {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), want[i], array[i])...)
+		}
+		return diffs
+
+	default:
+		if encode(want) != encode(got) {
+			return []string{mismatch(path, want, got)}
+		}
+		return nil
+	}
+}
+
+func mismatch(path string, want, got interface{}) string {
+	return fmt.Sprintf("%s: expected %s, got %s", path, encode(want), encode(got))
+}
+
+// encode renders a decoded value back as compact JSON
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,125 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// DefaultTimeout bounds how long a step waits for the server
+const DefaultTimeout = 10 * time.Second
+
+// notification is a message the server sent without being asked
+type notification struct {
+	method string
+	params json.RawMessage
+}
+
+// Runner plays scenarios against a server and reports where its responses differ from the expected ones
+type Runner struct {
+	out           io.Writer
+	timeout       time.Duration
+	notifications chan notification
+}
+
+// NewRunner creates a runner writing its report to out
+func NewRunner(out io.Writer, timeout time.Duration) *Runner {
+	return &Runner{
+		out:           out,
+		timeout:       timeout,
+		notifications: make(chan notification, 256),
+	}
+}
+
+// Handler answers the server's requests to the client and queues its notifications for wait steps
+// Requests get a null result, which servers treat as a client without the feature
+func (r *Runner) Handler() jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if _, isCall := req.(*jsonrpc2.Call); isCall {
+			return reply(ctx, nil, nil)
+		}
+		select {
+		case r.notifications <- notification{method: req.Method(), params: req.Params()}:
+		default:
+			// Nobody is waiting for this many notifications; drop them rather than stall the connection
+		}
+		return nil
+	}
+}
+
+// Run plays the steps in order over conn and returns the number of failed steps
+// An error means the scenario could not be played to the end, e.g. the server went away
+func (r *Runner) Run(ctx context.Context, conn jsonrpc2.Conn, steps []Step) (int, error) {
+	failures := 0
+	for _, step := range steps {
+		actual, err := r.play(ctx, conn, step)
+		if err != nil {
+			fmt.Fprintf(r.out, "FAIL line %d: %s %s: %v\n", step.Line, step.Kind, step.Method, err)
+			return failures + 1, err
+		}
+
+		var diffs []string
+		if step.Expect != nil {
+			diffs, err = DiffJSON(step.Expect, actual)
+			if err != nil {
+				return failures + 1, fmt.Errorf("line %d: %w", step.Line, err)
+			}
+		}
+		if len(diffs) > 0 {
+			failures++
+			fmt.Fprintf(r.out, "FAIL line %d: %s %s\n", step.Line, step.Kind, step.Method)
+			for _, diff := range diffs {
+				fmt.Fprintf(r.out, "  %s\n", diff)
+			}
+			fmt.Fprintf(r.out, "  actual: %s\n", actual)
+			continue
+		}
+		fmt.Fprintf(r.out, "ok   line %d: %s %s\n", step.Line, step.Kind, step.Method)
+	}
+
+	fmt.Fprintf(r.out, "%d/%d steps passed\n", len(steps)-failures, len(steps))
+	return failures, nil
+}
+
+// play performs a single step and returns what the server answered
+func (r *Runner) play(ctx context.Context, conn jsonrpc2.Conn, step Step) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	params := interface{}(step.Params)
+	if step.Params == nil {
+		params = nil
+	}
+
+	switch step.Kind {
+	case KindRequest:
+		var result json.RawMessage
+		if _, err := conn.Call(ctx, step.Method, params, &result); err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = json.RawMessage("null")
+		}
+		return result, nil
+
+	case KindNotify:
+		return nil, conn.Notify(ctx, step.Method, params)
+
+	case KindWait:
+		for {
+			select {
+			case msg := <-r.notifications:
+				if msg.method == step.Method {
+					return msg.params, nil
+				}
+			case <-ctx.Done():
+				return nil, fmt.Errorf("no %s notification within %s", step.Method, r.timeout)
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown step kind '%s'", step.Kind)
+}
@@ -0,0 +1,92 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// TestDiffJSON tests that only expected keys are compared
+func TestDiffJSON(t *testing.T) {
+	expected := `{"contents": {"kind": "markdown"}, "items": [1, {"label": "a"}], "missing": true}`
+	actual := `{"contents": {"kind": "plaintext", "value": "x"}, "items": [1, {"label": "a", "kind": 3}], "extra": 1}`
+
+	diffs, err := DiffJSON(json.RawMessage(expected), json.RawMessage(actual))
+	if err != nil {
+		t.Fatalf("DiffJSON failed: %v", err)
+	}
+	want := []string{
+		`$.contents.kind: expected "markdown", got "plaintext"`,
+		`$.missing: missing, expected true`,
+	}
+	if strings.Join(diffs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diffs:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	if diffs, _ := DiffJSON(json.RawMessage(`[1, 2]`), json.RawMessage(`[1]`)); len(diffs) != 1 || diffs[0] != "$: expected 2 elements, got 1" {
+		t.Errorf("unexpected array diffs %v", diffs)
+	}
+	if diffs, _ := DiffJSON(json.RawMessage(`null`), nil); len(diffs) != 0 {
+		t.Errorf("expected an empty response to match null, got %v", diffs)
+	}
+}
+
+// TestRunner tests playing a scenario against a server over a connection
+func TestRunner(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	server := jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide))
+	server.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		switch req.Method() {
+		case "echo":
+			return reply(ctx, req.Params(), nil)
+		case "ping":
+			// Notifications are answered by a notification, as servers publish diagnostics
+			server.Notify(ctx, "pong", map[string]int{"count": 1})
+			return nil
+		}
+		return reply(ctx, nil, jsonrpc2.ErrMethodNotFound)
+	})
+	defer server.Close()
+
+	steps, err := Parse(strings.NewReader(`[
+  {"request": "echo", "params": {"value": 1}, "expect": {"value": 1}},
+  {"request": "echo", "params": {"value": 2}, "expect": {"value": 3}},
+  {"notify": "ping"},
+  {"wait": "pong", "expect": {"count": 1}}
+]`), nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	runner := NewRunner(&out, time.Second)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), runner.Handler())
+	defer client.Close()
+
+	failures, err := runner.Run(context.Background(), client, steps)
+	if err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+	if failures != 1 {
+		t.Errorf("expected 1 failure, got %d", failures)
+	}
+	report := out.String()
+	for _, want := range []string{"ok   line 2: request echo", "FAIL line 3: request echo", "$.value: expected 3, got 2", "ok   line 5: wait pong", "3/4 steps passed"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected %q in report:\n%s", want, report)
+		}
+	}
+
+	// Unknown methods stop the scenario
+	failures, err = runner.Run(context.Background(), client, []Step{{Line: 1, Kind: KindRequest, Method: "nope"}})
+	if err == nil || failures != 1 {
+		t.Errorf("expected the failed request to stop the run, got %d, %v", failures, err)
+	}
+}
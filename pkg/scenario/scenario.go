@@ -0,0 +1,122 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Step kinds
+const (
+	KindRequest = "request" // send a request and compare its result
+	KindNotify  = "notify"  // send a notification
+	KindWait    = "wait"    // wait for a notification from the server and compare its params
+)
+
+// Step is a single scripted exchange with the server
+type Step struct {
+	Line   int             // line of the step in the scenario file, for reports
+	Kind   string          // KindRequest, KindNotify or KindWait
+	Method string          // LSP method
+	Params json.RawMessage // sent with requests and notifications, nil for none
+	Expect json.RawMessage // expected result or notification params, nil to accept anything
+}
+
+// Parse reads a scenario: a JSON list of steps, each an object naming one of request, notify
+// or wait with its method, plus optional params and expect values
+// Occurrences of ${name} are replaced with vars[name] before the JSON is decoded.
+func Parse(r io.Reader, vars map[string]string) ([]Step, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	data = []byte(expand(string(data), vars))
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("line %d: expected a list of steps", lineAt(data, 0))
+	}
+	var steps []Step
+	for decoder.More() {
+		step := Step{Line: lineAt(data, decoder.InputOffset())}
+		var fields map[string]json.RawMessage
+		if err := decoder.Decode(&fields); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, fmt.Errorf("line %d: %w", lineAt(data, syntaxErr.Offset-1), err)
+			}
+			return nil, fmt.Errorf("line %d: expected a step object", step.Line)
+		}
+
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := setField(&step, key, fields[key]); err != nil {
+				return nil, fmt.Errorf("line %d: %w", step.Line, err)
+			}
+		}
+
+		if step.Kind == "" {
+			return nil, fmt.Errorf("line %d: step needs one of request, notify or wait", step.Line)
+		}
+		if step.Kind == KindNotify && step.Expect != nil {
+			return nil, fmt.Errorf("line %d: notifications have no response to expect", step.Line)
+		}
+		steps = append(steps, step)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("line %d: expected the list of steps to end", lineAt(data, decoder.InputOffset()))
+	}
+	return steps, nil
+}
+
+// setField assigns a key of a step
+func setField(step *Step, key string, value json.RawMessage) error {
+	switch key {
+	case KindRequest, KindNotify, KindWait:
+		if step.Kind != "" {
+			return fmt.Errorf("step already is a %s", step.Kind)
+		}
+		if err := json.Unmarshal(value, &step.Method); err != nil || step.Method == "" {
+			return fmt.Errorf("%s needs a method", key)
+		}
+		step.Kind = key
+	case "params":
+		step.Params = value
+	case "expect":
+		step.Expect = value
+	default:
+		return fmt.Errorf("unknown key '%s'", key)
+	}
+	return nil
+}
+
+// lineAt returns the line of the first value at or after offset, skipping the separators before it
+func lineAt(data []byte, offset int64) int {
+	if offset < 0 {
+		offset = 0
+	}
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// expand replaces ${name} with its value, escaped to fit inside JSON strings
+func expand(text string, vars map[string]string) string {
+	for name, value := range vars {
+		escaped, _ := json.Marshal(value)
+		text = strings.ReplaceAll(text, "${"+name+"}", string(escaped[1:len(escaped)-1]))
+	}
+	return text
+}
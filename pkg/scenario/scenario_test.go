@@ -0,0 +1,70 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParse tests reading steps with nested JSON values
+func TestParse(t *testing.T) {
+	input := `[
+  {"request": "initialize", "params": {"rootUri": "file://${dir}"}},
+  {"notify": "initialized", "params": {}},
+
+  {
+    "request": "textDocument/hover",
+    "params": {
+      "textDocument": {"uri": "file://${dir}/a.tex"},
+      "position": {"line": 0, "character": 5}
+    },
+    "expect": {"contents": {"kind": "markdown"}}
+  },
+  {"wait": "textDocument/publishDiagnostics"},
+  {"request": "shutdown"}
+]
+`
+	steps, err := Parse(strings.NewReader(input), map[string]string{"dir": `/tmp/va"ult`})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(steps) != 5 {
+		t.Fatalf("expected 5 steps, got %d", len(steps))
+	}
+
+	if steps[0].Kind != KindRequest || steps[0].Method != "initialize" || string(steps[0].Params) != `{"rootUri": "file:///tmp/va\"ult"}` || steps[0].Line != 2 {
+		t.Errorf("unexpected first step %+v", steps[0])
+	}
+	if steps[1].Kind != KindNotify || steps[1].Method != "initialized" || steps[1].Line != 3 {
+		t.Errorf("unexpected second step %+v", steps[1])
+	}
+	hover := steps[2]
+	if hover.Line != 5 || !strings.Contains(string(hover.Params), `"position": {"line": 0, "character": 5}`) || string(hover.Expect) != `{"contents": {"kind": "markdown"}}` {
+		t.Errorf("unexpected hover step line %d, params %s, expect %s", hover.Line, hover.Params, hover.Expect)
+	}
+	if steps[3].Kind != KindWait || steps[3].Params != nil || steps[4].Method != "shutdown" {
+		t.Errorf("unexpected trailing steps %+v", steps[3:])
+	}
+}
+
+// TestParse_Errors tests that malformed scenarios point at the offending line
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"request": "initialize"}`, "line 1: expected a list of steps"},
+		{"[\n{\"request\": \"initialize\",\n \"color\": \"red\"}]", "line 2: unknown key 'color'"},
+		{"[{\"request\": \"a\"},\n {\"params\": {broken}]", "line 2: invalid character 'b'"},
+		{`[{"params": {}}]`, "line 1: step needs one of request, notify or wait"},
+		{`[{"notify": "exit", "expect": null}]`, "line 1: notifications have no response to expect"},
+		{`[{"request": "a", "notify": "b"}]`, "line 1: step already is a notify"},
+		{`[{"request": 1}]`, "line 1: request needs a method"},
+		{`["initialize"]`, "line 1: expected a step object"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeAcronymBeforeDefinition marks acronyms used before the note defines them
+const diagnosticCodeAcronymBeforeDefinition = "LX005"
+
+var (
+	// acronymPattern matches all-caps tokens such as "NLP", "GPUs" or "MP3S"
+	acronymPattern = regexp.MustCompile(`\b([A-Z][A-Z0-9]*[A-Z])s?\b`)
+
+	// acronymParenPattern matches a parenthetical definition such as "(NLP)"
+	acronymParenPattern = regexp.MustCompile(`\(\s*([A-Z][A-Z0-9]*[A-Z])s?\s*\)`)
+
+	// newAcronymPattern matches \newacronym[options]{key}{short}{long}
+	newAcronymPattern = regexp.MustCompile(`\\newacronym(?:\[[^\]]*\])?\{([^}]*)\}\{([^}]*)\}\{([^}]*)\}`)
+
+	// nonProsePattern matches commands whose arguments are identifiers rather than prose, and math
+	nonProsePattern = regexp.MustCompile(`\\(?:ref|eqref|cref|Cref|autoref|pageref|label|cite|input|include|includegraphics|usepackage|documentclass|newacronym|gls|Gls|glspl|acrshort|acrlong|acrfull|href|url)\*?(?:\[[^\]]*\])?(?:\{[^}]*\})*|\\[A-Za-z]+|\$[^$]*\$`)
+)
+
+// acronymStopwords may appear in a long form without contributing a letter ("Department of Energy")
+var acronymStopwords = map[string]bool{
+	"of": true, "and": true, "the": true, "for": true, "in": true, "on": true, "to": true, "a": true, "an": true,
+}
+
+// acronymDefinition is where a note first defines an acronym
+type acronymDefinition struct {
+	Short string
+	Long  string
+	Line  int
+	Start int
+	End   int
+	// Parenthetical is set for "Long Form (LF)" definitions, which span Start to End
+	Parenthetical bool
+}
+
+// acronymUse is a bare occurrence of an acronym in prose
+type acronymUse struct {
+	Short string
+	Line  int
+	Start int
+	End   int
+}
+
+func init() {
+	registerQuickFix(diagnosticCodeAcronymBeforeDefinition, defineAcronymFix)
+}
+
+// acronymDiagnostics reports acronyms used before their definition in the note
+func acronymDiagnostics(content string) []protocol.Diagnostic {
+	definitions, uses := scanAcronyms(content)
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, use := range uses {
+		def, ok := definitions[use.Short]
+		if !ok || !positionBefore(use.Line, use.Start, def.Line, def.Start) {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange(use.Line, use.Start, use.End),
+			Severity: protocol.DiagnosticSeverityInformation,
+			Code:     diagnosticCodeAcronymBeforeDefinition,
+			Message:  fmt.Sprintf("Acronym '%s' used before its definition on line %d", use.Short, def.Line+1),
+			Source:   "lx-ls",
+		})
+	}
+	return diagnostics
+}
+
+// scanAcronyms collects the first definition of each acronym and every bare use, in document order
+func scanAcronyms(content string) (map[string]acronymDefinition, []acronymUse) {
+	definitions := make(map[string]acronymDefinition)
+	uses := []acronymUse{}
+
+	define := func(def acronymDefinition) {
+		if _, exists := definitions[def.Short]; !exists {
+			definitions[def.Short] = def
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	for lineNum, line := range lines {
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		line = stripInlineComment(line)
+
+		for _, match := range newAcronymPattern.FindAllStringSubmatchIndex(line, -1) {
+			define(acronymDefinition{
+				Short: strings.TrimSpace(line[match[4]:match[5]]),
+				Long:  strings.TrimSpace(line[match[6]:match[7]]),
+				Line:  lineNum,
+				Start: match[0],
+				End:   match[1],
+			})
+		}
+
+		prose := maskNonProse(line)
+
+		for _, match := range acronymParenPattern.FindAllStringSubmatchIndex(prose, -1) {
+			short := prose[match[2]:match[3]]
+			long, longStart := acronymLongForm(prose[:match[0]], short)
+			if long == "" {
+				// Still a definition, just one the quick fix cannot reuse
+				longStart = match[0]
+			}
+			define(acronymDefinition{
+				Short:         short,
+				Long:          long,
+				Line:          lineNum,
+				Start:         longStart,
+				End:           match[1],
+				Parenthetical: long != "",
+			})
+		}
+
+		for _, match := range acronymPattern.FindAllStringSubmatchIndex(prose, -1) {
+			uses = append(uses, acronymUse{
+				Short: prose[match[2]:match[3]],
+				Line:  lineNum,
+				Start: match[0],
+				End:   match[1],
+			})
+		}
+	}
+
+	return definitions, uses
+}
+
+// acronymLongForm finds the words ending text whose initials spell short
+// Returns the long form and its start offset, or "" if the words do not match
+func acronymLongForm(text, short string) (string, int) {
+	letters := []rune{}
+	for _, r := range strings.ToLower(short) {
+		if unicode.IsLetter(r) {
+			letters = append(letters, r)
+		}
+	}
+
+	end := len(strings.TrimRightFunc(text, unicode.IsSpace))
+	start := end
+	i := len(letters) - 1
+	for i >= 0 {
+		trimmed := strings.TrimRightFunc(text[:start], unicode.IsSpace)
+		wordStart := strings.LastIndexFunc(trimmed, unicode.IsSpace) + 1
+		word := strings.TrimFunc(trimmed[wordStart:], func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if word == "" {
+			return "", 0
+		}
+
+		first := unicode.ToLower([]rune(word)[0])
+		switch {
+		case first == letters[i]:
+			i--
+		case acronymStopwords[strings.ToLower(word)] && i < len(letters)-1:
+		default:
+			return "", 0
+		}
+		start = wordStart
+	}
+
+	return text[start:end], start
+}
+
+// defineAcronymFix expands the first use of an acronym to "Long Form (LF)"
+// A parenthetical definition further down is collapsed to the bare acronym
+func defineAcronymFix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	definitions, uses := scanAcronyms(req.Content)
+
+	lines := strings.Split(req.Content, "\n")
+	line := int(diag.Range.Start.Line)
+	if line >= len(lines) || int(diag.Range.End.Character) > len(lines[line]) {
+		return nil
+	}
+	short := strings.TrimSuffix(lines[line][diag.Range.Start.Character:diag.Range.End.Character], "s")
+
+	def, ok := definitions[short]
+	if !ok || def.Long == "" {
+		return nil
+	}
+
+	var first *acronymUse
+	for i := range uses {
+		if uses[i].Short == short {
+			first = &uses[i]
+			break
+		}
+	}
+	if first == nil {
+		return nil
+	}
+
+	edits := []protocol.TextEdit{
+		{
+			Range:   lineRange(first.Line, first.Start, first.Start+len(short)),
+			NewText: fmt.Sprintf("%s (%s)", def.Long, short),
+		},
+	}
+	if def.Parenthetical {
+		edits = append(edits, protocol.TextEdit{
+			Range:   lineRange(def.Line, def.Start, def.End),
+			NewText: short,
+		})
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf("Define '%s' at first use", short),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: true,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					req.URI: edits,
+				},
+			},
+		},
+	}
+}
+
+// maskNonProse blanks out commands, identifier arguments and math, keeping offsets intact
+func maskNonProse(line string) string {
+	return nonProsePattern.ReplaceAllStringFunc(line, func(match string) string {
+		return strings.Repeat(" ", len(match))
+	})
+}
+
+// stripInlineComment removes an unescaped % comment from the end of a line
+func stripInlineComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '%' {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// positionBefore reports whether (line, char) comes before (otherLine, otherChar)
+func positionBefore(line, char, otherLine, otherChar int) bool {
+	return line < otherLine || (line == otherLine && char < otherChar)
+}
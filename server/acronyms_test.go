@@ -0,0 +1,82 @@
+package server
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestAcronymDiagnostics tests detection of acronyms used before their definition
+func TestAcronymDiagnostics(t *testing.T) {
+	content := `\newacronym{gpu}{GPU}{graphics processing unit}
+We use NLP and GPUs here. % NLP in a comment
+See \ref{NLP} and $NLP$.
+Natural language processing (NLP) is the topic.
+NLP again, and CPU which is never defined.`
+
+	diagnostics := acronymDiagnostics(content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %+v", len(diagnostics), diagnostics)
+	}
+
+	diag := diagnostics[0]
+	if diag.Code != diagnosticCodeAcronymBeforeDefinition {
+		t.Errorf("unexpected code %v", diag.Code)
+	}
+	if diag.Range.Start.Line != 1 || diag.Range.Start.Character != 7 || diag.Range.End.Character != 10 {
+		t.Errorf("unexpected range %+v", diag.Range)
+	}
+}
+
+// TestDefineAcronymFix tests the quick fix that moves the definition to the first use
+func TestDefineAcronymFix(t *testing.T) {
+	content := `We use NLP here.
+Natural language processing (NLP) is the topic.`
+
+	req := &codeActionRequest{URI: "file:///note.tex", Content: content}
+	diagnostics := acronymDiagnostics(content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+	}
+
+	actions := defineAcronymFix(nil, req, diagnostics[0])
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(actions))
+	}
+
+	edits := actions[0].Edit.Changes[req.URI]
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got %d", len(edits))
+	}
+	if edits[0].NewText != "Natural language processing (NLP)" {
+		t.Errorf("expected long form expansion, got %q", edits[0].NewText)
+	}
+	expected := protocol.Range{
+		Start: protocol.Position{Line: 1, Character: 0},
+		End:   protocol.Position{Line: 1, Character: 33},
+	}
+	if edits[1].Range != expected || edits[1].NewText != "NLP" {
+		t.Errorf("expected later definition collapsed, got %+v", edits[1])
+	}
+}
+
+// TestAcronymLongForm tests matching initials against preceding words
+func TestAcronymLongForm(t *testing.T) {
+	tests := []struct {
+		text     string
+		short    string
+		expected string
+	}{
+		{"We study natural language processing ", "NLP", "natural language processing"},
+		{"the Department of Energy ", "DOE", "Department of Energy"},
+		{"the Bank of America ", "BA", "Bank of America"},
+		{"something unrelated ", "NLP", ""},
+	}
+
+	for _, tt := range tests {
+		long, _ := acronymLongForm(tt.text, tt.short)
+		if long != tt.expected {
+			t.Errorf("acronymLongForm(%q, %q) = %q, want %q", tt.text, tt.short, long, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// MethodActivity is the custom notification emitted for every journal entry
+const MethodActivity = "lx/activity"
+
+// activityFilename is the journal file kept at the vault root
+const activityFilename = "activity.log"
+
+// ActivityEntry describes a significant action performed by the server
+type ActivityEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail"`
+}
+
+// String formats the entry as a human-readable journal line
+func (e ActivityEntry) String() string {
+	return fmt.Sprintf("%s  %-16s %s", e.Time.Format(time.RFC3339), e.Action, e.Detail)
+}
+
+// Journal is an append-only activity log stored in the vault
+type Journal struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Append writes an entry to the end of the journal file
+func (j *Journal) Append(entry ActivityEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open activity journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, entry.String()); err != nil {
+		return fmt.Errorf("failed to write activity journal: %w", err)
+	}
+	return nil
+}
+
+// recordActivity journals an action and notifies the client about it
+// Both sinks are optional so handlers can call this unconditionally
+func (s *LanguageServer) recordActivity(ctx context.Context, action, detail string) {
+	entry := ActivityEntry{
+		Time:   time.Now(),
+		Action: action,
+		Detail: detail,
+	}
+
+	if s.journal != nil {
+		s.journal.Append(entry)
+	}
+
+	if s.conn != nil {
+		s.conn.Notify(ctx, MethodActivity, entry)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestJournal_Append tests that activity is appended to the vault journal
+func TestJournal_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), activityFilename)
+
+	ls := &LanguageServer{
+		journal: NewJournal(path),
+	}
+
+	ls.recordActivity(context.Background(), "index.rebuild", "indexed 2 notes")
+	ls.recordActivity(context.Background(), "rename.label", "eq:foo -> eq:bar")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 journal lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], "index.rebuild") || !strings.Contains(lines[0], "indexed 2 notes") {
+		t.Errorf("unexpected first entry: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "eq:foo -> eq:bar") {
+		t.Errorf("unexpected second entry: %s", lines[1])
+	}
+}
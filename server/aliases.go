@@ -0,0 +1,231 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// AliasIndex maps the alternative names notes declare in their aliases metadata to their slugs
+// Aliases are read with the note content rather than the header, so notes taken from the lx CLI
+// index keep them
+type AliasIndex struct {
+	mu      sync.RWMutex
+	slugs   map[string]map[string]bool // alias -> slugs of the notes declaring it
+	aliases map[string][]string        // slug -> aliases
+}
+
+func NewAliasIndex() *AliasIndex {
+	return &AliasIndex{
+		slugs:   make(map[string]map[string]bool),
+		aliases: make(map[string][]string),
+	}
+}
+
+// Set replaces the aliases of a note
+func (a *AliasIndex) Set(slug string, aliases []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.remove(slug)
+	var kept []string
+	for _, alias := range aliases {
+		alias = normalizeSlug(alias)
+		if alias == "" || alias == slug || a.slugs[alias][slug] {
+			continue
+		}
+		if a.slugs[alias] == nil {
+			a.slugs[alias] = make(map[string]bool)
+		}
+		a.slugs[alias][slug] = true
+		kept = append(kept, alias)
+	}
+	if len(kept) > 0 {
+		a.aliases[slug] = kept
+	}
+}
+
+// Delete removes the aliases of a note
+func (a *AliasIndex) Delete(slug string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.remove(slug)
+}
+
+// remove drops the aliases slug declared
+// Callers hold a.mu
+func (a *AliasIndex) remove(slug string) {
+	for _, alias := range a.aliases[slug] {
+		delete(a.slugs[alias], slug)
+		if len(a.slugs[alias]) == 0 {
+			delete(a.slugs, alias)
+		}
+	}
+	delete(a.aliases, slug)
+}
+
+// Resolve returns the slug of the note declaring alias
+// When several notes declare it, the first slug in sort order wins, so the answer does not depend
+// on the order notes were indexed in
+func (a *AliasIndex) Resolve(alias string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var first string
+	for slug := range a.slugs[alias] {
+		if first == "" || slug < first {
+			first = slug
+		}
+	}
+	return first, first != ""
+}
+
+// Get returns the aliases a note declares, in the order of its metadata
+func (a *AliasIndex) Get(slug string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]string(nil), a.aliases[slug]...)
+}
+
+// All returns every alias with the slug it resolves to
+func (a *AliasIndex) All() map[string]string {
+	a.mu.RLock()
+	aliases := make([]string, 0, len(a.slugs))
+	for alias := range a.slugs {
+		aliases = append(aliases, alias)
+	}
+	a.mu.RUnlock()
+
+	all := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		if slug, ok := a.Resolve(alias); ok {
+			all[alias] = slug
+		}
+	}
+	return all
+}
+
+// indexAliases reads the aliases metadata of a note's content into the alias index
+func (s *LanguageServer) indexAliases(slug, text string) {
+	var aliases []string
+	if result, err := s.metadataParser().Parse(text); err == nil {
+		aliases = result.Metadata.Aliases
+	}
+	s.setAliases(slug, aliases)
+}
+
+// setAliases replaces the aliases of a note, pointing the references to an alias it gained at it and
+// those to an alias it lost at whichever note still declares the alias, if any
+func (s *LanguageServer) setAliases(slug string, aliases []string) {
+	before := s.index.Aliases().Get(slug)
+	s.index.Aliases().Set(slug, aliases)
+	after := s.index.Aliases().Get(slug)
+	if slices.Equal(before, after) {
+		return
+	}
+
+	lost := make(map[string]bool, len(before))
+	for _, alias := range before {
+		lost[alias] = true
+	}
+	sources := make(map[string]bool)
+	for _, link := range s.index.Links().Incoming(slug) {
+		if lost[link.Alias] {
+			sources[link.Source] = true
+		}
+	}
+	for _, alias := range after {
+		for _, link := range s.index.Links().Incoming(alias) {
+			sources[link.Source] = true
+		}
+	}
+	s.relink(sources)
+}
+
+// relinkSlug re-resolves the references naming slug once a note using it appears or disappears,
+// as a slug wins over an alias of the same name
+func (s *LanguageServer) relinkSlug(slug string) {
+	owner, aliased := s.index.Aliases().Resolve(slug)
+	if !aliased {
+		return
+	}
+	sources := make(map[string]bool)
+	for _, link := range s.index.Links().Incoming(owner) {
+		if link.Alias == slug {
+			sources[link.Source] = true
+		}
+	}
+	for _, link := range s.index.Links().Incoming(slug) {
+		sources[link.Source] = true
+	}
+	s.relink(sources)
+}
+
+// relink resolves the indexed links of the source notes again, from the names they were written with
+func (s *LanguageServer) relink(sources map[string]bool) {
+	for source := range sources {
+		links := s.index.Links().Outgoing(source)
+		for i, link := range links {
+			if link.Alias != "" {
+				links[i].Target, links[i].Alias = link.Alias, ""
+			}
+		}
+		s.index.Links().Set(source, s.resolveLinks(links))
+	}
+}
+
+// noteLinks extracts the links of a note for the link index, with references by alias pointing at
+// the note declaring it, so backlinks, stats and the note graph count them
+func (s *LanguageServer) noteLinks(slug, filename, text string) []Link {
+	return s.resolveLinks(extractLinks(s.linkPattern(), slug, filename, text))
+}
+
+// resolveLinks points links naming an alias rather than a slug at the note declaring the alias,
+// keeping the range of the alias as written
+func (s *LanguageServer) resolveLinks(links []Link) []Link {
+	for i, link := range links {
+		if _, exists := s.index.Get(link.Target); exists {
+			continue
+		}
+		if slug, ok := s.index.Aliases().Resolve(link.Target); ok {
+			links[i].Target, links[i].Alias = slug, link.Target
+		}
+	}
+	return links
+}
+
+// aliasTarget returns the note an alias names, for references that match no slug
+// Slugs win over aliases, so an alias never hides a note
+func (s *LanguageServer) aliasTarget(ref string) (*NoteHeader, bool) {
+	slug, ok := s.index.Aliases().Resolve(ref)
+	if !ok {
+		return nil, false
+	}
+	return s.index.Get(slug)
+}
+
+// aliasRefCompletions offers the aliases of the indexed notes next to the notes' own slugs
+// sortTexts holds the sort text of each note's slug item
+func (s *LanguageServer) aliasRefCompletions(sortTexts map[string]string) []protocol.CompletionItem {
+	aliases := s.index.Aliases().All()
+	items := make([]protocol.CompletionItem, 0, len(aliases))
+	for alias, slug := range aliases {
+		if _, taken := s.index.Get(alias); taken {
+			continue
+		}
+		note, exists := s.index.Get(slug)
+		if !exists {
+			continue
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:      alias,
+			Kind:       protocol.CompletionItemKindReference,
+			Detail:     fmt.Sprintf("%s (alias of %s)", note.Title, slug),
+			InsertText: alias,
+			SortText:   sortTexts[slug] + "-" + alias,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].SortText < items[j].SortText })
+	return items
+}
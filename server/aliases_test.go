@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestNoteAliases tests completing, resolving and hovering references written with a note's alias
+func TestNoteAliases(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "%% Metadata\n%% title: Work\n\\ref{ft} \\ref{fourier-transform} \\ref{series}\n\\ref{f"
+	os.WriteFile(filepath.Join(tempDir, "20240101-fourier-transform.tex"), []byte("%% Metadata\n%% title: Fourier Transform\n%% aliases: ft, fourier\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "20240102-series.tex"), []byte("%% Metadata\n%% title: Series\n%% aliases: fourier-transform\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "20240103-work.tex"), []byte(content), 0644)
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.RebuildIndex(context.Background())
+
+	// An alias never hides the note using it as slug
+	for ref, want := range map[string]string{"ft": "fourier-transform", "fourier": "fourier-transform", "fourier-transform": "fourier-transform"} {
+		if note, exists := ls.resolveNote(ref, ""); !exists || note.Slug != want {
+			t.Errorf("expected %s to resolve to %s, got %+v", ref, want, note)
+		}
+	}
+
+	workURI := pathToURI(filepath.Join(tempDir, "20240103-work.tex"))
+	for _, diag := range ls.analyzeNoteDiagnostics(workURI, content) {
+		if diag.Code == diagnosticCodeBrokenRef {
+			t.Errorf("expected aliases to count as existing notes, got %s", diag.Message)
+		}
+	}
+
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: workURI},
+			Position:     protocol.Position{Line: 3, Character: 6},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+		if item.Label == "ft" && item.Detail != "Fourier Transform (alias of fourier-transform)" {
+			t.Errorf("unexpected alias detail %q", item.Detail)
+		}
+	}
+	if strings.Join(labels, ",") != "fourier-transform,fourier,ft" {
+		t.Errorf("expected the aliases after their note, got %v", labels)
+	}
+
+	hover, _ := ls.Hover(context.Background(), &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: workURI},
+			Position:     protocol.Position{Line: 2, Character: 6},
+		},
+	})
+	if hover == nil || !strings.Contains(hover.Contents.Value, "Slug: `fourier-transform` (referenced by its alias `ft`)") ||
+		!strings.Contains(hover.Contents.Value, "Aliases: ft, fourier") {
+		t.Errorf("expected the canonical slug in the hover, got %+v", hover)
+	}
+
+	ls.dropNote("fourier-transform", "", "20240101-fourier-transform.tex")
+	if _, exists := ls.resolveNote("ft", ""); exists {
+		t.Error("expected the aliases of a removed note to be dropped")
+	}
+}
+
+// TestAliasBacklinks tests that references by alias count as links to the note declaring the alias,
+// whichever note is indexed first, and follow the alias as it comes and goes
+func TestAliasBacklinks(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "20240101-notes.tex"), []byte("See \\ref{gt}."), 0644)
+	graphsPath := filepath.Join(tempDir, "20240102-graphs.tex")
+	os.WriteFile(graphsPath, []byte("%% Metadata\n%% title: Graphs\n%% aliases: gt\n"), 0644)
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.RebuildIndex(context.Background())
+
+	incoming := ls.index.Links().Incoming("graphs")
+	if len(incoming) != 1 || incoming[0].Source != "notes" || incoming[0].Alias != "gt" || incoming[0].Range != lineRange(0, 9, 11) {
+		t.Fatalf("expected the reference by alias as a backlink keeping its range, got %+v", incoming)
+	}
+	if orphans := ls.orphans(); len(orphans) != 1 || orphans[0].Slug != "notes" {
+		t.Errorf("expected only the referencing note to be an orphan, got %v", orphans)
+	}
+
+	// Dropping the alias leaves the reference dangling under the name it was written with
+	os.WriteFile(graphsPath, []byte("%% Metadata\n%% title: Graphs\n"), 0644)
+	ls.updateIndexForFile(graphsPath)
+	if len(ls.index.Links().Incoming("graphs")) != 0 || len(ls.index.Links().Incoming("gt")) != 1 {
+		t.Errorf("expected the reference to leave the note once the alias is gone")
+	}
+
+	// A note using the name as slug wins over an alias
+	os.WriteFile(graphsPath, []byte("%% Metadata\n%% title: Graphs\n%% aliases: gt\n"), 0644)
+	ls.updateIndexForFile(graphsPath)
+	gtPath := filepath.Join(tempDir, "20240103-gt.tex")
+	os.WriteFile(gtPath, []byte("%% Metadata\n%% title: Graph Theory\n"), 0644)
+	ls.updateIndexForFile(gtPath)
+	if incoming := ls.index.Links().Incoming("gt"); len(incoming) != 1 || incoming[0].Alias != "" {
+		t.Errorf("expected the reference to point at the note using the slug, got %+v", incoming)
+	}
+	os.Remove(gtPath)
+	ls.updateIndexForFile(gtPath)
+	if incoming := ls.index.Links().Incoming("graphs"); len(incoming) != 1 || incoming[0].Alias != "gt" {
+		t.Errorf("expected the reference to fall back to the alias, got %+v", incoming)
+	}
+}
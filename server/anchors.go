@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+func init() {
+	registerCodeActionProvider(anchorLabelAction)
+}
+
+// sectionAnchor is a section heading an anchor resolves to
+type sectionAnchor struct {
+	heading string
+	line    int
+	end     int // character just past the sectioning command
+}
+
+// findSectionAnchor looks up the section of content an anchor names, either through a \label
+// or by its heading in kebab case; labeled is true when a \label already makes the anchor durable
+func findSectionAnchor(content, anchor string) (section sectionAnchor, found, labeled bool) {
+	for lineNum, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		for _, match := range labelPattern.FindAllStringSubmatch(line, -1) {
+			if strings.TrimSpace(match[1]) == anchor {
+				return sectionAnchor{}, true, true
+			}
+		}
+		if found {
+			continue
+		}
+		if match := sectionPattern.FindStringSubmatchIndex(line); match != nil {
+			heading := strings.TrimSpace(line[match[4]:match[5]])
+			if kebabCase(heading) == kebabCase(anchor) {
+				section = sectionAnchor{heading: heading, line: lineNum, end: match[1]}
+				found = true
+			}
+		}
+	}
+	return section, found, false
+}
+
+// anchorLabelAction offers to label the section a slug#section reference points at
+// Anchors resolved only by heading break when the heading changes; a \label keeps them working
+func anchorLabelAction(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+	lines := strings.Split(req.Content, "\n")
+	for lineNum := int(req.Range.Start.Line); lineNum <= int(req.Range.End.Line) && lineNum < len(lines); lineNum++ {
+		line := lines[lineNum]
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		for _, match := range s.linkPattern().FindAllStringSubmatchIndex(line, -1) {
+			// Only references touching the requested range
+			if lineNum == int(req.Range.Start.Line) && match[1] < int(req.Range.Start.Character) ||
+				lineNum == int(req.Range.End.Line) && match[0] > int(req.Range.End.Character) {
+				continue
+			}
+			if action, ok := s.anchorLabelEdit(line[match[2]:match[3]]); ok {
+				actions = append(actions, action)
+			}
+		}
+	}
+	return actions
+}
+
+// anchorLabelEdit builds the action inserting \label{anchor} after the target section's heading
+func (s *LanguageServer) anchorLabelEdit(raw string) (protocol.CodeAction, bool) {
+	_, anchor := splitAnchor(raw)
+	slug := normalizeSlug(raw)
+	if anchor == "" || slug == "" || strings.ContainsAny(anchor, "{},") {
+		return protocol.CodeAction{}, false
+	}
+	note, exists := s.resolveNote(slug, "")
+	if !exists {
+		return protocol.CodeAction{}, false
+	}
+
+	uri := pathToURI(s.headerPath(note))
+	content, err := s.GetDocument(uri)
+	if err != nil {
+		return protocol.CodeAction{}, false
+	}
+	section, found, labeled := findSectionAnchor(content, anchor)
+	if !found || labeled {
+		return protocol.CodeAction{}, false
+	}
+
+	return protocol.CodeAction{
+		Title: fmt.Sprintf("Add \\label{%s} to section '%s' in %s", anchor, section.heading, slug),
+		Kind:  protocol.RefactorRewrite,
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				uri: {{
+					Range:   lineRange(section.line, section.end, section.end),
+					NewText: fmt.Sprintf("\\label{%s}", anchor),
+				}},
+			},
+		},
+	}, true
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestAnchorLabelAction tests labeling the section a slug#section reference points at
+func TestAnchorLabelAction(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "20240101-graphs.tex")
+	os.WriteFile(target, []byte("\\section{Intro}\n\\subsection{Some Section}\ntext\n\\section{Proofs}\\label{proofs}"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "20240102-paper.tex"), []byte(""), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	content := "See \\ref{graphs#some-section} and \\ref{graphs#proofs} or \\ref{graphs#missing}."
+	req := &codeActionRequest{
+		URI:     "file:///paper.tex",
+		Content: content,
+		Range:   protocol.Range{End: protocol.Position{Character: uint32(len(content))}},
+	}
+	actions := anchorLabelAction(ls, req)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %+v", actions)
+	}
+	edit := actions[0].Edit.Changes[pathToURI(target)]
+	if len(edit) != 1 || edit[0].NewText != "\\label{some-section}" || edit[0].Range != lineRange(1, 25, 25) {
+		t.Errorf("unexpected edit %+v", edit)
+	}
+
+	// Only references in the requested range
+	req.Range = lineRange(0, 0, 3)
+	if actions := anchorLabelAction(ls, req); len(actions) != 0 {
+		t.Errorf("expected no actions away from the reference, got %+v", actions)
+	}
+}
+
+// TestAnchoredLinks tests that anchors are not part of the referenced slug
+func TestAnchoredLinks(t *testing.T) {
+	links := extractLinks(macroPattern(builtinLinkMacros, `\{([^}]+)\}`), "paper", "paper.tex", `\ref{graphs#some-section}`)
+	if len(links) != 1 || links[0].Target != "graphs" || links[0].Range != lineRange(0, 5, 11) {
+		t.Errorf("unexpected links %+v", links)
+	}
+}
@@ -0,0 +1,333 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeMissingAsset marks an \includegraphics whose file is not in the assets directory
+const diagnosticCodeMissingAsset = "LX014"
+
+// graphicsCompletionPattern matches a line prefix inside the file argument of \includegraphics
+var graphicsCompletionPattern = regexp.MustCompile(`\\includegraphics(?:\[[^\]]*\])?\{([^}]*)$`)
+
+// imagePreviewExtensions are the asset types hovers can show inline
+var imagePreviewExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true}
+
+// AssetFile is a file of the vault's assets directory
+type AssetFile struct {
+	Name     string // path relative to the assets directory, with forward slashes
+	Path     string
+	Size     int64
+	Modified time.Time
+}
+
+// AssetIndex lists the files of the assets directory, so \includegraphics completion, diagnostics
+// and hovers do not stat the disk on every request
+type AssetIndex struct {
+	mu    sync.RWMutex
+	root  string
+	files map[string]*AssetFile // name -> file
+}
+
+func NewAssetIndex() *AssetIndex {
+	return &AssetIndex{files: make(map[string]*AssetFile)}
+}
+
+// assetName is the key of a file inside root, or "" when it lies outside
+func assetName(root, path string) string {
+	if root == "" || !inTree(path, root) {
+		return ""
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Scan replaces the index with the files below root, skipping hidden files and folders
+// A missing root leaves the index empty
+func (a *AssetIndex) Scan(root string) {
+	files := make(map[string]*AssetFile)
+	if root != "" {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				name := assetName(root, path)
+				files[name] = &AssetFile{Name: name, Path: path, Size: info.Size(), Modified: info.ModTime()}
+			}
+			return nil
+		})
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.root = root
+	a.files = files
+}
+
+// Update indexes a file or folder created or written inside the assets directory, re-reading it from disk
+func (a *AssetIndex) Update(path string) {
+	a.mu.RLock()
+	root := a.root
+	a.mu.RUnlock()
+	name := assetName(root, path)
+	if name == "" || strings.HasPrefix(filepath.Base(path), ".") {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		a.Remove(path)
+		return
+	}
+	if !info.IsDir() {
+		a.mu.Lock()
+		a.files[name] = &AssetFile{Name: name, Path: path, Size: info.Size(), Modified: info.ModTime()}
+		a.mu.Unlock()
+		return
+	}
+
+	// A folder moved into the assets directory arrives with its files
+	scanned := NewAssetIndex()
+	scanned.Scan(path)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, file := range scanned.files {
+		file.Name = name + "/" + file.Name
+		a.files[file.Name] = file
+	}
+}
+
+// Remove forgets a file, or every file of a folder, deleted from the assets directory
+func (a *AssetIndex) Remove(path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	name := assetName(a.root, path)
+	if name == "" {
+		return
+	}
+	for key := range a.files {
+		if key == name || strings.HasPrefix(key, name+"/") {
+			delete(a.files, key)
+		}
+	}
+}
+
+// Get returns the file indexed under name, relative to the assets directory
+func (a *AssetIndex) Get(name string) (*AssetFile, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	file, ok := a.files[filepath.ToSlash(filepath.Clean(name))]
+	return file, ok
+}
+
+// Lookup returns the indexed file at an absolute path
+func (a *AssetIndex) Lookup(path string) (*AssetFile, bool) {
+	a.mu.RLock()
+	name := assetName(a.root, path)
+	a.mu.RUnlock()
+	if name == "" {
+		return nil, false
+	}
+	return a.Get(name)
+}
+
+// Contains reports whether path is inside the indexed assets directory
+func (a *AssetIndex) Contains(path string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.root != "" && inTree(path, a.root)
+}
+
+// All returns every indexed file, sorted by name
+func (a *AssetIndex) All() []*AssetFile {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	files := make([]*AssetFile, 0, len(a.files))
+	for _, file := range a.files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// Count returns the number of indexed files
+func (a *AssetIndex) Count() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.files)
+}
+
+// indexAssets reads the assets directory into the asset index
+func (s *LanguageServer) indexAssets() {
+	if s.vault == nil {
+		return
+	}
+	s.index.Assets().Scan(s.vault.AssetsPath)
+}
+
+// assetEvent follows files added to, changed in or removed from the assets directory
+// Open notes are checked again when an asset appears or disappears, for their missing-asset diagnostics
+func (s *LanguageServer) assetEvent(ctx context.Context, watcher *fsnotify.Watcher, event fsnotify.Event) {
+	assets := s.index.Assets()
+	if !assets.Contains(event.Name) || event.Op == fsnotify.Chmod {
+		return
+	}
+
+	switch {
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		unwatchTree(watcher, event.Name)
+		assets.Remove(event.Name)
+	case event.Has(fsnotify.Create):
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			watchTree(watcher, event.Name)
+		}
+		assets.Update(event.Name)
+	default:
+		// Writes change the size shown in hovers, not which files exist
+		assets.Update(event.Name)
+		return
+	}
+	s.republishOpenDocuments(ctx)
+}
+
+// resolveAsset finds the file an \includegraphics argument refers to
+// Paths in the assets directory are answered from the asset index, falling back to the disk for
+// files it does not know yet; others, such as absolute paths elsewhere, are looked up on disk
+// Returns an empty string if no matching file exists
+func (s *LanguageServer) resolveAsset(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	candidates := []string{name}
+	if filepath.Ext(name) == "" {
+		for _, ext := range graphicsExtensions {
+			candidates = append(candidates, name+ext)
+		}
+	}
+
+	assets := s.index.Assets()
+	for _, candidate := range candidates {
+		paths := []string{candidate}
+		if !filepath.IsAbs(candidate) {
+			// Relative to the assets directory, or to the notes directory ("../assets/x.png")
+			paths = []string{s.vault.GetAssetPath(candidate), filepath.Join(s.vault.NotesPath, candidate)}
+		}
+		for _, path := range paths {
+			if file, ok := assets.Lookup(path); ok {
+				return file.Path
+			}
+			// Files the watcher has not reported, because watching is off or the assets
+			// directory was created after startup, are found on disk and indexed from then on
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				if assets.Contains(path) {
+					assets.Update(path)
+				}
+				return path
+			}
+		}
+	}
+
+	return ""
+}
+
+// graphicsCompletions offers the files of the assets directory inside \includegraphics{
+func (s *LanguageServer) graphicsCompletions(linePrefix string) []protocol.CompletionItem {
+	matches := graphicsCompletionPattern.FindStringSubmatch(linePrefix)
+	if matches == nil {
+		return nil
+	}
+	typed := matches[1]
+
+	items := []protocol.CompletionItem{}
+	for _, file := range s.index.Assets().All() {
+		items = append(items, protocol.CompletionItem{
+			Label:  file.Name,
+			Kind:   protocol.CompletionItemKindFile,
+			Detail: formatBytes(int(file.Size)),
+		})
+	}
+	return filterCompletions(items, typed)
+}
+
+// missingAssetDiagnostics reports \includegraphics arguments no file answers to
+func (s *LanguageServer) missingAssetDiagnostics(content string) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	for lineNum, line := range s.documentLines(content) {
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		for _, match := range graphicsPattern.FindAllStringSubmatchIndex(line, -1) {
+			name := strings.TrimSpace(line[match[2]:match[3]])
+			if s.resolveAsset(name) != "" {
+				continue
+			}
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    lineRange(lineNum, match[2], match[3]),
+				Severity: protocol.DiagnosticSeverityWarning,
+				Code:     diagnosticCodeMissingAsset,
+				Message:  fmt.Sprintf("Asset '%s' not found in the assets directory", name),
+				Source:   "lx-ls",
+			})
+		}
+	}
+	return diagnostics
+}
+
+// assetHover describes the file of the \includegraphics under the cursor, with a preview for images
+func (s *LanguageServer) assetHover(content string, pos protocol.Position) *protocol.Hover {
+	lines := s.documentLines(content)
+	if int(pos.Line) >= len(lines) {
+		return nil
+	}
+	line := lines[pos.Line]
+	for _, match := range graphicsPattern.FindAllStringSubmatchIndex(line, -1) {
+		if int(pos.Character) < match[2] || int(pos.Character) > match[3] {
+			continue
+		}
+		name := strings.TrimSpace(line[match[2]:match[3]])
+		path := s.resolveAsset(name)
+		if path == "" {
+			return nil
+		}
+
+		text := fmt.Sprintf("**%s**", filepath.Base(path))
+		if file, ok := s.index.Assets().Lookup(path); ok {
+			text += fmt.Sprintf("\n\nAsset `%s`, %s, modified %s", file.Name, formatBytes(int(file.Size)), file.Modified.Format("2006-01-02"))
+		}
+		if imagePreviewExtensions[strings.ToLower(filepath.Ext(path))] {
+			text += fmt.Sprintf("\n\n![%s](%s)", filepath.Base(path), pathToURI(path))
+		}
+		hoverRange := lineRange(int(pos.Line), match[2], match[3])
+		return &protocol.Hover{
+			Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: text},
+			Range:    &hoverRange,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestAssetIndex tests serving \includegraphics completion, diagnostics and hovers from the asset index
+func TestAssetIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	assetsPath := filepath.Join(tempDir, "assets")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(filepath.Join(assetsPath, "figures"), 0755)
+	os.MkdirAll(filepath.Join(assetsPath, ".thumbs"), 0755)
+	os.WriteFile(filepath.Join(assetsPath, "figures", "tree.png"), []byte("png data"), 0644)
+	os.WriteFile(filepath.Join(assetsPath, "plot.pdf"), []byte("pdf"), 0644)
+	os.WriteFile(filepath.Join(assetsPath, ".thumbs", "tree.png"), []byte("thumb"), 0644)
+
+	content := "%% Metadata\n% title: Trees\n\\includegraphics[width=5cm]{figures/}\n\\includegraphics{figures/tree}\n\\includegraphics{missing.png}"
+	uri := pathToURI(filepath.Join(notesPath, "20240101-trees.tex"))
+	os.WriteFile(uriToPath(uri), []byte(content), 0644)
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath, AssetsPath: assetsPath},
+		index:     NewIndex(),
+		documents: map[protocol.DocumentURI]string{uri: content},
+	}
+	ls.RebuildIndex(context.Background())
+
+	if names := assetNames(ls.index.Assets().All()); names != "figures/tree.png,plot.pdf" {
+		t.Errorf("expected hidden files to be skipped, got %s", names)
+	}
+
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 2, Character: 36},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Label != "figures/tree.png" || list.Items[0].Detail != "8 bytes" {
+		t.Errorf("expected the figures to be completed, got %+v", list.Items)
+	}
+
+	var missing []string
+	for _, diag := range ls.analyzeNoteDiagnostics(uri, content) {
+		if diag.Code == diagnosticCodeMissingAsset {
+			missing = append(missing, diag.Message)
+		}
+	}
+	if len(missing) != 2 || !strings.Contains(missing[0], "'figures/'") || !strings.Contains(missing[1], "'missing.png'") {
+		t.Errorf("unexpected missing asset diagnostics %q", missing)
+	}
+
+	hover := ls.assetHover(content, protocol.Position{Line: 3, Character: 20})
+	if hover == nil {
+		t.Fatal("expected a hover on the figure")
+	}
+	text := hover.Contents.Value
+	if !strings.Contains(text, "Asset `figures/tree.png`, 8 bytes") || !strings.Contains(text, "![tree.png](file://") {
+		t.Errorf("unexpected asset hover %q", text)
+	}
+
+	// Files added and removed on disk follow the watcher events
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer watcher.Close()
+	os.WriteFile(filepath.Join(assetsPath, "missing.png"), []byte("png"), 0644)
+	ls.assetEvent(context.Background(), watcher, fsnotify.Event{Name: filepath.Join(assetsPath, "missing.png"), Op: fsnotify.Create})
+	os.RemoveAll(filepath.Join(assetsPath, "figures"))
+	ls.assetEvent(context.Background(), watcher, fsnotify.Event{Name: filepath.Join(assetsPath, "figures"), Op: fsnotify.Remove})
+	if names := assetNames(ls.index.Assets().All()); names != "missing.png,plot.pdf" {
+		t.Errorf("expected the index to follow the events, got %s", names)
+	}
+}
+
+// assetNames lists the names of asset files
+func assetNames(files []*AssetFile) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// TestResolveUnindexedAsset tests that assets the watcher never reported are found on disk
+func TestResolveUnindexedAsset(t *testing.T) {
+	tempDir := t.TempDir()
+	assetsPath := filepath.Join(tempDir, "assets")
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: filepath.Join(tempDir, "notes"), AssetsPath: assetsPath},
+		index: NewIndex(),
+	}
+	// The assets directory does not exist yet when the index is built
+	ls.indexAssets()
+
+	os.MkdirAll(assetsPath, 0755)
+	os.WriteFile(filepath.Join(assetsPath, "plot.png"), []byte("png"), 0644)
+	if path := ls.resolveAsset("plot"); path != filepath.Join(assetsPath, "plot.png") {
+		t.Fatalf("expected the file on disk, got %q", path)
+	}
+	if _, ok := ls.index.Assets().Get("plot.png"); !ok {
+		t.Error("expected the file to be indexed once found")
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandListOrphans lists the notes no other note references
+// Arguments: none
+const commandListOrphans = "lx.listOrphans"
+
+func init() {
+	registerCommand(commandListOrphans, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.orphans(), nil
+	})
+}
+
+// OrphanNote is a note in the lx.listOrphans result
+type OrphanNote struct {
+	Slug  string               `json:"slug"`
+	Title string               `json:"title"`
+	URI   protocol.DocumentURI `json:"uri"`
+}
+
+// orphans returns the notes without backlinks, sorted by slug
+// References from a note to itself do not count
+func (s *LanguageServer) orphans() []OrphanNote {
+	orphans := []OrphanNote{}
+	graph := s.index.Graph()
+	for _, note := range s.index.All() {
+		if len(graph.Incoming(note.Slug)) > 0 {
+			continue
+		}
+		orphans = append(orphans, OrphanNote{
+			Slug:  note.Slug,
+			Title: note.Title,
+			URI:   pathToURI(s.notePath(note.Filename)),
+		})
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Slug < orphans[j].Slug })
+	return orphans
+}
+
+// backlinkSummary describes the references to a note, e.g. "3 backlinks from 2 notes"
+// Returns an empty string when no other note references it
+func (s *LanguageServer) backlinkSummary(slug string) string {
+	references, notes := s.backlinkCounts(slug)
+	switch {
+	case references == 0:
+		return ""
+	case notes == 1:
+		return fmt.Sprintf("%s from 1 note", pluralBacklinks(references))
+	default:
+		return fmt.Sprintf("%s from %d notes", pluralBacklinks(references), notes)
+	}
+}
+
+// pluralBacklinks formats a number of backlinks, e.g. "1 backlink" or "3 backlinks"
+func pluralBacklinks(n int) string {
+	if n == 1 {
+		return "1 backlink"
+	}
+	return fmt.Sprintf("%d backlinks", n)
+}
+
+// backlinkLenses labels the first line of a note with the notes referencing it
+// Orphans get a label too, so they stand out while editing
+func (s *LanguageServer) backlinkLenses(docURI protocol.DocumentURI, content string) []protocol.CodeLens {
+	slug := s.parseFilenameToSlug(filepath.Base(uriToPath(docURI)))
+	if _, exists := s.index.Get(slug); !exists {
+		return nil
+	}
+
+	title := s.backlinkSummary(slug)
+	if title == "" {
+		title = "No backlinks"
+	}
+	firstLine := strings.SplitN(content, "\n", 2)[0]
+	return []protocol.CodeLens{{
+		// An empty command renders as a plain label
+		Range:   lineRange(0, 0, len(firstLine)),
+		Command: &protocol.Command{Title: title},
+	}}
+}
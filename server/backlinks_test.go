@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestBacklinksFollowOpenBuffers tests that unsaved references count as backlinks until the buffer is closed
+func TestBacklinksFollowOpenBuffers(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n",
+		"20240102-trees.tex":  "%% Metadata\n% title: Trees\nSee \\ref{graphs} and \\ref{trees}.",
+		"20240103-paths.tex":  "%% Metadata\n% title: Paths\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	orphans := ls.orphans()
+	if len(orphans) != 2 || orphans[0].Slug != "paths" || orphans[1].Slug != "trees" {
+		t.Fatalf("expected paths and the self-referencing trees to be orphans, got %+v", orphans)
+	}
+
+	uri := pathToURI(filepath.Join(tempDir, "20240103-paths.tex"))
+	ls.mu.Lock()
+	ls.documents[uri] = files["20240103-paths.tex"] + "Walks in \\ref{graphs}, \\ref{graphs} and \\ref{trees}."
+	ls.mu.Unlock()
+	ls.reindexDocument(uri)
+
+	if summary := ls.backlinkSummary("graphs"); summary != "3 backlinks from 2 notes" {
+		t.Errorf("expected the buffer's references to count, got %q", summary)
+	}
+	if orphans := ls.orphans(); len(orphans) != 1 || orphans[0].Slug != "paths" {
+		t.Errorf("expected only paths to be an orphan, got %+v", orphans)
+	}
+
+	lenses := ls.backlinkLenses(pathToURI(filepath.Join(tempDir, "20240102-trees.tex")), files["20240102-trees.tex"])
+	if len(lenses) != 1 || lenses[0].Command.Title != "1 backlink from 1 note" {
+		t.Errorf("unexpected backlink lens %+v", lenses)
+	}
+	lenses = ls.backlinkLenses(uri, files["20240103-paths.tex"])
+	if len(lenses) != 1 || lenses[0].Command.Title != "No backlinks" {
+		t.Errorf("unexpected orphan lens %+v", lenses)
+	}
+
+	// Closing the buffer discards the unsaved references
+	ls.mu.Lock()
+	delete(ls.documents, uri)
+	ls.mu.Unlock()
+	ls.reindexDocument(uri)
+	if summary := ls.backlinkSummary("graphs"); summary != "1 backlink from 1 note" {
+		t.Errorf("expected the file's references after closing, got %q", summary)
+	}
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+)
+
+// citeCompletionPattern matches a line prefix inside the keys of \cite, \citep or \citet, after the
+// optional pre- and postnote arguments
+var citeCompletionPattern = regexp.MustCompile(`\\cite[pt]?\*?(?:\[[^\]]*\]){0,2}\{([^}]*)$`)
+
+// citeKeysPattern matches a complete \cite, \citep or \citet with its keys
+var citeKeysPattern = regexp.MustCompile(`\\cite[pt]?\*?(?:\[[^\]]*\]){0,2}\{([^}]*)\}`)
+
+// bibliographyPattern matches the bibliography files a note names, \bibliography{refs,more} or
+// \addbibresource{refs.bib}
+var bibliographyPattern = regexp.MustCompile(`\\(?:bibliography|addbibresource)(?:\[[^\]]*\])?\{([^}]*)\}`)
+
+// BibEntry is an entry of a BibTeX file
+type BibEntry struct {
+	Key    string
+	Type   string // entry type, lowercased, e.g. article
+	Author string
+	Year   string
+	Title  string
+	Path   string // .bib file holding the entry
+}
+
+// BibIndex caches the entries of the BibTeX files read so far, reading a file again once its
+// modification time or size changes
+type BibIndex struct {
+	mu    sync.Mutex
+	files map[string]*bibFile // path -> entries
+}
+
+// bibFile is a BibTeX file as read
+type bibFile struct {
+	modified time.Time
+	size     int64
+	entries  []BibEntry
+}
+
+func NewBibIndex() *BibIndex {
+	return &BibIndex{files: make(map[string]*bibFile)}
+}
+
+// Entries returns the entries of a BibTeX file, nil when it cannot be read
+func (b *BibIndex) Entries(path string) []BibEntry {
+	info, err := os.Stat(path)
+	if err != nil {
+		b.mu.Lock()
+		delete(b.files, path)
+		b.mu.Unlock()
+		return nil
+	}
+
+	b.mu.Lock()
+	cached, ok := b.files[path]
+	b.mu.Unlock()
+	if ok && cached.modified.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.entries
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	entries := parseBibTeX(string(data))
+	for i := range entries {
+		entries[i].Path = path
+	}
+	b.mu.Lock()
+	b.files[path] = &bibFile{modified: info.ModTime(), size: info.Size(), entries: entries}
+	b.mu.Unlock()
+	return entries
+}
+
+// parseBibTeX reads the entries of a BibTeX file, skipping @string, @preamble and @comment blocks
+// and entries it cannot make sense of
+func parseBibTeX(text string) []BibEntry {
+	var entries []BibEntry
+	for i := 0; i < len(text); i++ {
+		if text[i] != '@' {
+			continue
+		}
+		open := strings.IndexAny(text[i:], "{(")
+		if open < 0 {
+			break
+		}
+		entryType := strings.ToLower(strings.TrimSpace(text[i+1 : i+open]))
+		body, end := bibBlock(text, i+open)
+		i = end
+		if entryType == "" || entryType == "string" || entryType == "preamble" || entryType == "comment" {
+			continue
+		}
+
+		key, fields, _ := strings.Cut(body, ",")
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t\n=") {
+			continue
+		}
+		entry := BibEntry{Key: key, Type: entryType}
+		for name, value := range bibFields(fields) {
+			switch name {
+			case "author":
+				entry.Author = value
+			case "editor":
+				if entry.Author == "" {
+					entry.Author = value
+				}
+			case "title":
+				entry.Title = value
+			case "year":
+				entry.Year = value
+			case "date":
+				if entry.Year == "" && len(value) >= 4 {
+					entry.Year = value[:4]
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// bibBlock returns the content of the block opening at start, with '{' or '(', and the index of
+// its closing delimiter, or the end of text when it is not closed
+func bibBlock(text string, start int) (string, int) {
+	closing := byte('}')
+	if text[start] == '(' {
+		closing = ')'
+	}
+	depth := 0
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case text[i] == '{':
+			depth++
+		case text[i] == '}' && depth > 0:
+			depth--
+		case text[i] == closing && depth == 0:
+			return text[start+1 : i], i
+		}
+	}
+	return text[start+1:], len(text)
+}
+
+// bibFields reads the name = value pairs of an entry, values braced, quoted or bare
+// Names are lowercased; values lose their braces and runs of whitespace
+func bibFields(text string) map[string]string {
+	fields := make(map[string]string)
+	for len(text) > 0 {
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			break
+		}
+		name := strings.ToLower(strings.Trim(text[:eq], " \t\r\n,"))
+		rest := strings.TrimLeft(text[eq+1:], " \t\r\n")
+		var value string
+		switch {
+		case strings.HasPrefix(rest, "{"):
+			value, eq = bibBlock(rest, 0)
+			rest = rest[min(eq+1, len(rest)):]
+		case strings.HasPrefix(rest, `"`):
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value, rest = rest[1:end+1], rest[min(end+2, len(rest)):]
+		default:
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		fields[name] = strings.Join(strings.Fields(strings.NewReplacer("{", "", "}", "").Replace(value)), " ")
+		text = rest
+	}
+	return fields
+}
+
+// bibliographyFiles lists the BibTeX files a note cites from: those it names with \bibliography or
+// \addbibresource, found next to the note, in the vault root or in the assets directory, then the
+// other .bib files of the vault root and the assets directory
+func (s *LanguageServer) bibliographyFiles(uri protocol.DocumentURI, content string) []string {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path = filepath.Clean(path); !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	dirs := []string{s.vault.RootPath, s.vault.AssetsPath}
+	if uri != "" {
+		dirs = append([]string{filepath.Dir(uriToPath(uri))}, dirs...)
+	}
+	for _, match := range bibliographyPattern.FindAllStringSubmatch(content, -1) {
+		for _, name := range strings.Split(match[1], ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if !strings.HasSuffix(name, ".bib") {
+				name += ".bib"
+			}
+			if filepath.IsAbs(name) {
+				add(name)
+				continue
+			}
+			for _, dir := range dirs {
+				if path := filepath.Join(dir, name); dir != "" && fileExists(path) {
+					add(path)
+					break
+				}
+			}
+		}
+	}
+
+	if s.vault.RootPath != "" {
+		if entries, err := os.ReadDir(s.vault.RootPath); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".bib") {
+					add(filepath.Join(s.vault.RootPath, entry.Name()))
+				}
+			}
+		}
+	}
+	for _, file := range s.index.Assets().All() {
+		if strings.HasSuffix(file.Name, ".bib") {
+			add(file.Path)
+		}
+	}
+	return files
+}
+
+// fileExists reports whether path names a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// bibEntries returns the entries a note can cite by key; a key defined in several files comes from
+// the first of bibliographyFiles
+func (s *LanguageServer) bibEntries(uri protocol.DocumentURI, content string) map[string]BibEntry {
+	entries := make(map[string]BibEntry)
+	for _, path := range s.bibliographyFiles(uri, content) {
+		for _, entry := range s.index.Bib().Entries(path) {
+			if _, defined := entries[entry.Key]; !defined {
+				entries[entry.Key] = entry
+			}
+		}
+	}
+	return entries
+}
+
+// citeCompletions offers the citation keys of the bibliography inside \cite, \citep and \citet,
+// leaving out the keys already in the argument
+func (s *LanguageServer) citeCompletions(uri protocol.DocumentURI, content, linePrefix string) []protocol.CompletionItem {
+	match := citeCompletionPattern.FindStringSubmatch(linePrefix)
+	if match == nil {
+		return nil
+	}
+	keys := strings.Split(match[1], ",")
+	typed := strings.TrimSpace(keys[len(keys)-1])
+	present := make(map[string]bool)
+	for _, key := range keys[:len(keys)-1] {
+		present[strings.TrimSpace(key)] = true
+	}
+
+	entries := s.bibEntries(uri, content)
+	items := make([]protocol.CompletionItem, 0, len(entries))
+	for key, entry := range entries {
+		if present[key] {
+			continue
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:         key,
+			Kind:          protocol.CompletionItemKindReference,
+			Detail:        citeDetail(entry),
+			Documentation: citeDocumentation(entry),
+			InsertText:    key,
+			SortText:      key,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return filterCompletions(items, typed)
+}
+
+// citeDetail summarizes an entry as authors and year, e.g. "Knuth (1984)" or "Cormen et al. (2009)"
+func citeDetail(entry BibEntry) string {
+	authors := strings.Split(entry.Author, " and ")
+	detail := bibLastName(authors[0])
+	if len(authors) == 2 {
+		detail += " and " + bibLastName(authors[1])
+	} else if len(authors) > 2 {
+		detail += " et al."
+	}
+	if entry.Year != "" {
+		detail = strings.TrimSpace(fmt.Sprintf("%s (%s)", detail, entry.Year))
+	}
+	if detail == "" {
+		return entry.Title
+	}
+	return detail
+}
+
+// bibLastName returns the last name of a BibTeX author, written "Last, First" or "First Last"
+func bibLastName(author string) string {
+	author = strings.TrimSpace(author)
+	if last, _, found := strings.Cut(author, ","); found {
+		return strings.TrimSpace(last)
+	}
+	words := strings.FieldsFunc(author, unicode.IsSpace)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// citeDocumentation describes an entry in full for the completion item
+func citeDocumentation(entry BibEntry) protocol.MarkupContent {
+	var lines []string
+	if entry.Title != "" {
+		lines = append(lines, fmt.Sprintf("**%s**", entry.Title))
+	}
+	if byline := strings.TrimSpace(strings.Join([]string{entry.Author, entry.Year}, " ")); byline != "" {
+		lines = append(lines, strings.ReplaceAll(byline, " and ", ", "))
+	}
+	lines = append(lines, fmt.Sprintf("`@%s` in `%s`", entry.Type, filepath.Base(entry.Path)))
+	return protocol.MarkupContent{Kind: protocol.Markdown, Value: strings.Join(lines, "\n\n")}
+}
+
+// citesBibliography reports whether every key of a \cite argument is an entry of the note's
+// bibliography; entries are read once per check, into *entries
+func (s *LanguageServer) citesBibliography(uri protocol.DocumentURI, content, keys string, entries *map[string]BibEntry) bool {
+	if *entries == nil {
+		*entries = s.bibEntries(uri, content)
+	}
+	for _, key := range strings.Split(keys, ",") {
+		if _, ok := (*entries)[strings.TrimSpace(key)]; !ok {
+			return false
+		}
+	}
+	return true
+}
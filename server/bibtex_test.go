@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestParseBibTeX tests reading entries with braced, quoted and bare values
+func TestParseBibTeX(t *testing.T) {
+	entries := parseBibTeX(`@string{acm = "ACM"}
+@Book{knuth1984,
+  author = {Knuth, Donald E.},
+  title  = {The {\TeX}book},
+  year   = 1984,
+}
+@article(turing1936, author = "Alan Turing", title = "On Computable Numbers", date = {1936-11-12})
+@comment{ignored}
+`)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if got := entries[0]; got.Key != "knuth1984" || got.Type != "book" || got.Author != "Knuth, Donald E." ||
+		got.Title != `The \TeXbook` || got.Year != "1984" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+	if got := entries[1]; got.Key != "turing1936" || got.Author != "Alan Turing" || got.Year != "1936" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+	if detail := citeDetail(BibEntry{Author: "Cormen, T. and Leiserson, C. and Rivest, R.", Year: "2009"}); detail != "Cormen et al. (2009)" {
+		t.Errorf("unexpected detail %q", detail)
+	}
+}
+
+// TestCompletion_Cite tests completing citation keys from the bibliography of a note
+func TestCompletion_Cite(t *testing.T) {
+	v := vaultAt(t.TempDir())
+	os.MkdirAll(v.NotesPath, 0755)
+	os.WriteFile(filepath.Join(v.RootPath, "refs.bib"), []byte(`@book{knuth1984, author = {Donald Knuth}, title = {The TeXbook}, year = {1984}}
+@article{turing1936, author = {Alan Turing}, title = {On Computable Numbers}, year = {1936}}
+`), 0644)
+	ls := &LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+
+	testFile := filepath.Join(v.NotesPath, "test.tex")
+	content := "\\bibliography{refs}\nSee \\citep[p.~3]{turing1936,kn"
+	os.WriteFile(testFile, []byte(content), 0644)
+	result, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+			Position:     protocol.Position{Line: 1, Character: uint32(len("See \\citep[p.~3]{turing1936,kn"))},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].Label != "knuth1984" {
+		t.Fatalf("expected knuth1984 only, got %+v", result.Items)
+	}
+	item := result.Items[0]
+	if item.Detail != "Knuth (1984)" {
+		t.Errorf("unexpected detail %q", item.Detail)
+	}
+	if doc, ok := item.Documentation.(protocol.MarkupContent); !ok || !strings.Contains(doc.Value, "The TeXbook") {
+		t.Errorf("expected the title in the documentation, got %+v", item.Documentation)
+	}
+
+	// Cited keys of the bibliography are not broken references
+	var broken []string
+	for _, diag := range ls.analyzeNoteDiagnostics(pathToURI(testFile), "\\bibliography{refs}\n\\cite{knuth1984} \\cite{missing}") {
+		if diag.Code == diagnosticCodeBrokenRef {
+			broken = append(broken, diag.Message)
+		}
+	}
+	if len(broken) != 1 || !strings.Contains(broken[0], "missing") {
+		t.Errorf("expected only missing to be broken, got %v", broken)
+	}
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandScanBrokenLinks checks every note that is not open for broken references and reports them
+// Arguments: none. The scan runs in the background; its findings are published as diagnostics and
+// summed up with window/showMessage
+const commandScanBrokenLinks = "lx.scanBrokenLinks"
+
+// linkScanDebounce is how long the background scan waits for notes to stop appearing and disappearing
+const linkScanDebounce = 2 * time.Second
+
+func init() {
+	registerCommand(commandScanBrokenLinks, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		s.inBackground(ctx, commandScanBrokenLinks, func(ctx context.Context) error {
+			report := s.scanBrokenLinks(ctx)
+			if s.conn != nil {
+				s.conn.Notify(ctx, protocol.MethodWindowShowMessage, &protocol.ShowMessageParams{
+					Type:    protocol.MessageTypeInfo,
+					Message: fmt.Sprintf("Found %d broken references in closed notes (%d checked)", len(report.References), report.Scanned),
+				})
+			}
+			return nil
+		})
+		return nil, nil
+	})
+}
+
+// BrokenLinkReport is the outcome of a broken reference scan
+type BrokenLinkReport struct {
+	Scanned    int          `json:"scanned"` // notes checked, open notes left out
+	References []BrokenLink `json:"references"`
+}
+
+// BrokenLink is a reference to a missing note in a note that is not open
+type BrokenLink struct {
+	URI     protocol.DocumentURI `json:"uri"`
+	Range   protocol.Range       `json:"range"`
+	Message string               `json:"message"`
+}
+
+// scanBrokenLinks checks the notes that are not open for broken references and publishes them
+// Clients pulling workspace diagnostics get them from workspace/diagnostic already, so they are only
+// pushed to clients that do not. Notes reported by an earlier scan whose references were fixed since are
+// cleared
+func (s *LanguageServer) scanBrokenLinks(ctx context.Context) *BrokenLinkReport {
+	report := &BrokenLinkReport{References: []BrokenLink{}}
+	found := make(map[protocol.DocumentURI][]protocol.Diagnostic)
+	config := s.settings()
+	if config.Diagnostics.Enabled && config.Diagnostics.BrokenRefs && config.Features.Diagnostics {
+		for _, path := range s.managedNotePaths() {
+			if ctx.Err() != nil {
+				break
+			}
+			uri := pathToURI(path)
+			if s.isOpen(uri) {
+				continue
+			}
+			report.Scanned++
+			diagnostics := s.brokenLinkDiagnostics(uri)
+			if len(diagnostics) == 0 {
+				continue
+			}
+			found[uri] = diagnostics
+			for _, diag := range diagnostics {
+				report.References = append(report.References, BrokenLink{URI: uri, Range: diag.Range, Message: diag.Message})
+			}
+		}
+	}
+
+	if !s.pullDiagnostics {
+		s.publishLinkScan(ctx, found)
+	}
+	s.logf(protocol.MessageTypeInfo, "Found %d broken references in %d of %d closed notes", len(report.References), len(found), report.Scanned)
+	return report
+}
+
+// brokenLinkDiagnostics returns the broken reference diagnostics of a note read from disk
+func (s *LanguageServer) brokenLinkDiagnostics(uri protocol.DocumentURI) []protocol.Diagnostic {
+	content, err := s.GetDocument(uri)
+	if err != nil {
+		return nil
+	}
+	var broken []protocol.Diagnostic
+	for _, diag := range s.analyzeNoteDiagnostics(uri, content) {
+		if diag.Code == diagnosticCodeBrokenRef {
+			broken = append(broken, diag)
+		}
+	}
+	return describeDiagnostics(s.applySeverities(broken))
+}
+
+// isOpen reports whether the editor has the document open
+func (s *LanguageServer) isOpen(uri protocol.DocumentURI) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, open := s.documents[uri]
+	return open
+}
+
+// publishLinkScan pushes the broken references found by a scan and clears the notes the previous scan
+// reported that are clean now; notes opened since are left to the diagnostics of the open document
+func (s *LanguageServer) publishLinkScan(ctx context.Context, found map[protocol.DocumentURI][]protocol.Diagnostic) {
+	s.mu.Lock()
+	previous := s.linkScanned
+	s.linkScanned = make(map[protocol.DocumentURI]bool, len(found))
+	for uri := range found {
+		s.linkScanned[uri] = true
+	}
+	s.mu.Unlock()
+
+	if s.conn == nil {
+		return
+	}
+	for uri, diagnostics := range found {
+		s.conn.Notify(ctx, protocol.MethodTextDocumentPublishDiagnostics, &protocol.PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	}
+	for uri := range previous {
+		if _, still := found[uri]; still || s.isOpen(uri) {
+			continue
+		}
+		s.conn.Notify(ctx, protocol.MethodTextDocumentPublishDiagnostics, &protocol.PublishDiagnosticsParams{URI: uri, Diagnostics: []protocol.Diagnostic{}})
+	}
+}
+
+// scheduleLinkScan runs the background broken reference scan once notes settle, when scanVault is on
+// Scans are only scheduled once the initial index is built, as references look broken until then
+func (s *LanguageServer) scheduleLinkScan() {
+	if !s.settings().Diagnostics.ScanVault || !s.indexed() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.linkScanTimer != nil {
+		s.linkScanTimer.Stop()
+	}
+	if s.stopped {
+		s.linkScanTimer = nil
+		return
+	}
+	s.linkScanTimer = time.AfterFunc(linkScanDebounce, func() {
+		s.scanBrokenLinks(context.Background())
+	})
+}
+
+// clearLinkScan withdraws the diagnostics published by background scans, when scanVault is turned off
+func (s *LanguageServer) clearLinkScan(ctx context.Context) {
+	s.mu.Lock()
+	if s.linkScanTimer != nil {
+		s.linkScanTimer.Stop()
+		s.linkScanTimer = nil
+	}
+	s.mu.Unlock()
+	s.publishLinkScan(ctx, nil)
+}
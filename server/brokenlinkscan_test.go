@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestScanBrokenLinks tests pushing the broken references of closed notes and clearing fixed ones
+func TestScanBrokenLinks(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-a.tex": "%% Metadata\n% title: A\n\\ref{b} \\ref{gone}\n",
+		"20240102-b.tex": "%% Metadata\n% title: B\n\\ref{a}\n",
+		"20240103-c.tex": "%% Metadata\n% title: C\n\\ref{gone}\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	openURI := pathToURI(filepath.Join(tempDir, "20240103-c.tex"))
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: map[protocol.DocumentURI]string{openURI: files["20240103-c.tex"]},
+	}
+	ls.RebuildIndex(context.Background())
+
+	serverSide, clientSide := net.Pipe()
+	published := make(chan protocol.PublishDiagnosticsParams, 16)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.PublishDiagnosticsParams
+		if req.Method() == protocol.MethodTextDocumentPublishDiagnostics && json.Unmarshal(req.Params(), &params) == nil {
+			published <- params
+		}
+		return reply(ctx, nil, nil)
+	})
+	ls.conn = jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide))
+	defer func() {
+		client.Close()
+		serverSide.Close()
+	}()
+
+	// The open note is left to its own diagnostics
+	report := ls.scanBrokenLinks(context.Background())
+	aURI := pathToURI(filepath.Join(tempDir, "20240101-a.tex"))
+	if report.Scanned != 2 || len(report.References) != 1 || report.References[0].URI != aURI || !strings.Contains(report.References[0].Message, "gone") {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if params := <-published; params.URI != aURI || len(params.Diagnostics) != 1 || params.Diagnostics[0].Code != diagnosticCodeBrokenRef {
+		t.Errorf("expected the broken reference of a to be published, got %+v", params)
+	}
+
+	// Once the missing note exists, the next scan clears the note
+	os.WriteFile(filepath.Join(tempDir, "20240104-gone.tex"), []byte("%% Metadata\n% title: Gone\n"), 0644)
+	ls.updateIndexForFile(filepath.Join(tempDir, "20240104-gone.tex"))
+	if report := ls.scanBrokenLinks(context.Background()); len(report.References) != 0 {
+		t.Errorf("expected no broken references, got %+v", report.References)
+	}
+	if params := <-published; params.URI != aURI || len(params.Diagnostics) != 0 {
+		t.Errorf("expected the diagnostics of a to be cleared, got %+v", params)
+	}
+}
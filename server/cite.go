@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandCiteNote generates a bibliography entry for a note, so papers can cite it
+// Arguments: [slug], [slug, format] or [slug, format, bibFile], format being "bibtex" (the default)
+// or "biblatex"; with a .bib file, given as a path or file URI, the entry is appended to it unless
+// an entry with its key is already there
+const commandCiteNote = "lx.citeNote"
+
+// Bibliography entry formats of lx.citeNote
+const (
+	citeFormatBibTeX   = "bibtex"   // @misc with year and month, understood by every BibTeX style
+	citeFormatBiblatex = "biblatex" // @unpublished with an ISO date
+)
+
+// citeKeyPrefix starts the keys of note entries, keeping them apart from other keys of a bibliography
+const citeKeyPrefix = "lx:"
+
+// bibtexMonths are the month macros predefined by BibTeX styles
+var bibtexMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+func init() {
+	registerCommand(commandCiteNote, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.citeNoteCommand(ctx, args)
+	})
+}
+
+// CiteNoteResult is returned by lx.citeNote
+type CiteNoteResult struct {
+	Key      string `json:"key"`
+	Entry    string `json:"entry"`
+	Path     string `json:"path,omitempty"`     // .bib file the entry was meant for
+	Appended bool   `json:"appended,omitempty"` // false when the file already had the key
+}
+
+// citeNoteCommand handles lx.citeNote
+func (s *LanguageServer) citeNoteCommand(ctx context.Context, args []interface{}) (*CiteNoteResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a slug", commandCiteNote)
+	}
+	slug, ok := args[0].(string)
+	if !ok || slug == "" {
+		return nil, fmt.Errorf("%s: invalid slug argument", commandCiteNote)
+	}
+	format := citeFormatBibTeX
+	if len(args) > 1 {
+		if format, ok = args[1].(string); !ok || format == "" {
+			format = citeFormatBibTeX
+		}
+	}
+	note, exists := s.index.Get(slug)
+	if !exists {
+		return nil, fmt.Errorf("note '%s' not found", slug)
+	}
+
+	entry, err := citeEntry(note, format, s.settings().Author)
+	if err != nil {
+		return nil, err
+	}
+	result := &CiteNoteResult{Key: citeKeyPrefix + note.Slug, Entry: entry}
+	if len(args) < 3 {
+		return result, nil
+	}
+
+	path, ok := args[2].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%s: invalid bib file argument", commandCiteNote)
+	}
+	if strings.HasPrefix(path, "file://") {
+		path = uriToPath(protocol.DocumentURI(path))
+	}
+	result.Path = path
+	if result.Appended, err = appendBibEntry(path, result.Key, entry); err != nil {
+		return nil, err
+	}
+	if result.Appended {
+		s.recordActivity(ctx, "note.cite", fmt.Sprintf("%s -> %s", note.Slug, path))
+	}
+	return result, nil
+}
+
+// citeEntry formats the bibliography entry of a note
+// Titles are written as in the metadata, which is LaTeX already; BibTeX ones are braced twice so styles keep their case
+func citeEntry(note *NoteHeader, format, author string) (string, error) {
+	type field struct{ name, value string }
+	var entryType string
+	fields := []field{{"title", note.Title}}
+	if author != "" {
+		fields = append(fields, field{"author", author})
+	}
+
+	date, dateErr := time.Parse("2006-01-02", note.Date)
+	switch format {
+	case citeFormatBibTeX:
+		entryType = "misc"
+		fields[0].value = "{" + fields[0].value + "}"
+		if dateErr == nil {
+			fields = append(fields, field{"year", date.Format("2006")})
+		}
+		fields = append(fields, field{"howpublished", "lx note \\texttt{" + note.Slug + "}"})
+	case citeFormatBiblatex:
+		entryType = "unpublished"
+		if dateErr == nil {
+			fields = append(fields, field{"date", note.Date})
+		}
+		fields = append(fields, field{"note", "lx note \\texttt{" + note.Slug + "}"})
+		if len(note.Tags) > 0 {
+			fields = append(fields, field{"keywords", strings.Join(note.Tags, ", ")})
+		}
+	default:
+		return "", fmt.Errorf("%s: unknown format '%s'", commandCiteNote, format)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "@%s{%s%s,\n", entryType, citeKeyPrefix, note.Slug)
+	for _, f := range fields {
+		fmt.Fprintf(&builder, "  %s = {%s},\n", f.name, f.value)
+	}
+	if format == citeFormatBibTeX && dateErr == nil {
+		// Month macros go unbraced, so styles can abbreviate or translate them
+		fmt.Fprintf(&builder, "  month = %s,\n", bibtexMonths[date.Month()-1])
+	}
+	builder.WriteString("}\n")
+	return builder.String(), nil
+}
+
+// appendBibEntry adds an entry at the end of a .bib file, creating the file if needed
+// Returns false, leaving the file alone, when an entry with key is already there
+func appendBibEntry(path, key, entry string) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read bibliography: %w", err)
+	}
+	if strings.Contains(string(existing), "{"+key+",") {
+		return false, nil
+	}
+
+	prefix := ""
+	if len(existing) > 0 {
+		prefix = "\n"
+		if !strings.HasSuffix(string(existing), "\n") {
+			prefix = "\n\n"
+		}
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open bibliography: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(prefix + entry); err != nil {
+		return false, fmt.Errorf("failed to write bibliography: %w", err)
+	}
+	return true, nil
+}
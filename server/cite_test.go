@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestCiteNote tests the bibliography entries of lx.citeNote and appending them to a .bib file
+func TestCiteNote(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "20240315-graph-theory.tex"), []byte("%% Metadata\n% title: Graph Theory\n% date: 2024-03-15\n% tags: math, graphs\n"), 0644)
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+		config:    &Config{Author: "Doe, Jane"},
+	}
+	ls.RebuildIndex(context.Background())
+
+	result, err := ls.citeNoteCommand(context.Background(), []interface{}{"graph-theory"})
+	if err != nil {
+		t.Fatalf("lx.citeNote failed: %v", err)
+	}
+	bibtex := "@misc{lx:graph-theory,\n  title = {{Graph Theory}},\n  author = {Doe, Jane},\n  year = {2024},\n  howpublished = {lx note \\texttt{graph-theory}},\n  month = mar,\n}\n"
+	if result.Key != "lx:graph-theory" || result.Entry != bibtex {
+		t.Errorf("unexpected BibTeX entry %q", result.Entry)
+	}
+
+	result, err = ls.citeNoteCommand(context.Background(), []interface{}{"graph-theory", "biblatex"})
+	if err != nil {
+		t.Fatalf("lx.citeNote failed: %v", err)
+	}
+	biblatex := "@unpublished{lx:graph-theory,\n  title = {Graph Theory},\n  author = {Doe, Jane},\n  date = {2024-03-15},\n  note = {lx note \\texttt{graph-theory}},\n  keywords = {math, graphs},\n}\n"
+	if result.Entry != biblatex {
+		t.Errorf("unexpected biblatex entry %q", result.Entry)
+	}
+
+	if _, err := ls.citeNoteCommand(context.Background(), []interface{}{"graph-theory", "ris"}); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+	if _, err := ls.citeNoteCommand(context.Background(), []interface{}{"missing"}); err == nil {
+		t.Error("expected a missing note to fail")
+	}
+
+	bibPath := filepath.Join(t.TempDir(), "refs.bib")
+	os.WriteFile(bibPath, []byte("@book{knuth,\n  title = {TAOCP},\n}"), 0644)
+	for i, want := range []bool{true, false} {
+		result, err = ls.citeNoteCommand(context.Background(), []interface{}{"graph-theory", "bibtex", string(pathToURI(bibPath))})
+		if err != nil {
+			t.Fatalf("lx.citeNote with a bib file failed: %v", err)
+		}
+		if result.Appended != want || result.Path != bibPath {
+			t.Errorf("call %d: expected appended=%v to %s, got %+v", i, want, bibPath, result)
+		}
+	}
+	data, _ := os.ReadFile(bibPath)
+	if string(data) != "@book{knuth,\n  title = {TAOCP},\n}\n\n"+bibtex || strings.Count(string(data), "lx:graph-theory") != 1 {
+		t.Errorf("unexpected bibliography %q", data)
+	}
+}
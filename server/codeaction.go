@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// codeActionRequest is the document state handed to code action providers
+type codeActionRequest struct {
+	URI     protocol.DocumentURI
+	Content string
+	Range   protocol.Range
+}
+
+// quickFixFunc computes fixes for a single diagnostic carrying the code it was registered for
+type quickFixFunc func(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction
+
+// codeActionFunc computes actions for a range regardless of diagnostics (refactors, sources)
+type codeActionFunc func(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction
+
+// codeActionRegistry holds the pluggable code action providers
+type codeActionRegistry struct {
+	mu         sync.RWMutex
+	quickFixes map[string][]quickFixFunc // diagnostic code -> fixes
+	providers  []codeActionFunc
+}
+
+// codeActions is the registry features add their providers to from init()
+var codeActions = &codeActionRegistry{
+	quickFixes: make(map[string][]quickFixFunc),
+}
+
+// registerQuickFix attaches a fix to diagnostics with the given code
+func registerQuickFix(code string, fix quickFixFunc) {
+	codeActions.mu.Lock()
+	defer codeActions.mu.Unlock()
+	codeActions.quickFixes[code] = append(codeActions.quickFixes[code], fix)
+}
+
+// registerCodeActionProvider adds a provider that runs on every code action request
+func registerCodeActionProvider(provider codeActionFunc) {
+	codeActions.mu.Lock()
+	defer codeActions.mu.Unlock()
+	codeActions.providers = append(codeActions.providers, provider)
+}
+
+// Handle CodeAction request
+func (s *LanguageServer) CodeAction(ctx context.Context, params *protocol.CodeActionParams) ([]protocol.CodeAction, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	req := &codeActionRequest{
+		URI:     params.TextDocument.URI,
+		Content: content,
+		Range:   params.Range,
+	}
+
+	return filterCodeActions(s.collectCodeActions(req, params.Context.Diagnostics), params.Context.Only), nil
+}
+
+// collectCodeActions runs every registered provider for the request
+func (s *LanguageServer) collectCodeActions(req *codeActionRequest, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	codeActions.mu.RLock()
+	defer codeActions.mu.RUnlock()
+
+	actions := []protocol.CodeAction{}
+
+	for _, diag := range diagnostics {
+		code, ok := diag.Code.(string)
+		if !ok {
+			continue
+		}
+		for _, fix := range codeActions.quickFixes[code] {
+			actions = append(actions, fix(s, req, diag)...)
+		}
+	}
+
+	for _, provider := range codeActions.providers {
+		actions = append(actions, provider(s, req)...)
+	}
+
+	return actions
+}
+
+// filterCodeActions keeps only actions whose kind falls under one of the requested kinds
+func filterCodeActions(actions []protocol.CodeAction, only []protocol.CodeActionKind) []protocol.CodeAction {
+	if len(only) == 0 {
+		return actions
+	}
+
+	filtered := []protocol.CodeAction{}
+	for _, action := range actions {
+		for _, kind := range only {
+			if action.Kind == kind || strings.HasPrefix(string(action.Kind), string(kind)+".") {
+				filtered = append(filtered, action)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestCodeAction_Registry tests dispatch of registered providers and kind filtering
+func TestCodeAction_Registry(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	testFile := filepath.Join(notesPath, "test.tex")
+	content := `See \ref{missing}.`
+	os.WriteFile(testFile, []byte(content), 0644)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath},
+		index: NewIndex(),
+	}
+
+	// Temporarily plug in a refactor provider
+	saved := codeActions.providers
+	defer func() { codeActions.providers = saved }()
+	registerCodeActionProvider(func(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction {
+		return []protocol.CodeAction{{Title: "Test refactor", Kind: protocol.RefactorRewrite}}
+	})
+
+	params := &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+		Context: protocol.CodeActionContext{
+			Diagnostics: ls.analyzeDiagnostics(content),
+		},
+	}
+
+	actions, err := ls.CodeAction(context.Background(), params)
+	if err != nil {
+		t.Fatalf("CodeAction failed: %v", err)
+	}
+	if len(actions) != 5 {
+		t.Fatalf("expected four quick fixes and refactor, got %d actions", len(actions))
+	}
+
+	// Only quick fixes
+	params.Context.Only = []protocol.CodeActionKind{protocol.QuickFix}
+	actions, _ = ls.CodeAction(context.Background(), params)
+	if len(actions) != 4 {
+		t.Errorf("expected only the quick fixes, got %+v", actions)
+	}
+	for _, action := range actions {
+		if action.Kind != protocol.QuickFix {
+			t.Errorf("expected quick fix, got %s", action.Kind)
+		}
+	}
+
+	// Parent kinds match sub-kinds
+	params.Context.Only = []protocol.CodeActionKind{protocol.Refactor}
+	actions, _ = ls.CodeAction(context.Background(), params)
+	if len(actions) != 1 || actions[0].Title != "Test refactor" {
+		t.Errorf("expected only the refactor, got %+v", actions)
+	}
+}
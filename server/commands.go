@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandPrefix namespaces the commands the server executes
+const commandPrefix = "lx."
+
+// commandFunc runs a workspace/executeCommand request; the result is sent back to the client
+type commandFunc func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error)
+
+// commandRegistry holds the commands advertised through ExecuteCommandProvider
+type commandRegistry struct {
+	mu       sync.RWMutex
+	commands map[string]commandFunc // command ID -> handler
+}
+
+// commands is the registry features add their commands to from init()
+var commands = &commandRegistry{
+	commands: make(map[string]commandFunc),
+}
+
+// registerCommand makes a command available to clients
+// Panics on IDs outside the lx. namespace or registered twice, which are programming errors
+func registerCommand(name string, fn commandFunc) {
+	if !strings.HasPrefix(name, commandPrefix) {
+		panic(fmt.Sprintf("command %s is not in the %s namespace", name, commandPrefix))
+	}
+
+	commands.mu.Lock()
+	defer commands.mu.Unlock()
+	if _, exists := commands.commands[name]; exists {
+		panic(fmt.Sprintf("command %s registered twice", name))
+	}
+	commands.commands[name] = fn
+}
+
+// withoutResult adapts a command that only reports failure
+func withoutResult(fn func(s *LanguageServer, ctx context.Context, args []interface{}) error) commandFunc {
+	return func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return nil, fn(s, ctx, args)
+	}
+}
+
+// inBackground runs the slow part of a command after the command has replied
+// Messages are handled one at a time, so a command waiting on the network or reading every note
+// would hold up the requests behind it. fn outlives the request's context and its error is shown
+// to the user, there being no reply left to carry it
+func (s *LanguageServer) inBackground(ctx context.Context, command string, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		if err := fn(ctx); err != nil {
+			s.logf(protocol.MessageTypeError, "%s: %v", command, err)
+			if s.conn != nil {
+				s.conn.Notify(ctx, protocol.MethodWindowShowMessage, &protocol.ShowMessageParams{
+					Type:    protocol.MessageTypeError,
+					Message: fmt.Sprintf("%s: %v", command, err),
+				})
+			}
+		}
+	}()
+}
+
+// registeredCommands returns the IDs of every registered command, sorted
+func registeredCommands() []string {
+	commands.mu.RLock()
+	defer commands.mu.RUnlock()
+
+	names := make([]string, 0, len(commands.commands))
+	for name := range commands.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runCommand dispatches a workspace/executeCommand request
+// Commands that scan the vault must check ctx, which is cancelled through window/workDoneProgress/cancel
+func (s *LanguageServer) runCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	commands.mu.RLock()
+	fn, exists := commands.commands[params.Command]
+	commands.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown command: %s", params.Command)
+	}
+	return fn(s, ctx, params.Arguments)
+}
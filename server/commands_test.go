@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestCommandRegistry tests that registered commands are advertised and dispatched
+func TestCommandRegistry(t *testing.T) {
+	tempDir := t.TempDir()
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+
+	result, err := ls.Initialize(context.Background(), &protocol.InitializeParams{})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	advertised := result.Capabilities.ExecuteCommandProvider.Commands
+	for _, name := range []string{commandFixDanglingReferences, commandCreateNote, commandNewNote, commandOpenDailyNote, commandDeleteNote, commandBuildPDF, commandOpenPDF, commandCompileNote, commandSetStatus, commandMergeNotes, commandImportDirectory, commandTransitiveRefs, commandExportGraph, commandIndexInfo, commandListTrash, commandRestoreNote, commandListOrphans, commandDoctor, commandListTodos, commandUnlinkedMentions, commandExportObsidianGraph, commandCiteNote, commandVersion, commandCheckUpdate, commandScanBrokenLinks, commandRelatedNotes} {
+		found := false
+		for _, command := range advertised {
+			found = found || command == name
+		}
+		if !found {
+			t.Errorf("expected %s to be advertised, got %v", name, advertised)
+		}
+	}
+	for _, command := range advertised {
+		if !strings.HasPrefix(command, commandPrefix) {
+			t.Errorf("expected %s to be in the %s namespace", command, commandPrefix)
+		}
+	}
+
+	// Registered commands reach their handler, which may reject the missing arguments
+	for _, command := range advertised {
+		_, err := ls.runCommand(context.Background(), &protocol.ExecuteCommandParams{Command: command})
+		if err != nil && strings.HasPrefix(err.Error(), "unknown command") {
+			t.Errorf("expected %s to be dispatched, got %v", command, err)
+		}
+	}
+
+	if _, err := ls.runCommand(context.Background(), &protocol.ExecuteCommandParams{Command: "lx.missing"}); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected unknown command error, got %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// commandBuildPDF compiles a note into the vault cache and opens the PDF
+	// Arguments: [uri]
+	commandBuildPDF = "lx.buildPDF"
+
+	// commandOpenPDF opens the last compiled PDF of a note
+	// Arguments: [uri]
+	commandOpenPDF = "lx.openPDF"
+
+	// commandCompileNote compiles a note into the vault cache, reporting the outcome without opening the PDF
+	// Arguments: [uri]
+	commandCompileNote = "lx.compileNote"
+)
+
+func init() {
+	registerCommand(commandBuildPDF, withoutResult((*LanguageServer).buildPDF))
+	registerCommand(commandOpenPDF, withoutResult((*LanguageServer).openPDF))
+	registerCommand(commandCompileNote, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.compileNoteCommand(ctx, args)
+	})
+}
+
+var (
+	// documentclassPattern matches the \documentclass line the compile lenses attach to
+	documentclassPattern = regexp.MustCompile(`^\s*\\documentclass`)
+	// compileErrorPattern matches the file:line: message errors of -file-line-error output
+	compileErrorPattern = regexp.MustCompile(`^\S.*:\d+: .+$`)
+	// compileStepPattern matches the compiler output lines worth reporting as progress
+	compileStepPattern = regexp.MustCompile(`^(Latexmk: |Run number |Output written on )`)
+)
+
+// CompileResult is returned by lx.compileNote
+type CompileResult struct {
+	Success bool                 `json:"success"`
+	PDF     protocol.DocumentURI `json:"pdf,omitempty"`
+	Errors  []string             `json:"errors,omitempty"` // file:line: message lines from the compiler output
+}
+
+// Handle CodeLens request
+func (s *LanguageServer) CodeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	lenses := s.compileLenses(params.TextDocument.URI, content)
+	lenses = append(lenses, s.statusLenses(params.TextDocument.URI, content)...)
+	lenses = append(lenses, s.backlinkLenses(params.TextDocument.URI, content)...)
+	lenses = append(lenses, s.sectionBacklinkLenses(params.TextDocument.URI, content)...)
+	return lenses, nil
+}
+
+// compileLenses places "Build PDF" and, once built, "Open PDF" on the \documentclass line
+func (s *LanguageServer) compileLenses(docURI protocol.DocumentURI, content string) []protocol.CodeLens {
+	lenses := []protocol.CodeLens{}
+
+	lines := s.documentLines(content)
+	for lineNum, line := range lines {
+		if !documentclassPattern.MatchString(line) {
+			continue
+		}
+
+		lensRange := lineRange(lineNum, 0, len(line))
+		lenses = append(lenses, protocol.CodeLens{
+			Range: lensRange,
+			Command: &protocol.Command{
+				Title:     "Build PDF",
+				Command:   commandBuildPDF,
+				Arguments: []interface{}{string(docURI)},
+			},
+		})
+
+		if _, err := os.Stat(s.pdfPath(uriToPath(docURI))); err == nil {
+			lenses = append(lenses, protocol.CodeLens{
+				Range: lensRange,
+				Command: &protocol.Command{
+					Title:     "Open PDF",
+					Command:   commandOpenPDF,
+					Arguments: []interface{}{string(docURI)},
+				},
+			})
+		}
+		break
+	}
+
+	return lenses
+}
+
+// pdfPath returns where latexmk writes the PDF of a note (the vault cache, as lx build does)
+func (s *LanguageServer) pdfPath(notePath string) string {
+	return s.vault.GetCachePath(strings.TrimSuffix(filepath.Base(notePath), ".tex") + ".pdf")
+}
+
+// buildPDF handles lx.buildPDF
+func (s *LanguageServer) buildPDF(ctx context.Context, args []interface{}) error {
+	notePath, err := notePathArgument(commandBuildPDF, args)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.compileNote(ctx, notePath); err != nil {
+		return err
+	}
+	s.recordActivity(ctx, "note.build", filepath.Base(notePath))
+
+	return s.showPDF(ctx, notePath)
+}
+
+// compileNoteCommand handles lx.compileNote
+// Compilation failures are reported through showMessage and the result rather than as a command error
+func (s *LanguageServer) compileNoteCommand(ctx context.Context, args []interface{}) (*CompileResult, error) {
+	notePath, err := notePathArgument(commandCompileNote, args)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(notePath); err != nil {
+		return nil, fmt.Errorf("note %s not found", filepath.Base(notePath))
+	}
+
+	output, err := s.compileNote(ctx, notePath)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result := &CompileResult{Success: err == nil}
+	for _, line := range strings.Split(output, "\n") {
+		if compileErrorPattern.MatchString(line) {
+			result.Errors = append(result.Errors, line)
+		}
+	}
+
+	name := filepath.Base(notePath)
+	message := &protocol.ShowMessageParams{Type: protocol.MessageTypeInfo, Message: fmt.Sprintf("Compiled %s", name)}
+	if err != nil {
+		message = &protocol.ShowMessageParams{Type: protocol.MessageTypeError, Message: fmt.Sprintf("Failed to compile %s", name)}
+		if len(result.Errors) > 0 {
+			message.Message += ": " + result.Errors[0]
+		} else if output == "" {
+			message.Message += ": " + err.Error()
+		}
+	} else {
+		result.PDF = pathToURI(s.pdfPath(notePath))
+		s.recordActivity(ctx, "note.build", name)
+	}
+	if s.conn != nil {
+		s.conn.Notify(ctx, protocol.MethodWindowShowMessage, message)
+	}
+
+	return result, nil
+}
+
+// openPDF handles lx.openPDF
+func (s *LanguageServer) openPDF(ctx context.Context, args []interface{}) error {
+	notePath, err := notePathArgument(commandOpenPDF, args)
+	if err != nil {
+		return err
+	}
+	return s.showPDF(ctx, notePath)
+}
+
+// compileNote compiles a note with the engine its metadata asks for, using the vault cache as output directory
+// Compiler steps are reported to the progress of the running request; the full output is returned
+func (s *LanguageServer) compileNote(ctx context.Context, notePath string) (string, error) {
+	if err := os.MkdirAll(s.vault.CachePath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	profile, err := s.noteCompileProfile(notePath)
+	if err != nil {
+		return "", err
+	}
+	cmd, err := compilerCommand(ctx, notePath, s.vault.CachePath, profile)
+	if err != nil {
+		return "", err
+	}
+
+	cmd.Dir = s.vault.CachePath
+	cmd.Env = append(os.Environ(), "TEXINPUTS="+s.vault.GetTexInputsEnv())
+
+	reader, writer := io.Pipe()
+	cmd.Stdout, cmd.Stderr = writer, writer
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start compiler: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+		writer.Close()
+	}()
+
+	progress := s.progressFrom(ctx)
+	var output strings.Builder
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		output.WriteString(line)
+		output.WriteString("\n")
+		if compileStepPattern.MatchString(line) {
+			progress.Report(ctx, line, 0)
+		}
+	}
+	// Keep draining after an overlong line so the compiler does not block on a full pipe
+	io.Copy(io.Discard, reader)
+
+	if err := <-done; err != nil {
+		return output.String(), fmt.Errorf("compilation failed: %w\nOutput: %s", err, output.String())
+	}
+	return output.String(), nil
+}
+
+// showPDF asks the client to open the compiled PDF of a note in an external viewer
+func (s *LanguageServer) showPDF(ctx context.Context, notePath string) error {
+	pdf := s.pdfPath(notePath)
+	if _, err := os.Stat(pdf); err != nil {
+		return fmt.Errorf("no PDF for %s, build it first", filepath.Base(notePath))
+	}
+
+	var result protocol.ShowDocumentResult
+	if _, err := s.conn.Call(ctx, protocol.MethodShowDocument, &protocol.ShowDocumentParams{
+		URI:      protocol.URI(pathToURI(pdf)),
+		External: true,
+	}, &result); err != nil {
+		return fmt.Errorf("failed to open PDF: %w", err)
+	}
+	return nil
+}
+
+// notePathArgument extracts the note path from a command's [uri] arguments
+func notePathArgument(command string, args []interface{}) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("%s requires a document URI argument", command)
+	}
+	docURI, ok := args[0].(string)
+	if !ok || docURI == "" {
+		return "", fmt.Errorf("%s: invalid document URI argument", command)
+	}
+	return uriToPath(protocol.DocumentURI(docURI)), nil
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestCodeLens_Compile tests the build and open lenses on the \documentclass line
+func TestCodeLens_Compile(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	cachePath := filepath.Join(tempDir, "cache")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(cachePath, 0755)
+
+	testFile := filepath.Join(notesPath, "20240101-test.tex")
+	content := "%% Metadata\n% title: Test\n\n\\documentclass[12pt]{article}\n\\begin{document}\n\\end{document}\n"
+	os.WriteFile(testFile, []byte(content), 0644)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath, CachePath: cachePath},
+		index: NewIndex(),
+	}
+
+	params := &protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+	}
+
+	lenses, err := ls.CodeLens(context.Background(), params)
+	if err != nil {
+		t.Fatalf("CodeLens failed: %v", err)
+	}
+	if len(lenses) != 1 || lenses[0].Command.Command != commandBuildPDF {
+		t.Fatalf("expected only the build lens, got %+v", lenses)
+	}
+	if lenses[0].Range.Start.Line != 3 {
+		t.Errorf("expected lens on line 3, got %d", lenses[0].Range.Start.Line)
+	}
+
+	// Once a PDF exists it can be opened
+	os.WriteFile(filepath.Join(cachePath, "20240101-test.pdf"), []byte("%PDF"), 0644)
+	lenses, _ = ls.CodeLens(context.Background(), params)
+	if len(lenses) != 2 || lenses[1].Command.Command != commandOpenPDF {
+		t.Errorf("expected build and open lenses, got %+v", lenses)
+	}
+}
+
+// TestNotePathArgument tests command argument validation
+func TestNotePathArgument(t *testing.T) {
+	if _, err := notePathArgument(commandBuildPDF, nil); err == nil {
+		t.Error("expected error for missing argument")
+	}
+	path, err := notePathArgument(commandBuildPDF, []interface{}{"file:///vault/notes/a.tex"})
+	if err != nil || path != "/vault/notes/a.tex" {
+		t.Errorf("unexpected result %q, %v", path, err)
+	}
+}
+
+// TestCompileNoteCommand tests compiling a note with a stand-in latexmk
+func TestCompileNoteCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in compiler is a shell script")
+	}
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"echo \"Latexmk: applying rule 'pdflatex'\"\n" +
+		"for last; do :; done\n" +
+		"if grep -q broken \"$last\"; then echo \"$last:3: Undefined control sequence.\"; exit 12; fi\n" +
+		"echo 'Output written on note.pdf'\n"
+	os.WriteFile(filepath.Join(binDir, "latexmk"), []byte(script), 0755)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	good := filepath.Join(v.NotesPath, "20240101-good.tex")
+	bad := filepath.Join(v.NotesPath, "20240101-bad.tex")
+	os.WriteFile(good, []byte("\\documentclass{article}"), 0644)
+	os.WriteFile(bad, []byte("\\documentclass{article}\n\\broken"), 0644)
+
+	ls := &LanguageServer{vault: v, index: NewIndex()}
+
+	result, err := ls.compileNoteCommand(context.Background(), []interface{}{string(pathToURI(good))})
+	if err != nil {
+		t.Fatalf("compileNoteCommand failed: %v", err)
+	}
+	if !result.Success || result.PDF != pathToURI(ls.pdfPath(good)) || len(result.Errors) != 0 {
+		t.Errorf("expected success, got %+v", result)
+	}
+
+	result, err = ls.compileNoteCommand(context.Background(), []interface{}{string(pathToURI(bad))})
+	if err != nil {
+		t.Fatalf("expected compile failure in the result, got error %v", err)
+	}
+	if result.Success || len(result.Errors) != 1 || !strings.HasSuffix(result.Errors[0], ":3: Undefined control sequence.") {
+		t.Errorf("expected the error line, got %+v", result)
+	}
+
+	if _, err := ls.compileNoteCommand(context.Background(), []interface{}{string(pathToURI(filepath.Join(v.NotesPath, "missing.tex")))}); err == nil {
+		t.Error("expected missing note to fail")
+	}
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeInvalidEngine marks an engine metadata field naming an unsupported engine
+const diagnosticCodeInvalidEngine = "LX009"
+
+// diagnosticCodeCompileArgs marks compileargs the compile commands refuse to pass on
+const diagnosticCodeCompileArgs = "LX016"
+
+// allowedCompileArgs are the compiler arguments notes may ask for. Anything else is refused:
+// -shell-escape, latexmk's -e or -pdflatex="..." and the like would let a note run commands
+var allowedCompileArgs = map[string]bool{
+	"-synctex=1":                 true,
+	"-synctex=0":                 true,
+	"-halt-on-error":             true,
+	"-file-line-error":           true,
+	"-interaction=nonstopmode":   true,
+	"-interaction=batchmode":     true,
+	"-interaction=scrollmode":    true,
+	"-interaction=errorstopmode": true,
+	"-draftmode":                 true,
+	"-recorder":                  true,
+	"-no-shell-escape":           true,
+	"-quiet":                     true,
+	"-silent":                    true,
+	"--synctex":                  true,
+	"--keep-logs":                true,
+	"--keep-intermediates":       true,
+}
+
+// engineFieldPattern matches a metadata engine line up to the cursor; group 1 is the value typed so far
+var engineFieldPattern = regexp.MustCompile(`^\s*%+\s*engine:\s*(\w*)$`)
+
+// compileProfile is how a note asks to be compiled
+type compileProfile struct {
+	engine string   // one of metadata.Engines, empty for the default
+	args   []string // extra compiler arguments
+}
+
+func init() {
+	registerQuickFix(diagnosticCodeInvalidEngine, fixEngine)
+}
+
+// noteCompileProfile reads the engine and compileargs metadata of a note file
+// An unreadable note or an invalid engine falls back to the default; the engine is reported as a diagnostic instead
+// Arguments outside allowedCompileArgs are an error: the note is not compiled with them or without them
+func (s *LanguageServer) noteCompileProfile(notePath string) (compileProfile, error) {
+	data, err := os.ReadFile(notePath)
+	if err != nil {
+		return compileProfile{}, nil
+	}
+	result, err := s.metadataParser().Parse(metadata.Normalize(string(data)))
+	if err != nil {
+		return compileProfile{}, nil
+	}
+	profile := compileProfile{args: strings.Fields(result.Metadata.CompileArgs)}
+	if refused := refusedCompileArgs(profile.args); len(refused) > 0 {
+		return compileProfile{}, fmt.Errorf("compileargs not allowed: %s", strings.Join(refused, " "))
+	}
+	for _, engine := range metadata.Engines {
+		if result.Metadata.Engine == engine {
+			profile.engine = engine
+		}
+	}
+	return profile, nil
+}
+
+// refusedCompileArgs returns the arguments not in allowedCompileArgs
+func refusedCompileArgs(args []string) []string {
+	var refused []string
+	for _, arg := range args {
+		if !allowedCompileArgs[arg] {
+			refused = append(refused, arg)
+		}
+	}
+	return refused
+}
+
+// compilerCommand builds the command compiling a note into outDir
+// latexmk drives the LaTeX engines when installed; otherwise the engine runs once on its own
+func compilerCommand(ctx context.Context, notePath, outDir string, profile compileProfile) (*exec.Cmd, error) {
+	var name string
+	var args []string
+	_, latexmkErr := exec.LookPath("latexmk")
+
+	switch {
+	case profile.engine == "tectonic":
+		name = "tectonic"
+		args = []string{"--outdir=" + outDir}
+	case latexmkErr == nil:
+		name = "latexmk"
+		mode := map[string]string{"": "-pdf", "pdflatex": "-pdf", "xelatex": "-pdfxe", "lualatex": "-pdflua"}[profile.engine]
+		args = []string{mode, "-output-directory=" + outDir, "-interaction=nonstopmode", "-file-line-error"}
+	default:
+		name = profile.engine
+		if name == "" {
+			name = "pdflatex"
+		}
+		args = []string{"-output-directory=" + outDir, "-interaction=nonstopmode", "-file-line-error"}
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		if name == "pdflatex" {
+			return nil, fmt.Errorf("neither latexmk nor pdflatex found in PATH")
+		}
+		return nil, fmt.Errorf("%s not found in PATH", name)
+	}
+
+	args = append(args, profile.args...)
+	return exec.CommandContext(ctx, name, append(args, notePath)...), nil
+}
+
+// engineDiagnostics reports engine metadata the compile commands do not support
+func (s *LanguageServer) engineDiagnostics(content string) []protocol.Diagnostic {
+	result, err := s.metadataParser().Parse(content)
+	if err != nil {
+		return nil
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, parseErr := range result.Errors {
+		if parseErr.Field != "engine" || parseErr.Line == 0 {
+			continue
+		}
+		// Parser lines are 1-based
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange(parseErr.Line-1, parseErr.Column, parseErr.Column+parseErr.Length),
+			Severity: protocol.DiagnosticSeverityWarning,
+			Code:     diagnosticCodeInvalidEngine,
+			Message:  parseErr.Message,
+			Source:   "lx-ls",
+		})
+	}
+	return append(diagnostics, s.compileArgsDiagnostics(content)...)
+}
+
+// compileArgsDiagnostics reports the compileargs of the metadata block that are not allowed
+func (s *LanguageServer) compileArgsDiagnostics(content string) []protocol.Diagnostic {
+	blockStart, blockEnd, found := s.metadataParser().FindBlock(content)
+	if !found {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+
+	var diagnostics []protocol.Diagnostic
+	for lineNum := blockStart + 1; lineNum <= blockEnd && lineNum < len(lines); lineNum++ {
+		line := lines[lineNum]
+		match := metadataFieldPattern.FindStringSubmatchIndex(line)
+		if match == nil || strings.ToLower(line[match[2]:match[3]]) != "compileargs" {
+			continue
+		}
+		offset := match[4]
+		for _, arg := range strings.Fields(line[match[4]:]) {
+			start := offset + strings.Index(line[offset:], arg)
+			offset = start + len(arg)
+			if allowedCompileArgs[arg] {
+				continue
+			}
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    lineRange(lineNum, start, offset),
+				Severity: protocol.DiagnosticSeverityError,
+				Code:     diagnosticCodeCompileArgs,
+				Message:  fmt.Sprintf("Compile argument '%s' is not allowed; the note will not compile until it is removed", arg),
+				Source:   "lx-ls",
+			})
+		}
+		break
+	}
+	return diagnostics
+}
+
+// fixEngine offers to replace an unknown engine with each supported one
+func fixEngine(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	for _, engine := range metadata.Engines {
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Change engine to %s", engine),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					req.URI: {{Range: diag.Range, NewText: engine}},
+				},
+			},
+		})
+	}
+	return actions
+}
+
+// engineCompletions offers the supported engines after "engine:" inside the metadata block
+func (s *LanguageServer) engineCompletions(content string, line int, linePrefix string) []protocol.CompletionItem {
+	match := engineFieldPattern.FindStringSubmatch(linePrefix)
+	if match == nil {
+		return nil
+	}
+	blockStart, blockEnd, found := s.metadataParser().FindBlock(content)
+	if !found || line < blockStart || line > blockEnd {
+		return nil
+	}
+
+	var items []protocol.CompletionItem
+	for _, engine := range metadata.Engines {
+		if strings.HasPrefix(engine, strings.ToLower(match[1])) {
+			items = append(items, protocol.CompletionItem{
+				Label:  engine,
+				Kind:   protocol.CompletionItemKindEnumMember,
+				Detail: "TeX engine",
+			})
+		}
+	}
+	return items
+}
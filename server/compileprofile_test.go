@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// TestCompilerCommand tests choosing the compiler from a note's compile profile
+func TestCompilerCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in compilers are shell scripts")
+	}
+	binDir := t.TempDir()
+	for _, name := range []string{"pdflatex", "xelatex", "tectonic"} {
+		os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"), 0755)
+	}
+	t.Setenv("PATH", binDir)
+
+	tests := []struct {
+		profile compileProfile
+		want    []string
+	}{
+		{compileProfile{}, []string{"pdflatex", "-output-directory=/cache", "-interaction=nonstopmode", "-file-line-error", "note.tex"}},
+		{compileProfile{engine: "xelatex", args: []string{"-synctex=1"}}, []string{"xelatex", "-output-directory=/cache", "-interaction=nonstopmode", "-file-line-error", "-synctex=1", "note.tex"}},
+		{compileProfile{engine: "tectonic"}, []string{"tectonic", "--outdir=/cache", "note.tex"}},
+	}
+	for _, tt := range tests {
+		cmd, err := compilerCommand(context.Background(), "note.tex", "/cache", tt.profile)
+		if err != nil {
+			t.Fatalf("compilerCommand(%+v) failed: %v", tt.profile, err)
+		}
+		if got := append([]string{filepath.Base(cmd.Path)}, cmd.Args[1:]...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("compilerCommand(%+v) = %v, want %v", tt.profile, got, tt.want)
+		}
+	}
+
+	if _, err := compilerCommand(context.Background(), "note.tex", "/cache", compileProfile{engine: "lualatex"}); err == nil {
+		t.Error("expected a missing engine to fail")
+	}
+
+	// latexmk drives the engine when installed
+	os.WriteFile(filepath.Join(binDir, "latexmk"), []byte("#!/bin/sh\n"), 0755)
+	cmd, err := compilerCommand(context.Background(), "note.tex", "/cache", compileProfile{engine: "xelatex"})
+	if err != nil || filepath.Base(cmd.Path) != "latexmk" || cmd.Args[1] != "-pdfxe" {
+		t.Errorf("expected latexmk -pdfxe, got %v, %v", cmd, err)
+	}
+}
+
+// TestNoteCompileProfile tests reading engine and compileargs from a note
+func TestNoteCompileProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	ls := &LanguageServer{index: NewIndex()}
+
+	note := filepath.Join(tempDir, "note.tex")
+	os.WriteFile(note, []byte("%% Metadata\n% title: Test\n% engine: XeLaTeX\n% compileargs: -halt-on-error  -synctex=1\n"), 0644)
+	profile, err := ls.noteCompileProfile(note)
+	if err != nil || profile.engine != "xelatex" || !reflect.DeepEqual(profile.args, []string{"-halt-on-error", "-synctex=1"}) {
+		t.Errorf("unexpected profile %+v (%v)", profile, err)
+	}
+
+	// Unknown engines fall back to the default
+	os.WriteFile(note, []byte("%% Metadata\n% title: Test\n% engine: context\n"), 0644)
+	if profile, _ := ls.noteCompileProfile(note); profile.engine != "" {
+		t.Errorf("expected default engine, got %q", profile.engine)
+	}
+
+	// Arguments that could run commands are refused, and the note is not compiled
+	for _, args := range []string{"-shell-escape", "-synctex=1 -e $pdflatex=q", "-pdflatex=evil"} {
+		os.WriteFile(note, []byte("%% Metadata\n% title: Test\n% compileargs: "+args+"\n"), 0644)
+		if _, err := ls.noteCompileProfile(note); err == nil {
+			t.Errorf("expected compileargs %q to be refused", args)
+		}
+	}
+	content := "%% Metadata\n% title: Test\n% compileargs: -synctex=1 -shell-escape\n"
+	diagnostics := ls.engineDiagnostics(content)
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnosticCodeCompileArgs || diagnostics[0].Range != lineRange(2, 26, 39) {
+		t.Errorf("expected -shell-escape to be reported, got %+v", diagnostics)
+	}
+}
+
+// TestEngineDiagnostics tests reporting, fixing and completing the engine field
+func TestEngineDiagnostics(t *testing.T) {
+	content := "%% Metadata\n% title: Test\n% engine: context\n"
+	ls := &LanguageServer{index: NewIndex()}
+
+	diagnostics := ls.engineDiagnostics(content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+	}
+	diag := diagnostics[0]
+	if diag.Code != diagnosticCodeInvalidEngine || diag.Range.Start.Line != 2 || diag.Range.Start.Character != 10 || diag.Range.End.Character != 17 {
+		t.Errorf("unexpected diagnostic %+v", diag)
+	}
+
+	req := &codeActionRequest{URI: "file:///note.tex", Content: content}
+	actions := fixEngine(nil, req, diag)
+	if len(actions) != 4 || actions[1].Edit.Changes[req.URI][0].NewText != "xelatex" {
+		t.Errorf("unexpected fixes %+v", actions)
+	}
+
+	if diagnostics := ls.engineDiagnostics("%% Metadata\n% title: Test\n% engine: tectonic\n"); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %d", len(diagnostics))
+	}
+
+	content = "%% Metadata\n% title: Test\n% engine: xe\n\nbody engine: "
+	items := ls.engineCompletions(content, 2, "% engine: xe")
+	if len(items) != 1 || items[0].Label != "xelatex" {
+		t.Errorf("unexpected completions %+v", items)
+	}
+	if items := ls.engineCompletions(content, 4, "body engine: "); len(items) != 0 {
+		t.Errorf("expected no completions outside the metadata block, got %+v", items)
+	}
+}
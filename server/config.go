@@ -0,0 +1,406 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// configSection is the settings key clients nest lx-lsp settings under
+const configSection = "lx-lsp"
+
+// completionRegistrationID identifies the dynamically registered completion capability
+const completionRegistrationID = "lx-completion"
+
+// Config holds the user-tunable server settings
+type Config struct {
+	TriggerCharacters     []string          `json:"triggerCharacters,omitempty"`
+	Diagnostics           DiagnosticsConfig `json:"diagnostics"`
+	Severities            map[string]string `json:"severities,omitempty"` // diagnostic code -> "error", "warning", "information", "hint" or "off"
+	Completion            CompletionConfig  `json:"completion"`
+	VaultPath             string            `json:"vaultPath,omitempty"`
+	MetadataScope         string            `json:"metadataScope,omitempty"` // "preamble", "top" or "anywhere"
+	TagPolicy             TagPolicy         `json:"tagPolicy"`
+	SkeletonIgnore        []string          `json:"skeletonIgnore,omitempty"`  // templates whose required structure is not checked
+	ReferenceMacros       []string          `json:"referenceMacros,omitempty"` // extra commands like \lxlink{} whose argument is a note slug
+	UpdateModified        bool              `json:"updateModified"`            // stamp the modified metadata date on save
+	DuplicateRefThreshold int               `json:"duplicateRefThreshold"`     // references to one note within a paragraph that trigger a hint
+	Features              FeaturesConfig    `json:"features"`
+	Coexist               bool              `json:"coexist"`                 // leave generic LaTeX features to another server such as texlab
+	FetchURLTitles        bool              `json:"fetchUrlTitles"`          // offer to wrap bare URLs in \href with the page title fetched from the web
+	LogLevel              string            `json:"logLevel,omitempty"`      // "off", "error", "warning", "info" or "debug"; messages go to the client log
+	TrashRetentionDays    int               `json:"trashRetentionDays"`      // days deleted notes stay in the trash, 0 keeps them forever
+	DoctorIntervalHours   int               `json:"doctorIntervalHours"`     // hours between background lx.doctor runs, 0 turns them off
+	Author                string            `json:"author,omitempty"`        // author of lx.citeNote bibliography entries, e.g. "Doe, Jane"
+	RecentNotesSize       int               `json:"recentNotesSize"`         // notes kept in the lx/recentNotes history, 0 for no limit
+	DateFormats           []string          `json:"dateFormats,omitempty"`   // legacy metadata date formats like DD.MM.YYYY, accepted and normalized to YYYY-MM-DD
+	LabelPrefixes         map[string]string `json:"labelPrefixes,omitempty"` // environment -> required label prefix, "" to drop the requirement
+	HeaderMemoryMB        int               `json:"headerMemoryMB"`          // megabytes of note headers kept in memory, the rest in the vault cache; 0 keeps them all. Other indexes stay in memory
+}
+
+// FeaturesConfig switches whole feature groups on or off, e.g. to leave LaTeX editing to texlab
+// Capabilities are advertised from the settings at initialize; later changes only stop the handlers
+type FeaturesConfig struct {
+	Diagnostics bool `json:"diagnostics"`
+	Completion  bool `json:"completion"`
+	Rename      bool `json:"rename"`
+	Watchers    bool `json:"watchers"` // fsnotify and client file watching; the index then only follows edits made through the server
+}
+
+// CompletionConfig tunes what completion offers
+type CompletionConfig struct {
+	Snippets  bool   `json:"snippets"`  // LaTeX snippets and theorem environments outside of references
+	MaxItems  int    `json:"maxItems"`  // cap on returned items, 0 for no limit; the client asks again as the user types
+	RefInsert string `json:"refInsert"` // what accepting a reference inserts: "slug", "closeBrace" or "full"
+}
+
+// DiagnosticsConfig toggles individual diagnostic rules
+type DiagnosticsConfig struct {
+	Enabled         bool `json:"enabled"`
+	BrokenRefs      bool `json:"brokenRefs"`
+	Todos           bool `json:"todos"`
+	Dates           bool `json:"dates"`
+	Acronyms        bool `json:"acronyms"`
+	Tags            bool `json:"tags"`
+	Skeleton        bool `json:"skeleton"`
+	DuplicateRefs   bool `json:"duplicateRefs"`
+	Engine          bool `json:"engine"`
+	LabelPrefixes   bool `json:"labelPrefixes"`
+	DuplicateLabels bool `json:"duplicateLabels"`
+	MissingAssets   bool `json:"missingAssets"`
+	ScanVault       bool `json:"scanVault"` // check notes that are not open for broken references in the background
+}
+
+// DefaultConfig returns the settings used before the client sends any configuration
+func DefaultConfig() Config {
+	return Config{
+		MetadataScope:      "preamble",
+		LogLevel:           defaultLogLevel,
+		TrashRetentionDays: 30,
+		RecentNotesSize:    50,
+		UpdateModified:     true,
+		TriggerCharacters:  []string{"{", "\\", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "-"},
+		Diagnostics: DiagnosticsConfig{
+			Enabled:         true,
+			BrokenRefs:      true,
+			Todos:           true,
+			Dates:           true,
+			Acronyms:        true,
+			Tags:            true,
+			Skeleton:        true,
+			DuplicateRefs:   true,
+			Engine:          true,
+			LabelPrefixes:   true,
+			DuplicateLabels: true,
+			MissingAssets:   true,
+		},
+		LabelPrefixes:         defaultLabelPrefixes(),
+		DuplicateRefThreshold: 3,
+		Completion: CompletionConfig{
+			Snippets:  true,
+			RefInsert: refInsertSlug,
+		},
+		Features: FeaturesConfig{
+			Diagnostics: true,
+			Completion:  true,
+			Rename:      true,
+			Watchers:    true,
+		},
+		TagPolicy: TagPolicy{
+			Lowercase: true,
+			KebabCase: true,
+			MaxLength: 32,
+		},
+	}
+}
+
+// parseConfig decodes client settings on top of base
+// Accepts both {"lx-lsp": {...}} and the bare settings object
+func parseConfig(settings interface{}, base Config) (Config, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return base, fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	var wrapped map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapped); err == nil {
+		if section, ok := wrapped[configSection]; ok {
+			data = section
+		}
+	}
+
+	config := base
+	// Decoding merges into maps, which must not change base's
+	config.Severities = cloneStringMap(base.Severities)
+	config.LabelPrefixes = cloneStringMap(base.LabelPrefixes)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return base, fmt.Errorf("invalid %s settings: %w", configSection, err)
+	}
+	if !refInsertModes[config.Completion.RefInsert] {
+		return base, fmt.Errorf("invalid %s settings: unknown completion.refInsert %q", configSection, config.Completion.RefInsert)
+	}
+	for _, format := range config.DateFormats {
+		if _, err := metadata.DateLayout(format); err != nil {
+			return base, fmt.Errorf("invalid %s settings: %w", configSection, err)
+		}
+	}
+	return config, nil
+}
+
+// cloneStringMap copies a map, keeping nil as nil
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+	return clone
+}
+
+// settings returns the active configuration
+func (s *LanguageServer) settings() Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config == nil {
+		return DefaultConfig()
+	}
+	return *s.config
+}
+
+// loadSettings replaces the settings before the index is built, switching to the configured vault
+// Used for initialization options and settings pulled in Initialized, when nothing depends on them yet
+func (s *LanguageServer) loadSettings(ctx context.Context, settings interface{}) error {
+	config, err := parseConfig(settings, s.settings())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.config = &config
+	s.mu.Unlock()
+
+	if config.VaultPath != "" && config.VaultPath != s.vault.RootPath {
+		return s.switchVault(ctx, config.VaultPath)
+	}
+	s.applyHeaderMemory()
+	return nil
+}
+
+// pullSettings asks a client supporting workspace/configuration for the lx-lsp section
+// Returns nil when the client has no settings for it
+func (s *LanguageServer) pullSettings(ctx context.Context) (interface{}, error) {
+	var sections []interface{}
+	if _, err := s.conn.Call(ctx, protocol.MethodWorkspaceConfiguration, &protocol.ConfigurationParams{
+		Items: []protocol.ConfigurationItem{{Section: configSection}},
+	}, &sections); err != nil {
+		return nil, fmt.Errorf("failed to fetch settings: %w", err)
+	}
+	if len(sections) == 0 {
+		return nil, nil
+	}
+	return sections[0], nil
+}
+
+// Handle DidChangeConfiguration notification
+// Applies the new settings and re-runs whatever depends on the ones that changed
+// Clients using the pull model send no settings, only that they changed, so they are fetched
+func (s *LanguageServer) DidChangeConfiguration(ctx context.Context, params *protocol.DidChangeConfigurationParams) error {
+	settings := params.Settings
+	if settings == nil && s.configurationPull {
+		pulled, err := s.pullSettings(ctx)
+		if err != nil {
+			return err
+		}
+		settings = pulled
+	}
+
+	old := s.settings()
+	config, err := parseConfig(settings, old)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.config = &config
+	s.mu.Unlock()
+
+	if config.VaultPath != old.VaultPath && config.VaultPath != "" {
+		if err := s.switchVault(ctx, config.VaultPath); err != nil {
+			return err
+		}
+	}
+
+	if !reflect.DeepEqual(config.TriggerCharacters, old.TriggerCharacters) && s.dynamicCompletion {
+		if err := s.reregisterCompletion(ctx, config.TriggerCharacters); err != nil {
+			return err
+		}
+	}
+
+	macrosChanged := !reflect.DeepEqual(config.ReferenceMacros, old.ReferenceMacros)
+	dateFormatsChanged := !reflect.DeepEqual(config.DateFormats, old.DateFormats)
+	if macrosChanged || dateFormatsChanged {
+		// Links through the added or removed macros change the backlinks of every note,
+		// date formats the dates of notes written in them
+		if err := s.rebuildIndex(ctx, true); err != nil {
+			return fmt.Errorf("failed to rebuild index: %w", err)
+		}
+	}
+
+	// Switching vaults applies the budget to the new index
+	if config.HeaderMemoryMB != old.HeaderMemoryMB && config.VaultPath == old.VaultPath {
+		s.applyHeaderMemory()
+	}
+
+	// The schedule is kept per vault
+	if config.DoctorIntervalHours != old.DoctorIntervalHours || config.VaultPath != old.VaultPath {
+		s.scheduleDoctor()
+	}
+
+	// Background scans follow the settings deciding which references are broken
+	if old.Diagnostics.ScanVault && !config.Diagnostics.ScanVault {
+		s.clearLinkScan(ctx)
+	} else if macrosChanged || config.Diagnostics != old.Diagnostics || !reflect.DeepEqual(config.Severities, old.Severities) || config.VaultPath != old.VaultPath || config.Features.Diagnostics != old.Features.Diagnostics {
+		s.scheduleLinkScan()
+	}
+
+	if macrosChanged || dateFormatsChanged || config.Diagnostics != old.Diagnostics || !reflect.DeepEqual(config.Severities, old.Severities) || !reflect.DeepEqual(config.LabelPrefixes, old.LabelPrefixes) || config.DuplicateRefThreshold != old.DuplicateRefThreshold || config.TagPolicy != old.TagPolicy || !reflect.DeepEqual(config.SkeletonIgnore, old.SkeletonIgnore) || config.VaultPath != old.VaultPath || config.Features.Diagnostics != old.Features.Diagnostics || config.Coexist != old.Coexist {
+		s.republishOpenDocuments(ctx)
+	}
+
+	return nil
+}
+
+// Handle Initialized notification
+// Registers capabilities the client prefers to receive dynamically
+func (s *LanguageServer) Initialized(ctx context.Context, params *protocol.InitializedParams) error {
+	// Settings pulled now still apply to the initial index, but not to the capabilities already advertised
+	if s.configurationPull {
+		settings, err := s.pullSettings(ctx)
+		if err == nil {
+			err = s.loadSettings(ctx, settings)
+		}
+		if err != nil {
+			s.logf(protocol.MessageTypeError, "Failed to load settings: %v", err)
+		}
+	}
+	s.startWarmUp(ctx)
+
+	features := s.settings().Features
+	// A client refusing one registration still gets the others
+	if s.dynamicWatchedFiles && features.Watchers {
+		if err := s.registerWatchedFiles(ctx); err != nil {
+			s.logf(protocol.MessageTypeError, "Failed to register file watchers: %v", err)
+		}
+	}
+
+	if !s.dynamicCompletion {
+		return nil
+	}
+	return s.registerCompletion(ctx, s.settings().TriggerCharacters)
+}
+
+// registerCompletion registers the completion capability with the client
+func (s *LanguageServer) registerCompletion(ctx context.Context, triggerCharacters []string) error {
+	_, err := s.conn.Call(ctx, protocol.MethodClientRegisterCapability, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{
+			{
+				ID:     completionRegistrationID,
+				Method: protocol.MethodTextDocumentCompletion,
+				RegisterOptions: protocol.CompletionRegistrationOptions{
+					TextDocumentRegistrationOptions: protocol.TextDocumentRegistrationOptions{
+						DocumentSelector: protocol.DocumentSelector{{Scheme: "file"}},
+					},
+					TriggerCharacters: triggerCharacters,
+				},
+			},
+		},
+	}, nil)
+	return err
+}
+
+// reregisterCompletion swaps the completion registration for one with new trigger characters
+func (s *LanguageServer) reregisterCompletion(ctx context.Context, triggerCharacters []string) error {
+	if _, err := s.conn.Call(ctx, protocol.MethodClientUnregisterCapability, &protocol.UnregistrationParams{
+		Unregisterations: []protocol.Unregistration{
+			{ID: completionRegistrationID, Method: protocol.MethodTextDocumentCompletion},
+		},
+	}, nil); err != nil {
+		return fmt.Errorf("failed to unregister completion: %w", err)
+	}
+
+	if err := s.registerCompletion(ctx, triggerCharacters); err != nil {
+		return fmt.Errorf("failed to register completion: %w", err)
+	}
+	return nil
+}
+
+// switchVault points the server at a different vault and rebuilds the index
+func (s *LanguageServer) switchVault(ctx context.Context, root string) error {
+	v := vaultAt(root)
+	if !v.Exists() {
+		return fmt.Errorf("vault not initialized at %s", v.RootPath)
+	}
+
+	if s.watcher != nil {
+		unwatchTree(s.watcher, s.vault.NotesPath)
+		unwatchTree(s.watcher, s.vault.AssetsPath)
+		s.watcher.Remove(s.vault.TemplatesPath)
+		if err := watchTree(s.watcher, v.NotesPath); err != nil {
+			return fmt.Errorf("failed to watch notes directory: %w", err)
+		}
+		watchTree(s.watcher, v.AssetsPath)
+		s.watcher.Add(v.TemplatesPath)
+	}
+
+	// The history of the vault left is saved before the new vault's takes its place
+	s.flushRecent()
+	journal := NewJournal(filepath.Join(v.RootPath, activityFilename))
+	recent := loadRecentHistory(filepath.Join(v.CachePath, recentFilename))
+
+	// Swap everything tied to the vault at once, so requests never mix the old vault with the new
+	s.mu.Lock()
+	previous := s.index
+	s.vault, s.index, s.journal, s.recent = v, NewIndex(), journal, recent
+	s.mu.Unlock()
+	previous.Headers().Close()
+	s.applyHeaderMemory()
+
+	if err := s.RebuildIndex(ctx); err != nil {
+		return fmt.Errorf("failed to rebuild index: %w", err)
+	}
+	s.recordActivity(ctx, "index.rebuild", fmt.Sprintf("switched vault to %s, indexed %d notes", root, s.index.Count()))
+
+	return nil
+}
+
+// republishOpenDocuments re-runs diagnostics on every open document
+func (s *LanguageServer) republishOpenDocuments(ctx context.Context) {
+	s.mu.RLock()
+	documents := make(map[protocol.DocumentURI]string, len(s.documents))
+	for uri, content := range s.documents {
+		documents[uri] = content
+	}
+	s.mu.RUnlock()
+
+	for uri, content := range documents {
+		s.publishDiagnostics(ctx, uri, content)
+	}
+}
+
+// vaultAt returns a vault rooted at the given directory, using the lx-cli layout
+func vaultAt(root string) *vault.Vault {
+	return &vault.Vault{
+		RootPath:      root,
+		NotesPath:     filepath.Join(root, "notes"),
+		TemplatesPath: filepath.Join(root, "templates"),
+		AssetsPath:    filepath.Join(root, "assets"),
+		CachePath:     filepath.Join(root, "cache"),
+	}
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestParseConfig tests decoding wrapped and bare settings objects
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings interface{}
+		wantTodo bool
+		wantRefs bool
+	}{
+		{
+			name:     "Wrapped section",
+			settings: map[string]interface{}{"lx-lsp": map[string]interface{}{"diagnostics": map[string]interface{}{"todos": false}}},
+			wantTodo: false,
+			wantRefs: true,
+		},
+		{
+			name:     "Bare settings",
+			settings: map[string]interface{}{"diagnostics": map[string]interface{}{"brokenRefs": false}},
+			wantTodo: true,
+			wantRefs: false,
+		},
+		{
+			name:     "Unrelated settings keep defaults",
+			settings: map[string]interface{}{"editor": true},
+			wantTodo: true,
+			wantRefs: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseConfig(tt.settings, DefaultConfig())
+			if err != nil {
+				t.Fatalf("parseConfig failed: %v", err)
+			}
+			if config.Diagnostics.Todos != tt.wantTodo || config.Diagnostics.BrokenRefs != tt.wantRefs {
+				t.Errorf("got diagnostics %+v", config.Diagnostics)
+			}
+			if len(config.TriggerCharacters) == 0 {
+				t.Error("expected default trigger characters to be kept")
+			}
+		})
+	}
+}
+
+// TestDidChangeConfiguration tests applying settings at runtime
+func TestDidChangeConfiguration(t *testing.T) {
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: t.TempDir()},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+
+	content := `\todo{Fix this}`
+	if got := len(ls.analyzeDiagnostics(content)); got != 1 {
+		t.Fatalf("expected 1 diagnostic before change, got %d", got)
+	}
+
+	// Disable TODO diagnostics
+	err := ls.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"lx-lsp": map[string]interface{}{"diagnostics": map[string]interface{}{"todos": false}}},
+	})
+	if err != nil {
+		t.Fatalf("DidChangeConfiguration failed: %v", err)
+	}
+	if got := len(ls.analyzeDiagnostics(content)); got != 0 {
+		t.Errorf("expected 0 diagnostics after disabling todos, got %d", got)
+	}
+
+	// Switch to another vault
+	root := filepath.Join(t.TempDir(), "lx")
+	notesPath := filepath.Join(root, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-moved.tex"), []byte("%% Metadata\n%% title: Moved"), 0644)
+
+	err = ls.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"lx-lsp": map[string]interface{}{"vaultPath": root}},
+	})
+	if err != nil {
+		t.Fatalf("DidChangeConfiguration failed: %v", err)
+	}
+	if _, exists := ls.index.Get("moved"); !exists {
+		t.Error("expected index to be rebuilt from the new vault")
+	}
+	if ls.settings().Diagnostics.Todos {
+		t.Error("expected earlier settings to be preserved")
+	}
+}
+
+// TestFeatureGroups tests that disabled feature groups are neither advertised nor served
+func TestFeatureGroups(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "20240101-a.tex")
+	os.WriteFile(testFile, []byte("\\ref{"), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	result, err := ls.Initialize(context.Background(), &protocol.InitializeParams{
+		InitializationOptions: map[string]interface{}{
+			"features": map[string]interface{}{"completion": false, "rename": false, "watchers": false},
+		},
+		Capabilities: protocol.ClientCapabilities{
+			TextDocument: &protocol.TextDocumentClientCapabilities{
+				Completion: &protocol.CompletionTextDocumentClientCapabilities{DynamicRegistration: true},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	if result.Capabilities.CompletionProvider != nil || ls.dynamicCompletion {
+		t.Error("expected completion not to be advertised or registered")
+	}
+	if result.Capabilities.RenameProvider != false {
+		t.Errorf("expected rename not to be advertised, got %v", result.Capabilities.RenameProvider)
+	}
+	if !result.Capabilities.HoverProvider.(bool) {
+		t.Error("expected features outside the disabled groups to stay advertised")
+	}
+
+	uri := pathToURI(testFile)
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 0, Character: 5},
+		},
+	})
+	if err != nil || len(list.Items) != 0 {
+		t.Errorf("expected no completions, got %v, %v", list, err)
+	}
+	edit, err := ls.Rename(context.Background(), &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}},
+		NewName:                    "b",
+	})
+	if err != nil || edit != nil {
+		t.Errorf("expected no rename edit, got %v, %v", edit, err)
+	}
+}
+
+// TestCoexistMode tests that generic LaTeX features are left to another server
+func TestCoexistMode(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "20240101-a.tex")
+	content := "We use NLP here, R\\&D.\nNatural language processing (NLP) is the topic.\n"
+	os.WriteFile(testFile, []byte(content), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	result, err := ls.Initialize(context.Background(), &protocol.InitializeParams{
+		InitializationOptions: map[string]interface{}{"coexist": true},
+	})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if result.Capabilities.SignatureHelpProvider != nil || result.Capabilities.DocumentOnTypeFormattingProvider != nil {
+		t.Error("expected signature help and on-type formatting not to be advertised")
+	}
+	if result.Capabilities.CompletionProvider == nil {
+		t.Error("expected note completion to stay advertised")
+	}
+
+	if len(acronymDiagnostics(content)) == 0 {
+		t.Fatal("expected the content to trigger an acronym diagnostic")
+	}
+	for _, diag := range ls.analyzeDiagnostics(content) {
+		if diag.Code == diagnosticCodeAcronymBeforeDefinition {
+			t.Errorf("expected no acronym diagnostics, got %v", diag)
+		}
+	}
+
+	uri := pathToURI(testFile)
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 2},
+		},
+	})
+	if err != nil || len(list.Items) != 0 {
+		t.Errorf("expected no snippet completions, got %v, %v", list, err)
+	}
+
+	position := protocol.Position{Line: 0, Character: 18}
+	if escapeHover(content, position) == nil {
+		t.Fatal("expected the content to have an escape hover")
+	}
+	hover, err := ls.Hover(context.Background(), &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		},
+	})
+	if err != nil || hover != nil {
+		t.Errorf("expected no escape hover, got %v, %v", hover, err)
+	}
+}
+
+// TestSeverities tests overriding and turning off diagnostics by code
+func TestSeverities(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex()}
+	err := ls.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"lx-lsp": map[string]interface{}{"severities": map[string]interface{}{"todo": "hint", "broken-ref": "off"}}},
+	})
+	if err != nil {
+		t.Fatalf("DidChangeConfiguration failed: %v", err)
+	}
+
+	diagnostics := ls.analyzeDiagnostics("\\todo{Fix this} \\ref{missing}")
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnosticCodeTodo || diagnostics[0].Severity != protocol.DiagnosticSeverityHint {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+
+	// Later settings add to the severities without touching the earlier ones
+	old := ls.settings()
+	config, err := parseConfig(map[string]interface{}{"severities": map[string]interface{}{"todo": "error"}}, old)
+	if err != nil {
+		t.Fatalf("parseConfig failed: %v", err)
+	}
+	if config.Severities["todo"] != "error" || config.Severities["broken-ref"] != "off" || old.Severities["todo"] != "hint" {
+		t.Errorf("unexpected severities %v, old %v", config.Severities, old.Severities)
+	}
+}
+
+// TestCompletionSettings tests turning off snippets and capping the number of items
+func TestCompletionSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "20240101-a.tex")
+	os.WriteFile(testFile, []byte("\n\\ref{"), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	for _, slug := range []string{"a", "b", "c"} {
+		ls.index.Set(slug, &NoteHeader{Slug: slug, Title: slug, Filename: "20240101-" + slug + ".tex"})
+	}
+	complete := func(line, character uint32) *protocol.CompletionList {
+		list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+				Position:     protocol.Position{Line: line, Character: character},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Completion failed: %v", err)
+		}
+		return list
+	}
+
+	if len(complete(0, 0).Items) == 0 {
+		t.Fatal("expected snippets by default")
+	}
+	ls.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+		Settings: map[string]interface{}{"completion": map[string]interface{}{"snippets": false, "maxItems": 2}},
+	})
+	if list := complete(0, 0); len(list.Items) != 0 {
+		t.Errorf("expected no snippets, got %d items", len(list.Items))
+	}
+	if list := complete(1, 5); len(list.Items) != 2 || !list.IsIncomplete {
+		t.Errorf("expected 2 items of an incomplete list, got %d items, incomplete %v", len(list.Items), list.IsIncomplete)
+	}
+}
+
+// TestPullSettings tests fetching settings from clients that send didChangeConfiguration without them
+func TestPullSettings(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.ConfigurationParams
+		if req.Method() != protocol.MethodWorkspaceConfiguration || json.Unmarshal(req.Params(), &params) != nil || params.Items[0].Section != configSection {
+			return reply(ctx, nil, jsonrpc2.ErrMethodNotFound)
+		}
+		return reply(ctx, []interface{}{map[string]interface{}{"diagnostics": map[string]interface{}{"todos": false}}}, nil)
+	})
+	defer client.Close()
+
+	ls := &LanguageServer{
+		vault:             &vault.Vault{NotesPath: t.TempDir()},
+		index:             NewIndex(),
+		conn:              jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide)),
+		configurationPull: true,
+	}
+	ls.conn.Go(context.Background(), jsonrpc2.MethodNotFoundHandler)
+
+	if err := ls.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{}); err != nil {
+		t.Fatalf("DidChangeConfiguration failed: %v", err)
+	}
+	if ls.settings().Diagnostics.Todos {
+		t.Error("expected the pulled settings to disable TODO diagnostics")
+	}
+}
+
+// TestInitializedRegistrations tests that a refused file watcher registration does not keep
+// completion from being registered
+func TestInitializedRegistrations(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	registered := make(chan string, 4)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.RegistrationParams
+		if req.Method() != protocol.MethodClientRegisterCapability || json.Unmarshal(req.Params(), &params) != nil {
+			return reply(ctx, nil, nil)
+		}
+		if params.Registrations[0].Method == protocol.MethodWorkspaceDidChangeWatchedFiles {
+			return reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InternalError, "watching is not supported"))
+		}
+		registered <- params.Registrations[0].Method
+		return reply(ctx, nil, nil)
+	})
+	defer client.Close()
+
+	ls := &LanguageServer{
+		vault:               &vault.Vault{NotesPath: t.TempDir()},
+		index:               NewIndex(),
+		conn:                jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide)),
+		dynamicWatchedFiles: true,
+		dynamicCompletion:   true,
+	}
+	ls.conn.Go(context.Background(), jsonrpc2.MethodNotFoundHandler)
+
+	if err := ls.Initialized(context.Background(), &protocol.InitializedParams{}); err != nil {
+		t.Fatalf("Initialized failed: %v", err)
+	}
+	if method := <-registered; method != protocol.MethodTextDocumentCompletion {
+		t.Errorf("expected completion to be registered, got %s", method)
+	}
+}
+
+// TestSwitchVault tests that switching vaults swaps the index, journal and open history together
+func TestSwitchVault(t *testing.T) {
+	first, second := vaultAt(t.TempDir()), vaultAt(t.TempDir())
+	for _, v := range []*vault.Vault{first, second} {
+		os.MkdirAll(v.NotesPath, 0755)
+		os.MkdirAll(v.CachePath, 0755)
+	}
+	os.WriteFile(filepath.Join(first.NotesPath, "20240101-old.tex"), []byte("%% Metadata\n% title: Old\n"), 0644)
+	os.WriteFile(filepath.Join(second.NotesPath, "20240101-new.tex"), []byte("%% Metadata\n% title: New\n"), 0644)
+
+	config := DefaultConfig()
+	ls := &LanguageServer{vault: first, index: NewIndex(), config: &config, documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+	ls.recordOpen(pathToURI(filepath.Join(first.NotesPath, "20240101-old.tex")))
+	journal := ls.journal
+
+	if err := ls.switchVault(context.Background(), second.RootPath); err != nil {
+		t.Fatalf("switchVault failed: %v", err)
+	}
+	if ls.vault.RootPath != second.RootPath || ls.journal == journal {
+		t.Errorf("expected the vault and journal to be replaced")
+	}
+	if _, exists := ls.index.Get("new"); !exists || ls.index.Count() != 1 {
+		t.Errorf("expected the index of the new vault, got %d notes", ls.index.Count())
+	}
+	if _, err := os.Stat(filepath.Join(first.CachePath, recentFilename)); err != nil {
+		t.Errorf("expected the history of the vault left to be saved: %v", err)
+	}
+	if _, opened := ls.recentNotes().list(); len(opened) != 0 {
+		t.Errorf("expected the new vault to start without history, got %v", opened)
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeInvalidDate marks metadata dates that are not YYYY-MM-DD
+const diagnosticCodeInvalidDate = "LX003"
+
+// diagnosticCodeLegacyDate marks metadata dates accepted through one of the configured dateFormats
+const diagnosticCodeLegacyDate = "LX004"
+
+// looseDateLayouts are the date spellings the quick fix knows how to rewrite
+// Both month-first and day-first orders are tried, so ambiguous dates yield two fixes
+var looseDateLayouts = []string{
+	"01/02/2006",
+	"1/2/2006",
+	"02/01/2006",
+	"2/1/2006",
+	"2006/01/02",
+	"2006/1/2",
+	"2006-1-2",
+	"2006.01.02",
+	"02.01.2006",
+	"2.1.2006",
+	"01-02-2006",
+	"02-01-2006",
+	"20060102",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+func init() {
+	registerQuickFix(diagnosticCodeInvalidDate, fixDateFormat)
+	registerQuickFix(diagnosticCodeLegacyDate, fixDateFormat)
+}
+
+// dateDiagnostics reports metadata dates the parser rejects, and those written in a legacy format
+func (s *LanguageServer) dateDiagnostics(content string) []protocol.Diagnostic {
+	result, err := s.metadataParser().Parse(content)
+	if err != nil {
+		return nil
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, parseErr := range result.Errors {
+		if (parseErr.Field != "date" && parseErr.Field != "modified") || parseErr.Line == 0 {
+			continue
+		}
+		// Parser lines are 1-based
+		line := parseErr.Line - 1
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange(line, parseErr.Column, parseErr.Column+parseErr.Length),
+			Severity: protocol.DiagnosticSeverityWarning,
+			Code:     diagnosticCodeInvalidDate,
+			Message:  parseErr.Message,
+			Source:   "lx-ls",
+		})
+	}
+	for _, legacy := range result.Legacy {
+		line := legacy.Line - 1
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange(line, legacy.Column, legacy.Column+legacy.Length),
+			Severity: protocol.DiagnosticSeverityInformation,
+			Code:     diagnosticCodeLegacyDate,
+			Message:  legacy.Message,
+			Source:   "lx-ls",
+		})
+	}
+	return diagnostics
+}
+
+// fixDateFormat rewrites an invalid or legacy metadata date to YYYY-MM-DD
+// Dates in a configured dateFormat convert unambiguously; others are guessed through looseDateLayouts
+func fixDateFormat(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	lines := strings.Split(req.Content, "\n")
+	line := int(diag.Range.Start.Line)
+	if line >= len(lines) || diag.Range.Start.Line != diag.Range.End.Line || int(diag.Range.End.Character) > len(lines[line]) {
+		return nil
+	}
+	value := lines[line][diag.Range.Start.Character:diag.Range.End.Character]
+
+	candidates := parseLooseDate(value)
+	if diag.Code == diagnosticCodeLegacyDate {
+		normalized, _, ok := s.metadataParser().NormalizeDate(value)
+		date, err := time.Parse("2006-01-02", normalized)
+		if !ok || err != nil {
+			return nil
+		}
+		candidates = []time.Time{date}
+	}
+	actions := []protocol.CodeAction{}
+	for _, candidate := range candidates {
+		formatted := candidate.Format("2006-01-02")
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Change date to %s", formatted),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: len(candidates) == 1,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					req.URI: {{Range: diag.Range, NewText: formatted}},
+				},
+			},
+		})
+	}
+	return actions
+}
+
+// parseLooseDate returns every distinct date value could denote under looseDateLayouts
+func parseLooseDate(value string) []time.Time {
+	value = strings.TrimSpace(value)
+
+	var dates []time.Time
+	seen := make(map[time.Time]bool)
+	for _, layout := range looseDateLayouts {
+		date, err := time.Parse(layout, value)
+		if err != nil || seen[date] {
+			continue
+		}
+		seen[date] = true
+		dates = append(dates, date)
+	}
+	return dates
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"testing"
+)
+
+// TestDateDiagnostics tests reporting and fixing invalid metadata dates
+func TestDateDiagnostics(t *testing.T) {
+	content := "%% Metadata\n% title: Test\n% date: 01/15/2024\n% tags: a\n"
+
+	ls := &LanguageServer{index: NewIndex()}
+
+	diagnostics := ls.dateDiagnostics(content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+	}
+	diag := diagnostics[0]
+	if diag.Range.Start.Line != 2 || diag.Range.Start.Character != 8 || diag.Range.End.Character != 18 {
+		t.Errorf("unexpected range %+v", diag.Range)
+	}
+
+	req := &codeActionRequest{URI: "file:///note.tex", Content: content}
+	actions := fixDateFormat(nil, req, diag)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 fix, got %d", len(actions))
+	}
+	edit := actions[0].Edit.Changes[req.URI][0]
+	if edit.NewText != "2024-01-15" || edit.Range != diag.Range {
+		t.Errorf("unexpected edit %+v", edit)
+	}
+
+	// Valid dates produce no diagnostics
+	if diagnostics := ls.dateDiagnostics("%% Metadata\n% title: Test\n% date: 2024-01-15\n"); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %d", len(diagnostics))
+	}
+}
+
+// TestParseLooseDate tests interpretation of common date spellings
+func TestParseLooseDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"01/15/2024", []string{"2024-01-15"}},
+		{"15.01.2024", []string{"2024-01-15"}},
+		{"March 3, 2024", []string{"2024-03-03"}},
+		{"03/04/2024", []string{"2024-03-04", "2024-04-03"}},
+		{"not-a-date", nil},
+	}
+
+	for _, tt := range tests {
+		dates := parseLooseDate(tt.value)
+		if len(dates) != len(tt.expected) {
+			t.Errorf("parseLooseDate(%q) returned %d dates, want %d", tt.value, len(dates), len(tt.expected))
+			continue
+		}
+		for i, date := range dates {
+			if got := date.Format("2006-01-02"); got != tt.expected[i] {
+				t.Errorf("parseLooseDate(%q)[%d] = %s, want %s", tt.value, i, got, tt.expected[i])
+			}
+		}
+	}
+}
+
+// TestLegacyDateFormats tests accepting configured date formats, reporting them and converting them to YYYY-MM-DD
+func TestLegacyDateFormats(t *testing.T) {
+	config, err := parseConfig(map[string]interface{}{"dateFormats": []string{"DD.MM.YYYY"}}, DefaultConfig())
+	if err != nil {
+		t.Fatalf("parseConfig failed: %v", err)
+	}
+	ls := &LanguageServer{index: NewIndex(), config: &config}
+
+	content := "%% Metadata\n% title: Test\n% date: 05.03.2021\n"
+	diagnostics := ls.dateDiagnostics(content)
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnosticCodeLegacyDate || diagnostics[0].Range.Start.Character != 8 {
+		t.Fatalf("expected one legacy date diagnostic, got %+v", diagnostics)
+	}
+
+	// 05.03.2021 is ambiguous to the loose layouts, but not under the configured format
+	req := &codeActionRequest{URI: "file:///note.tex", Content: content}
+	actions := fixDateFormat(ls, req, diagnostics[0])
+	if len(actions) != 1 || actions[0].Edit.Changes[req.URI][0].NewText != "2021-03-05" {
+		t.Errorf("unexpected fixes %+v", actions)
+	}
+
+	if _, err := parseConfig(map[string]interface{}{"dateFormats": []string{"DD.MM"}}, DefaultConfig()); err == nil {
+		t.Error("expected a date format without a year to be rejected")
+	}
+}
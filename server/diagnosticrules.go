@@ -0,0 +1,155 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticDocsURL is the page explaining every diagnostic rule, generated from diagnosticRules
+// into docs/diagnostics.md: go test ./server -run TestDiagnosticDocs -update
+const diagnosticDocsURL = "https://github.com/kamal-hamza/lx-lsp/blob/main/docs/diagnostics.md"
+
+// diagnosticRule documents a diagnostic code
+// Codes are stable: a retired rule keeps its code unused rather than handing it to another rule
+type diagnosticRule struct {
+	Code        string
+	Name        string // the rule's name, accepted for the code in the severities setting
+	Setting     string // diagnostics setting switching the rule off, "" for rules that cannot be
+	Summary     string
+	Explanation string
+}
+
+// diagnosticRules lists every diagnostic the server reports, by code
+var diagnosticRules = []diagnosticRule{
+	{
+		Code: diagnosticCodeBrokenRef, Name: "broken-ref", Setting: "brokenRefs",
+		Summary:     "Reference to a note that does not exist",
+		Explanation: "A `\\ref{}`, `\\cite{}` or reference macro names a slug no note of the vault or its workspace folders uses. Notes moved to the trash are pointed out, with a quick fix restoring them, and references written before the note exists can be fixed with `lx.fixDanglingReferences`.",
+	},
+	{
+		Code: diagnosticCodeTodo, Name: "todo", Setting: "todos",
+		Summary:     "Open TODO marker",
+		Explanation: "Each `\\todo{}` in a note is reported with its text, so open work shows in the problems panel. `lx.listTodos` lists them across the vault.",
+	},
+	{
+		Code: diagnosticCodeInvalidDate, Name: "invalid-date", Setting: "dates",
+		Summary:     "Metadata date that cannot be read",
+		Explanation: "The `date` or `modified` metadata field is not a valid `YYYY-MM-DD` date nor in one of the configured `dateFormats`. A quick fix rewrites dates whose format can be guessed.",
+	},
+	{
+		Code: diagnosticCodeLegacyDate, Name: "legacy-date", Setting: "dates",
+		Summary:     "Metadata date in a legacy format",
+		Explanation: "The date is in one of the configured `dateFormats` rather than `YYYY-MM-DD`. It is read correctly; a quick fix normalizes it.",
+	},
+	{
+		Code: diagnosticCodeAcronymBeforeDefinition, Name: "acronym-before-definition", Setting: "acronyms",
+		Summary:     "Acronym used before its definition",
+		Explanation: "An acronym defined with `\\newacronym` later in the note is written out earlier, where readers meet it unexplained. Left to the other server when `coexist` is set.",
+	},
+	{
+		Code: diagnosticCodeTagPolicy, Name: "tag-policy", Setting: "tags",
+		Summary:     "Tag breaking the tag policy",
+		Explanation: "A metadata tag does not follow `tagPolicy`: its case, allowed characters or length. A quick fix rewrites the tag.",
+	},
+	{
+		Code: diagnosticCodeMissingStructure, Name: "missing-structure", Setting: "skeleton",
+		Summary:     "Structure required by the note's template is missing",
+		Explanation: "A template the note loads declares `% lx-requires:` structure the note does not contain. A quick fix inserts it; templates listed in `skeletonIgnore` are not checked.",
+	},
+	{
+		Code: diagnosticCodeDuplicateRef, Name: "duplicate-ref", Setting: "duplicateRefs",
+		Summary:     "Note referenced repeatedly within a paragraph",
+		Explanation: "A paragraph references the same note `duplicateRefThreshold` times or more, which usually reads better with a single reference.",
+	},
+	{
+		Code: diagnosticCodeInvalidEngine, Name: "invalid-engine", Setting: "engine",
+		Summary:     "Unknown compile engine",
+		Explanation: "The `engine` metadata field is not one of `pdflatex`, `xelatex`, `lualatex` or `tectonic`.",
+	},
+	{
+		Code: diagnosticCodeInvalidMetadata, Name: "invalid-metadata",
+		Summary:     "Invalid metadata block",
+		Explanation: "The metadata block of a note that is not open could not be read completely, for instance because its title is missing. Reported through workspace diagnostics.",
+	},
+	{
+		Code: diagnosticCodeDuplicateSlug, Name: "duplicate-slug",
+		Summary:     "Slug used by more than one file",
+		Explanation: "Two note files resolve to the same slug, such as `20240101-foo.tex` and `20240202-foo.tex`, so references to it are ambiguous. Files of the same directory are reported on both notes, open or not, and references go to the first filename in sort order; files of different vaults are reported through workspace diagnostics, with the vault of the other file.",
+	},
+	{
+		Code: diagnosticCodeLabelPrefix, Name: "label-prefix", Setting: "labelPrefixes",
+		Summary:     "Label missing the prefix of its environment",
+		Explanation: "A `\\label` inside an environment listed in `labelPrefixes` does not start with its prefix, e.g. `fig:` in a figure. A quick fix renames the label along with its references.",
+	},
+	{
+		Code: diagnosticCodeDuplicateLabel, Name: "duplicate-label", Setting: "duplicateLabels",
+		Summary:     "Label defined more than once",
+		Explanation: "A label is defined twice in the note or also in another note, which leaves references to it ambiguous.",
+	},
+	{
+		Code: diagnosticCodeMissingAsset, Name: "missing-asset", Setting: "missingAssets",
+		Summary:     "Graphic missing from the assets directory",
+		Explanation: "The file an `\\includegraphics` names is not in the assets directory, with or without one of the usual graphics extensions.",
+	},
+	{
+		Code: diagnosticCodeAmbiguousRef, Name: "ambiguous-ref", Setting: "brokenRefs",
+		Summary:     "Reference to a slug several vaults use",
+		Explanation: "Notes of more than one open vault use the slug. The reference goes to the note of the referencing note's own vault; write `vault/slug` to name another.",
+	},
+	{
+		Code: diagnosticCodeCompileArgs, Name: "compile-args", Setting: "engine",
+		Summary:     "Compile argument that is not allowed",
+		Explanation: "The `compileargs` metadata field holds an argument outside the allowed ones, such as `-shell-escape` or latexmk's `-e`, which could run commands when the note is compiled. Allowed are `-synctex=1`, `-synctex=0`, `-halt-on-error`, `-file-line-error`, the `-interaction=` modes, `-draftmode`, `-recorder`, `-no-shell-escape`, `-quiet`, `-silent`, and tectonic's `--synctex`, `--keep-logs` and `--keep-intermediates`. The note is not compiled until the argument is removed.",
+	},
+}
+
+// diagnosticRuleByCode looks up a rule by its code or name
+func diagnosticRuleByCode(code string) (diagnosticRule, bool) {
+	for _, rule := range diagnosticRules {
+		if rule.Code == code || rule.Name == code {
+			return rule, true
+		}
+	}
+	return diagnosticRule{}, false
+}
+
+// diagnosticDocsAnchor is the anchor of a rule's section in the generated page
+func diagnosticDocsAnchor(rule diagnosticRule) string {
+	return strings.ToLower(rule.Code + "-" + rule.Name)
+}
+
+// describeDiagnostics links each diagnostic to the explanation of its rule
+func describeDiagnostics(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	for i := range diagnostics {
+		code, _ := diagnostics[i].Code.(string)
+		if rule, ok := diagnosticRuleByCode(code); ok {
+			diagnostics[i].CodeDescription = &protocol.CodeDescription{
+				Href: protocol.URI(diagnosticDocsURL + "#" + diagnosticDocsAnchor(rule)),
+			}
+		}
+	}
+	return diagnostics
+}
+
+// diagnosticDocs renders the explanation page of every rule
+func diagnosticDocs() string {
+	var builder strings.Builder
+	builder.WriteString("# Diagnostics\n\n")
+	builder.WriteString("<!-- Generated from server/diagnosticrules.go: go test ./server -run TestDiagnosticDocs -update -->\n\n")
+	builder.WriteString("Every diagnostic of `lx-lsp` carries a stable code. Rules can be switched off in the `diagnostics` settings, and `severities` changes the severity of a code, or drops it with `off`; both the code and the rule's name are accepted there.\n\n")
+	builder.WriteString("| Code | Rule | Summary |\n| --- | --- | --- |\n")
+	for _, rule := range diagnosticRules {
+		fmt.Fprintf(&builder, "| [%s](#%s) | `%s` | %s |\n", rule.Code, diagnosticDocsAnchor(rule), rule.Name, rule.Summary)
+	}
+	for _, rule := range diagnosticRules {
+		fmt.Fprintf(&builder, "\n## %s %s\n\n%s.\n\n%s\n", rule.Code, rule.Name, rule.Summary, rule.Explanation)
+		if rule.Setting != "" {
+			fmt.Fprintf(&builder, "\nSwitched off with `\"diagnostics\": {\"%s\": false}`, or `\"severities\": {\"%s\": \"off\"}`.\n", rule.Setting, rule.Code)
+		} else {
+			fmt.Fprintf(&builder, "\nDropped with `\"severities\": {\"%s\": \"off\"}`.\n", rule.Code)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticDocsPath is the generated explanation page, relative to the package
+var diagnosticDocsPath = filepath.Join("..", "docs", "diagnostics.md")
+
+// TestDiagnosticDocs tests that every rule has a unique code and docs/diagnostics.md is current
+func TestDiagnosticDocs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, rule := range diagnosticRules {
+		if !strings.HasPrefix(rule.Code, "LX") || len(rule.Code) != 5 || seen[rule.Code] || seen[rule.Name] {
+			t.Errorf("invalid or duplicate rule %s %s", rule.Code, rule.Name)
+		}
+		seen[rule.Code], seen[rule.Name] = true, true
+	}
+
+	got := diagnosticDocs()
+	if *updateGolden {
+		os.MkdirAll(filepath.Dir(diagnosticDocsPath), 0755)
+		if err := os.WriteFile(diagnosticDocsPath, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(diagnosticDocsPath)
+	if err != nil {
+		t.Fatalf("missing %s, run with -update to generate it: %v", diagnosticDocsPath, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s is out of date, run with -update to regenerate it", diagnosticDocsPath)
+	}
+}
+
+// TestDiagnosticCodes tests linking diagnostics to their rule and configuring rules by name or code
+func TestDiagnosticCodes(t *testing.T) {
+	diagnostics := describeDiagnostics([]protocol.Diagnostic{{Code: diagnosticCodeBrokenRef}, {Code: "other"}})
+	if diagnostics[0].CodeDescription == nil || diagnostics[0].CodeDescription.Href != protocol.URI(diagnosticDocsURL+"#lx001-broken-ref") {
+		t.Errorf("unexpected code description %+v", diagnostics[0].CodeDescription)
+	}
+	if diagnostics[1].CodeDescription != nil {
+		t.Error("expected unknown codes to have no description")
+	}
+
+	ls := &LanguageServer{config: &Config{Severities: map[string]string{"todo": "off", diagnosticCodeBrokenRef: "hint"}}}
+	kept := ls.applySeverities([]protocol.Diagnostic{
+		{Code: diagnosticCodeTodo, Severity: protocol.DiagnosticSeverityWarning},
+		{Code: diagnosticCodeBrokenRef, Severity: protocol.DiagnosticSeverityError},
+	})
+	if len(kept) != 1 || kept[0].Code != diagnosticCodeBrokenRef || kept[0].Severity != protocol.DiagnosticSeverityHint {
+		t.Errorf("expected todo turned off by name and broken-ref lowered by code, got %+v", kept)
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// MethodDiffOutline is the custom request comparing the structure of an open buffer with the note on disk
+const MethodDiffOutline = "lx/diffOutline"
+
+// DiffOutlineParams selects the note to compare
+type DiffOutlineParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// OutlineEntry is a section, reference or TODO of a note
+// Ranges of added entries point into the buffer, ranges of removed entries into the file on disk
+type OutlineEntry struct {
+	Text  string         `json:"text"`
+	Level int            `json:"level,omitempty"` // sections only, 0 for \chapter
+	Range protocol.Range `json:"range"`
+}
+
+// OutlineChanges lists the entries of one kind the buffer gained or lost
+type OutlineChanges struct {
+	Added   []OutlineEntry `json:"added"`
+	Removed []OutlineEntry `json:"removed"`
+}
+
+// DiffOutlineResult summarizes the structural changes of an editing session
+type DiffOutlineResult struct {
+	URI        protocol.DocumentURI `json:"uri"`
+	Modified   bool                 `json:"modified"` // the buffer differs from disk at all
+	Sections   OutlineChanges       `json:"sections"`
+	References OutlineChanges       `json:"references"`
+	Todos      OutlineChanges       `json:"todos"`
+}
+
+// noteOutline is the structure of one version of a note
+type noteOutline struct {
+	sections   []OutlineEntry
+	references []OutlineEntry
+	todos      []OutlineEntry
+}
+
+// Handle lx/diffOutline request
+// A note missing on disk compares against an empty file, so everything in it is added
+func (s *LanguageServer) DiffOutline(ctx context.Context, params *DiffOutlineParams) (*DiffOutlineResult, error) {
+	uri := params.TextDocument.URI
+	if !s.IsManaged(uri) {
+		return nil, fmt.Errorf("%s is not a note", uri)
+	}
+
+	buffer, err := s.GetDocument(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read note: %w", err)
+	}
+
+	disk := ""
+	data, err := os.ReadFile(uriToPath(uri))
+	switch {
+	case err == nil:
+		disk = metadata.Normalize(string(data))
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read note: %w", err)
+	}
+
+	before := s.noteOutline(disk)
+	after := s.noteOutline(buffer)
+	return &DiffOutlineResult{
+		URI:        uri,
+		Modified:   buffer != disk,
+		Sections:   diffOutlineEntries(before.sections, after.sections),
+		References: diffOutlineEntries(before.references, after.references),
+		Todos:      diffOutlineEntries(before.todos, after.todos),
+	}, nil
+}
+
+// noteOutline extracts the sections, references and TODOs of note content
+func (s *LanguageServer) noteOutline(content string) noteOutline {
+	var outline noteOutline
+
+	for lineNum, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		for _, match := range sectionPattern.FindAllStringSubmatchIndex(line, -1) {
+			outline.sections = append(outline.sections, OutlineEntry{
+				Text:  strings.TrimSpace(line[match[4]:match[5]]),
+				Level: sectionLevels[line[match[2]:match[3]]],
+				Range: lineRange(lineNum, match[0], match[1]),
+			})
+		}
+	}
+
+	for _, link := range extractLinks(s.linkPattern(), "", "", content) {
+		outline.references = append(outline.references, OutlineEntry{Text: link.Target, Range: link.Full})
+	}
+
+	for _, todo := range extractTodos("", content) {
+		outline.todos = append(outline.todos, OutlineEntry{Text: todo.Text, Range: todo.Range})
+	}
+
+	return outline
+}
+
+// diffOutlineEntries pairs equal entries of the two versions in order
+// Moving an entry is not a change; repeating one adds only the extra copy
+func diffOutlineEntries(before, after []OutlineEntry) OutlineChanges {
+	type key struct {
+		text  string
+		level int
+	}
+
+	unmatched := make(map[key][]OutlineEntry)
+	for _, entry := range before {
+		k := key{entry.Text, entry.Level}
+		unmatched[k] = append(unmatched[k], entry)
+	}
+
+	changes := OutlineChanges{Added: []OutlineEntry{}, Removed: []OutlineEntry{}}
+	for _, entry := range after {
+		k := key{entry.Text, entry.Level}
+		if len(unmatched[k]) > 0 {
+			unmatched[k] = unmatched[k][1:]
+			continue
+		}
+		changes.Added = append(changes.Added, entry)
+	}
+
+	// Walk before again to report removals in document order
+	for _, entry := range before {
+		k := key{entry.Text, entry.Level}
+		if len(unmatched[k]) > 0 && unmatched[k][0].Range == entry.Range {
+			changes.Removed = append(changes.Removed, entry)
+			unmatched[k] = unmatched[k][1:]
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestDiffOutline tests that structural changes of the buffer are reported against the disk version
+func TestDiffOutline(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "20240101-a.tex")
+	disk := "\\section{Intro}\n\\ref{b} and \\ref{c}\n\\todo{fix intro}\n\\section{Old}\n"
+	os.WriteFile(path, []byte(disk), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	uri := pathToURI(path)
+	params := &DiffOutlineParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}}
+
+	// A note without unsaved changes has nothing to report
+	result, err := ls.DiffOutline(context.Background(), params)
+	if err != nil {
+		t.Fatalf("DiffOutline failed: %v", err)
+	}
+	if result.Modified || len(result.Sections.Added)+len(result.Sections.Removed) != 0 {
+		t.Errorf("expected no changes, got %+v", result)
+	}
+
+	ls.documents[uri] = "\\section{Intro}\n\\ref{c} and \\ref{b}\n\\ref{c}\n\\subsection{New}\n"
+
+	result, err = ls.DiffOutline(context.Background(), params)
+	if err != nil {
+		t.Fatalf("DiffOutline failed: %v", err)
+	}
+	if !result.Modified {
+		t.Error("expected buffer to be modified")
+	}
+	if len(result.Sections.Added) != 1 || result.Sections.Added[0].Text != "New" || result.Sections.Added[0].Level != 2 {
+		t.Errorf("expected subsection New added, got %+v", result.Sections.Added)
+	}
+	if len(result.Sections.Removed) != 1 || result.Sections.Removed[0].Text != "Old" || result.Sections.Removed[0].Range.Start.Line != 3 {
+		t.Errorf("expected section Old removed from line 3, got %+v", result.Sections.Removed)
+	}
+	// Reordered references are unchanged; only the repeated one is added
+	if len(result.References.Added) != 1 || result.References.Added[0].Text != "c" || result.References.Added[0].Range.Start.Line != 2 {
+		t.Errorf("expected second reference to c added, got %+v", result.References.Added)
+	}
+	if len(result.References.Removed) != 0 {
+		t.Errorf("expected no references removed, got %+v", result.References.Removed)
+	}
+	if len(result.Todos.Removed) != 1 || result.Todos.Removed[0].Text != "fix intro" || len(result.Todos.Added) != 0 {
+		t.Errorf("expected TODO removed, got %+v", result.Todos)
+	}
+}
+
+// TestDiffOutlineUnsavedNote tests that a note missing on disk reports everything as added
+func TestDiffOutlineUnsavedNote(t *testing.T) {
+	tempDir := t.TempDir()
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	uri := pathToURI(filepath.Join(tempDir, "20240101-new.tex"))
+	ls.documents[uri] = "\\section{Draft}\n\\todo{write}\n"
+
+	result, err := ls.DiffOutline(context.Background(), &DiffOutlineParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if err != nil {
+		t.Fatalf("DiffOutline failed: %v", err)
+	}
+	if len(result.Sections.Added) != 1 || len(result.Todos.Added) != 1 {
+		t.Errorf("expected everything added, got %+v", result)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// doctorStampFilename is the file in the vault cache keeping when lx.doctor last ran in the background,
+// so the schedule carries over editor sessions shorter than the interval
+const doctorStampFilename = "doctor-last-run"
+
+// doctorInterval is the time between background lx.doctor runs, 0 when they are off
+func (s *LanguageServer) doctorInterval() time.Duration {
+	return time.Duration(s.settings().DoctorIntervalHours) * time.Hour
+}
+
+// doctorStampPath returns where the time of the last background run is kept
+func (s *LanguageServer) doctorStampPath() string {
+	if s.vault == nil || s.vault.CachePath == "" {
+		return ""
+	}
+	return filepath.Join(s.vault.CachePath, doctorStampFilename)
+}
+
+// lastDoctorRun returns when lx.doctor last ran in the background, zero if never
+func (s *LanguageServer) lastDoctorRun() time.Time {
+	path := s.doctorStampPath()
+	if path == "" {
+		return time.Time{}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	last, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}
+	}
+	return last
+}
+
+// saveDoctorRun records a background run
+func (s *LanguageServer) saveDoctorRun(at time.Time) error {
+	path := s.doctorStampPath()
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(at.Format(time.RFC3339)+"\n"), 0644)
+}
+
+// scheduleDoctor (re)arms the background lx.doctor run for doctorIntervalHours after the last one
+// A run overdue when the server starts happens right away; an interval of 0 disarms it
+func (s *LanguageServer) scheduleDoctor() {
+	interval := s.doctorInterval()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doctorTimer != nil {
+		s.doctorTimer.Stop()
+		s.doctorTimer = nil
+	}
+	if interval <= 0 || s.stopped {
+		return
+	}
+
+	delay := time.Until(s.lastDoctorRun().Add(interval))
+	if delay < 0 {
+		delay = 0
+	}
+	s.doctorTimer = time.AfterFunc(delay, func() {
+		s.runScheduledDoctor(context.Background(), time.Now())
+		s.scheduleDoctor()
+	})
+}
+
+// stopSchedules disarms the background lx.doctor run and broken reference scan for good, once the
+// server shuts down
+func (s *LanguageServer) stopSchedules() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	if s.doctorTimer != nil {
+		s.doctorTimer.Stop()
+		s.doctorTimer = nil
+	}
+	if s.linkScanTimer != nil {
+		s.linkScanTimer.Stop()
+		s.linkScanTimer = nil
+	}
+}
+
+// runScheduledDoctor runs lx.doctor in the background, writes its summary to the activity log and
+// shows the number of problems when something needs attention
+func (s *LanguageServer) runScheduledDoctor(ctx context.Context, now time.Time) {
+	report := s.doctor()
+	summary, problems := doctorSummary(report)
+	if err := s.saveDoctorRun(now); err != nil {
+		s.logf(protocol.MessageTypeWarning, "Failed to save the doctor schedule: %v", err)
+	}
+	s.recordActivity(ctx, "doctor.run", summary)
+	if !problems {
+		s.logf(protocol.MessageTypeInfo, "%s", summary)
+		return
+	}
+	if s.conn != nil {
+		s.conn.Notify(ctx, protocol.MethodWindowShowMessage, &protocol.ShowMessageParams{
+			Type:    protocol.MessageTypeWarning,
+			Message: "lx-lsp: vault check found " + duplicateCount(report) + "; run lx.doctor for details",
+		})
+	}
+}
+
+// doctorSummary condenses a doctor report to one line, reporting whether it found anything
+func doctorSummary(report *DoctorReport) (string, bool) {
+	if len(report.Duplicates) == 0 {
+		return "vault check found no problems", false
+	}
+	groups := make([]string, len(report.Duplicates))
+	for i, group := range report.Duplicates {
+		groups[i] = strings.Join(group.Notes, ", ")
+	}
+	return fmt.Sprintf("vault check found %s (%s)", duplicateCount(report), strings.Join(groups, "; ")), true
+}
+
+// duplicateCount returns the number of duplicate groups of a report, e.g. "2 groups of duplicate notes"
+func duplicateCount(report *DoctorReport) string {
+	noun := "groups"
+	if len(report.Duplicates) == 1 {
+		noun = "group"
+	}
+	return fmt.Sprintf("%d %s of duplicate notes", len(report.Duplicates), noun)
+}
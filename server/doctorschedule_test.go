@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestScheduledDoctor tests background doctor runs: their summary, activity entry and schedule
+func TestScheduledDoctor(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	for _, name := range []string{"20240101-a.tex", "20240102-b.tex"} {
+		os.WriteFile(filepath.Join(notesPath, name), []byte("%% Metadata\n% title: Copy\nSame body."), 0644)
+	}
+	journalPath := filepath.Join(tempDir, activityFilename)
+	ls := &LanguageServer{
+		vault:     &vault.Vault{RootPath: tempDir, NotesPath: notesPath, CachePath: filepath.Join(tempDir, ".cache")},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+		journal:   NewJournal(journalPath),
+	}
+	ls.RebuildIndex(context.Background())
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	ls.runScheduledDoctor(context.Background(), now)
+	if last := ls.lastDoctorRun(); !last.Equal(now) {
+		t.Errorf("expected the run to be recorded at %v, got %v", now, last)
+	}
+	journal, _ := os.ReadFile(journalPath)
+	if !strings.Contains(string(journal), "doctor.run") || !strings.Contains(string(journal), "1 group of duplicate notes (a, b)") {
+		t.Errorf("expected the summary in the activity log, got %q", journal)
+	}
+
+	if summary, problems := doctorSummary(&DoctorReport{}); problems || summary != "vault check found no problems" {
+		t.Errorf("unexpected summary of a clean vault %q", summary)
+	}
+	if count := duplicateCount(&DoctorReport{Duplicates: make([]DuplicateGroup, 3)}); count != "3 groups of duplicate notes" {
+		t.Errorf("unexpected count %q", count)
+	}
+
+	// The last run was long ago, so the next one is due right away
+	ls.config = &Config{DoctorIntervalHours: 24}
+	ls.scheduleDoctor()
+	for deadline := time.Now().Add(5 * time.Second); ls.lastDoctorRun().Equal(now); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the overdue run")
+		}
+	}
+
+	ls.mu.Lock()
+	ls.config = &Config{}
+	ls.mu.Unlock()
+	ls.scheduleDoctor()
+	ls.mu.RLock()
+	if ls.doctorTimer != nil {
+		t.Error("expected an interval of 0 to turn the runs off")
+	}
+	ls.mu.RUnlock()
+
+	// Once the server shuts down, runs are no longer scheduled
+	ls.mu.Lock()
+	ls.config = &Config{DoctorIntervalHours: 24}
+	ls.mu.Unlock()
+	ls.stopSchedules()
+	ls.scheduleDoctor()
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	if ls.doctorTimer != nil {
+		t.Error("expected no run to be scheduled after shutdown")
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// graphicsPattern matches \includegraphics with optional options
+var graphicsPattern = regexp.MustCompile(`\\includegraphics(?:\[[^\]]*\])?\{([^}]+)\}`)
+
+// graphicsExtensions are tried in order when \includegraphics omits the extension
+var graphicsExtensions = []string{".pdf", ".png", ".jpg", ".jpeg", ".eps", ".svg"}
+
+// Handle DocumentLink request
+// Returns clickable links for note references and included assets that resolve on disk
+func (s *LanguageServer) DocumentLink(ctx context.Context, params *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	return s.documentLinks(content), nil
+}
+
+// documentLinks scans content for link targets
+func (s *LanguageServer) documentLinks(content string) []protocol.DocumentLink {
+	links := []protocol.DocumentLink{}
+
+	lines := s.documentLines(content)
+	for lineNum, line := range lines {
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+
+		for _, match := range s.linkPattern().FindAllStringSubmatchIndex(line, -1) {
+			slug := normalizeSlug(line[match[2]:match[3]])
+			note, exists := s.resolveNote(slug, "")
+			if !exists {
+				continue
+			}
+			links = append(links, protocol.DocumentLink{
+				Range:   lineRange(lineNum, match[2], match[3]),
+				Target:  pathToURI(s.headerPath(note)),
+				Tooltip: note.Title,
+			})
+		}
+
+		for _, match := range graphicsPattern.FindAllStringSubmatchIndex(line, -1) {
+			path := s.resolveAsset(strings.TrimSpace(line[match[2]:match[3]]))
+			if path == "" {
+				continue
+			}
+			links = append(links, protocol.DocumentLink{
+				Range:   lineRange(lineNum, match[2], match[3]),
+				Target:  pathToURI(path),
+				Tooltip: filepath.Base(path),
+			})
+		}
+	}
+
+	return links
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestDocumentLinks tests link resolution for note references and assets
+func TestDocumentLinks(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	assetsPath := filepath.Join(tempDir, "assets")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(assetsPath, 0755)
+	os.WriteFile(filepath.Join(assetsPath, "diagram.png"), []byte("png"), 0644)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath, AssetsPath: assetsPath},
+		index: NewIndex(),
+	}
+	ls.index.Set("graph-theory", &NoteHeader{
+		Title:    "Graph Theory",
+		Slug:     "graph-theory",
+		Filename: "20240101-graph-theory.tex",
+	})
+	ls.indexAssets()
+
+	content := `See \ref{graph-theory} and \ref{missing}.
+\input{../notes/graph-theory.tex}
+\includegraphics[width=0.8\linewidth]{diagram}
+\includegraphics{nonexistent.png}
+% \ref{graph-theory}`
+
+	links := ls.documentLinks(content)
+	if len(links) != 3 {
+		t.Fatalf("expected 3 links, got %d: %+v", len(links), links)
+	}
+
+	noteURI := pathToURI(filepath.Join(notesPath, "20240101-graph-theory.tex"))
+	if links[0].Target != noteURI || links[1].Target != noteURI {
+		t.Errorf("expected note links to %s, got %s and %s", noteURI, links[0].Target, links[1].Target)
+	}
+	if links[0].Range.Start.Character != 9 || links[0].Range.End.Character != 21 {
+		t.Errorf("unexpected range for first link: %+v", links[0].Range)
+	}
+
+	assetURI := pathToURI(filepath.Join(assetsPath, "diagram.png"))
+	if links[2].Target != assetURI {
+		t.Errorf("expected asset link to %s, got %s", assetURI, links[2].Target)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeDuplicateRef marks a reference repeated within one paragraph
+const diagnosticCodeDuplicateRef = "LX008"
+
+// duplicateRefDiagnostics hints at references to the same note repeated within a paragraph,
+// often left behind by copy-paste
+// Paragraphs end at blank lines and sectioning commands; threshold is the count that triggers the hint
+func (s *LanguageServer) duplicateRefDiagnostics(content string, threshold int) []protocol.Diagnostic {
+	if threshold < 2 {
+		return nil
+	}
+
+	lines := s.documentLines(content)
+	paragraphs := make([]int, len(lines))
+	paragraph := 0
+	for lineNum, line := range lines {
+		if strings.TrimSpace(line) == "" || sectionPattern.MatchString(line) {
+			paragraph++
+		}
+		paragraphs[lineNum] = paragraph
+	}
+
+	type group struct {
+		paragraph int
+		slug      string
+	}
+	occurrences := make(map[group][]Link)
+	var order []group
+	for _, link := range extractLinks(s.linkPattern(), "", "", content) {
+		key := group{paragraphs[link.Range.Start.Line], link.Target}
+		if occurrences[key] == nil {
+			order = append(order, key)
+		}
+		occurrences[key] = append(occurrences[key], link)
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, key := range order {
+		links := occurrences[key]
+		if len(links) < threshold {
+			continue
+		}
+		// The first reference stays; the repeats are the ones to consolidate
+		for _, link := range links[1:] {
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    link.Range,
+				Severity: protocol.DiagnosticSeverityHint,
+				Code:     diagnosticCodeDuplicateRef,
+				Message:  fmt.Sprintf("'%s' is referenced %d times in this paragraph; consider consolidating", key.slug, len(links)),
+				Source:   "lx-ls",
+			})
+		}
+	}
+	return diagnostics
+}
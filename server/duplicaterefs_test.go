@@ -0,0 +1,39 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestDuplicateRefDiagnostics tests hints for references repeated within a paragraph
+func TestDuplicateRefDiagnostics(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex()}
+
+	content := "\\ref{trees} and \\ref{trees}, again \\ref{trees}\n% \\ref{trees} commented\n\\ref{graphs} \\ref{graphs}\n\n\\ref{trees} in a new paragraph\n\\section{Next}\n\\ref{trees} \\ref{trees} \\ref{trees}"
+
+	diagnostics := ls.duplicateRefDiagnostics(content, 3)
+	// Two repeats in the first paragraph, two in the last section
+	if len(diagnostics) != 4 {
+		t.Fatalf("expected 4 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+	first := diagnostics[0]
+	if first.Range.Start.Line != 0 || first.Range.Start.Character != 21 {
+		t.Errorf("expected the second reference to be flagged first, got %v", first.Range)
+	}
+	if first.Message != "'trees' is referenced 3 times in this paragraph; consider consolidating" {
+		t.Errorf("unexpected message: %s", first.Message)
+	}
+	if diagnostics[2].Range.Start.Line != 6 {
+		t.Errorf("expected the section to start a new paragraph, got line %d", diagnostics[2].Range.Start.Line)
+	}
+
+	// A lower threshold also catches pairs
+	if got := len(ls.duplicateRefDiagnostics(content, 2)); got != 5 {
+		t.Errorf("expected 5 diagnostics with threshold 2, got %d", got)
+	}
+	// Thresholds below 2 disable the rule
+	if got := len(ls.duplicateRefDiagnostics(content, 0)); got != 0 {
+		t.Errorf("expected no diagnostics with threshold 0, got %d", got)
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandDoctor checks the vault for problems no single note shows, such as duplicate notes
+// Arguments: none
+const commandDoctor = "lx.doctor"
+
+func init() {
+	registerCommand(commandDoctor, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.doctor(), nil
+	})
+}
+
+// Kinds of duplicate groups reported by lx.doctor
+const (
+	duplicateIdentical     = "identical"      // bodies match byte for byte
+	duplicateNearIdentical = "near-identical" // bodies match once comments, case and whitespace are ignored
+)
+
+// contentHashes fingerprints the body of a note, without its metadata block
+type contentHashes struct {
+	exact string
+	near  string
+}
+
+// ContentHashIndex tracks the body fingerprints of every note
+type ContentHashIndex struct {
+	mu     sync.RWMutex
+	hashes map[string]contentHashes // slug -> fingerprints
+}
+
+func NewContentHashIndex() *ContentHashIndex {
+	return &ContentHashIndex{
+		hashes: make(map[string]contentHashes),
+	}
+}
+
+// Set replaces the fingerprints of a note
+// Notes with an empty body are left out, they are all alike until written
+func (c *ContentHashIndex) Set(slug string, hashes contentHashes) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hashes.near == "" {
+		delete(c.hashes, slug)
+		return
+	}
+	c.hashes[slug] = hashes
+}
+
+// Delete removes a note from the hash index
+func (c *ContentHashIndex) Delete(slug string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hashes, slug)
+}
+
+// Duplicates groups the notes sharing a near-identical body, each group and the groups sorted by slug
+func (c *ContentHashIndex) Duplicates() []DuplicateGroup {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	byNear := make(map[string][]string)
+	for slug, hashes := range c.hashes {
+		byNear[hashes.near] = append(byNear[hashes.near], slug)
+	}
+
+	groups := []DuplicateGroup{}
+	for _, slugs := range byNear {
+		if len(slugs) < 2 {
+			continue
+		}
+		sort.Strings(slugs)
+		kind := duplicateIdentical
+		for _, slug := range slugs[1:] {
+			if c.hashes[slug].exact != c.hashes[slugs[0]].exact {
+				kind = duplicateNearIdentical
+				break
+			}
+		}
+		groups = append(groups, DuplicateGroup{Kind: kind, Notes: slugs})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Notes[0] < groups[j].Notes[0] })
+	return groups
+}
+
+// boilerplateLines are the body lines of a new note; a body of nothing else counts as empty
+var boilerplateLines = map[string]bool{`\maketitle`: true, `\tableofcontents`: true, `\newpage`: true, `\clearpage`: true}
+
+// hashContent fingerprints the body of a note, between \begin{document} and \end{document} when
+// it has a document environment, so notes sharing a template's preamble are not alike
+// The near hash drops comments, case and whitespace, so reflowed or re-cased copies still match
+func (s *LanguageServer) hashContent(content string) contentHashes {
+	lines := strings.Split(content, "\n")
+	if blockStart, blockEnd, found := s.metadataParser().FindBlock(content); found {
+		lines = append(lines[:blockStart:blockStart], lines[min(blockEnd+1, len(lines)):]...)
+	}
+	start, end := 0, len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, `\begin{document}`) {
+			start = i + 1
+		} else if strings.HasPrefix(trimmed, `\end{document}`) {
+			end = i
+		}
+	}
+	if start > end {
+		return contentHashes{}
+	}
+	lines = lines[start:end]
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+
+	var words []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(stripInlineComment(line))
+		if trimmed == "" || boilerplateLines[trimmed] {
+			continue
+		}
+		words = append(words, strings.Fields(strings.ToLower(trimmed))...)
+	}
+	if len(words) == 0 {
+		return contentHashes{}
+	}
+
+	exact := sha256.Sum256([]byte(body))
+	near := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return contentHashes{exact: hex.EncodeToString(exact[:]), near: hex.EncodeToString(near[:])}
+}
+
+// DuplicateGroup is a set of notes with the same body, as reported by lx.doctor
+// Merge holds one lx.mergeNotes command per extra note, folding it into the first
+type DuplicateGroup struct {
+	Kind  string             `json:"kind"`
+	Notes []string           `json:"notes"`
+	Merge []protocol.Command `json:"merge"`
+}
+
+// DoctorReport is returned by lx.doctor
+type DoctorReport struct {
+	Duplicates []DuplicateGroup `json:"duplicates"`
+}
+
+// doctor checks the indexed vault for duplicate notes
+func (s *LanguageServer) doctor() *DoctorReport {
+	groups := s.index.Hashes().Duplicates()
+	for i, group := range groups {
+		target := group.Notes[0]
+		for _, source := range group.Notes[1:] {
+			groups[i].Merge = append(groups[i].Merge, protocol.Command{
+				Title:     "Merge " + source + " into " + target,
+				Command:   commandMergeNotes,
+				Arguments: []interface{}{source, target},
+			})
+		}
+	}
+	return &DoctorReport{Duplicates: groups}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestDoctorDuplicates tests that notes with the same body are grouped regardless of their metadata
+func TestDoctorDuplicates(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex":      "%% Metadata\n% title: Graphs\n\nA graph is a set of vertices.\n",
+		"20240102-graphs-copy.tex": "%% Metadata\n% title: Graphs (imported)\n\nA graph is a set of vertices.\n",
+		"20240103-graphs-edit.tex": "%% Metadata\n% title: Graphs\n% Imported from old vault\nA graph   is a set\nof Vertices.\n",
+		"20240104-trees.tex":       "%% Metadata\n% title: Trees\n\nA tree is a connected acyclic graph.\n",
+		"20240105-empty.tex":       "%% Metadata\n% title: Empty\n",
+		"20240106-blank.tex":       "%% Metadata\n% title: Blank\n\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	report := ls.doctor()
+	if len(report.Duplicates) != 1 {
+		t.Fatalf("expected one duplicate group, got %+v", report.Duplicates)
+	}
+	group := report.Duplicates[0]
+	if group.Kind != duplicateNearIdentical || len(group.Notes) != 3 || group.Notes[0] != "graphs" {
+		t.Fatalf("unexpected duplicate group %+v", group)
+	}
+	if len(group.Merge) != 2 || group.Merge[0].Command != commandMergeNotes || group.Merge[0].Arguments[0] != "graphs-copy" || group.Merge[0].Arguments[1] != "graphs" {
+		t.Errorf("unexpected merge suggestions %+v", group.Merge)
+	}
+
+	// Removing the edited copy leaves byte-identical bodies
+	os.Remove(filepath.Join(tempDir, "20240103-graphs-edit.tex"))
+	ls.dropNote("graphs-edit", "", "20240103-graphs-edit.tex")
+	report = ls.doctor()
+	if len(report.Duplicates) != 1 || report.Duplicates[0].Kind != duplicateIdentical || len(report.Duplicates[0].Notes) != 2 {
+		t.Errorf("expected an identical pair, got %+v", report.Duplicates)
+	}
+}
+
+// TestDoctorFreshNotes tests that new notes made from the same template are not reported as duplicates
+func TestDoctorFreshNotes(t *testing.T) {
+	tempDir := t.TempDir()
+	graphs := renderNoteContent(&NoteHeader{Title: "Graphs", Date: "2024-01-01"}, "math")
+	trees := renderNoteContent(&NoteHeader{Title: "Trees", Date: "2024-01-02"}, "math")
+	os.WriteFile(filepath.Join(tempDir, "20240101-graphs.tex"), []byte(graphs), 0644)
+	os.WriteFile(filepath.Join(tempDir, "20240102-trees.tex"), []byte(trees), 0644)
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	if report := ls.doctor(); len(report.Duplicates) != 0 {
+		t.Errorf("expected no duplicates among fresh notes, got %+v", report.Duplicates)
+	}
+
+	// The same text written into both bodies makes them duplicates, whatever their preambles
+	body := "\\maketitle\n\nA graph is a set of vertices.\n"
+	for name, content := range map[string]string{"20240101-graphs.tex": graphs, "20240102-trees.tex": trees} {
+		content = strings.Replace(content, "\\maketitle\n", body, 1)
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls.RebuildIndex(context.Background())
+	if report := ls.doctor(); len(report.Duplicates) != 1 || report.Duplicates[0].Kind != duplicateIdentical {
+		t.Errorf("expected an identical pair, got %+v", report.Duplicates)
+	}
+}
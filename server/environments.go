@@ -0,0 +1,169 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+var (
+	// beginCompletionPattern matches a line prefix inside the name of \begin{...}
+	beginCompletionPattern = regexp.MustCompile(`\\begin\{([^}]*)$`)
+
+	// newenvironmentPattern matches \newenvironment{env}, \renewenvironment{env} and
+	// \NewDocumentEnvironment{env}
+	newenvironmentPattern = regexp.MustCompile(`\\(?:(?:re)?newenvironment|(?:New|Renew|Provide|Declare)DocumentEnvironment)\*?\{([^}]+)\}`)
+)
+
+// latexEnvironment is an environment offered after \begin{
+type latexEnvironment struct {
+	Name   string
+	Detail string
+	Args   string // snippet after \begin{name}, e.g. a placement or column specification
+	Body   string // snippet for the body, $0 where the cursor goes
+}
+
+// commonEnvironments are the environments of LaTeX and the usual math and theorem packages
+var commonEnvironments = []latexEnvironment{
+	{Name: "itemize", Detail: "Bulleted list", Body: `\item $0`},
+	{Name: "enumerate", Detail: "Numbered list", Body: `\item $0`},
+	{Name: "description", Detail: "Description list", Body: `\item[${1:term}] $0`},
+	{Name: "equation", Detail: "Numbered equation"},
+	{Name: "equation*", Detail: "Unnumbered equation"},
+	{Name: "align", Detail: "Aligned equations (amsmath)"},
+	{Name: "align*", Detail: "Unnumbered aligned equations (amsmath)"},
+	{Name: "gather", Detail: "Centered equations (amsmath)"},
+	{Name: "cases", Detail: "Piecewise definition (amsmath)"},
+	{Name: "pmatrix", Detail: "Matrix in parentheses (amsmath)"},
+	{Name: "bmatrix", Detail: "Matrix in brackets (amsmath)"},
+	{Name: "figure", Detail: "Floating figure", Args: "[${1:htbp}]", Body: "\\centering\n$0\n\\caption{${2:caption}}"},
+	{Name: "table", Detail: "Floating table", Args: "[${1:htbp}]", Body: "\\centering\n$0\n\\caption{${2:caption}}"},
+	{Name: "tabular", Detail: "Table", Args: "{${1:ll}}"},
+	{Name: "theorem", Detail: "Theorem (amsthm)"},
+	{Name: "lemma", Detail: "Lemma (amsthm)"},
+	{Name: "definition", Detail: "Definition (amsthm)"},
+	{Name: "proof", Detail: "Proof (amsthm)"},
+	{Name: "center", Detail: "Centered lines"},
+	{Name: "quote", Detail: "Quotation"},
+	{Name: "verbatim", Detail: "Verbatim text"},
+	{Name: "minipage", Detail: "Box of text", Args: "{${1:0.5\\linewidth}}"},
+	{Name: "abstract", Detail: "Abstract"},
+}
+
+// environmentCompletions offers environment names inside \begin{, those of the templates the note
+// loads first. Accepting one completes the \begin line and adds the matching \end, unless the
+// document already holds an \end without its \begin, as when renaming an existing environment
+func (s *LanguageServer) environmentCompletions(content string, lines []string, pos protocol.Position) []protocol.CompletionItem {
+	line := lines[pos.Line]
+	match := beginCompletionPattern.FindStringSubmatchIndex(line[:pos.Character])
+	if match == nil {
+		return nil
+	}
+	typed := line[match[2]:pos.Character]
+
+	// Replace the rest of the name after the cursor and a closing brace the editor added
+	end := int(pos.Character)
+	for end < len(line) && (isLetter(line[end]) || line[end] == '*') {
+		end++
+	}
+	if end < len(line) && line[end] == '}' {
+		end++
+	}
+	replaced := protocol.Range{
+		Start: protocol.Position{Line: pos.Line, Character: uint32(match[2])},
+		End:   protocol.Position{Line: pos.Line, Character: uint32(end)},
+	}
+	closeEnvironment := strings.TrimSpace(line[end:]) == "" && !danglingEnd(lines, int(pos.Line))
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	var environments []latexEnvironment
+	seen := make(map[string]bool)
+	for _, env := range s.templateEnvironments(content) {
+		seen[env.Name] = true
+		environments = append(environments, env)
+	}
+	for _, env := range commonEnvironments {
+		if !seen[env.Name] {
+			environments = append(environments, env)
+		}
+	}
+
+	items := make([]protocol.CompletionItem, 0, len(environments))
+	for i, env := range environments {
+		text := env.Name + "}"
+		if closeEnvironment {
+			body := env.Body
+			if body == "" {
+				body = "$0"
+			}
+			body = strings.ReplaceAll(body, "\n", "\n"+indent+"\t")
+			text += fmt.Sprintf("%s\n%s\t%s\n%s\\end{%s}", env.Args, indent, body, indent, env.Name)
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:            env.Name,
+			Kind:             protocol.CompletionItemKindSnippet,
+			Detail:           env.Detail,
+			SortText:         fmt.Sprintf("%03d", i),
+			InsertTextFormat: protocol.InsertTextFormatSnippet,
+			TextEdit:         &protocol.TextEdit{Range: replaced, NewText: text},
+		})
+	}
+	return filterCompletions(items, typed)
+}
+
+// templateEnvironments returns the environments declared by the vault templates the note loads,
+// with \newenvironment and its xparse forms or \newtheorem
+func (s *LanguageServer) templateEnvironments(content string) []latexEnvironment {
+	var environments []latexEnvironment
+	seen := make(map[string]bool)
+	add := func(name, detail string) {
+		if name = strings.TrimSpace(name); name != "" && !seen[name] {
+			seen[name] = true
+			environments = append(environments, latexEnvironment{Name: name, Detail: detail})
+		}
+	}
+
+	for _, template := range usedPackages(content) {
+		data, ok := s.templateContent(template)
+		if !ok {
+			continue // Not a vault template
+		}
+		for _, env := range extractTheorems(template, data) {
+			add(env.Name, fmt.Sprintf("%s (%s)", env.Title, template))
+		}
+		for _, line := range strings.Split(data, "\n") {
+			for _, match := range newenvironmentPattern.FindAllStringSubmatch(stripInlineComment(line), -1) {
+				add(match[1], fmt.Sprintf("Environment (%s)", template))
+			}
+		}
+	}
+	return environments
+}
+
+// danglingEnd reports whether the document, besides the given line, closes an environment it
+// never opens
+func danglingEnd(lines []string, skip int) bool {
+	open := make(map[string]int)
+	for lineNum, line := range lines {
+		if lineNum == skip {
+			continue
+		}
+		for _, match := range environmentPattern.FindAllStringSubmatch(stripInlineComment(line), -1) {
+			if match[1] == "begin" {
+				open[match[2]]++
+			} else if open[match[2]] == 0 {
+				return true
+			} else {
+				open[match[2]]--
+			}
+		}
+	}
+	return false
+}
+
+// isLetter reports whether b is an ASCII letter
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
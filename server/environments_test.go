@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestEnvironmentCompletions tests completing environment names after \begin{ with their \end
+func TestEnvironmentCompletions(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	templatesPath := filepath.Join(tempDir, "templates")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(templatesPath, 0755)
+	os.WriteFile(filepath.Join(templatesPath, "course.sty"), []byte(`\newtheorem{exercise}{Exercise}
+\NewDocumentEnvironment{solution}{}{}{}
+`), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath, TemplatesPath: templatesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	uri := pathToURI(filepath.Join(notesPath, "test.tex"))
+	complete := func(content string, pos protocol.Position) map[string]protocol.CompletionItem {
+		ls.documents[uri] = content
+		result, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     pos,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Completion failed: %v", err)
+		}
+		items := make(map[string]protocol.CompletionItem)
+		for _, item := range result.Items {
+			items[item.Label] = item
+		}
+		return items
+	}
+
+	// The brace the editor closed is replaced, and the \end follows at the line's indentation
+	items := complete("\\usepackage{course}\n  \\begin{ite}", protocol.Position{Line: 1, Character: 11})
+	itemize, ok := items["itemize"]
+	if !ok {
+		t.Fatalf("expected itemize, got %+v", items)
+	}
+	for _, item := range items {
+		if item.SortText < itemize.SortText {
+			t.Errorf("expected itemize to rank first, got %s before it", item.Label)
+		}
+	}
+	want := "itemize}\n  \t\\item $0\n  \\end{itemize}"
+	if itemize.TextEdit.NewText != want || itemize.TextEdit.Range != lineRange(1, 9, 13) {
+		t.Errorf("unexpected edit %+v, expected %q", itemize.TextEdit, want)
+	}
+
+	// Environments of the templates the note loads are offered first
+	items = complete("\\usepackage{course}\n\\begin{", protocol.Position{Line: 1, Character: 7})
+	if items["exercise"].SortText >= items["solution"].SortText || items["solution"].SortText >= items["equation"].SortText {
+		t.Errorf("expected template environments first, got %+v", items)
+	}
+	if items["exercise"].Detail != "Exercise (course)" {
+		t.Errorf("unexpected detail %q", items["exercise"].Detail)
+	}
+	if _, ok := complete("\\begin{", protocol.Position{Line: 0, Character: 7})["exercise"]; ok {
+		t.Error("expected no template environments without the template")
+	}
+
+	// Renaming an environment whose \end is already there completes the name alone
+	items = complete("\\begin{al}\nx\n\\end{align}", protocol.Position{Line: 0, Character: 9})
+	if edit := items["align"].TextEdit; edit == nil || edit.NewText != "align}" {
+		t.Errorf("expected the name alone, got %+v", items["align"])
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// codeActionKindEscapeUnicode converts Unicode characters in prose to LaTeX escapes
+const codeActionKindEscapeUnicode protocol.CodeActionKind = "refactor.rewrite.escapeUnicode"
+
+// escapePattern matches escaped special characters, named symbol commands and accent commands
+// Accents are written \"{o}, \"o, \c{c} or \v{s}
+var escapePattern = regexp.MustCompile(`\\[&%$#_{}]|\\(["'` + "`" + `^~=.])(?:\{(\\?[A-Za-z])\}|([A-Za-z]))|\\([cvuHrkd])\{(\\?[A-Za-z])\}|\\[A-Za-z]+`)
+
+// symbolEscapes maps escape commands to the characters they render
+var symbolEscapes = map[string]string{
+	`\&`: "&", `\%`: "%", `\$`: "$", `\#`: "#", `\_`: "_", `\{`: "{", `\}`: "}",
+	`\textasciitilde`: "~", `\textasciicircum`: "^", `\textbackslash`: `\`,
+	`\textbar`: "|", `\textless`: "<", `\textgreater`: ">",
+	`\ldots`: "…", `\dots`: "…", `\textellipsis`: "…",
+	`\textendash`: "–", `\textemdash`: "—",
+	`\textquoteleft`: "‘", `\textquoteright`: "’", `\textquotedblleft`: "“", `\textquotedblright`: "”",
+	`\S`: "§", `\P`: "¶", `\copyright`: "©", `\textregistered`: "®", `\texttrademark`: "™",
+	`\pounds`: "£", `\textdegree`: "°", `\textbullet`: "•", `\dag`: "†", `\ddag`: "‡",
+	`\ss`: "ß", `\o`: "ø", `\O`: "Ø", `\ae`: "æ", `\AE`: "Æ", `\oe`: "œ", `\OE`: "Œ",
+	`\aa`: "å", `\AA`: "Å", `\l`: "ł", `\L`: "Ł", `\i`: "ı", `\j`: "ȷ",
+}
+
+// accentMarks maps accent commands to Unicode combining marks
+var accentMarks = map[string]rune{
+	`"`: '̈', `'`: '́', "`": '̀', `^`: '̂', `~`: '̃',
+	`=`: '̄', `.`: '̇', `c`: '̧', `v`: '̌', `u`: '̆',
+	`H`: '̋', `r`: '̊', `k`: '̨', `d`: '̣',
+}
+
+// accentedCharacters maps precomposed characters to their accent escapes
+var accentedCharacters = map[rune]string{
+	'à': "`{a}", 'á': "'{a}", 'â': "^{a}", 'ã': "~{a}", 'ä': `"{a}`, 'ā': "={a}", 'ă': "u{a}", 'ą': "k{a}",
+	'À': "`{A}", 'Á': "'{A}", 'Â': "^{A}", 'Ã': "~{A}", 'Ä': `"{A}`, 'Ā': "={A}", 'Ă': "u{A}", 'Ą': "k{A}",
+	'ç': "c{c}", 'ć': "'{c}", 'č': "v{c}", 'Ç': "c{C}", 'Ć': "'{C}", 'Č': "v{C}",
+	'ď': "v{d}", 'Ď': "v{D}",
+	'è': "`{e}", 'é': "'{e}", 'ê': "^{e}", 'ë': `"{e}`, 'ē': "={e}", 'ė': ".{e}", 'ę': "k{e}", 'ě': "v{e}",
+	'È': "`{E}", 'É': "'{E}", 'Ê': "^{E}", 'Ë': `"{E}`, 'Ē': "={E}", 'Ė': ".{E}", 'Ę': "k{E}", 'Ě': "v{E}",
+	'ğ': "u{g}", 'Ğ': "u{G}",
+	'ì': "`{\\i}", 'í': "'{\\i}", 'î': "^{\\i}", 'ï': `"{\i}`, 'ī': "={\\i}",
+	'Ì': "`{I}", 'Í': "'{I}", 'Î': "^{I}", 'Ï': `"{I}`, 'Ī': "={I}", 'İ': ".{I}",
+	'ľ': "v{l}", 'ĺ': "'{l}", 'Ľ': "v{L}", 'Ĺ': "'{L}",
+	'ñ': "~{n}", 'ń': "'{n}", 'ň': "v{n}", 'Ñ': "~{N}", 'Ń': "'{N}", 'Ň': "v{N}",
+	'ò': "`{o}", 'ó': "'{o}", 'ô': "^{o}", 'õ': "~{o}", 'ö': `"{o}`, 'ō': "={o}", 'ő': "H{o}",
+	'Ò': "`{O}", 'Ó': "'{O}", 'Ô': "^{O}", 'Õ': "~{O}", 'Ö': `"{O}`, 'Ō': "={O}", 'Ő': "H{O}",
+	'ř': "v{r}", 'ŕ': "'{r}", 'Ř': "v{R}", 'Ŕ': "'{R}",
+	'ś': "'{s}", 'š': "v{s}", 'ş': "c{s}", 'Ś': "'{S}", 'Š': "v{S}", 'Ş': "c{S}",
+	'ť': "v{t}", 'ţ': "c{t}", 'Ť': "v{T}", 'Ţ': "c{T}",
+	'ù': "`{u}", 'ú': "'{u}", 'û': "^{u}", 'ü': `"{u}`, 'ū': "={u}", 'ů': "r{u}", 'ű': "H{u}",
+	'Ù': "`{U}", 'Ú': "'{U}", 'Û': "^{U}", 'Ü': `"{U}`, 'Ū': "={U}", 'Ů': "r{U}", 'Ű': "H{U}",
+	'ý': "'{y}", 'ÿ': `"{y}`, 'Ý': "'{Y}", 'Ÿ': `"{Y}`,
+	'ź': "'{z}", 'ż': ".{z}", 'ž': "v{z}", 'Ź': "'{Z}", 'Ż': ".{Z}", 'Ž': "v{Z}",
+}
+
+// unicodeEscapes maps characters to the LaTeX that produces them, for the conversion action
+var unicodeEscapes = buildUnicodeEscapes()
+
+func buildUnicodeEscapes() map[rune]string {
+	escapes := make(map[rune]string)
+	for char, accent := range accentedCharacters {
+		escapes[char] = `\` + accent
+	}
+	for command, rendered := range symbolEscapes {
+		runes := []rune(rendered)
+		// ASCII is fine as typed; only non-ASCII needs an escape
+		if len(runes) != 1 || runes[0] < 0x80 {
+			continue
+		}
+		// Several commands render some characters; keep the shortest, then alphabetical, so the choice is stable
+		if current, ok := escapes[runes[0]]; ok && (len(current) < len(command) || (len(current) == len(command) && current < command)) {
+			continue
+		}
+		// Braces keep a following letter from merging into the command name ("\ss{}e")
+		escapes[runes[0]] = command + "{}"
+	}
+	return escapes
+}
+
+// renderEscape returns the character an escape sequence renders, if known
+func renderEscape(match []string) (string, bool) {
+	sequence := match[0]
+	if rendered, ok := symbolEscapes[sequence]; ok {
+		return rendered, true
+	}
+
+	accent, base := match[1], match[2]+match[3]
+	if accent == "" {
+		accent, base = match[4], match[5]
+	}
+	mark, ok := accentMarks[accent]
+	if !ok || base == "" {
+		return "", false
+	}
+	for char, escape := range accentedCharacters {
+		if escape == accent+"{"+base+"}" {
+			return string(char), true
+		}
+	}
+	// Dotless i and j carry the accent without their own dot
+	if dotless, ok := symbolEscapes[base]; ok {
+		base = dotless
+	}
+	return base + string(mark), true
+}
+
+// escapeHover describes the escape sequence under pos
+func escapeHover(content string, pos protocol.Position) *protocol.Hover {
+	lines := strings.Split(content, "\n")
+	if int(pos.Line) >= len(lines) {
+		return nil
+	}
+	line := lines[pos.Line]
+	offset := byteColumn(line, int(pos.Character))
+
+	for _, indexes := range escapePattern.FindAllStringSubmatchIndex(line, -1) {
+		if offset < indexes[0] || offset > indexes[1] {
+			continue
+		}
+		match := make([]string, len(indexes)/2)
+		for i := range match {
+			if indexes[2*i] >= 0 {
+				match[i] = line[indexes[2*i]:indexes[2*i+1]]
+			}
+		}
+		rendered, ok := renderEscape(match)
+		if !ok {
+			return nil
+		}
+		codePoints := make([]string, 0, 2)
+		for _, r := range rendered {
+			codePoints = append(codePoints, fmt.Sprintf("U+%04X", r))
+		}
+		hoverRange := textRange(int(pos.Line), line, indexes[0], indexes[1])
+		return &protocol.Hover{
+			Contents: protocol.MarkupContent{
+				Kind:  protocol.Markdown,
+				Value: fmt.Sprintf("`%s` renders as **%s** (%s)", match[0], rendered, strings.Join(codePoints, " ")),
+			},
+			Range: &hoverRange,
+		}
+	}
+	return nil
+}
+
+func init() {
+	registerCodeActionProvider(escapeUnicodeAction)
+}
+
+// escapeUnicodeAction converts Unicode characters in the prose of the selected lines to LaTeX escapes
+// Comments, math, identifier arguments and the metadata block are left alone
+func escapeUnicodeAction(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction {
+	if s.settings().Coexist {
+		return nil
+	}
+	lines := strings.Split(req.Content, "\n")
+	blockStart, blockEnd, hasBlock := s.metadataParser().FindBlock(req.Content)
+
+	var edits []protocol.TextEdit
+	for lineNum := int(req.Range.Start.Line); lineNum <= int(req.Range.End.Line) && lineNum < len(lines); lineNum++ {
+		if hasBlock && lineNum >= blockStart && lineNum <= blockEnd {
+			continue
+		}
+		edits = append(edits, unicodeEscapeEdits(lineNum, lines[lineNum])...)
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title: "Convert Unicode characters to LaTeX escapes",
+			Kind:  codeActionKindEscapeUnicode,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{req.URI: edits},
+			},
+		},
+	}
+}
+
+// unicodeEscapeEdits replaces the convertible characters in the prose of one line
+func unicodeEscapeEdits(lineNum int, line string) []protocol.TextEdit {
+	prose := maskNonProse(stripInlineComment(line))
+
+	var edits []protocol.TextEdit
+	for offset, char := range prose {
+		escape, ok := unicodeEscapes[char]
+		if !ok {
+			continue
+		}
+		edits = append(edits, protocol.TextEdit{
+			Range:   textRange(lineNum, line, offset, offset+len(string(char))),
+			NewText: escape,
+		})
+	}
+	return edits
+}
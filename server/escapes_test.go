@@ -0,0 +1,97 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestEscapeHover tests rendering of escape sequences under the cursor
+func TestEscapeHover(t *testing.T) {
+	tests := map[string]string{
+		`Tom \& Jerry`:    "&",
+		`50\% off`:        "%",
+		`\textasciitilde`: "~",
+		`Schr\"{o}dinger`: "ö",
+		`Schr\"odinger`:   "ö",
+		`Gau\ss`:          "ß",
+		`fa\c{c}ade`:      "ç",
+		`na\"{\i}ve`:      "ï",
+		`\v{g}`:           "g\u030c", // no precomposed entry: base plus combining mark
+		`Ni\v{s}`:         "š",
+	}
+	for line, expected := range tests {
+		column := strings.Index(line, `\`) + 1
+		hover := escapeHover(line, protocol.Position{Line: 0, Character: uint32(column)})
+		if hover == nil {
+			t.Errorf("%q: expected hover", line)
+			continue
+		}
+		value := hover.Contents.Value
+		if !strings.Contains(value, "**"+expected+"**") {
+			t.Errorf("%q: expected %q in %q", line, expected, value)
+		}
+	}
+
+	if hover := escapeHover(`\section{Intro}`, protocol.Position{Line: 0, Character: 2}); hover != nil {
+		t.Errorf("expected no hover on ordinary commands, got %+v", hover)
+	}
+}
+
+// TestEscapeUnicodeAction tests conversion of Unicode prose to escapes
+func TestEscapeUnicodeAction(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex()}
+
+	content := "%% Metadata\n%% title: Schrödinger\n\nSchrödinger’s cat — Gauß $α$ % naïve\n\\ref{café}"
+	req := &codeActionRequest{
+		URI:     "file:///note.tex",
+		Content: content,
+		Range:   protocol.Range{End: protocol.Position{Line: 4}},
+	}
+
+	actions := escapeUnicodeAction(ls, req)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(actions))
+	}
+	edits := actions[0].Edit.Changes[protocol.DocumentURI("file:///note.tex")]
+	converted := applyTextEdits(content, edits)
+
+	expected := "%% Metadata\n%% title: Schrödinger\n\nSchr\\\"{o}dinger\\textquoteright{}s cat \\textemdash{} Gau\\ss{} $α$ % naïve\n\\ref{café}"
+	if converted != expected {
+		t.Errorf("unexpected conversion:\n%s\nwant:\n%s", converted, expected)
+	}
+
+	// Plain ASCII offers nothing
+	req.Content = "Plain text"
+	if actions := escapeUnicodeAction(ls, req); len(actions) != 0 {
+		t.Errorf("expected no action, got %d", len(actions))
+	}
+}
+
+// TestEscapeColumns tests that escape ranges count UTF-16 code units on lines with accented and
+// astral characters
+func TestEscapeColumns(t *testing.T) {
+	line := "😀é 𝔸 \\\"{o} ö"
+
+	// The escape starts at byte 12 but UTF-16 column 7
+	hover := escapeHover(line, protocol.Position{Line: 0, Character: 8})
+	if hover == nil || !strings.Contains(hover.Contents.Value, "**ö**") {
+		t.Fatalf("expected hover on the escape, got %+v", hover)
+	}
+	if want := lineRange(0, 7, 12); *hover.Range != want {
+		t.Errorf("expected hover range %+v, got %+v", want, *hover.Range)
+	}
+
+	edits := unicodeEscapeEdits(0, line)
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got %+v", edits)
+	}
+	if edits[0].Range != lineRange(0, 2, 3) || edits[1].Range != lineRange(0, 13, 14) {
+		t.Errorf("unexpected ranges %+v and %+v", edits[0].Range, edits[1].Range)
+	}
+	if converted := applyTextEdits(line, edits); converted != "😀\\'{e} 𝔸 \\\"{o} \\\"{o}" {
+		t.Errorf("unexpected conversion %q", converted)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// MethodDocumentRenamed is the custom notification sent when an open note is renamed outside the editor
+const MethodDocumentRenamed = "lx/documentRenamed"
+
+// DocumentRenamedParams tells the client which URI an open document now lives at
+type DocumentRenamedParams struct {
+	OldURI protocol.DocumentURI `json:"oldUri"`
+	NewURI protocol.DocumentURI `json:"newUri"`
+}
+
+// trackOpenFile remembers the identity of an open document's file so renames on disk can be recognized
+func (s *LanguageServer) trackOpenFile(uri protocol.DocumentURI) {
+	info, err := os.Stat(uriToPath(uri))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		delete(s.openFiles, uri)
+		return
+	}
+	if s.openFiles == nil {
+		s.openFiles = make(map[protocol.DocumentURI]os.FileInfo)
+	}
+	s.openFiles[uri] = info
+}
+
+// detectExternalRename checks whether a newly created file is an open document moved on disk
+// If so, the document is remapped to the new URI and the client is told; returns the old URI
+func (s *LanguageServer) detectExternalRename(ctx context.Context, path string) (protocol.DocumentURI, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	newURI := pathToURI(path)
+
+	s.mu.Lock()
+	var oldURI protocol.DocumentURI
+	for uri, openInfo := range s.openFiles {
+		if uri == newURI || !os.SameFile(info, openInfo) {
+			continue
+		}
+		// A hard link leaves the old path in place; only a missing original is a rename
+		if _, err := os.Stat(uriToPath(uri)); !os.IsNotExist(err) {
+			continue
+		}
+		oldURI = uri
+		break
+	}
+	if oldURI == "" {
+		s.mu.Unlock()
+		return "", false
+	}
+
+	content, open := s.documents[oldURI]
+	delete(s.documents, oldURI)
+	if open {
+		s.documents[newURI] = content
+	}
+	if idx, indexed := s.lineIndexes[oldURI]; indexed {
+		delete(s.lineIndexes, oldURI)
+		s.lineIndexes[newURI] = idx
+	}
+	if s.unnormalized[oldURI] {
+		delete(s.unnormalized, oldURI)
+		s.unnormalized[newURI] = true
+	}
+	delete(s.openFiles, oldURI)
+	s.openFiles[newURI] = info
+	if s.movedDocuments == nil {
+		s.movedDocuments = make(map[protocol.DocumentURI]protocol.DocumentURI)
+	}
+	for from, to := range s.movedDocuments {
+		if to == oldURI {
+			s.movedDocuments[from] = newURI
+		}
+	}
+	s.movedDocuments[oldURI] = newURI
+	s.mu.Unlock()
+
+	s.cancelReindex(oldURI)
+	s.notifyExternalRename(ctx, oldURI, newURI, content)
+
+	return oldURI, true
+}
+
+// currentURI follows external renames, for clients that keep sending the URI they opened
+func (s *LanguageServer) currentURI(uri protocol.DocumentURI) protocol.DocumentURI {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if moved, ok := s.movedDocuments[uri]; ok {
+		return moved
+	}
+	return uri
+}
+
+// followRenamesHandler points requests at the URI an open document was renamed to on disk
+// Clients that ignore lx/documentRenamed keep sending the URI they opened, so every handler gets
+// the current one instead. didClose passes through unchanged, it releases the old URI itself
+func (s *LanguageServer) followRenamesHandler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if req.Method() == protocol.MethodTextDocumentDidClose {
+			return next(ctx, reply, req)
+		}
+		if params, ok := s.followRenames(req.Params()); ok {
+			switch msg := req.(type) {
+			case *jsonrpc2.Call:
+				if call, err := jsonrpc2.NewCall(msg.ID(), msg.Method(), params); err == nil {
+					req = call
+				}
+			case *jsonrpc2.Notification:
+				if notification, err := jsonrpc2.NewNotification(msg.Method(), params); err == nil {
+					req = notification
+				}
+			}
+		}
+		return next(ctx, reply, req)
+	}
+}
+
+// followRenames rewrites the textDocument.uri of request parameters to the document's current URI
+// Returns false when the parameters name no document or it was not renamed
+func (s *LanguageServer) followRenames(raw json.RawMessage) (json.RawMessage, bool) {
+	s.mu.RLock()
+	moved := len(s.movedDocuments) > 0
+	s.mu.RUnlock()
+	if !moved {
+		return nil, false
+	}
+
+	var params, document map[string]json.RawMessage
+	var uri protocol.DocumentURI
+	if json.Unmarshal(raw, &params) != nil || json.Unmarshal(params["textDocument"], &document) != nil || json.Unmarshal(document["uri"], &uri) != nil {
+		return nil, false
+	}
+	current := s.currentURI(uri)
+	if current == uri {
+		return nil, false
+	}
+	document["uri"], _ = json.Marshal(current)
+	params["textDocument"], _ = json.Marshal(document)
+	rewritten, err := json.Marshal(params)
+	return rewritten, err == nil
+}
+
+// notifyExternalRename moves diagnostics to the new URI and tells the client where the document went
+func (s *LanguageServer) notifyExternalRename(ctx context.Context, oldURI, newURI protocol.DocumentURI, content string) {
+	s.recordActivity(ctx, "note.moved", fmt.Sprintf("%s -> %s", filepath.Base(uriToPath(oldURI)), filepath.Base(uriToPath(newURI))))
+
+	if s.conn == nil {
+		return
+	}
+
+	// Clear what the orphaned URI showed, then publish under the new one
+	s.conn.Notify(ctx, protocol.MethodTextDocumentPublishDiagnostics, &protocol.PublishDiagnosticsParams{
+		URI:         oldURI,
+		Diagnostics: []protocol.Diagnostic{},
+	})
+	s.publishDiagnostics(ctx, newURI, content)
+
+	s.conn.Notify(ctx, MethodDocumentRenamed, &DocumentRenamedParams{OldURI: oldURI, NewURI: newURI})
+	s.conn.Notify(ctx, protocol.MethodWindowShowMessage, &protocol.ShowMessageParams{
+		Type:    protocol.MessageTypeInfo,
+		Message: fmt.Sprintf("%s was renamed to %s on disk", filepath.Base(uriToPath(oldURI)), filepath.Base(uriToPath(newURI))),
+	})
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestExternalRename tests that open documents follow renames on disk
+func TestExternalRename(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	oldPath := filepath.Join(notesPath, "20240101-draft.tex")
+	newPath := filepath.Join(notesPath, "20240101-final.tex")
+	otherPath := filepath.Join(notesPath, "20240102-other.tex")
+	os.WriteFile(oldPath, []byte("saved"), 0644)
+	os.WriteFile(otherPath, []byte("other"), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	oldURI, newURI := pathToURI(oldPath), pathToURI(newPath)
+	ls.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: oldURI, Text: "unsaved edits"},
+	})
+
+	// Unrelated files are not mistaken for the open document
+	if _, renamed := ls.detectExternalRename(context.Background(), otherPath); renamed {
+		t.Error("expected unrelated file to be ignored")
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	from, renamed := ls.detectExternalRename(context.Background(), newPath)
+	if !renamed || from != oldURI {
+		t.Fatalf("expected rename from %s, got %s, %v", oldURI, from, renamed)
+	}
+
+	// The unsaved buffer moves with the file
+	if content, _ := ls.GetDocument(newURI); content != "unsaved edits" {
+		t.Errorf("expected buffer under new URI, got %q", content)
+	}
+	if _, open := ls.documents[oldURI]; open {
+		t.Error("expected old URI to be released")
+	}
+
+	// Edits sent under the old URI land on the new one
+	ls.DidChange(context.Background(), &protocol.DidChangeTextDocumentParams{
+		TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: oldURI}},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "more edits"}},
+	})
+	if ls.documents[newURI] != "more edits" {
+		t.Errorf("expected edit to follow the rename, got %q", ls.documents[newURI])
+	}
+	ls.cancelReindex(newURI)
+
+	ls.DidClose(context.Background(), &protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: oldURI}})
+	if len(ls.documents) != 0 || len(ls.movedDocuments) != 0 || len(ls.openFiles) != 0 {
+		t.Error("expected close to release the renamed document")
+	}
+}
+
+// TestHoverAfterExternalRename tests that requests naming the URI a document was opened with reach
+// it after a rename on disk
+func TestHoverAfterExternalRename(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	oldPath := filepath.Join(notesPath, "20240101-draft.tex")
+	newPath := filepath.Join(notesPath, "20240101-final.tex")
+	os.WriteFile(oldPath, []byte("saved"), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	oldURI := pathToURI(oldPath)
+	ls.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: oldURI, Text: `Tom \& Jerry`},
+	})
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, renamed := ls.detectExternalRename(context.Background(), newPath); !renamed {
+		t.Fatal("expected the rename to be detected")
+	}
+
+	if content, _ := ls.GetDocument(oldURI); content != `Tom \& Jerry` {
+		t.Errorf("expected the buffer under the old URI, got %q", content)
+	}
+
+	params := &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: oldURI},
+		Position:     protocol.Position{Line: 0, Character: 5},
+	}}
+	call, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodTextDocumentHover, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hover *protocol.Hover
+	reply := func(ctx context.Context, result interface{}, err error) error {
+		hover, _ = result.(*protocol.Hover)
+		return err
+	}
+	if err := ls.followRenamesHandler(ls.handler())(context.Background(), reply, call); err != nil {
+		t.Fatalf("hover failed: %v", err)
+	}
+	if hover == nil || !strings.Contains(hover.Contents.Value, "**&**") {
+		t.Errorf("expected hover on the renamed document, got %+v", hover)
+	}
+}
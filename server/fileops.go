@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// settleWindow is how long the outcome of a server file operation is remembered,
+// which bounds how late a watcher event can arrive and still be recognized as already handled
+const settleWindow = time.Minute
+
+// fileStamp identifies the state of a note file on disk; the zero stamp is a missing file
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// stampFile returns the current stamp of path
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+// settledFile is the state a server file operation left a file in
+type settledFile struct {
+	stamp fileStamp
+	at    time.Time
+}
+
+// fileOperations sequences index updates from watcher events and the server's own file operations
+// An operation holds the lock while it runs and reconciles the index with its outcome before releasing it,
+// so events caused by it are handled after it and, when they report what it already indexed, dropped
+type fileOperations struct {
+	mu      sync.Mutex
+	settled map[string]settledFile // path -> state left by a recent operation
+}
+
+// settle records the state an operation left path in; caller holds mu
+func (f *fileOperations) settle(path string, stamp fileStamp, now time.Time) {
+	if f.settled == nil {
+		f.settled = make(map[string]settledFile)
+	}
+	for other, file := range f.settled {
+		if now.Sub(file.at) > settleWindow {
+			delete(f.settled, other)
+		}
+	}
+	f.settled[path] = settledFile{stamp: stamp, at: now}
+}
+
+// isSettled reports whether path is still as a recent operation left it; caller holds mu
+// Any later change to the file makes the record stale, so events reporting it are not dropped
+func (f *fileOperations) isSettled(path string, now time.Time) bool {
+	file, ok := f.settled[path]
+	if !ok {
+		return false
+	}
+	if now.Sub(file.at) > settleWindow || stampFile(path) != file.stamp {
+		delete(f.settled, path)
+		return false
+	}
+	return true
+}
+
+// fileOperation runs a server-initiated change to note files, such as an lx rename, and reconciles the index
+// with every note file it created, changed or removed, in that order: removals first so renames are recognized
+func (s *LanguageServer) fileOperation(ctx context.Context, op func() error) error {
+	s.fileOps.mu.Lock()
+	defer s.fileOps.mu.Unlock()
+
+	before := s.snapshotNotes()
+	err := op()
+	after := s.snapshotNotes()
+
+	var removed, changed, created []string
+	for path := range before {
+		if _, exists := after[path]; !exists {
+			removed = append(removed, path)
+		}
+	}
+	for path, stamp := range after {
+		if old, existed := before[path]; !existed {
+			created = append(created, path)
+		} else if old != stamp {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(changed)
+	sort.Strings(created)
+
+	now := time.Now()
+	for _, paths := range [][]string{removed, changed, created} {
+		for _, path := range paths {
+			s.reconcileFile(ctx, path, after[path].exists && !before[path].exists)
+			s.fileOps.settle(path, after[path], now)
+		}
+	}
+
+	return err
+}
+
+// snapshotNotes stamps the notes of the vault and workspace folders
+func (s *LanguageServer) snapshotNotes() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, dir := range s.noteDirsTree() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tex") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			stamps[filepath.Join(dir, entry.Name())] = fileStamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestFileOperation tests that watcher events racing a server rename neither leave ghost entries nor get lost
+func TestFileOperation(t *testing.T) {
+	tempDir := t.TempDir()
+	oldPath := filepath.Join(tempDir, "20240101-draft.tex")
+	newPath := filepath.Join(tempDir, "20240101-final.tex")
+	os.WriteFile(oldPath, []byte("%% Metadata\n% title: Draft\n"), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- ls.fileOperation(context.Background(), func() error {
+			close(started)
+			<-release
+			return os.Rename(oldPath, newPath)
+		})
+	}()
+	<-started
+
+	// An event for the old file waits for the operation instead of re-adding the note midway
+	handled := make(chan struct{})
+	go func() {
+		ls.fileChanged(context.Background(), oldPath, false)
+		close(handled)
+	}()
+	select {
+	case <-handled:
+		t.Fatal("expected the watcher event to wait for the operation")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("fileOperation failed: %v", err)
+	}
+	<-handled
+	ls.fileChanged(context.Background(), newPath, true)
+
+	if _, exists := ls.index.Get("draft"); exists {
+		t.Error("expected no ghost entry for the old name")
+	}
+	if _, exists := ls.index.Get("final"); !exists {
+		t.Error("expected the renamed note to be indexed")
+	}
+	if !ls.fileOps.isSettled(newPath, time.Now()) {
+		t.Error("expected the renamed file to be recorded as settled")
+	}
+
+	// Later changes are not mistaken for the operation's
+	os.WriteFile(newPath, []byte("%% Metadata\n% title: Final version\n"), 0644)
+	ls.fileChanged(context.Background(), newPath, false)
+	if note, _ := ls.index.Get("final"); note == nil || note.Title != "Final version" {
+		t.Errorf("expected the edit to be indexed, got %+v", note)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// folderNotesDir returns the notes directory a workspace folder contributes
+// A folder holding an lx vault contributes its notes directory; a folder of .tex notes contributes itself
+func folderNotesDir(root string) (string, bool) {
+	// vault.Exists only checks the root, which every folder passes
+	if info, err := os.Stat(vaultAt(root).NotesPath); err == nil && info.IsDir() {
+		return vaultAt(root).NotesPath, true
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".tex") {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+// workspaceFolders returns the notes directories managed besides the vault's own
+func (s *LanguageServer) workspaceFolders() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.folders...)
+}
+
+// setWorkspaceFolders records the notes directories of the folders opened in the editor
+// Called from Initialize, before the initial index is built
+func (s *LanguageServer) setWorkspaceFolders(folders []protocol.WorkspaceFolder) {
+	for _, folder := range folders {
+		s.addWorkspaceFolder(folder)
+	}
+}
+
+// addWorkspaceFolder starts managing the notes of a folder
+// Returns the notes directory, or "" when the folder holds no notes or is already managed
+func (s *LanguageServer) addWorkspaceFolder(folder protocol.WorkspaceFolder) string {
+	dir, ok := folderNotesDir(uriToPath(protocol.DocumentURI(folder.URI)))
+	if !ok {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+	if dir == filepath.Clean(s.vault.NotesPath) {
+		return "" // The vault is always managed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.folders {
+		if existing == dir {
+			return ""
+		}
+	}
+	s.folders = append(s.folders, dir)
+
+	if s.watcher != nil {
+		watchTree(s.watcher, dir)
+	}
+	return dir
+}
+
+// removeWorkspaceFolder stops managing the notes of a folder
+// Returns the notes directory that was dropped, or ""
+// Matched by path alone, since the folder may already be gone from disk
+func (s *LanguageServer) removeWorkspaceFolder(folder protocol.WorkspaceFolder) string {
+	root := filepath.Clean(uriToPath(protocol.DocumentURI(folder.URI)))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.folders {
+		if existing != root && existing != vaultAt(root).NotesPath {
+			continue
+		}
+		s.folders = append(s.folders[:i], s.folders[i+1:]...)
+		if s.watcher != nil {
+			unwatchTree(s.watcher, existing)
+		}
+		return existing
+	}
+	return ""
+}
+
+// Handle DidChangeWorkspaceFolders notification
+func (s *LanguageServer) DidChangeWorkspaceFolders(ctx context.Context, params *protocol.DidChangeWorkspaceFoldersParams) error {
+	for _, folder := range params.Event.Removed {
+		dir := s.removeWorkspaceFolder(folder)
+		if dir == "" {
+			continue
+		}
+		for _, note := range s.index.AllVariants() {
+			if note.Dir != "" && inTree(note.Dir, dir) {
+				s.dropNote(note.Slug, note.Dir, note.Filename)
+				s.publishBacklinkDiagnostics(ctx, note.Slug)
+			}
+		}
+	}
+
+	for _, folder := range params.Event.Added {
+		dir := s.addWorkspaceFolder(folder)
+		if dir == "" {
+			continue
+		}
+		headers, err := s.listDirHeaders(dir, nil)
+		if err != nil {
+			continue
+		}
+		for _, header := range headers {
+			s.setNote(header)
+			s.publishBacklinkDiagnostics(ctx, header.Slug)
+		}
+	}
+
+	return nil
+}
+
+// notePath returns the path of an indexed note, wherever it lives
+func (s *LanguageServer) notePath(filename string) string {
+	if dir := s.index.Dir(filename); dir != "" {
+		return filepath.Join(dir, filename)
+	}
+	return s.vault.GetNotePath(filename)
+}
+
+// notesDirs returns every directory whose notes the server manages, the vault's first
+func (s *LanguageServer) notesDirs() []string {
+	return append([]string{s.vault.NotesPath}, s.workspaceFolders()...)
+}
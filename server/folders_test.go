@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestWorkspaceFolders tests managing notes of workspace folders besides the vault
+func TestWorkspaceFolders(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "vault", "notes")
+	otherVault := filepath.Join(tempDir, "other")
+	plainNotes := filepath.Join(tempDir, "plain")
+	empty := filepath.Join(tempDir, "empty")
+	for _, dir := range []string{notesPath, filepath.Join(otherVault, "notes"), plainNotes, empty} {
+		os.MkdirAll(dir, 0755)
+	}
+	os.WriteFile(filepath.Join(notesPath, "20240101-home.tex"), []byte("%% Metadata\n% title: Home\n\n\\ref{away}"), 0644)
+	os.WriteFile(filepath.Join(otherVault, "notes", "20240102-away.tex"), []byte("%% Metadata\n% title: Away\n"), 0644)
+	os.WriteFile(filepath.Join(plainNotes, "20240103-loose.tex"), []byte("%% Metadata\n% title: Loose\n"), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	folder := func(path string) protocol.WorkspaceFolder {
+		return protocol.WorkspaceFolder{URI: string(pathToURI(path)), Name: filepath.Base(path)}
+	}
+	ls.setWorkspaceFolders([]protocol.WorkspaceFolder{
+		folder(filepath.Dir(notesPath)), // The vault itself is not added twice
+		folder(otherVault),
+		folder(plainNotes),
+		folder(empty),
+	})
+	if folders := ls.workspaceFolders(); len(folders) != 2 {
+		t.Fatalf("expected 2 workspace folders, got %v", folders)
+	}
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	away, exists := ls.index.Get("away")
+	if !exists {
+		t.Fatal("expected note of another vault to be indexed")
+	}
+	awayPath := filepath.Join(otherVault, "notes", "20240102-away.tex")
+	if got := ls.notePath(away.Filename); got != awayPath {
+		t.Errorf("expected path %s, got %s", awayPath, got)
+	}
+	if !ls.IsManaged(pathToURI(filepath.Join(plainNotes, "20240103-loose.tex"))) {
+		t.Error("expected notes of a plain folder to be managed")
+	}
+	if ls.IsManaged(pathToURI(filepath.Join(empty, "20240104-stray.tex"))) {
+		t.Error("expected folders without notes to be ignored")
+	}
+
+	// References resolve across roots
+	locations, err := ls.Definition(context.Background(), &protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(filepath.Join(notesPath, "20240101-home.tex"))},
+			Position:     protocol.Position{Line: 3, Character: 6},
+		},
+	})
+	if err != nil || len(locations) != 1 || uriToPath(locations[0].URI) != awayPath {
+		t.Errorf("expected definition in the other vault, got %v (%v)", locations, err)
+	}
+
+	// Removing a folder drops its notes
+	ls.DidChangeWorkspaceFolders(context.Background(), &protocol.DidChangeWorkspaceFoldersParams{
+		Event: protocol.WorkspaceFoldersChangeEvent{Removed: []protocol.WorkspaceFolder{folder(otherVault)}},
+	})
+	if _, exists := ls.index.Get("away"); exists {
+		t.Error("expected notes of a removed folder to leave the index")
+	}
+
+	// Adding it back indexes them again
+	ls.DidChangeWorkspaceFolders(context.Background(), &protocol.DidChangeWorkspaceFoldersParams{
+		Event: protocol.WorkspaceFoldersChangeEvent{Added: []protocol.WorkspaceFolder{folder(otherVault)}},
+	})
+	if _, exists := ls.index.Get("away"); !exists {
+		t.Error("expected notes of an added folder to be indexed")
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// formattableFields are the metadata fields metadata.Format writes back
+// Blocks with other fields are left alone so formatting never drops data
+var formattableFields = map[string]bool{
+	"title":       true,
+	"date":        true,
+	"modified":    true,
+	"tags":        true,
+	"aliases":     true,
+	"status":      true,
+	"engine":      true,
+	"compileargs": true,
+}
+
+// Handle Formatting request
+func (s *LanguageServer) Formatting(ctx context.Context, params *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	return s.formatDocument(content), nil
+}
+
+// formatDocument canonicalizes the metadata block and trims trailing whitespace from the body
+func (s *LanguageServer) formatDocument(content string) []protocol.TextEdit {
+	lines := strings.Split(content, "\n")
+	edits := []protocol.TextEdit{}
+
+	blockStart, blockEnd, hasBlock := s.metadataParser().FindBlock(content)
+	if hasBlock {
+		if formatted, ok := s.formatMetadataBlock(content, lines[blockStart:blockEnd+1]); ok {
+			current := strings.Join(lines[blockStart:blockEnd+1], "\n")
+			if formatted != current {
+				edits = append(edits, protocol.TextEdit{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: uint32(blockStart)},
+						End:   lineEnd(lines, blockEnd),
+					},
+					NewText: formatted,
+				})
+			}
+		}
+	}
+
+	for lineNum, line := range lines {
+		if hasBlock && lineNum >= blockStart && lineNum <= blockEnd {
+			continue
+		}
+		trimmed := strings.TrimRight(line, " \t")
+		if len(trimmed) != len(line) {
+			edits = append(edits, protocol.TextEdit{
+				Range:   lineRange(lineNum, len(trimmed), len(line)),
+				NewText: "",
+			})
+		}
+	}
+
+	return edits
+}
+
+// formatMetadataBlock renders the block through metadata.Format
+// Returns false when rewriting would lose information: parse errors, fields Format does not know,
+// or comment lines in the block that are not fields, such as % !TeX directives
+func (s *LanguageServer) formatMetadataBlock(content string, block []string) (string, bool) {
+	parsed, ok := s.formattableMetadata(content, block)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSuffix(metadata.Format(parsed), "\n"), true
+}
+
+// formattableMetadata parses a block that can be rewritten through metadata.Format without losing information
+func (s *LanguageServer) formattableMetadata(content string, block []string) (*metadata.Metadata, bool) {
+	for _, line := range block[1:] {
+		match := metadataFieldPattern.FindStringSubmatch(line)
+		if match == nil || !formattableFields[strings.ToLower(match[1])] {
+			return nil, false
+		}
+	}
+
+	result, err := s.metadataParser().Parse(content)
+	if err != nil || len(result.Errors) > 0 {
+		return nil, false
+	}
+	return result.Metadata, true
+}
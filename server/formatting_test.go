@@ -0,0 +1,66 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// applyTextEdits applies non-overlapping edits to content, for assertions
+// Columns count UTF-16 code units, as LSP positions do
+func applyTextEdits(content string, edits []protocol.TextEdit) string {
+	lines := strings.Split(content, "\n")
+	offset := func(line, character uint32) int {
+		pos := 0
+		for i := 0; i < int(line); i++ {
+			pos += len(lines[i]) + 1
+		}
+		return pos + byteColumn(lines[line], int(character))
+	}
+
+	// Apply back to front so earlier offsets stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		start := offset(edits[i].Range.Start.Line, edits[i].Range.Start.Character)
+		end := offset(edits[i].Range.End.Line, edits[i].Range.End.Character)
+		content = content[:start] + edits[i].NewText + content[end:]
+	}
+	return content
+}
+
+// TestFormatDocument tests metadata canonicalization and whitespace trimming
+func TestFormatDocument(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex()}
+
+	content := "%% Metadata\n%% tags: math, Math , graphs\n%%   title:   Graph Theory  \n%% date: 2024-01-01\n\nBody line   \n\tIndented\t\nclean"
+	formatted := applyTextEdits(content, ls.formatDocument(content))
+
+	expected := "%% Metadata\n%% title: Graph Theory\n%% date: 2024-01-01\n%% tags: math, graphs\n\nBody line\n\tIndented\nclean"
+	if formatted != expected {
+		t.Errorf("unexpected formatting:\n%q\nwant\n%q", formatted, expected)
+	}
+
+	// Formatting is idempotent
+	if edits := ls.formatDocument(formatted); len(edits) != 0 {
+		t.Errorf("expected no edits on formatted content, got %+v", edits)
+	}
+
+	// A block without a date is formatted without one
+	undated := "%% Metadata\n%%  title:  Graphs\n%% tags: b, a"
+	if formatted := applyTextEdits(undated, ls.formatDocument(undated)); formatted != "%% Metadata\n%% title: Graphs\n%% tags: b, a" {
+		t.Errorf("unexpected formatting of an undated block %q", formatted)
+	}
+
+	// Blocks that would lose information are left alone
+	for _, block := range []string{
+		"%% Metadata\n%% title: Graphs\n%% date: 01/02/2024\n%% tags: a",
+		"%% Metadata\n%% title: Graphs\n%% date: 2024-01-01\n%% author: me",
+		"%% Metadata\n%% title:  Graphs\n%% date: 2024-01-01\n% !TeX program = lualatex",
+		"%% Metadata\n% keep this comment\n%% title:  Graphs\n%% date: 2024-01-01",
+	} {
+		if edits := ls.formatDocument(block); len(edits) != 0 {
+			t.Errorf("expected block to be left alone, got %+v", edits)
+		}
+	}
+}
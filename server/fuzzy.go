@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// Fuzzy match scoring, see fuzzyMatch
+const (
+	fuzzyPrefixBonus      = 40 // the candidate starts with everything typed
+	fuzzyFirstBonus       = 10 // the first typed character starts the candidate
+	fuzzyWordStartBonus   = 8  // a typed character starts a word, e.g. the t of graph-theory
+	fuzzyConsecutiveBonus = 5  // a typed character follows the previous one in the candidate
+	fuzzyGapPenalty       = 1  // per candidate character skipped, up to fuzzyMaxGapPenalty a gap
+	fuzzyMaxGapPenalty    = 3
+	maxFuzzyScore         = 999 // caps fuzzyMatch so SortText stays fixed-width
+)
+
+// fuzzyMatch reports whether the characters of pattern appear in candidate in order, ignoring case,
+// as "grth" in "graph-theory", and scores the match: higher for prefixes, characters starting
+// words and runs of adjacent characters, lower for characters skipped in between
+// Each typed character takes the next word start it can reach when there is one, so "gt" scores
+// graph-theory on its t rather than the one inside another word
+func fuzzyMatch(pattern, candidate string) (int, bool) {
+	pattern, candidate = strings.ToLower(pattern), strings.ToLower(candidate)
+	if pattern == "" {
+		return 0, true
+	}
+	if strings.HasPrefix(candidate, pattern) {
+		return clampFuzzyScore(fuzzyPrefixBonus + fuzzyFirstBonus + (len(pattern)-1)*fuzzyConsecutiveBonus + len(pattern)), true
+	}
+
+	score, last := 0, -1
+	for i := 0; i < len(pattern); i++ {
+		at := strings.IndexByte(candidate[last+1:], pattern[i])
+		if at < 0 {
+			return 0, false
+		}
+		at += last + 1
+		// Skip ahead to a word start holding the character, unless the match continues a run
+		if at != last+1 && !isWordStart(candidate, at) {
+			for j := at + 1; j < len(candidate); j++ {
+				if candidate[j] == pattern[i] && isWordStart(candidate, j) && remainingMatch(pattern[i+1:], candidate[j+1:]) {
+					at = j
+					break
+				}
+			}
+		}
+
+		score++
+		switch {
+		case at == 0:
+			score += fuzzyFirstBonus
+		case at == last+1 && last >= 0:
+			score += fuzzyConsecutiveBonus
+		default:
+			if isWordStart(candidate, at) {
+				score += fuzzyWordStartBonus
+			}
+			score -= min(at-last-1, fuzzyMaxGapPenalty) * fuzzyGapPenalty
+		}
+		last = at
+	}
+	return clampFuzzyScore(score), true
+}
+
+// isWordStart reports whether the character at i of s starts a word of a slug or label
+func isWordStart(s string, i int) bool {
+	return i == 0 || strings.IndexByte("-_:/. ", s[i-1]) >= 0
+}
+
+// remainingMatch reports whether pattern is a subsequence of s
+func remainingMatch(pattern, s string) bool {
+	for i := 0; i < len(pattern); i++ {
+		at := strings.IndexByte(s, pattern[i])
+		if at < 0 {
+			return false
+		}
+		s = s[at+1:]
+	}
+	return true
+}
+
+func clampFuzzyScore(score int) int {
+	return max(0, min(score, maxFuzzyScore))
+}
+
+// filterCompletions keeps the items whose label, or filter text, fuzzily matches what is already
+// typed, best matches first: their sort text is prefixed with the match rank, so the order the items
+// had among equal matches is kept
+func filterCompletions(items []protocol.CompletionItem, typed string) []protocol.CompletionItem {
+	if typed == "" {
+		return items
+	}
+	filtered := []protocol.CompletionItem{}
+	for _, item := range items {
+		score, ok := fuzzyMatch(typed, item.Label)
+		if item.FilterText != "" {
+			if filterScore, filterOK := fuzzyMatch(typed, item.FilterText); filterOK && (!ok || filterScore > score) {
+				score, ok = filterScore, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		sortText := item.SortText
+		if sortText == "" {
+			sortText = item.Label
+		}
+		item.SortText = fmt.Sprintf("%03d-%s", maxFuzzyScore-score, sortText)
+		filtered = append(filtered, item)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].SortText < filtered[j].SortText })
+	return filtered
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestFuzzyMatch tests subsequence matching and how matches rank
+func TestFuzzyMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, candidate string
+		matches            bool
+	}{
+		{"grth", "graph-theory", true},
+		{"GT", "graph-theory", true},
+		{"gra", "graph-theory", true},
+		{"", "graph-theory", true},
+		{"thg", "graph-theory", false},
+		{"graphs", "graph-theory", false},
+	} {
+		if _, ok := fuzzyMatch(tc.pattern, tc.candidate); ok != tc.matches {
+			t.Errorf("fuzzyMatch(%q, %q): expected %v", tc.pattern, tc.candidate, tc.matches)
+		}
+	}
+
+	// Prefixes beat word starts, which beat characters scattered inside words
+	prefix, _ := fuzzyMatch("gra", "graph-theory")
+	words, _ := fuzzyMatch("gt", "graph-theory")
+	scattered, _ := fuzzyMatch("gt", "algorithms")
+	if prefix <= words || words <= scattered {
+		t.Errorf("expected prefix > word starts > scattered, got %d, %d, %d", prefix, words, scattered)
+	}
+}
+
+// TestCompletion_Fuzzy tests completing references from a subsequence of the slug
+func TestCompletion_Fuzzy(t *testing.T) {
+	notesPath := t.TempDir()
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	for slug, title := range map[string]string{
+		"graph-theory":   "Graph Theory",
+		"growth-rates":   "Growth Rates",
+		"linear-algebra": "Linear Algebra",
+	} {
+		ls.index.Set(slug, &NoteHeader{Title: title, Slug: slug, Filename: slug + ".tex"})
+	}
+
+	testFile := filepath.Join(notesPath, "test.tex")
+	os.WriteFile(testFile, []byte("See \\ref{grth"), 0644)
+	result, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+			Position:     protocol.Position{Line: 0, Character: 13},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("expected graph-theory and growth-rates, got %+v", result.Items)
+	}
+	if result.Items[0].Label != "graph-theory" || result.Items[0].SortText >= result.Items[1].SortText {
+		t.Errorf("expected graph-theory to rank first, got %q (%s) before %q (%s)",
+			result.Items[0].Label, result.Items[0].SortText, result.Items[1].Label, result.Items[1].SortText)
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// updateGolden rewrites golden files from the current output: go test ./server -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "rewrite golden files under testdata/golden")
+
+// goldenScenario is the input of a golden test, read from testdata/golden/<feature>/<name>.in.json
+type goldenScenario struct {
+	Note     string            `json:"note"`
+	Position protocol.Position `json:"position"`
+}
+
+// goldenFeatures runs a scenario against one capability
+// The result is compared as JSON with <name>.out.json next to the input
+var goldenFeatures = map[string]func(ls *LanguageServer, uri protocol.DocumentURI, scenario goldenScenario) (interface{}, error){
+	"completion": func(ls *LanguageServer, uri protocol.DocumentURI, scenario goldenScenario) (interface{}, error) {
+		list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     scenario.Position,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Items come from map iteration; clients order by SortText, so the snapshot does too
+		sort.SliceStable(list.Items, func(i, j int) bool {
+			if list.Items[i].SortText != list.Items[j].SortText {
+				return list.Items[i].SortText < list.Items[j].SortText
+			}
+			return list.Items[i].Label < list.Items[j].Label
+		})
+		return list, nil
+	},
+	"hover": func(ls *LanguageServer, uri protocol.DocumentURI, scenario goldenScenario) (interface{}, error) {
+		return ls.Hover(context.Background(), &protocol.HoverParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     scenario.Position,
+			},
+		})
+	},
+	"diagnostics": func(ls *LanguageServer, uri protocol.DocumentURI, scenario goldenScenario) (interface{}, error) {
+		content, err := ls.GetDocument(uri)
+		if err != nil {
+			return nil, err
+		}
+		return ls.analyzeDiagnostics(content), nil
+	},
+	"symbols": func(ls *LanguageServer, uri protocol.DocumentURI, scenario goldenScenario) (interface{}, error) {
+		return ls.DocumentSymbol(context.Background(), &protocol.DocumentSymbolParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+	},
+}
+
+// TestGolden runs every scenario under testdata/golden against the testdata vault
+func TestGolden(t *testing.T) {
+	notesPath, err := filepath.Abs(filepath.Join("testdata", "vault", "notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("failed to index testdata vault: %v", err)
+	}
+
+	for feature, run := range goldenFeatures {
+		inputs, _ := filepath.Glob(filepath.Join("testdata", "golden", feature, "*.in.json"))
+		if len(inputs) == 0 {
+			t.Errorf("no golden scenarios for %s", feature)
+		}
+
+		for _, input := range inputs {
+			name := strings.TrimSuffix(filepath.Base(input), ".in.json")
+			t.Run(feature+"/"+name, func(t *testing.T) {
+				data, err := os.ReadFile(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var scenario goldenScenario
+				if err := json.Unmarshal(data, &scenario); err != nil {
+					t.Fatalf("invalid scenario: %v", err)
+				}
+
+				result, err := run(ls, pathToURI(filepath.Join(notesPath, scenario.Note)), scenario)
+				if err != nil {
+					t.Fatalf("%s failed: %v", feature, err)
+				}
+
+				got, err := json.MarshalIndent(result, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Machine-specific paths would make snapshots unportable
+				got = []byte(strings.ReplaceAll(string(got), string(pathToURI(notesPath)), "file:///vault/notes") + "\n")
+
+				output := strings.TrimSuffix(input, ".in.json") + ".out.json"
+				if *updateGolden {
+					if err := os.WriteFile(output, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(output)
+				if err != nil {
+					t.Fatalf("missing golden file, run with -update to create it: %v", err)
+				}
+				if string(got) != string(want) {
+					t.Errorf("output differs from %s, run with -update if the change is intended\ngot:\n%s\nwant:\n%s", output, got, want)
+				}
+			})
+		}
+	}
+}
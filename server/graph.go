@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandExportGraph writes the note graph of the vault to a file for visualization tools
+// Arguments: [path] or [path, format], format being "dot" or "json"; without it the extension decides
+const commandExportGraph = "lx.exportGraph"
+
+// Graph export formats
+const (
+	graphFormatDOT  = "dot"
+	graphFormatJSON = "json"
+)
+
+func init() {
+	registerCommand(commandExportGraph, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.exportGraphCommand(ctx, args)
+	})
+}
+
+// GraphNode is a note in the exported graph
+type GraphNode struct {
+	Slug      string   `json:"slug"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags"`
+	Component int      `json:"component"` // connected component of the note, 0 for the largest
+}
+
+// GraphEdge is a link between two notes; repeated references are counted in Weight
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// NoteGraph is the note graph as written by lx.exportGraph in JSON
+type NoteGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// ExportGraphResult is returned by lx.exportGraph
+type ExportGraphResult struct {
+	Path   string `json:"path"`
+	Format string `json:"format"`
+	Nodes  int    `json:"nodes"`
+	Edges  int    `json:"edges"`
+}
+
+// exportGraphCommand handles lx.exportGraph
+func (s *LanguageServer) exportGraphCommand(ctx context.Context, args []interface{}) (*ExportGraphResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a path argument", commandExportGraph)
+	}
+	path, ok := args[0].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%s: invalid path argument", commandExportGraph)
+	}
+	if strings.HasPrefix(path, "file://") {
+		path = uriToPath(protocol.DocumentURI(path))
+	}
+
+	format := graphFormatDOT
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = graphFormatJSON
+	}
+	if len(args) > 1 {
+		format, _ = args[1].(string)
+	}
+
+	graph := s.noteGraph()
+	var data []byte
+	switch format {
+	case graphFormatDOT:
+		data = []byte(graph.dot())
+	case graphFormatJSON:
+		encoded, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode graph: %w", err)
+		}
+		data = append(encoded, '\n')
+	default:
+		return nil, fmt.Errorf("%s: unknown format '%s'", commandExportGraph, format)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write graph: %w", err)
+	}
+	s.recordActivity(ctx, "graph.export", path)
+
+	return &ExportGraphResult{Path: path, Format: format, Nodes: len(graph.Nodes), Edges: len(graph.Edges)}, nil
+}
+
+// noteGraph builds the graph of indexed notes from the note graph of the index
+// References to missing notes and labels are left out, as are links from a note to itself
+func (s *LanguageServer) noteGraph() *NoteGraph {
+	notes := s.index.All()
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Slug < notes[j].Slug })
+
+	components := make(map[string]int)
+	for n, component := range s.index.Graph().Components() {
+		for _, slug := range component {
+			components[slug] = n
+		}
+	}
+
+	graph := &NoteGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, note := range notes {
+		tags := note.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{Slug: note.Slug, Title: note.Title, Tags: tags, Component: components[note.Slug]})
+
+		for _, edge := range s.index.Graph().Outgoing(note.Slug) {
+			graph.Edges = append(graph.Edges, GraphEdge{Source: note.Slug, Target: edge.Target, Weight: len(edge.Ranges)})
+		}
+	}
+
+	return graph
+}
+
+// dot renders the graph in Graphviz DOT, labelling nodes with note titles
+func (g *NoteGraph) dot() string {
+	var builder strings.Builder
+	builder.WriteString("digraph notes {\n")
+	for _, node := range g.Nodes {
+		builder.WriteString(fmt.Sprintf("  %s [label=%s];\n", strconv.Quote(node.Slug), strconv.Quote(node.Title)))
+	}
+	for _, edge := range g.Edges {
+		if edge.Weight > 1 {
+			builder.WriteString(fmt.Sprintf("  %s -> %s [weight=%d];\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target), edge.Weight))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("  %s -> %s;\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target)))
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
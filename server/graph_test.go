@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestExportGraph tests writing the note graph as DOT and JSON
+func TestExportGraph(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-a.tex"), []byte("%% Metadata\n%% title: Note \"A\"\n%% date: 2024-01-01\n%% tags: x\n\n\\ref{b} \\ref{b} \\ref{gone} \\ref{a}"), 0644)
+	os.WriteFile(filepath.Join(notesPath, "20240102-b.tex"), []byte("\\input{a}"), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: notesPath}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	dotPath := filepath.Join(tempDir, "graph.dot")
+	result, err := ls.exportGraphCommand(context.Background(), []interface{}{dotPath})
+	if err != nil {
+		t.Fatalf("exportGraphCommand failed: %v", err)
+	}
+	if result.Format != graphFormatDOT || result.Nodes != 2 || result.Edges != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	data, _ := os.ReadFile(dotPath)
+	for _, want := range []string{`"a" [label="Note \"A\""];`, `"a" -> "b" [weight=2];`, `"b" -> "a";`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected DOT to contain %s, got:\n%s", want, data)
+		}
+	}
+
+	jsonPath := filepath.Join(tempDir, "graph.json")
+	if _, err := ls.exportGraphCommand(context.Background(), []interface{}{jsonPath}); err != nil {
+		t.Fatalf("exportGraphCommand failed: %v", err)
+	}
+	var graph NoteGraph
+	data, _ = os.ReadFile(jsonPath)
+	if err := json.Unmarshal(data, &graph); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(graph.Nodes) != 2 || graph.Nodes[0].Tags[0] != "x" || graph.Nodes[1].Component != 0 || len(graph.Edges) != 2 || graph.Edges[0].Weight != 2 {
+		t.Errorf("unexpected graph %+v", graph)
+	}
+
+	if _, err := ls.exportGraphCommand(context.Background(), []interface{}{dotPath, "svg"}); err == nil {
+		t.Error("expected unknown format to be rejected")
+	}
+}
+
+// TestExportObsidianGraph tests writing the note graph as a JSON Canvas of Markdown mirrors
+func TestExportObsidianGraph(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-a.tex"), []byte("%% Metadata\n%% title: Note A\n%% date: 2024-01-01\n%% tags: x\n\n\\ref{b} \\ref{b}"), 0644)
+	os.WriteFile(filepath.Join(notesPath, "20240102-b.tex"), []byte("\\ref{a}"), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{RootPath: tempDir, NotesPath: notesPath}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	canvasPath := filepath.Join(tempDir, "notes.canvas")
+	result, err := ls.exportObsidianGraphCommand(context.Background(), []interface{}{"notes.canvas", "Notes/"})
+	if err != nil {
+		t.Fatalf("exportObsidianGraphCommand failed: %v", err)
+	}
+	if result.Format != graphFormatCanvas || result.Nodes != 2 || result.Edges != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	var canvas Canvas
+	data, _ := os.ReadFile(canvasPath)
+	if err := json.Unmarshal(data, &canvas); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	a, b := canvas.Nodes[0], canvas.Nodes[1]
+	if a.Type != "file" || a.File != "Notes/a.md" || a.Metadata.Title != "Note A" || a.Metadata.Date != "2024-01-01" || a.Metadata.Tags[0] != "x" {
+		t.Errorf("unexpected node %+v", a)
+	}
+	if a.X == b.X && a.Y == b.Y {
+		t.Errorf("expected the cards not to overlap, got %+v and %+v", a, b)
+	}
+	if edge := canvas.Edges[0]; edge.FromNode != "a" || edge.ToNode != "b" || edge.Label != "2 references" {
+		t.Errorf("unexpected edge %+v", edge)
+	}
+
+	// Existing files are only replaced when asked to, and nothing is written outside the vault
+	if _, err := ls.exportObsidianGraphCommand(context.Background(), []interface{}{canvasPath}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected the existing canvas to be kept, got %v", err)
+	}
+	if _, err := ls.exportObsidianGraphCommand(context.Background(), []interface{}{canvasPath, nil, true}); err != nil {
+		t.Errorf("expected overwrite to replace the canvas, got %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "notes.canvas")
+	for _, target := range []string{outside, "../escape.canvas"} {
+		if _, err := ls.exportObsidianGraphCommand(context.Background(), []interface{}{target}); err == nil || !strings.Contains(err.Error(), "outside the vault") {
+			t.Errorf("expected %s to be refused, got %v", target, err)
+		}
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Error("expected nothing to be written outside the vault")
+	}
+}
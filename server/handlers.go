@@ -3,42 +3,130 @@ package server
 import (
 	"context"
 	"fmt"
-	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"go.lsp.dev/protocol"
 )
 
+// InitializeResult is the protocol's initialize result with the capabilities protocol v0.12 does not model
+type InitializeResult struct {
+	Capabilities ServerCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
+}
+
+// ServerCapabilities adds LSP 3.17 capabilities to the protocol's
+type ServerCapabilities struct {
+	protocol.ServerCapabilities
+	DiagnosticProvider *DiagnosticOptions `json:"diagnosticProvider,omitempty"`
+}
+
 // Handle Initialize request
-func (s *LanguageServer) Initialize(ctx context.Context, params *protocol.InitializeParams) (*protocol.InitializeResult, error) {
-	return &protocol.InitializeResult{
-		Capabilities: protocol.ServerCapabilities{
-			TextDocumentSync: protocol.TextDocumentSyncOptions{
-				OpenClose: true,
-				Change:    protocol.TextDocumentSyncKindFull,
+func (s *LanguageServer) Initialize(ctx context.Context, params *protocol.InitializeParams) (*InitializeResult, error) {
+	if params.InitializationOptions != nil {
+		if err := s.loadSettings(ctx, params.InitializationOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	features := s.settings().Features
+	if !features.Watchers {
+		s.stopWatcher()
+	}
+
+	s.setWorkspaceFolders(params.WorkspaceFolders)
+
+	// Clients supporting dynamic registration get completion registered in Initialized,
+	// so trigger characters can be swapped when settings change
+	var completionProvider *protocol.CompletionOptions
+	caps := params.Capabilities.TextDocument
+	s.dynamicCompletion = features.Completion && caps != nil && caps.Completion != nil && caps.Completion.DynamicRegistration
+	s.workDoneProgress = params.Capabilities.Window != nil && params.Capabilities.Window.WorkDoneProgress
+	s.showDocument = params.Capabilities.Window != nil && params.Capabilities.Window.ShowDocument != nil && params.Capabilities.Window.ShowDocument.Support
+	workspace := params.Capabilities.Workspace
+	s.configurationPull = workspace != nil && workspace.Configuration
+	s.dynamicWatchedFiles = features.Watchers && workspace != nil && workspace.DidChangeWatchedFiles != nil && workspace.DidChangeWatchedFiles.DynamicRegistration
+	if features.Completion && !s.dynamicCompletion {
+		completionProvider = &protocol.CompletionOptions{
+			TriggerCharacters: s.settings().TriggerCharacters,
+		}
+	}
+
+	// In coexistence mode the generic LaTeX editing aids are left to the other server
+	var signatureHelpProvider *protocol.SignatureHelpOptions
+	var onTypeFormattingProvider *protocol.DocumentOnTypeFormattingOptions
+	if !s.settings().Coexist {
+		signatureHelpProvider = &protocol.SignatureHelpOptions{
+			TriggerCharacters:   []string{"{", "["},
+			RetriggerCharacters: []string{"}", "]"},
+		}
+		onTypeFormattingProvider = &protocol.DocumentOnTypeFormattingOptions{
+			FirstTriggerCharacter: "}",
+			MoreTriggerCharacter:  []string{"\n"},
+		}
+	}
+
+	// Pulling clients get the diagnostics of the notes that are not open, for a vault-wide problems panel
+	var diagnosticProvider *DiagnosticOptions
+	if features.Diagnostics && s.pullDiagnostics {
+		diagnosticProvider = &DiagnosticOptions{
+			Identifier:            "lx-ls",
+			InterFileDependencies: true,
+			WorkspaceDiagnostics:  true,
+		}
+	}
+
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{ServerCapabilities: protocol.ServerCapabilities{
+			Workspace: &protocol.ServerCapabilitiesWorkspace{
+				WorkspaceFolders: &protocol.ServerCapabilitiesWorkspaceFolders{
+					Supported:           true,
+					ChangeNotifications: true,
+				},
 			},
-			CompletionProvider: &protocol.CompletionOptions{
-				TriggerCharacters: []string{"{", "\\", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", "-"},
+			TextDocumentSync: protocol.TextDocumentSyncOptions{
+				OpenClose:         true,
+				Change:            protocol.TextDocumentSyncKindFull,
+				WillSaveWaitUntil: true,
+				Save:              &protocol.SaveOptions{},
 			},
-			DefinitionProvider: true,
-			HoverProvider:      true,
-			RenameProvider:     true,
+			CompletionProvider:               completionProvider,
+			DefinitionProvider:               true,
+			HoverProvider:                    true,
+			ReferencesProvider:               true,
+			DocumentSymbolProvider:           true,
+			WorkspaceSymbolProvider:          map[string]bool{"resolveProvider": true},
+			DocumentHighlightProvider:        true,
+			RenameProvider:                   features.Rename,
+			DocumentFormattingProvider:       true,
+			SignatureHelpProvider:            signatureHelpProvider,
+			DocumentOnTypeFormattingProvider: onTypeFormattingProvider,
 			DocumentLinkProvider: &protocol.DocumentLinkOptions{
 				ResolveProvider: false,
 			},
+			CodeActionProvider: true,
+			CodeLensProvider: &protocol.CodeLensOptions{
+				ResolveProvider: false,
+			},
+			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+				Commands: registeredCommands(),
+			},
+		},
+			DiagnosticProvider: diagnosticProvider,
 		},
 		ServerInfo: &protocol.ServerInfo{
 			Name:    "lx-ls",
-			Version: "0.1.1",
+			Version: Version,
 		},
 	}, nil
 }
 
 // Handle Rename request
 func (s *LanguageServer) Rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
-	if !s.IsManaged(params.TextDocument.URI) {
+	if !s.settings().Features.Rename || !s.IsManaged(params.TextDocument.URI) {
 		return nil, nil
 	}
 
@@ -47,6 +135,30 @@ func (s *LanguageServer) Rename(ctx context.Context, params *protocol.RenamePara
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	// Tags are renamed on the metadata of every note carrying them
+	if tag, ok := s.tagAtPosition(content, params.Position); ok {
+		edit, err := s.tagRenameEdit(tag.Name, params.NewName)
+		if err != nil {
+			return nil, err
+		}
+		s.recordActivity(ctx, "rename.tag", fmt.Sprintf("%s -> %s", tag.Name, params.NewName))
+		return s.deliverEdit(ctx, fmt.Sprintf("Rename tag '%s'", tag.Name), edit)
+	}
+
+	// Labels are renamed in the note defining them and the references resolving to it
+	if label := s.getLabelAtPosition(content, params.Position); label != "" {
+		owner := s.labelOwner(filepath.Base(uriToPath(params.TextDocument.URI)), content, label)
+		if owner == "" {
+			return nil, fmt.Errorf("label '%s' is defined in several notes; rename it from its \\label", label)
+		}
+		edit, err := s.labelRenameEdit(owner, label, params.NewName)
+		if err != nil {
+			return nil, err
+		}
+		s.recordActivity(ctx, "rename.label", fmt.Sprintf("%s -> %s", label, params.NewName))
+		return s.deliverEdit(ctx, fmt.Sprintf("Rename label '%s'", label), edit)
+	}
+
 	oldSlug := s.getSlugAtPosition(content, params.Position)
 	if oldSlug == "" {
 		return nil, fmt.Errorf("no valid note reference found at cursor")
@@ -54,16 +166,39 @@ func (s *LanguageServer) Rename(ctx context.Context, params *protocol.RenamePara
 
 	newTitle := params.NewName
 
-	// Shell out to LX CLI
-	cmd := exec.Command("lx", "rename", oldSlug, newTitle)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return nil, fmt.Errorf("lx rename failed: %s", string(output))
+	// Shell out to LX CLI; its file changes are indexed before the watcher events they cause are handled
+	err = s.fileOperation(ctx, func() error {
+		cmd := exec.Command("lx", "rename", oldSlug, newTitle)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("lx rename failed: %s", string(output))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	s.recordActivity(ctx, "rename.note", fmt.Sprintf("%s -> %q", oldSlug, newTitle))
 
 	// Return nil edit so editor reloads from disk
 	return &protocol.WorkspaceEdit{}, nil
 }
 
+// Handle ExecuteCommand request
+func (s *LanguageServer) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	ctx, progress := s.beginProgress(ctx, params.WorkDoneToken, params.Command)
+	result, err := s.runCommand(ctx, params)
+
+	switch {
+	case ctx.Err() != nil:
+		progress.End(ctx, "Cancelled")
+	case err != nil:
+		progress.End(ctx, err.Error())
+	default:
+		progress.End(ctx, "Done")
+	}
+	return result, err
+}
+
 // Handle DidOpen notification
 func (s *LanguageServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
 	if !s.IsManaged(params.TextDocument.URI) {
@@ -71,12 +206,12 @@ func (s *LanguageServer) DidOpen(ctx context.Context, params *protocol.DidOpenTe
 	}
 
 	// Store document in memory
-	s.mu.Lock()
-	s.documents[params.TextDocument.URI] = params.TextDocument.Text
-	s.mu.Unlock()
+	text := s.storeDocument(params.TextDocument.URI, params.TextDocument.Text)
+	s.trackOpenFile(params.TextDocument.URI)
+	s.recordOpen(params.TextDocument.URI)
 
 	// Run diagnostics
-	return s.publishDiagnostics(ctx, params.TextDocument.URI, params.TextDocument.Text)
+	return s.publishDiagnostics(ctx, params.TextDocument.URI, text)
 }
 
 // Handle DidChange notification
@@ -89,29 +224,69 @@ func (s *LanguageServer) DidChange(ctx context.Context, params *protocol.DidChan
 		return nil
 	}
 
-	text := params.ContentChanges[0].Text
-
-	// Update document in memory
-	s.mu.Lock()
-	s.documents[params.TextDocument.URI] = text
-	s.mu.Unlock()
+	// Update document in memory, under its new URI if it was renamed on disk
+	uri := s.currentURI(params.TextDocument.URI)
+	text := s.storeDocument(uri, params.ContentChanges[0].Text)
+	s.scheduleReindex(uri)
 
 	// Run diagnostics
-	return s.publishDiagnostics(ctx, params.TextDocument.URI, text)
+	return s.publishDiagnostics(ctx, uri, text)
 }
 
 // Handle DidClose notification
 func (s *LanguageServer) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	uri := s.currentURI(params.TextDocument.URI)
+
 	// Remove from memory to prevent leaks
 	s.mu.Lock()
-	delete(s.documents, params.TextDocument.URI)
+	delete(s.documents, uri)
+	delete(s.unnormalized, uri)
+	delete(s.lineIndexes, uri)
+	delete(s.openFiles, uri)
+	delete(s.movedDocuments, params.TextDocument.URI)
 	s.mu.Unlock()
+
+	// Unsaved edits are discarded, so search and backlinks fall back to the file
+	s.cancelReindex(uri)
+	s.reindexDocument(uri)
+	return nil
+}
+
+// Handle DidSave notification
+// Re-indexes the note right away instead of waiting for the file watcher
+func (s *LanguageServer) DidSave(ctx context.Context, params *protocol.DidSaveTextDocumentParams) error {
+	uri := s.currentURI(params.TextDocument.URI)
+	if !s.IsManaged(uri) {
+		return nil
+	}
+
+	path := uriToPath(uri)
+	s.updateIndexForFile(path)
+
+	// Open notes referencing the saved one see it created or gone
+	slug := s.parseFilenameToSlug(filepath.Base(path))
+	seen := make(map[protocol.DocumentURI]bool)
+	for _, link := range s.index.Links().Incoming(slug) {
+		linkURI := pathToURI(s.notePath(link.Filename))
+		if seen[linkURI] {
+			continue
+		}
+		seen[linkURI] = true
+
+		s.mu.RLock()
+		content, open := s.documents[linkURI]
+		s.mu.RUnlock()
+		if open {
+			s.publishDiagnostics(ctx, linkURI, content)
+		}
+	}
+
 	return nil
 }
 
 // Handle Completion request
 func (s *LanguageServer) Completion(ctx context.Context, params *protocol.CompletionParams) (*protocol.CompletionList, error) {
-	if !s.IsManaged(params.TextDocument.URI) {
+	if !s.settings().Features.Completion || !s.IsManaged(params.TextDocument.URI) {
 		return &protocol.CompletionList{Items: []protocol.CompletionItem{}}, nil
 	}
 
@@ -121,7 +296,7 @@ func (s *LanguageServer) Completion(ctx context.Context, params *protocol.Comple
 		return &protocol.CompletionList{Items: []protocol.CompletionItem{}}, nil
 	}
 
-	lines := strings.Split(content, "\n")
+	lines := s.documentLines(content)
 	if int(params.Position.Line) >= len(lines) {
 		return &protocol.CompletionList{Items: []protocol.CompletionItem{}}, nil
 	}
@@ -134,79 +309,175 @@ func (s *LanguageServer) Completion(ctx context.Context, params *protocol.Comple
 	linePrefix := line[:params.Position.Character]
 
 	var items []protocol.CompletionItem
+	refInsert := s.settings().Completion.RefInsert
 
-	// Check if we're inside \ref{...}
-	refPattern := regexp.MustCompile(`\\ref\{([^}]*)$`)
-	if matches := refPattern.FindStringSubmatch(linePrefix); matches != nil {
-		prefix := matches[1]
-		items = s.getRefCompletions()
+	// Check if we're inside \ref{...} or a custom reference macro
+	refPattern := macroPattern(append([]string{"ref"}, s.referenceMacros()...), `\{([^}]*)$`)
+	if match := refPattern.FindStringSubmatchIndex(linePrefix); match != nil {
+		prefixStart := match[len(match)-2]
+		items = s.getRefCompletions(currentSectionHeading(lines, int(params.Position.Line)), s.documentRoot(params.TextDocument.URI))
+
+		// \ref also reaches labels inside notes
+		if strings.HasPrefix(linePrefix[match[0]:], "\\ref{") {
+			items = append(items, s.labelTargetCompletions(params.TextDocument.URI, content)...)
+		}
 
 		// Filter completions based on what's already typed
-		if prefix != "" {
-			filtered := []protocol.CompletionItem{}
-			for _, item := range items {
-				if strings.HasPrefix(item.Label, prefix) {
-					filtered = append(filtered, item)
-				}
-			}
-			items = filtered
+		items = filterCompletions(items, linePrefix[prefixStart:])
+		if refInsert == refInsertCloseBrace || refInsert == refInsertFull {
+			closeRefEdits(items, line, int(params.Position.Line), prefixStart, int(params.Position.Character))
 		}
+	} else if match := labelRefCompletionPattern.FindStringSubmatchIndex(linePrefix); match != nil {
+		// Label-only references such as \eqref and \cref, whose argument may be a comma list
+		typed := linePrefix[match[2]:]
+		typed = strings.TrimSpace(typed[strings.LastIndex(typed, ",")+1:])
+		items = filterCompletions(s.labelTargetCompletions(params.TextDocument.URI, content), typed)
+	} else if refInsert == refInsertFull {
+		// Check if we're inside [[...
+		items = s.wikiRefCompletions(lines, int(params.Position.Line), int(params.Position.Character), s.documentRoot(params.TextDocument.URI))
+	}
+
+	// Check if we're inside \label{...}
+	if matches := labelCompletionPattern.FindStringSubmatch(linePrefix); matches != nil {
+		items = append(items, s.labelCompletions(lines, int(params.Position.Line), int(params.Position.Character), matches[1])...)
+	}
+
+	// Check if we're inside \begin{...}, left to the other server when coexisting
+	if !s.settings().Coexist {
+		items = append(items, s.environmentCompletions(content, lines, params.Position)...)
 	}
 
 	// Check if we're inside \usepackage{...}
 	pkgPattern := regexp.MustCompile(`\\usepackage\{([^}]*)$`)
 	if matches := pkgPattern.FindStringSubmatch(linePrefix); matches != nil {
-		prefix := matches[1]
-		templateItems := s.getTemplateCompletions()
-
-		if prefix != "" {
-			filtered := []protocol.CompletionItem{}
-			for _, item := range templateItems {
-				if strings.HasPrefix(item.Label, prefix) {
-					filtered = append(filtered, item)
-				}
-			}
-			items = append(items, filtered...)
-		} else {
-			items = append(items, templateItems...)
-		}
+		items = append(items, filterCompletions(s.getTemplateCompletions(), matches[1])...)
 	}
 
+	// Check if we're inside \includegraphics{...}
+	items = append(items, s.graphicsCompletions(linePrefix)...)
+
+	// Check if we're inside \cite{...}
+	items = append(items, s.citeCompletions(params.TextDocument.URI, content, linePrefix)...)
+
+	// Check if we're completing the engine metadata field
+	items = append(items, s.engineCompletions(content, int(params.Position.Line), linePrefix)...)
+
+	// Check if we're completing a tag in the metadata block
+	items = append(items, s.tagCompletions(content, int(params.Position.Line), linePrefix)...)
+
 	// Add custom snippets when not inside a completion context
-	if len(items) == 0 {
+	settings := s.settings()
+	if len(items) == 0 && !settings.Coexist && settings.Completion.Snippets {
 		items = append(items, s.getSnippetCompletions()...)
+		items = append(items, s.getTheoremCompletions(content)...)
+	}
+
+	// Ask the client to come back for the notes still being indexed, or those cut off
+	incomplete := !s.indexed()
+	if max := settings.Completion.MaxItems; max > 0 && len(items) > max {
+		items = items[:max]
+		incomplete = true
 	}
 
 	return &protocol.CompletionList{
-		IsIncomplete: false,
+		IsIncomplete: incomplete,
 		Items:        items,
 	}, nil
 }
 
 // getRefCompletions returns completions for note references
-func (s *LanguageServer) getRefCompletions() []protocol.CompletionItem {
+// Notes whose title or tags match the enclosing section heading sort first
+func (s *LanguageServer) getRefCompletions(heading, ownRoot string) []protocol.CompletionItem {
 	notes := s.index.All()
 	items := make([]protocol.CompletionItem, 0, len(notes))
+	keywords := headingKeywords(heading)
+	multiRoot := s.multiRoot()
+	sortTexts := make(map[string]string, len(notes))
 
 	for _, note := range notes {
+		score := sectionScore(note, keywords)
+		sortText := fmt.Sprintf("%02d-%s", maxSectionScore-score, note.Slug)
+		sortTexts[note.Slug] = sortText
+
+		// Slugs several vaults use are offered once per vault, qualified with its name
+		if multiRoot {
+			if qualified := s.qualifiedRefCompletions(note, sortText, ownRoot); qualified != nil {
+				items = append(items, qualified...)
+				continue
+			}
+		}
+
+		detail := note.Title
+		if multiRoot {
+			detail = fmt.Sprintf("%s (%s)", note.Title, s.noteRoot(note))
+		}
 		items = append(items, protocol.CompletionItem{
 			Label:      note.Slug,
 			Kind:       protocol.CompletionItemKindReference,
-			Detail:     note.Title,
+			Detail:     detail,
 			InsertText: note.Slug,
+			SortText:   sortText,
 		})
 	}
 
-	return items
+	// Aliases sort right after the slug of their note
+	return append(items, s.aliasRefCompletions(sortTexts)...)
 }
 
-// getTemplateCompletions returns completions for templates
-func (s *LanguageServer) getTemplateCompletions() []protocol.CompletionItem {
-	templates, err := s.listTemplates()
-	if err != nil {
-		return []protocol.CompletionItem{}
+// maxSectionScore caps sectionScore so SortText stays fixed-width
+const maxSectionScore = 99
+
+// currentSectionHeading returns the text of the closest sectioning command above line
+func currentSectionHeading(lines []string, line int) string {
+	if line >= len(lines) {
+		line = len(lines) - 1
+	}
+	for i := line; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "%") {
+			continue
+		}
+		if matches := sectionPattern.FindAllStringSubmatch(lines[i], -1); matches != nil {
+			return matches[len(matches)-1][2]
+		}
+	}
+	return ""
+}
+
+// headingKeywords splits a heading into lowercase words worth matching on
+func headingKeywords(heading string) []string {
+	var keywords []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) >= 3 {
+			keywords = append(keywords, word)
+		}
+	}
+	return keywords
+}
+
+// sectionScore counts how many heading keywords appear in a note's title, slug or tags
+func sectionScore(note *NoteHeader, keywords []string) int {
+	if len(keywords) == 0 {
+		return 0
 	}
 
+	haystack := strings.ToLower(note.Title + " " + note.Slug + " " + strings.Join(note.Tags, " "))
+	score := 0
+	for _, keyword := range keywords {
+		if strings.Contains(haystack, keyword) {
+			score++
+		}
+	}
+	if score > maxSectionScore {
+		score = maxSectionScore
+	}
+	return score
+}
+
+// getTemplateCompletions returns completions for templates
+func (s *LanguageServer) getTemplateCompletions() []protocol.CompletionItem {
+	templates := s.listTemplates()
 	items := make([]protocol.CompletionItem, 0, len(templates))
 	for _, tmpl := range templates {
 		items = append(items, protocol.CompletionItem{
@@ -219,25 +490,6 @@ func (s *LanguageServer) getTemplateCompletions() []protocol.CompletionItem {
 	return items
 }
 
-// listTemplates returns all available template names
-func (s *LanguageServer) listTemplates() ([]string, error) {
-	entries, err := os.ReadDir(s.vault.TemplatesPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var templates []string
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sty") {
-			continue
-		}
-		name := strings.TrimSuffix(entry.Name(), ".sty")
-		templates = append(templates, name)
-	}
-
-	return templates, nil
-}
-
 // getSnippetCompletions returns custom LX snippets
 func (s *LanguageServer) getSnippetCompletions() []protocol.CompletionItem {
 	return []protocol.CompletionItem{
@@ -268,16 +520,13 @@ func (s *LanguageServer) Definition(ctx context.Context, params *protocol.Defini
 	}
 
 	slug := s.getSlugAtPosition(content, params.Position)
-	if slug == "" {
-		return nil, nil
-	}
-
-	note, exists := s.index.Get(slug)
-	if !exists {
-		return nil, nil
+	note, exists := s.resolveNote(slug, params.TextDocument.URI)
+	if slug == "" || !exists {
+		// Not a note: the reference may target a label inside one
+		return s.labelDefinitions(params.TextDocument.URI, content, params.Position), nil
 	}
 
-	notePath := s.vault.GetNotePath(note.Filename)
+	notePath := s.headerPath(note)
 	uri := protocol.DocumentURI("file://" + notePath)
 
 	return []protocol.Location{
@@ -302,26 +551,63 @@ func (s *LanguageServer) Hover(ctx context.Context, params *protocol.HoverParams
 		return nil, nil
 	}
 
+	if tag, ok := s.tagAtPosition(content, params.Position); ok {
+		return s.tagHover(tag.Name), nil
+	}
+
+	if hover := s.todoHover(params.TextDocument.URI, content, params.Position); hover != nil {
+		return hover, nil
+	}
+
+	if hover := s.assetHover(content, params.Position); hover != nil {
+		return hover, nil
+	}
+
 	slug := s.getSlugAtPosition(content, params.Position)
 	if slug == "" {
-		return nil, nil
+		if s.settings().Coexist {
+			return nil, nil
+		}
+		return escapeHover(content, params.Position), nil
 	}
 
-	note, exists := s.index.Get(slug)
+	note, exists := s.resolveNote(slug, params.TextDocument.URI)
 	if !exists {
+		if !s.indexed() {
+			return &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: fmt.Sprintf("`%s`\n\n_Indexing notes, details are available once indexing finishes._", slug),
+				},
+			}, nil
+		}
 		return nil, nil
 	}
 
-	hoverText := fmt.Sprintf("**%s**\n\nSlug: `%s`\nDate: %s",
-		note.Title,
-		note.Slug,
-		note.Date,
-	)
+	hoverText := fmt.Sprintf("**%s**\n\nSlug: `%s`", note.Title, note.Slug)
+	if _, ref := splitQualified(slug); ref != note.Slug {
+		hoverText += fmt.Sprintf(" (referenced by its alias `%s`)", ref)
+	}
+	hoverText += fmt.Sprintf("\nDate: %s", note.Date)
 
 	if len(note.Tags) > 0 {
 		hoverText += fmt.Sprintf("\nTags: %s", strings.Join(note.Tags, ", "))
 	}
 
+	if aliases := s.index.Aliases().Get(note.Slug); len(aliases) > 0 {
+		hoverText += fmt.Sprintf("\nAliases: %s", strings.Join(aliases, ", "))
+	}
+
+	if s.multiRoot() {
+		hoverText += fmt.Sprintf("\nVault: %s", s.noteRoot(note))
+	}
+
+	if backlinks := s.backlinkSummary(note.Slug); backlinks != "" {
+		hoverText += fmt.Sprintf("\nReferenced by: %s", backlinks)
+	}
+
+	hoverText += "\n\n" + todoStatus(len(s.index.Todos().Get(note.Slug)))
+
 	return &protocol.Hover{
 		Contents: protocol.MarkupContent{
 			Kind:  protocol.Markdown,
@@ -332,7 +618,7 @@ func (s *LanguageServer) Hover(ctx context.Context, params *protocol.HoverParams
 
 // getSlugAtPosition extracts a slug from the given position
 func (s *LanguageServer) getSlugAtPosition(content string, pos protocol.Position) string {
-	lines := strings.Split(content, "\n")
+	lines := s.documentLines(content)
 	if int(pos.Line) >= len(lines) {
 		return ""
 	}
@@ -340,17 +626,11 @@ func (s *LanguageServer) getSlugAtPosition(content string, pos protocol.Position
 	line := lines[pos.Line]
 
 	// Find \ref{slug} or similar patterns
-	refPattern := regexp.MustCompile(`\\(?:ref|cite|input|include)\{([^}]+)\}`)
-	matches := refPattern.FindAllStringSubmatchIndex(line, -1)
+	matches := s.linkPattern().FindAllStringSubmatchIndex(line, -1)
 
 	for _, match := range matches {
 		if int(pos.Character) >= match[2] && int(pos.Character) <= match[3] {
-			rawSlug := line[match[2]:match[3]]
-			// Normalize
-			slug := strings.TrimSpace(rawSlug)
-			slug = strings.TrimSuffix(slug, ".tex")
-			slug = strings.TrimPrefix(slug, "../notes/")
-			return slug
+			return normalizeSlug(line[match[2]:match[3]])
 		}
 	}
 
@@ -359,7 +639,14 @@ func (s *LanguageServer) getSlugAtPosition(content string, pos protocol.Position
 
 // publishDiagnostics analyzes content and publishes diagnostics
 func (s *LanguageServer) publishDiagnostics(ctx context.Context, uri protocol.DocumentURI, content string) error {
-	diagnostics := s.analyzeDiagnostics(content)
+	if s.conn == nil {
+		return nil
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	if config := s.settings(); config.Diagnostics.Enabled && config.Features.Diagnostics {
+		diagnostics = s.analyzeNoteDiagnostics(uri, content)
+	}
 
 	return s.conn.Notify(ctx, protocol.MethodTextDocumentPublishDiagnostics, &protocol.PublishDiagnosticsParams{
 		URI:         uri,
@@ -369,10 +656,20 @@ func (s *LanguageServer) publishDiagnostics(ctx context.Context, uri protocol.Do
 
 // analyzeDiagnostics scans content for issues
 func (s *LanguageServer) analyzeDiagnostics(content string) []protocol.Diagnostic {
+	return s.analyzeNoteDiagnostics("", content)
+}
+
+// analyzeNoteDiagnostics scans the content of the note at uri for issues
+// Knowing the note tells its own labels from other notes', so labels defined elsewhere are reported too;
+// uri may be empty for content that is no note's
+func (s *LanguageServer) analyzeNoteDiagnostics(uri protocol.DocumentURI, content string) []protocol.Diagnostic {
 	var diagnostics []protocol.Diagnostic
+	config := s.settings().Diagnostics
 
-	lines := strings.Split(content, "\n")
-	refPattern := regexp.MustCompile(`\\(?:ref|cite)\{([^}]+)\}`)
+	lines := s.documentLines(content)
+	refPattern := macroPattern(append([]string{"ref", "cite"}, s.referenceMacros()...), `\{([^}]+)\}`)
+	var trash map[string]TrashedNote
+	var bib map[string]BibEntry
 	todoPattern := regexp.MustCompile(`\\todo\{([^}]+)\}`)
 
 	for lineNum, line := range lines {
@@ -384,26 +681,38 @@ func (s *LanguageServer) analyzeDiagnostics(content string) []protocol.Diagnosti
 		// Check for broken note references
 		refMatches := refPattern.FindAllStringSubmatchIndex(line, -1)
 		for _, match := range refMatches {
-			rawSlug := line[match[2]:match[3]]
-			slug := strings.TrimSpace(rawSlug)
-			slug = strings.TrimSuffix(slug, ".tex")
+			slug := normalizeSlug(line[match[2]:match[3]])
 
-			if _, exists := s.index.Get(slug); !exists {
+			// Citations of bibliography entries are no note references
+			if citeKeysPattern.MatchString(line[match[0]:match[1]]) && s.citesBibliography(uri, content, line[match[2]:match[3]], &bib) {
+				continue
+			}
+
+			// Until the initial index is built, missing notes may just not be indexed yet
+			if _, exists := s.resolveNote(slug, uri); !exists && config.BrokenRefs && s.indexed() {
 				diagnostics = append(diagnostics, protocol.Diagnostic{
 					Range: protocol.Range{
 						Start: protocol.Position{Line: uint32(lineNum), Character: uint32(match[2])},
 						End:   protocol.Position{Line: uint32(lineNum), Character: uint32(match[3])},
 					},
 					Severity: protocol.DiagnosticSeverityError,
-					Message:  fmt.Sprintf("Note '%s' not found", slug),
+					Code:     diagnosticCodeBrokenRef,
+					Message:  s.brokenRefMessage(slug, &trash),
 					Source:   "lx-ls",
 				})
+			} else if exists && config.BrokenRefs {
+				if diag, ok := s.ambiguousRefDiagnostic(uri, slug, lineRange(lineNum, match[2], match[3])); ok {
+					diagnostics = append(diagnostics, diag)
+				}
 			}
 		}
 
 		// Check for TODOs
 		todoMatches := todoPattern.FindAllStringSubmatchIndex(line, -1)
 		for _, match := range todoMatches {
+			if !config.Todos {
+				break
+			}
 			todoText := line[match[2]:match[3]]
 			diagnostics = append(diagnostics, protocol.Diagnostic{
 				Range: protocol.Range{
@@ -411,11 +720,58 @@ func (s *LanguageServer) analyzeDiagnostics(content string) []protocol.Diagnosti
 					End:   protocol.Position{Line: uint32(lineNum), Character: uint32(match[1])},
 				},
 				Severity: protocol.DiagnosticSeverityWarning,
+				Code:     diagnosticCodeTodo,
 				Message:  fmt.Sprintf("TODO: %s", todoText),
 				Source:   "lx-ls",
 			})
 		}
 	}
 
-	return diagnostics
+	if config.Dates {
+		diagnostics = append(diagnostics, s.dateDiagnostics(content)...)
+	}
+
+	// Acronyms are a generic LaTeX check, left to the other server in coexistence mode
+	if config.Acronyms && !s.settings().Coexist {
+		diagnostics = append(diagnostics, acronymDiagnostics(content)...)
+	}
+
+	if config.Tags {
+		diagnostics = append(diagnostics, s.tagPolicyDiagnostics(content)...)
+	}
+
+	if config.LabelPrefixes {
+		diagnostics = append(diagnostics, s.labelPrefixDiagnostics(content)...)
+	}
+
+	if config.Skeleton {
+		diagnostics = append(diagnostics, s.skeletonDiagnostics(content)...)
+	}
+
+	if config.DuplicateRefs {
+		diagnostics = append(diagnostics, s.duplicateRefDiagnostics(content, s.settings().DuplicateRefThreshold)...)
+	}
+
+	if config.DuplicateLabels {
+		filename := ""
+		if uri != "" {
+			filename = filepath.Base(uriToPath(uri))
+		}
+		diagnostics = append(diagnostics, s.duplicateLabelDiagnostics(filename, content)...)
+	}
+
+	if config.Engine {
+		diagnostics = append(diagnostics, s.engineDiagnostics(content)...)
+	}
+
+	// Until the initial index is built, the asset index may not be read yet either
+	if config.MissingAssets && s.indexed() {
+		diagnostics = append(diagnostics, s.missingAssetDiagnostics(content)...)
+	}
+
+	if uri != "" {
+		diagnostics = append(diagnostics, s.slugCollisionDiagnostics(uri)...)
+	}
+
+	return describeDiagnostics(s.applySeverities(diagnostics))
 }
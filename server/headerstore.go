@@ -0,0 +1,433 @@
+package server
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// headerSpillPattern names the file a bounded header store keeps cold headers in
+	headerSpillPattern = "lx-lsp-headers-*.jsonl"
+	// headerSpillCompactBytes is the amount of stale records in the spill file that triggers a rewrite
+	headerSpillCompactBytes = 1 << 20
+	// listElementOverhead approximates the size of a list element and its entry
+	listElementOverhead = 64
+)
+
+// HeaderStore holds the note headers of the index, by the key of their noteRef
+// Without a budget every header stays in memory. With one, only the most recently used headers do,
+// up to the budget in estimated bytes; the others are spilled to a file in the vault cache and
+// loaded back when asked for. Headers read from disk are new copies, equal to the ones stored
+// The spill file is unlinked as soon as it is created, so a killed server leaves nothing behind;
+// where open files cannot be removed, leftovers are cleaned up the next time a budget is set
+type HeaderStore struct {
+	mu      sync.Mutex
+	budget  int                      // bytes of headers kept in memory, 0 for no limit
+	size    int                      // estimated bytes of the headers in memory
+	hot     map[string]*list.Element // key -> element of lru
+	lru     *list.List               // *hotHeader, most recently used first
+	cold    map[string]headerSpan    // key -> record in the spill file, also kept for unchanged hot headers
+	file    *os.File                 // spill file, nil until a budget is set
+	path    string                   // name of the spill file while it could not be unlinked
+	end     int64                    // size of the spill file
+	garbage int64                    // bytes of stale records in the spill file
+	stats   cacheCounter             // lookups answered from memory or from the spill file
+}
+
+// hotHeader is a header kept in memory
+type hotHeader struct {
+	key    string
+	header *NoteHeader
+	size   int
+}
+
+// headerSpan locates a header record in the spill file
+type headerSpan struct {
+	offset int64
+	length int
+}
+
+func NewHeaderStore() *HeaderStore {
+	return &HeaderStore{
+		hot:  make(map[string]*list.Element),
+		lru:  list.New(),
+		cold: make(map[string]headerSpan),
+	}
+}
+
+// headerSize estimates the memory held by a header in the store
+func headerSize(key string, header *NoteHeader) int {
+	return mapEntryOverhead + listElementOverhead + int(unsafe.Sizeof(*header)) +
+		stringsSize(key, header.Title, header.Date, header.Slug, header.Filename, header.Dir) + stringsSize(header.Tags...)
+}
+
+// Put stores the header of a note as its most recently used one
+func (h *HeaderStore) Put(key string, header *NoteHeader) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dropSpan(key)
+	if element, ok := h.hot[key]; ok {
+		entry := element.Value.(*hotHeader)
+		h.size -= entry.size
+		entry.header, entry.size = header, headerSize(key, header)
+		h.size += entry.size
+		h.lru.MoveToFront(element)
+	} else {
+		h.insert(key, header)
+	}
+	h.evict()
+}
+
+// Get returns the header of a note, loading it from the spill file if needed, and marks it used
+func (h *HeaderStore) Get(key string) (*NoteHeader, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if element, ok := h.hot[key]; ok {
+		h.stats.hit()
+		h.lru.MoveToFront(element)
+		return element.Value.(*hotHeader).header, true
+	}
+	header, ok := h.load(key)
+	if !ok {
+		return nil, false
+	}
+	h.stats.miss()
+	h.insert(key, header)
+	h.evict()
+	return header, true
+}
+
+// Peek returns the header of a note without marking it used, so listing every note does not push
+// the headers in use out of memory
+func (h *HeaderStore) Peek(key string) (*NoteHeader, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if element, ok := h.hot[key]; ok {
+		return element.Value.(*hotHeader).header, true
+	}
+	return h.load(key)
+}
+
+// PeekMany returns the headers of several notes, in the order of keys, without marking them used
+// Headers not in memory are read back in a single pass over the spill file and decoded after the
+// store is unlocked, so listing every note neither reads each record on its own nor holds up lookups
+func (h *HeaderStore) PeekMany(keys []string) []*NoteHeader {
+	h.mu.Lock()
+	headers := make([]*NoteHeader, len(keys))
+	var cold []int
+	for n, key := range keys {
+		if element, ok := h.hot[key]; ok {
+			headers[n] = element.Value.(*hotHeader).header
+		} else if _, ok := h.cold[key]; ok {
+			cold = append(cold, n)
+		}
+	}
+	spans := make([]headerSpan, len(cold))
+	for n, at := range cold {
+		spans[n] = h.cold[keys[at]]
+	}
+	var data []byte
+	if len(cold) > 0 && h.file != nil {
+		data = make([]byte, h.end)
+		if _, err := h.file.ReadAt(data, 0); err != nil {
+			data = nil
+		}
+	}
+	h.mu.Unlock()
+
+	for n, span := range spans {
+		if data == nil || span.offset+int64(span.length) > int64(len(data)) {
+			break
+		}
+		var header NoteHeader
+		if json.Unmarshal(data[span.offset:span.offset+int64(span.length)], &header) == nil {
+			headers[cold[n]] = &header
+		}
+	}
+
+	found := headers[:0]
+	for _, header := range headers {
+		if header != nil {
+			found = append(found, header)
+		}
+	}
+	return found
+}
+
+// Delete forgets the header of a note
+func (h *HeaderStore) Delete(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if element, ok := h.hot[key]; ok {
+		h.size -= element.Value.(*hotHeader).size
+		h.lru.Remove(element)
+		delete(h.hot, key)
+	}
+	h.dropSpan(key)
+}
+
+// Len returns the number of headers stored, in memory or not
+func (h *HeaderStore) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := len(h.hot)
+	for key := range h.cold {
+		if _, ok := h.hot[key]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// SetBudget bounds the memory of the headers to budget bytes, spilling the others to a file in dir
+// A budget of 0 loads every header back and removes the file. When the file cannot be created,
+// the store stays unbounded and the error is returned
+func (h *HeaderStore) SetBudget(budget int, dir string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if budget <= 0 {
+		for key := range h.cold {
+			if _, ok := h.hot[key]; ok {
+				continue
+			}
+			if header, ok := h.load(key); ok {
+				entry := &hotHeader{key: key, header: header, size: headerSize(key, header)}
+				h.hot[key] = h.lru.PushBack(entry)
+				h.size += entry.size
+			}
+		}
+		h.budget = 0
+		return h.closeFile()
+	}
+
+	if h.file == nil {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create header cache directory: %w", err)
+		}
+		removeStaleSpills(dir)
+		file, path, err := createSpillFile(dir)
+		if err != nil {
+			return fmt.Errorf("failed to create header cache: %w", err)
+		}
+		h.file, h.path = file, path
+	}
+	h.budget = budget
+	h.evict()
+	return nil
+}
+
+// Close removes the spill file; headers only kept there are lost
+func (h *HeaderStore) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closeFile()
+}
+
+// info summarizes the headers kept in memory and the lookups that had to go to disk
+func (h *HeaderStore) info() CacheInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats.info(len(h.hot))
+}
+
+// footprint returns the estimated bytes of the headers in memory
+func (h *HeaderStore) footprint() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.size
+}
+
+// compact replaces the headers in memory with copies of interned strings
+func (h *HeaderStore) compact(in interner) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for element := h.lru.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*hotHeader)
+		// Headers are shared with readers, so the compacted header is a copy
+		compacted := *entry.header
+		compacted.Title = in.intern(entry.header.Title)
+		compacted.Date = in.intern(entry.header.Date)
+		compacted.Slug = in.intern(entry.header.Slug)
+		compacted.Filename = in.intern(entry.header.Filename)
+		compacted.Dir = in.intern(entry.header.Dir)
+		if entry.header.Tags != nil {
+			compacted.Tags = make([]string, len(entry.header.Tags))
+			for j, tag := range entry.header.Tags {
+				compacted.Tags[j] = in.intern(tag)
+			}
+		}
+		entry.header = &compacted
+	}
+}
+
+// insert adds a header to the front of the headers in memory
+// Callers hold h.mu
+func (h *HeaderStore) insert(key string, header *NoteHeader) {
+	entry := &hotHeader{key: key, header: header, size: headerSize(key, header)}
+	h.hot[key] = h.lru.PushFront(entry)
+	h.size += entry.size
+}
+
+// evict spills the least recently used headers until the ones in memory fit the budget
+// The most recently used header always stays. A header that cannot be written stays in memory
+// Callers hold h.mu
+func (h *HeaderStore) evict() {
+	if h.budget <= 0 || h.file == nil {
+		return
+	}
+	for h.size > h.budget && h.lru.Len() > 1 {
+		element := h.lru.Back()
+		entry := element.Value.(*hotHeader)
+		if _, clean := h.cold[entry.key]; !clean && h.spill(entry.key, entry.header) != nil {
+			return
+		}
+		h.size -= entry.size
+		h.lru.Remove(element)
+		delete(h.hot, entry.key)
+	}
+	if h.garbage > headerSpillCompactBytes && h.garbage > h.end/2 {
+		h.rewrite()
+	}
+}
+
+// spill appends a header to the spill file
+// Callers hold h.mu
+func (h *HeaderStore) spill(key string, header *NoteHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := h.file.WriteAt(data, h.end); err != nil {
+		return err
+	}
+	h.cold[key] = headerSpan{offset: h.end, length: len(data)}
+	h.end += int64(len(data))
+	return nil
+}
+
+// load reads a header back from the spill file
+// Callers hold h.mu
+func (h *HeaderStore) load(key string) (*NoteHeader, bool) {
+	span, ok := h.cold[key]
+	if !ok || h.file == nil {
+		return nil, false
+	}
+	data := make([]byte, span.length)
+	if _, err := h.file.ReadAt(data, span.offset); err != nil {
+		return nil, false
+	}
+	var header NoteHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, false
+	}
+	return &header, true
+}
+
+// dropSpan marks the spill file record of a header stale
+// Callers hold h.mu
+func (h *HeaderStore) dropSpan(key string) {
+	if span, ok := h.cold[key]; ok {
+		h.garbage += int64(span.length)
+		delete(h.cold, key)
+	}
+}
+
+// rewrite copies the live records of the spill file into a new one, dropping the stale ones
+// The old file is kept when the new one cannot be written
+// Callers hold h.mu
+func (h *HeaderStore) rewrite() {
+	file, path, err := createSpillFile(filepath.Dir(h.file.Name()))
+	if err != nil {
+		return
+	}
+	cold := make(map[string]headerSpan, len(h.cold))
+	var end int64
+	for key, span := range h.cold {
+		data := make([]byte, span.length)
+		if _, err := h.file.ReadAt(data, span.offset); err == nil {
+			_, err = file.WriteAt(data, end)
+		}
+		if err != nil {
+			file.Close()
+			if path != "" {
+				os.Remove(path)
+			}
+			return
+		}
+		cold[key] = headerSpan{offset: end, length: span.length}
+		end += int64(span.length)
+	}
+	h.closeFile()
+	h.file, h.path, h.cold, h.end, h.garbage = file, path, cold, end, 0
+}
+
+// createSpillFile creates a spill file in dir and unlinks it right away, the open file living on
+// Returns the name of the file when it could not be unlinked, as on Windows, for removal on close
+func createSpillFile(dir string) (*os.File, string, error) {
+	file, err := os.CreateTemp(dir, headerSpillPattern)
+	if err != nil {
+		return nil, "", err
+	}
+	if os.Remove(file.Name()) == nil {
+		return file, "", nil
+	}
+	return file, file.Name(), nil
+}
+
+// removeStaleSpills removes the spill files servers that were killed left in dir
+// Spill files in use are unlinked already, or cannot be removed while open
+func removeStaleSpills(dir string) {
+	stale, _ := filepath.Glob(filepath.Join(dir, headerSpillPattern))
+	for _, path := range stale {
+		os.Remove(path)
+	}
+}
+
+// closeFile removes the spill file and forgets the records in it
+// Callers hold h.mu
+func (h *HeaderStore) closeFile() error {
+	if h.file == nil {
+		return nil
+	}
+	h.file.Close()
+	var err error
+	if h.path != "" {
+		err = os.Remove(h.path)
+	}
+	h.file, h.path = nil, ""
+	h.cold = make(map[string]headerSpan)
+	h.end, h.garbage = 0, 0
+	return err
+}
+
+// sameHeader reports whether two headers describe a note alike, such as a header and its copy read
+// back from the spill file
+func sameHeader(a, b *NoteHeader) bool {
+	return a == b || (a.Title == b.Title && a.Date == b.Date && a.Slug == b.Slug && a.Filename == b.Filename &&
+		a.Dir == b.Dir && a.Modified.Equal(b.Modified) && slices.Equal(a.Tags, b.Tags))
+}
+
+// applyHeaderMemory bounds the memory of the note headers to the headerMemoryMB setting
+func (s *LanguageServer) applyHeaderMemory() {
+	budget := s.settings().HeaderMemoryMB << 20
+	if budget > 0 && (s.vault == nil || s.vault.CachePath == "") {
+		s.logf(protocol.MessageTypeWarning, "Keeping every note header in memory: the vault has no cache directory")
+		return
+	}
+	dir := ""
+	if s.vault != nil {
+		dir = s.vault.CachePath
+	}
+	if err := s.index.Headers().SetBudget(budget, dir); err != nil {
+		s.logf(protocol.MessageTypeWarning, "Keeping every note header in memory: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestHeaderStore tests keeping the most recently used headers within the budget and the rest on disk
+func TestHeaderStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewHeaderStore()
+	header := func(n int) *NoteHeader {
+		slug := fmt.Sprintf("note-%03d", n)
+		return &NoteHeader{Title: "Note " + slug, Slug: slug, Filename: slug + ".tex", Tags: []string{"tag"}, Modified: time.Unix(int64(n), 0)}
+	}
+	for n := 0; n < 100; n++ {
+		store.Put(fmt.Sprintf("note-%03d", n), header(n))
+	}
+
+	budget := 10 * headerSize("note-000", header(0))
+	if err := store.SetBudget(budget, dir); err != nil {
+		t.Fatalf("SetBudget failed: %v", err)
+	}
+	if store.footprint() > budget {
+		t.Errorf("expected at most %d bytes in memory, got %d", budget, store.footprint())
+	}
+	if store.Len() != 100 {
+		t.Errorf("expected 100 headers stored, got %d", store.Len())
+	}
+
+	// Cold headers come back from disk as equal copies and push others out
+	got, ok := store.Get("note-000")
+	if !ok || !sameHeader(got, header(0)) {
+		t.Fatalf("expected note-000 back from disk, got %+v", got)
+	}
+	if info := store.info(); info.Misses != 1 {
+		t.Errorf("expected one lookup from disk, got %+v", info)
+	}
+	if again, _ := store.Get("note-000"); again != got {
+		t.Error("expected the loaded header to stay in memory")
+	}
+	if store.footprint() > budget {
+		t.Errorf("expected at most %d bytes in memory after loading, got %d", budget, store.footprint())
+	}
+
+	// Replaced and deleted headers do not come back
+	store.Put("note-001", &NoteHeader{Title: "Renamed", Slug: "note-001", Filename: "note-001.tex"})
+	if got, _ := store.Peek("note-001"); got.Title != "Renamed" {
+		t.Errorf("expected the replaced header, got %+v", got)
+	}
+	store.Delete("note-002")
+	if _, ok := store.Peek("note-002"); ok {
+		t.Error("expected note-002 to be gone")
+	}
+
+	// Lifting the budget loads everything back and removes the file
+	if err := store.SetBudget(0, dir); err != nil {
+		t.Fatalf("SetBudget failed: %v", err)
+	}
+	if store.Len() != 99 {
+		t.Errorf("expected 99 headers in memory, got %d", store.Len())
+	}
+	if got, ok := store.Peek("note-050"); !ok || !sameHeader(got, header(50)) {
+		t.Errorf("expected note-050 in memory, got %+v", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected the spill file to be removed, got %v", entries)
+	}
+}
+
+// TestIndex_MemoryBudget tests indexing a vault whose headers do not all fit in memory
+func TestIndex_MemoryBudget(t *testing.T) {
+	v := vaultAt(t.TempDir())
+	os.MkdirAll(v.NotesPath, 0755)
+	for n := 0; n < 50; n++ {
+		content := fmt.Sprintf("%%%% Metadata\n%%%% title: Note %d\n%%%% tags: t%d\n\\ref{note-%02d}\n", n, n%5, (n+1)%50)
+		os.WriteFile(filepath.Join(v.NotesPath, fmt.Sprintf("note-%02d.tex", n)), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	if err := ls.index.Headers().SetBudget(2048, v.CachePath); err != nil {
+		t.Fatalf("SetBudget failed: %v", err)
+	}
+	defer ls.index.Headers().Close()
+	ls.RebuildIndex(context.Background())
+
+	if ls.index.Count() != 50 || len(ls.index.All()) != 50 {
+		t.Fatalf("expected 50 notes, got %d", ls.index.Count())
+	}
+	if ls.index.Headers().footprint() > 2048 {
+		t.Errorf("expected headers within the budget, got %d bytes", ls.index.Headers().footprint())
+	}
+	for n := 0; n < 50; n++ {
+		note, ok := ls.index.Get(fmt.Sprintf("note-%02d", n))
+		if !ok || note.Title != fmt.Sprintf("Note %d", n) {
+			t.Errorf("note-%02d: unexpected header %+v", n, note)
+		}
+	}
+	if notes := ls.index.Tags().Notes("t1"); len(notes) != 10 {
+		t.Errorf("expected 10 notes tagged t1, got %v", notes)
+	}
+
+	// Headers read back from disk count as indexed, so an unchanged vault is not indexed again
+	indexed := 0
+	for _, note := range ls.index.AllVariants() {
+		if ls.index.Has(note) {
+			indexed++
+		}
+	}
+	if indexed != 50 {
+		t.Errorf("expected every stored header to count as indexed, got %d", indexed)
+	}
+	spilled := ls.index.Headers().end
+	ls.RebuildIndex(context.Background())
+	if note, _ := ls.index.Get("note-07"); note == nil || note.Title != "Note 7" {
+		t.Errorf("unexpected header after rebuilding %+v", note)
+	}
+	if ls.index.Headers().end != spilled {
+		t.Errorf("expected the rebuild to leave headers alone, the spill file grew from %d to %d bytes", spilled, ls.index.Headers().end)
+	}
+}
+
+// TestHeaderStore_SpillFile tests that the spill file leaves nothing behind and that listing
+// headers reads the cold ones back in order
+func TestHeaderStore_SpillFile(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "lx-lsp-headers-123.jsonl")
+	os.WriteFile(stale, []byte("{}\n"), 0644)
+
+	store := NewHeaderStore()
+	keys := make([]string, 20)
+	for n := range keys {
+		keys[n] = fmt.Sprintf("note-%02d", n)
+		store.Put(keys[n], &NoteHeader{Title: "Note " + keys[n], Slug: keys[n], Filename: keys[n] + ".tex"})
+	}
+	if err := store.SetBudget(1, dir); err != nil {
+		t.Fatalf("SetBudget failed: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expected the spill file of a killed server to be removed")
+	}
+	if runtime.GOOS != "windows" {
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("expected the spill file to be unlinked while in use, got %v", entries)
+		}
+	}
+
+	headers := store.PeekMany(append([]string{"missing"}, keys...))
+	if len(headers) != len(keys) {
+		t.Fatalf("expected %d headers, got %d", len(keys), len(headers))
+	}
+	for n, header := range headers {
+		if header.Slug != keys[n] {
+			t.Errorf("expected %s at %d, got %s", keys[n], n, header.Slug)
+		}
+	}
+	if info := store.info(); info.Misses != 0 {
+		t.Errorf("expected listing not to count as lookups, got %+v", info)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MethodHeatmap is the custom request returning per-day note activity
+const MethodHeatmap = "lx/heatmap"
+
+// heatmapDateLayout is the day format used in heatmap requests and results
+const heatmapDateLayout = "2006-01-02"
+
+// HeatmapParams selects the inclusive date range of the heatmap
+// Empty bounds default to the year ending today
+type HeatmapParams struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// HeatmapDay holds the activity counts of a single day
+type HeatmapDay struct {
+	Date     string `json:"date"`
+	Created  int    `json:"created"`
+	Modified int    `json:"modified"`
+}
+
+// HeatmapResult lists every day in the range, including days without activity
+type HeatmapResult struct {
+	From string       `json:"from"`
+	To   string       `json:"to"`
+	Days []HeatmapDay `json:"days"`
+}
+
+// Handle lx/heatmap request
+func (s *LanguageServer) Heatmap(ctx context.Context, params *HeatmapParams) (*HeatmapResult, error) {
+	to := time.Now()
+	if params.To != "" {
+		parsed, err := time.ParseInLocation(heatmapDateLayout, params.To, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'to' date: %w", err)
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(-1, 0, 1)
+	if params.From != "" {
+		parsed, err := time.ParseInLocation(heatmapDateLayout, params.From, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'from' date: %w", err)
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return nil, fmt.Errorf("'from' (%s) is after 'to' (%s)", from.Format(heatmapDateLayout), to.Format(heatmapDateLayout))
+	}
+
+	return buildHeatmap(s.index.All(), from, to), nil
+}
+
+// buildHeatmap counts note creations and modifications per day between from and to
+func buildHeatmap(notes []*NoteHeader, from, to time.Time) *HeatmapResult {
+	created := make(map[string]int)
+	modified := make(map[string]int)
+
+	for _, note := range notes {
+		if day, ok := noteCreated(note); ok {
+			created[day.Format(heatmapDateLayout)]++
+		}
+		if !note.Modified.IsZero() {
+			modified[note.Modified.In(time.Local).Format(heatmapDateLayout)]++
+		}
+	}
+
+	result := &HeatmapResult{
+		From: from.Format(heatmapDateLayout),
+		To:   to.Format(heatmapDateLayout),
+		Days: []HeatmapDay{},
+	}
+
+	last := to.Format(heatmapDateLayout)
+	for day := from; ; day = day.AddDate(0, 0, 1) {
+		key := day.Format(heatmapDateLayout)
+		result.Days = append(result.Days, HeatmapDay{
+			Date:     key,
+			Created:  created[key],
+			Modified: modified[key],
+		})
+		if key == last {
+			break
+		}
+	}
+
+	return result
+}
+
+// noteCreated returns the creation day of a note from its metadata date,
+// falling back to the YYYYMMDD filename prefix
+func noteCreated(note *NoteHeader) (time.Time, bool) {
+	if day, err := time.ParseInLocation(heatmapDateLayout, note.Date, time.Local); err == nil {
+		return day, true
+	}
+	if len(note.Filename) >= 8 {
+		if day, err := time.ParseInLocation("20060102", note.Filename[:8], time.Local); err == nil {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestHeatmap tests per-day activity counts
+func TestHeatmap(t *testing.T) {
+	ls := &LanguageServer{
+		index: NewIndex(),
+	}
+
+	modified := time.Date(2024, 1, 3, 15, 0, 0, 0, time.Local)
+	ls.index.Set("a", &NoteHeader{Slug: "a", Date: "2024-01-01", Filename: "20240101-a.tex", Modified: modified})
+	ls.index.Set("b", &NoteHeader{Slug: "b", Filename: "20240101-b.tex", Modified: modified})
+	ls.index.Set("c", &NoteHeader{Slug: "c", Date: "2023-06-01", Filename: "c.tex"})
+
+	result, err := ls.Heatmap(context.Background(), &HeatmapParams{From: "2024-01-01", To: "2024-01-03"})
+	if err != nil {
+		t.Fatalf("Heatmap failed: %v", err)
+	}
+
+	if len(result.Days) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(result.Days))
+	}
+	if result.Days[0].Date != "2024-01-01" || result.Days[0].Created != 2 {
+		t.Errorf("expected 2 notes created on 2024-01-01, got %+v", result.Days[0])
+	}
+	if result.Days[1].Created != 0 || result.Days[1].Modified != 0 {
+		t.Errorf("expected no activity on 2024-01-02, got %+v", result.Days[1])
+	}
+	if result.Days[2].Modified != 2 {
+		t.Errorf("expected 2 notes modified on 2024-01-03, got %+v", result.Days[2])
+	}
+
+	if _, err := ls.Heatmap(context.Background(), &HeatmapParams{From: "2024-02-01", To: "2024-01-01"}); err == nil {
+		t.Error("expected error for inverted range")
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+
+	"go.lsp.dev/protocol"
+)
+
+// Handle DocumentHighlight request
+// Highlights every occurrence in the document of the label or note slug under the cursor
+func (s *LanguageServer) DocumentHighlight(ctx context.Context, params *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	return s.documentHighlights(content, params.Position), nil
+}
+
+// documentHighlights computes the highlights for the symbol at pos
+func (s *LanguageServer) documentHighlights(content string, pos protocol.Position) []protocol.DocumentHighlight {
+	highlights := []protocol.DocumentHighlight{}
+
+	// Labels take precedence, as \ref{x} may point at a label rather than a note
+	definitions, usages := extractLabels("", content)
+	if label := labelAt(pos, definitions, usages); label != "" {
+		definedHere := false
+		for _, loc := range definitions {
+			if loc.Label == label {
+				definedHere = true
+				highlights = append(highlights, protocol.DocumentHighlight{Range: loc.Range, Kind: protocol.DocumentHighlightKindWrite})
+			}
+		}
+		if definedHere || len(s.index.Labels().Definitions(label)) > 0 {
+			for _, loc := range usages {
+				if loc.Label == label {
+					highlights = append(highlights, protocol.DocumentHighlight{Range: loc.Range, Kind: protocol.DocumentHighlightKindRead})
+				}
+			}
+			return highlights
+		}
+	}
+
+	slug := s.getSlugAtPosition(content, pos)
+	if slug == "" {
+		return highlights
+	}
+	for _, link := range extractLinks(s.linkPattern(), "", "", content) {
+		if link.Target == slug {
+			highlights = append(highlights, protocol.DocumentHighlight{Range: link.Range, Kind: protocol.DocumentHighlightKindRead})
+		}
+	}
+	return highlights
+}
+
+// labelAt returns the label whose definition or usage contains pos
+func labelAt(pos protocol.Position, locations ...[]LabelLocation) string {
+	for _, locs := range locations {
+		for _, loc := range locs {
+			if pos.Line == loc.Range.Start.Line && pos.Character >= loc.Range.Start.Character && pos.Character <= loc.Range.End.Character {
+				return loc.Label
+			}
+		}
+	}
+	return ""
+}
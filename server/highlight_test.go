@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestDocumentHighlight tests highlighting repeated slugs and labels
+func TestDocumentHighlight(t *testing.T) {
+	ls := &LanguageServer{index: NewIndex()}
+
+	content := "See \\ref{graph-theory} and \\input{graph-theory.tex}.\n" +
+		"Also \\cite{other} and \\ref{graph-theory}.\n" +
+		"\\label{eq:main}\n" +
+		"By \\eqref{eq:main} and \\ref{eq:main}."
+
+	// Slug occurrences, including the .tex spelling
+	highlights := ls.documentHighlights(content, protocol.Position{Line: 0, Character: 12})
+	if len(highlights) != 3 {
+		t.Fatalf("expected 3 slug highlights, got %d: %+v", len(highlights), highlights)
+	}
+	for _, h := range highlights {
+		if h.Kind != protocol.DocumentHighlightKindRead {
+			t.Errorf("expected read highlight, got %v", h.Kind)
+		}
+	}
+
+	// Label definition and usages
+	highlights = ls.documentHighlights(content, protocol.Position{Line: 3, Character: 12})
+	if len(highlights) != 3 {
+		t.Fatalf("expected 3 label highlights, got %d: %+v", len(highlights), highlights)
+	}
+	if highlights[0].Kind != protocol.DocumentHighlightKindWrite || highlights[0].Range.Start.Line != 2 {
+		t.Errorf("expected definition as write highlight, got %+v", highlights[0])
+	}
+
+	// Nothing under the cursor
+	if highlights := ls.documentHighlights(content, protocol.Position{Line: 0, Character: 1}); len(highlights) != 0 {
+		t.Errorf("expected no highlights, got %d", len(highlights))
+	}
+}
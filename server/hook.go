@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// hookProblem is a validation failure found in hook mode
+type hookProblem struct {
+	path    string
+	line    int // 1-based
+	message string
+}
+
+// RunHook validates the notes listed on r, one path per line as git hooks provide them, and the notes referencing them
+// The working directory is managed like a workspace folder, so a git-managed vault is covered when run from its root
+// Writes a report to w and returns the number of problems found
+func (s *LanguageServer) RunHook(ctx context.Context, r io.Reader, w io.Writer) (int, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	s.addWorkspaceFolder(protocol.WorkspaceFolder{URI: string(pathToURI(cwd)), Name: filepath.Base(cwd)})
+
+	if err := s.RebuildIndex(ctx); err != nil {
+		return 0, fmt.Errorf("failed to build index: %w", err)
+	}
+
+	targets := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+		if !s.IsManaged(pathToURI(path)) {
+			continue
+		}
+
+		// Deleted notes have nothing to check themselves, but may leave references dangling
+		if _, err := os.Stat(path); err == nil {
+			targets[path] = true
+		}
+		for _, link := range s.index.Links().Incoming(s.parseFilenameToSlug(filepath.Base(path))) {
+			targets[s.notePath(link.Filename)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read changed files: %w", err)
+	}
+
+	paths := make([]string, 0, len(targets))
+	for path := range targets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var problems []hookProblem
+	for _, path := range paths {
+		problems = append(problems, s.hookProblems(path)...)
+	}
+
+	for _, problem := range problems {
+		display := problem.path
+		if rel, err := filepath.Rel(cwd, problem.path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		fmt.Fprintf(w, "%s:%d: %s\n", display, problem.line, problem.message)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%d problem(s) in %d checked note(s)\n", len(problems), len(paths))
+	}
+
+	return len(problems), nil
+}
+
+// hookProblems checks the metadata and note references of a single file
+func (s *LanguageServer) hookProblems(path string) []hookProblem {
+	content, err := s.GetDocument(pathToURI(path))
+	if err != nil {
+		return []hookProblem{{path: path, line: 1, message: fmt.Sprintf("failed to read note: %v", err)}}
+	}
+
+	var problems []hookProblem
+	if result, err := s.metadataParser().Parse(content); err == nil {
+		for _, parseErr := range result.Errors {
+			line := parseErr.Line
+			if line == 0 {
+				line = 1 // Block-level errors, such as a missing block, have no line of their own
+			}
+			problems = append(problems, hookProblem{path: path, line: line, message: parseErr.Message})
+		}
+	}
+
+	for _, diag := range s.analyzeDiagnostics(content) {
+		if diag.Code == diagnosticCodeBrokenRef {
+			problems = append(problems, hookProblem{path: path, line: int(diag.Range.Start.Line) + 1, message: diag.Message})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestRunHook tests validating changed notes and the notes referencing them
+func TestRunHook(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(v.NotesPath, name), []byte(content), 0644)
+	}
+	write("20240101-a.tex", "%% Metadata\n%% title: A\n%% date: 2024-01-01\n\n\\ref{b}\n")
+	write("20240102-c.tex", "%% Metadata\n%% title: C\n%% date: 01/02/2024\n\n\\ref{missing}\n")
+	write("20240103-d.tex", "%% Metadata\n%% title: D\n%% date: 2024-01-03\n\n\\ref{missing}\n")
+	t.Chdir(tempDir)
+
+	ls := &LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+
+	// b was deleted, breaking a; c is invalid itself; d is untouched
+	var out bytes.Buffer
+	problems, err := ls.RunHook(context.Background(), strings.NewReader("notes/20240102-b.tex\nnotes/20240102-c.tex\nREADME.md\n\n"), &out)
+	if err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	report := out.String()
+	if problems != 3 {
+		t.Errorf("expected 3 problems, got %d:\n%s", problems, report)
+	}
+	for _, want := range []string{
+		filepath.Join("notes", "20240101-a.tex") + ":5: Note 'b' not found",
+		filepath.Join("notes", "20240102-c.tex") + ":3:",
+		filepath.Join("notes", "20240102-c.tex") + ":5: Note 'missing' not found",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "20240103-d.tex") {
+		t.Errorf("expected unchanged notes not to be checked, got:\n%s", report)
+	}
+
+	out.Reset()
+	problems, err = (&LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}).
+		RunHook(context.Background(), strings.NewReader("notes/20240101-a.tex\n"), &out)
+	if err != nil || problems != 1 {
+		t.Errorf("expected 1 problem, got %d, %v:\n%s", problems, err, out.String())
+	}
+}
+
+// TestRunHookVaultOutsideWorkingDirectory tests that notes outside the vault and working directory are ignored
+func TestRunHookVaultOutsideWorkingDirectory(t *testing.T) {
+	vaultDir := t.TempDir()
+	os.WriteFile(filepath.Join(vaultDir, "20240101-a.tex"), []byte("\\ref{missing}\n"), 0644)
+	t.Chdir(t.TempDir())
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: vaultDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	var out bytes.Buffer
+	problems, err := ls.RunHook(context.Background(), strings.NewReader("elsewhere/20240101-a.tex\n"), &out)
+	if err != nil || problems != 0 || out.Len() != 0 {
+		t.Errorf("expected nothing checked, got %d, %v:\n%s", problems, err, out.String())
+	}
+}
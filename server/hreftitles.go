@@ -0,0 +1,293 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandHrefTitle fetches the title of a bare URL and wraps the URL as \href{url}{Title}
+// Arguments: [uri, url, line, start, end], the range locating the URL in the document
+// The command returns at once; the edit is applied through workspace/applyEdit once the title arrives
+const commandHrefTitle = "lx.hrefTitle"
+
+const (
+	// titleFetchInterval is the minimum time between two title requests
+	titleFetchInterval = time.Second
+	// titleFetchTimeout bounds a single title request
+	titleFetchTimeout = 5 * time.Second
+	// titleFetchLimit caps how much of a page is read looking for its title
+	titleFetchLimit = 64 * 1024
+)
+
+var (
+	// bareURLPattern matches http(s) URLs in prose; a preceding { means the URL is already a command argument
+	bareURLPattern = regexp.MustCompile(`(^|[^{\w])(https?://[^\s{}\\%]+)`)
+	// htmlTitlePattern matches the <title> element of a page; group 1 is the title
+	htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	// latexTextEscaper escapes the characters LaTeX treats specially in text
+	latexTextEscaper = strings.NewReplacer(
+		`\`, `\textbackslash{}`, `&`, `\&`, `%`, `\%`, `$`, `\$`, `#`, `\#`, `_`, `\_`,
+		`{`, `\{`, `}`, `\}`, `~`, `\textasciitilde{}`, `^`, `\textasciicircum{}`,
+	)
+)
+
+func init() {
+	registerCommand(commandHrefTitle, withoutResult((*LanguageServer).hrefTitleCommand))
+	registerCodeActionProvider(hrefTitleAction)
+}
+
+// titleResult is a cached title lookup; failures are cached too, so a dead link is not retried
+type titleResult struct {
+	title string
+	err   error
+}
+
+// titleFetcher looks up page titles, one request per titleFetchInterval at most
+type titleFetcher struct {
+	mu       sync.Mutex
+	client   *http.Client
+	interval time.Duration
+	next     time.Time // earliest start of the next request
+	titles   map[string]titleResult
+	stats    cacheCounter
+}
+
+func newTitleFetcher(interval time.Duration) *titleFetcher {
+	return &titleFetcher{
+		client:   &http.Client{Timeout: titleFetchTimeout},
+		interval: interval,
+		titles:   make(map[string]titleResult),
+	}
+}
+
+// Cached returns the title of a URL if it was fetched before
+func (f *titleFetcher) Cached(url string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result, ok := f.titles[url]
+	if ok {
+		f.stats.hit()
+	} else {
+		f.stats.miss()
+	}
+	return result.title, ok && result.err == nil
+}
+
+// Len returns the number of cached lookups
+func (f *titleFetcher) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.titles)
+}
+
+// Clear drops the cached lookups, so failed URLs are tried again
+func (f *titleFetcher) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.titles = make(map[string]titleResult)
+}
+
+// Fetch returns the title of a URL, waiting for its turn under the rate limit
+func (f *titleFetcher) Fetch(ctx context.Context, url string) (string, error) {
+	f.mu.Lock()
+	if result, ok := f.titles[url]; ok {
+		f.mu.Unlock()
+		f.stats.hit()
+		return result.title, result.err
+	}
+	f.stats.miss()
+	now := time.Now()
+	start := f.next
+	if start.Before(now) {
+		start = now
+	}
+	f.next = start.Add(f.interval)
+	f.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(start)):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	title, err := f.fetch(ctx, url)
+	// A cancelled request says nothing about the page
+	if ctx.Err() == nil {
+		f.mu.Lock()
+		f.titles[url] = titleResult{title: title, err: err}
+		f.mu.Unlock()
+	}
+	return title, err
+}
+
+// fetch requests a page and extracts its title
+func (f *titleFetcher) fetch(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "lx-ls")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, titleFetchLimit))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	match := htmlTitlePattern.FindSubmatch(body)
+	if match == nil {
+		return "", fmt.Errorf("%s has no title", url)
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+	if title == "" {
+		return "", fmt.Errorf("%s has no title", url)
+	}
+	return title, nil
+}
+
+// urlTitles returns the server's title fetcher
+func (s *LanguageServer) urlTitles() *titleFetcher {
+	s.titlesOnce.Do(func() {
+		if s.titles == nil {
+			s.titles = newTitleFetcher(titleFetchInterval)
+		}
+	})
+	return s.titles
+}
+
+// bareURL is a URL in the prose of a line, not yet wrapped in \href or \url
+type bareURL struct {
+	url   string
+	line  int
+	start int
+	end   int
+}
+
+// bareURLs finds the unwrapped URLs of a line, ignoring its comment
+func bareURLs(lineNum int, line string) []bareURL {
+	var urls []bareURL
+	for _, match := range bareURLPattern.FindAllStringSubmatchIndex(stripInlineComment(line), -1) {
+		start, end := match[4], match[5]
+		// Sentence punctuation after a URL is not part of it
+		end = start + len(strings.TrimRight(line[start:end], ".,;:!?)]'\""))
+		urls = append(urls, bareURL{url: line[start:end], line: lineNum, start: start, end: end})
+	}
+	return urls
+}
+
+// hrefTitleAction offers to wrap the bare URLs of the selected lines in \href with their page title
+// Only offered when fetchUrlTitles is on; titles fetched before are applied directly, others through lx.hrefTitle
+func hrefTitleAction(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction {
+	if !s.settings().FetchURLTitles {
+		return nil
+	}
+
+	var actions []protocol.CodeAction
+	lines := strings.Split(req.Content, "\n")
+	for lineNum := int(req.Range.Start.Line); lineNum <= int(req.Range.End.Line) && lineNum < len(lines); lineNum++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[lineNum]), "%") {
+			continue
+		}
+		for _, found := range bareURLs(lineNum, lines[lineNum]) {
+			if title, ok := s.urlTitles().Cached(found.url); ok {
+				actions = append(actions, protocol.CodeAction{
+					Title: fmt.Sprintf("Wrap as \\href with title '%s'", title),
+					Kind:  protocol.RefactorRewrite,
+					Edit:  hrefEdit(req.URI, found, title),
+				})
+				continue
+			}
+			actions = append(actions, protocol.CodeAction{
+				Title: "Fetch page title and wrap as \\href",
+				Kind:  protocol.RefactorRewrite,
+				Command: &protocol.Command{
+					Title:     "Fetch page title",
+					Command:   commandHrefTitle,
+					Arguments: []interface{}{string(req.URI), found.url, found.line, found.start, found.end},
+				},
+			})
+		}
+	}
+	return actions
+}
+
+// hrefEdit replaces a bare URL with \href{url}{title}
+func hrefEdit(uri protocol.DocumentURI, found bareURL, title string) *protocol.WorkspaceEdit {
+	return &protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			uri: {{
+				Range:   lineRange(found.line, found.start, found.end),
+				NewText: fmt.Sprintf("\\href{%s}{%s}", found.url, latexTextEscaper.Replace(title)),
+			}},
+		},
+	}
+}
+
+// hrefTitleCommand handles lx.hrefTitle, fetching the title in the background
+func (s *LanguageServer) hrefTitleCommand(ctx context.Context, args []interface{}) error {
+	if len(args) < 5 {
+		return fmt.Errorf("%s requires uri, url, line, start and end arguments", commandHrefTitle)
+	}
+	uri, _ := args[0].(string)
+	url, _ := args[1].(string)
+	position := make([]int, 3)
+	for i := range position {
+		// JSON numbers decode as float64
+		value, ok := args[2+i].(float64)
+		if !ok {
+			return fmt.Errorf("%s: invalid range argument", commandHrefTitle)
+		}
+		position[i] = int(value)
+	}
+	if uri == "" || url == "" {
+		return fmt.Errorf("%s: invalid uri or url argument", commandHrefTitle)
+	}
+
+	found := bareURL{url: url, line: position[0], start: position[1], end: position[2]}
+	s.inBackground(ctx, commandHrefTitle, func(ctx context.Context) error {
+		edit, err := s.hrefTitleEdit(ctx, protocol.DocumentURI(uri), found)
+		if err != nil {
+			return err
+		}
+		return s.applyEdit(ctx, "Wrap URL as \\href", edit)
+	})
+	return nil
+}
+
+// hrefTitleEdit fetches the title of a bare URL and builds the edit wrapping it
+// Fails when the URL moved while the title was being fetched
+func (s *LanguageServer) hrefTitleEdit(ctx context.Context, uri protocol.DocumentURI, found bareURL) (*protocol.WorkspaceEdit, error) {
+	title, err := s.urlTitles().Fetch(ctx, found.url)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.GetDocument(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	lines := strings.Split(content, "\n")
+	if found.line < 0 || found.line >= len(lines) || found.start < 0 || found.end > len(lines[found.line]) ||
+		found.start > found.end || lines[found.line][found.start:found.end] != found.url {
+		return nil, fmt.Errorf("the URL moved while its title was fetched, try again")
+	}
+
+	return hrefEdit(uri, found, title), nil
+}
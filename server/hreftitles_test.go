@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestBareURLs tests finding URLs not yet wrapped in \href or \url
+func TestBareURLs(t *testing.T) {
+	line := `See https://example.com/a_b. And \url{https://skip.me} or \href{https://skip.too}{x} % https://comment.org`
+	urls := bareURLs(0, line)
+	if len(urls) != 1 {
+		t.Fatalf("expected 1 bare URL, got %+v", urls)
+	}
+	if urls[0].url != "https://example.com/a_b" || line[urls[0].start:urls[0].end] != urls[0].url {
+		t.Errorf("unexpected URL %+v", urls[0])
+	}
+}
+
+// TestHrefTitle tests fetching, caching and escaping page titles for \href
+func TestHrefTitle(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>\n  Graphs &amp; 100% Trees\n</title></head></html>")
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: t.TempDir()},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+		config:    &config,
+		titles:    newTitleFetcher(0),
+	}
+	uri := protocol.DocumentURI("file:///notes/20240101-a.tex")
+	content := "Read " + server.URL + "/page today."
+	ls.documents[uri] = content
+	req := &codeActionRequest{URI: uri, Content: content}
+
+	// Opt-in
+	if actions := hrefTitleAction(ls, req); len(actions) != 0 {
+		t.Fatalf("expected no actions while disabled, got %+v", actions)
+	}
+	config.FetchURLTitles = true
+
+	actions := hrefTitleAction(ls, req)
+	if len(actions) != 1 || actions[0].Command == nil || actions[0].Command.Command != commandHrefTitle {
+		t.Fatalf("expected a fetch action, got %+v", actions)
+	}
+	found := bareURLs(0, content)[0]
+	edit, err := ls.hrefTitleEdit(context.Background(), uri, found)
+	if err != nil {
+		t.Fatalf("hrefTitleEdit failed: %v", err)
+	}
+	want := "\\href{" + server.URL + "/page}{Graphs \\& 100\\% Trees}"
+	if got := edit.Changes[uri][0].NewText; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Cached titles are applied without another request
+	actions = hrefTitleAction(ls, req)
+	if len(actions) != 1 || actions[0].Edit == nil {
+		t.Fatalf("expected a direct edit, got %+v", actions)
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	if _, err := ls.urlTitles().Fetch(context.Background(), server.URL+"/missing"); err == nil {
+		t.Error("expected missing page to fail")
+	}
+
+	// The URL moved while fetching
+	ls.documents[uri] = "x" + content
+	if _, err := ls.hrefTitleEdit(context.Background(), uri, found); err == nil {
+		t.Error("expected moved URL to be rejected")
+	}
+}
+
+// TestTitleFetcherRateLimit tests that requests are spaced by the interval
+func TestTitleFetcherRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<title>T</title>")
+	}))
+	defer server.Close()
+
+	fetcher := newTitleFetcher(50 * time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/%d", server.URL, i)); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected requests to be spaced, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetcher.Fetch(ctx, server.URL+"/cancelled"); err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("expected cancelled fetch to fail, got %v", err)
+	}
+}
+
+// TestHrefTitleCommandInBackground tests that lx.hrefTitle replies before the title is fetched and
+// applies the edit once it is
+func TestHrefTitleCommandInBackground(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, "<title>Graphs</title>")
+	}))
+	defer server.Close()
+
+	serverSide, clientSide := net.Pipe()
+	applied := make(chan protocol.ApplyWorkspaceEditParams, 1)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.ApplyWorkspaceEditParams
+		if req.Method() == protocol.MethodWorkspaceApplyEdit && json.Unmarshal(req.Params(), &params) == nil {
+			applied <- params
+		}
+		return reply(ctx, &protocol.ApplyWorkspaceEditResponse{Applied: true}, nil)
+	})
+	defer func() {
+		client.Close()
+		serverSide.Close()
+	}()
+
+	uri := protocol.DocumentURI("file:///notes/20240101-a.tex")
+	content := "Read " + server.URL + " today."
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: t.TempDir()},
+		index:     NewIndex(),
+		documents: map[protocol.DocumentURI]string{uri: content},
+		titles:    newTitleFetcher(0),
+		conn:      jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide)),
+	}
+	ls.conn.Go(context.Background(), jsonrpc2.MethodNotFoundHandler)
+
+	found := bareURLs(0, content)[0]
+	args := []interface{}{string(uri), found.url, float64(found.line), float64(found.start), float64(found.end)}
+	if err := ls.hrefTitleCommand(context.Background(), args); err != nil {
+		t.Fatalf("hrefTitleCommand failed: %v", err)
+	}
+	select {
+	case params := <-applied:
+		t.Fatalf("expected no edit before the title arrived, got %+v", params)
+	default:
+	}
+
+	close(release)
+	ls.background.Wait()
+	params := <-applied
+	if got := params.Edit.Changes[uri][0].NewText; got != "\\href{"+server.URL+"}{Graphs}" {
+		t.Errorf("unexpected edit %q", got)
+	}
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// commandImportDirectory copies the .tex files of a directory into the vault as notes
+// Arguments: [directory] as a path or file URI
+const commandImportDirectory = "lx.importDirectory"
+
+func init() {
+	registerCommand(commandImportDirectory, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.importDirectoryCommand(ctx, args)
+	})
+}
+
+// titleCommandPattern matches \title{...}; group 1 is the title
+var titleCommandPattern = regexp.MustCompile(`\\title\{([^}]*)\}`)
+
+// ImportedNote is a file imported into the vault
+type ImportedNote struct {
+	Source string               `json:"source"`
+	Slug   string               `json:"slug"`
+	URI    protocol.DocumentURI `json:"uri"`
+}
+
+// ImportReference is a \ref in one imported note to a \label defined in another
+type ImportReference struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Label  string `json:"label"`
+}
+
+// ImportResult reports what lx.importDirectory did
+type ImportResult struct {
+	Imported   []ImportedNote    `json:"imported"`
+	Skipped    map[string]string `json:"skipped,omitempty"` // source path -> reason
+	References []ImportReference `json:"references"`
+}
+
+// pendingImport is a file prepared for import but not yet written
+type pendingImport struct {
+	source   string
+	header   *NoteHeader
+	content  string
+	stemName string // source filename without extension, as \input{} refers to it
+}
+
+// importDirectoryCommand handles lx.importDirectory
+func (s *LanguageServer) importDirectoryCommand(ctx context.Context, args []interface{}) (*ImportResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a directory argument", commandImportDirectory)
+	}
+	dir, ok := args[0].(string)
+	if !ok || dir == "" {
+		return nil, fmt.Errorf("%s: invalid directory argument", commandImportDirectory)
+	}
+	if strings.HasPrefix(dir, "file://") {
+		dir = uriToPath(protocol.DocumentURI(dir))
+	}
+
+	result, err := s.importDirectory(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, "note.import", fmt.Sprintf("imported %d notes from %s", len(result.Imported), dir))
+	return result, nil
+}
+
+// importDirectory copies every .tex file of dir into the vault
+// Files get a metadata block, unless they already have one, and a date-prefixed filename from their
+// modification time; \input and \include between them are rewritten to the new slugs
+func (s *LanguageServer) importDirectory(ctx context.Context, dir string) (*ImportResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	result := &ImportResult{
+		Imported:   []ImportedNote{},
+		Skipped:    make(map[string]string),
+		References: []ImportReference{},
+	}
+
+	var pending []*pendingImport
+	taken := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tex") {
+			continue
+		}
+		source := filepath.Join(dir, entry.Name())
+		item, err := s.prepareImport(source, taken)
+		if err != nil {
+			result.Skipped[source] = err.Error()
+			continue
+		}
+		taken[item.header.Slug] = true
+		pending = append(pending, item)
+	}
+
+	// Point \input{chapter1} at the note chapter1.tex became
+	for _, item := range pending {
+		for _, other := range pending {
+			if other.stemName != other.header.Slug {
+				item.content = retargetLinks(s.linkPattern(), item.content, other.stemName, other.header.Slug)
+			}
+		}
+	}
+	result.References = importReferences(pending)
+
+	// References to the imported notes are no longer broken once they are indexed
+	s.fileOperation(ctx, func() error {
+		for _, item := range pending {
+			// A cancelled import keeps the notes written so far
+			if ctx.Err() != nil {
+				break
+			}
+			path := s.vault.GetNotePath(item.header.Filename)
+			if _, err := os.Stat(path); err == nil {
+				result.Skipped[item.source] = fmt.Sprintf("file %s already exists", item.header.Filename)
+				continue
+			}
+			if err := os.WriteFile(path, []byte(item.content), 0644); err != nil {
+				result.Skipped[item.source] = fmt.Sprintf("failed to write note: %v", err)
+				continue
+			}
+			result.Imported = append(result.Imported, ImportedNote{
+				Source: item.source,
+				Slug:   item.header.Slug,
+				URI:    pathToURI(path),
+			})
+		}
+		return nil
+	})
+
+	return result, nil
+}
+
+// prepareImport reads a file and works out the note it becomes
+// taken holds the slugs already claimed by this import
+func (s *LanguageServer) prepareImport(source string, taken map[string]bool) (*pendingImport, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	content := metadata.Normalize(string(data))
+	stem := strings.TrimSuffix(filepath.Base(source), ".tex")
+
+	meta := &metadata.Metadata{Date: info.ModTime().Format("2006-01-02")}
+	hasBlock := false
+	if result, err := s.metadataParser().Parse(content); err == nil && result.Metadata.Title != "" {
+		meta, hasBlock = result.Metadata, true
+		if meta.Date == "" {
+			meta.Date = info.ModTime().Format("2006-01-02")
+		}
+	} else if match := titleCommandPattern.FindStringSubmatch(content); match != nil && strings.TrimSpace(match[1]) != "" {
+		meta.Title = strings.TrimSpace(match[1])
+	} else {
+		meta.Title = titleFromSlug(kebabCase(stem))
+	}
+
+	base := kebabCase(stem)
+	if base == "" {
+		base = kebabCase(meta.Title)
+	}
+	if base == "" {
+		return nil, fmt.Errorf("cannot derive a slug")
+	}
+	slug := base
+	for n := 2; ; n++ {
+		if _, exists := s.index.Get(slug); !exists && !taken[slug] {
+			break
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	if !hasBlock {
+		content = metadata.Update(content, meta)
+	}
+
+	datePrefix := info.ModTime().Format("20060102")
+	return &pendingImport{
+		source:   source,
+		content:  content,
+		stemName: stem,
+		header: &NoteHeader{
+			Title:    meta.Title,
+			Date:     meta.Date,
+			Tags:     meta.Tags,
+			Slug:     slug,
+			Filename: fmt.Sprintf("%s-%s.tex", datePrefix, slug),
+		},
+	}, nil
+}
+
+// importReferences finds the \ref usages of labels defined in another imported file
+func importReferences(pending []*pendingImport) []ImportReference {
+	definedIn := make(map[string]string) // label -> slug
+	usages := make(map[string][]LabelLocation)
+	for _, item := range pending {
+		definitions, used := extractLabels(item.header.Filename, item.content)
+		for _, definition := range definitions {
+			definedIn[definition.Label] = item.header.Slug
+		}
+		usages[item.header.Slug] = used
+	}
+
+	references := []ImportReference{}
+	seen := make(map[ImportReference]bool)
+	for slug, used := range usages {
+		for _, usage := range used {
+			target, defined := definedIn[usage.Label]
+			reference := ImportReference{Source: slug, Target: target, Label: usage.Label}
+			if !defined || target == slug || seen[reference] {
+				continue
+			}
+			seen[reference] = true
+			references = append(references, reference)
+		}
+	}
+
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].Source != references[j].Source {
+			return references[i].Source < references[j].Source
+		}
+		return references[i].Label < references[j].Label
+	})
+	return references
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestImportDirectory tests importing external LaTeX files as notes
+func TestImportDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	external := filepath.Join(tempDir, "external")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(external, 0755)
+
+	os.WriteFile(filepath.Join(notesPath, "20240101-intro.tex"), []byte("%% Metadata\n% title: Existing Intro\n"), 0644)
+	files := map[string]string{
+		"Intro.tex":       "\\documentclass{article}\n\\title{Introduction to Graphs}\n\\begin{document}\n\\input{chapter_one}\nSee \\ref{thm:euler}.\n\\end{document}",
+		"chapter_one.tex": "\\section{Euler}\n\\label{thm:euler}",
+		"annotated.tex":   "%% Metadata\n% title: Already Annotated\n% date: 2023-05-01\n% tags: kept\n\nBody",
+		"notes.txt":       "not LaTeX",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(external, name), []byte(content), 0644)
+	}
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.RebuildIndex(context.Background())
+
+	result, err := ls.importDirectoryCommand(context.Background(), []interface{}{string(pathToURI(external))})
+	if err != nil {
+		t.Fatalf("importDirectoryCommand failed: %v", err)
+	}
+	if len(result.Imported) != 3 || len(result.Skipped) != 0 {
+		t.Fatalf("expected 3 imported notes, got %+v", result)
+	}
+
+	// Slugs avoid notes already in the vault
+	intro, exists := ls.index.Get("intro-2")
+	if !exists {
+		t.Fatal("expected 'intro-2' to be indexed")
+	}
+	if intro.Title != "Introduction to Graphs" {
+		t.Errorf("expected title from \\title{}, got %q", intro.Title)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(notesPath, intro.Filename))
+	content := string(data)
+	if !strings.HasPrefix(content, "%% Metadata\n%% title: Introduction to Graphs\n") {
+		t.Errorf("expected a generated metadata block, got:\n%s", content)
+	}
+	if !strings.Contains(content, "\\input{chapter-one}") {
+		t.Errorf("expected \\input to point at the new slug, got:\n%s", content)
+	}
+	if len(ls.index.Links().Incoming("chapter-one")) != 1 {
+		t.Error("expected the rewritten \\input to be indexed as a link")
+	}
+
+	// Existing metadata is kept as is
+	annotated, exists := ls.index.Get("annotated")
+	if !exists || annotated.Date != "2023-05-01" || len(annotated.Tags) != 1 {
+		t.Errorf("expected metadata of annotated file to be kept, got %+v", annotated)
+	}
+
+	// Cross-file label references are reported
+	if len(result.References) != 1 || result.References[0] != (ImportReference{Source: "intro-2", Target: "chapter-one", Label: "thm:euler"}) {
+		t.Errorf("unexpected references: %+v", result.References)
+	}
+
+	// Importing the same files again picks fresh slugs rather than overwriting
+	again, err := ls.importDirectory(context.Background(), external)
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	for _, note := range again.Imported {
+		if note.Slug == "chapter-one" || note.Slug == "annotated" {
+			t.Errorf("expected a fresh slug, got %q", note.Slug)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+// commandIndexInfo reports the size of the index and the hit rates of the server's caches
+// Arguments: none, or ["compact"] to drop the caches and re-intern index strings first
+const commandIndexInfo = "lx.indexInfo"
+
+// indexInfoCompact is the lx.indexInfo action compacting the index
+const indexInfoCompact = "compact"
+
+const (
+	// stringOverhead is the size of a string header
+	stringOverhead = int(unsafe.Sizeof(""))
+	// mapEntryOverhead approximates the per-entry cost of a Go map beyond keys and values
+	mapEntryOverhead = 48
+)
+
+func init() {
+	registerCommand(commandIndexInfo, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.indexInfoCommand(ctx, args)
+	})
+}
+
+// cacheCounter counts the lookups of a cache
+type cacheCounter struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (c *cacheCounter) hit()  { c.hits.Add(1) }
+func (c *cacheCounter) miss() { c.misses.Add(1) }
+
+// info summarizes the counter for a cache holding entries
+func (c *cacheCounter) info(entries int) CacheInfo {
+	info := CacheInfo{Entries: entries, Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if lookups := info.Hits + info.Misses; lookups > 0 {
+		info.HitRate = float64(info.Hits) / float64(lookups)
+	}
+	return info
+}
+
+// CacheInfo describes one cache of the server
+type CacheInfo struct {
+	Entries int     `json:"entries"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+}
+
+// IndexInfo is returned by lx.indexInfo
+// Byte counts of the index are estimates; heap figures come from the Go runtime
+type IndexInfo struct {
+	Notes          int                  `json:"notes"`
+	Links          int                  `json:"links"`
+	Labels         int                  `json:"labels"`
+	Todos          int                  `json:"todos"`
+	SearchTerms    int                  `json:"searchTerms"`
+	OpenDocuments  int                  `json:"openDocuments"`
+	EstimatedBytes int                  `json:"estimatedBytes"`
+	HeapBytes      uint64               `json:"heapBytes"`
+	SysBytes       uint64               `json:"sysBytes"`
+	Caches         map[string]CacheInfo `json:"caches"`
+	Compacted      bool                 `json:"compacted"`
+	FreedBytes     int64                `json:"freedBytes,omitempty"` // heap released by compaction
+}
+
+// indexInfoCommand handles lx.indexInfo
+func (s *LanguageServer) indexInfoCommand(ctx context.Context, args []interface{}) (*IndexInfo, error) {
+	var freed int64
+	compacted := false
+	if len(args) > 0 {
+		action, _ := args[0].(string)
+		if action != indexInfoCompact {
+			return nil, fmt.Errorf("%s: unknown action '%v'", commandIndexInfo, args[0])
+		}
+		before := heapAlloc()
+		s.compactIndex()
+		freed = int64(before) - int64(heapAlloc())
+		compacted = true
+		s.recordActivity(ctx, "index.compact", fmt.Sprintf("%d bytes", freed))
+	}
+
+	info := s.indexInfo()
+	info.Compacted = compacted
+	if freed > 0 {
+		info.FreedBytes = freed
+	}
+	return info, nil
+}
+
+// indexInfo measures the index and the caches
+func (s *LanguageServer) indexInfo() *IndexInfo {
+	info := &IndexInfo{Caches: make(map[string]CacheInfo)}
+
+	var bytes int
+	info.Notes, bytes = s.index.footprint()
+	info.EstimatedBytes += bytes
+	info.Links, bytes = s.index.Links().footprint()
+	info.EstimatedBytes += bytes
+	info.Labels, bytes = s.index.Labels().footprint()
+	info.EstimatedBytes += bytes
+	info.Todos, bytes = s.index.Todos().footprint()
+	info.EstimatedBytes += bytes
+	info.SearchTerms, bytes = s.index.Search().footprint()
+	info.EstimatedBytes += bytes
+	info.EstimatedBytes += s.index.Hashes().footprint()
+
+	s.mu.RLock()
+	info.OpenDocuments = len(s.documents)
+	titles := s.titles
+	s.mu.RUnlock()
+
+	patterns := 0
+	macroPatterns.Range(func(_, _ interface{}) bool {
+		patterns++
+		return true
+	})
+	info.Caches["macroPatterns"] = macroPatternStats.info(patterns)
+	info.Caches["noteHeaders"] = s.index.Headers().info()
+	if titles != nil {
+		info.Caches["urlTitles"] = titles.stats.info(titles.Len())
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	info.HeapBytes = mem.HeapAlloc
+	info.SysBytes = mem.Sys
+	return info
+}
+
+// compactIndex drops the caches, rebuilds the index maps and re-interns their strings
+// Index strings are often cut from note contents and keep whole files alive; interned copies release them
+func (s *LanguageServer) compactIndex() {
+	in := make(interner)
+	s.index.compact(in)
+	s.index.Links().compact(in)
+	s.index.Labels().compact(in)
+	s.index.Todos().compact(in)
+	s.index.Search().compact(in)
+	s.index.Hashes().compact(in)
+
+	macroPatterns.Range(func(key, _ interface{}) bool {
+		macroPatterns.Delete(key)
+		return true
+	})
+	s.mu.RLock()
+	titles := s.titles
+	s.mu.RUnlock()
+	if titles != nil {
+		titles.Clear()
+	}
+
+	debug.FreeOSMemory()
+}
+
+// heapAlloc returns the live heap after a collection
+func heapAlloc() uint64 {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return mem.HeapAlloc
+}
+
+// interner deduplicates strings, copying each once so it no longer pins the text it was cut from
+type interner map[string]string
+
+func (in interner) intern(s string) string {
+	if interned, ok := in[s]; ok {
+		return interned
+	}
+	interned := strings.Clone(s)
+	in[interned] = interned
+	return interned
+}
+
+// stringsSize estimates the memory held by strings
+func stringsSize(values ...string) int {
+	size := 0
+	for _, value := range values {
+		size += stringOverhead + len(value)
+	}
+	return size
+}
+
+// footprint returns the number of notes and an estimate of their size, headers kept out of memory aside
+func (i *Index) footprint() (int, int) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	size := i.headers.footprint()
+	for slug, ref := range i.notes {
+		size += mapEntryOverhead + int(unsafe.Sizeof(ref)) + stringsSize(slug, ref.Slug, ref.Dir, ref.Filename)
+	}
+	for slug, refs := range i.shared {
+		size += mapEntryOverhead + stringsSize(slug)
+		for _, ref := range refs {
+			size += int(unsafe.Sizeof(ref)) + stringsSize(ref.Slug, ref.Dir, ref.Filename)
+		}
+	}
+	for filename, dir := range i.dirs {
+		size += mapEntryOverhead + stringsSize(filename, dir)
+	}
+	return len(i.notes), size
+}
+
+// compact rebuilds the note maps and the headers in memory with interned strings
+func (i *Index) compact(in interner) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	intern := func(ref noteRef) noteRef {
+		return noteRef{Slug: in.intern(ref.Slug), Dir: in.intern(ref.Dir), Filename: in.intern(ref.Filename)}
+	}
+	notes := make(map[string]noteRef, len(i.notes))
+	for slug, ref := range i.notes {
+		notes[in.intern(slug)] = intern(ref)
+	}
+	i.notes = notes
+	shared := make(map[string][]noteRef, len(i.shared))
+	for slug, refs := range i.shared {
+		compacted := make([]noteRef, len(refs))
+		for j, ref := range refs {
+			compacted[j] = intern(ref)
+		}
+		shared[in.intern(slug)] = compacted
+	}
+	i.shared = shared
+	i.headers.compact(in)
+	dirs := make(map[string]string, len(i.dirs))
+	for filename, dir := range i.dirs {
+		dirs[in.intern(filename)] = in.intern(dir)
+	}
+	i.dirs = dirs
+}
+
+// footprint returns the number of links and an estimate of their size
+// Incoming lists share their strings with the outgoing ones
+func (l *LinkIndex) footprint() (int, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	count, size := 0, 0
+	for source, links := range l.outgoing {
+		count += len(links)
+		size += mapEntryOverhead + stringsSize(source) + 2*len(links)*int(unsafe.Sizeof(Link{}))
+		for _, link := range links {
+			size += len(link.Source) + len(link.Filename) + len(link.Target) + len(link.Alias) + len(link.Anchor)
+		}
+	}
+	size += len(l.incoming) * mapEntryOverhead
+	return count, size
+}
+
+// compact rebuilds both link maps with interned strings
+func (l *LinkIndex) compact(in interner) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	outgoing := make(map[string][]Link, len(l.outgoing))
+	incoming := make(map[string][]Link, len(l.incoming))
+	for source, links := range l.outgoing {
+		compacted := make([]Link, len(links))
+		for j, link := range links {
+			link.Source = in.intern(link.Source)
+			link.Filename = in.intern(link.Filename)
+			link.Target = in.intern(link.Target)
+			link.Alias = in.intern(link.Alias)
+			link.Anchor = in.intern(link.Anchor)
+			compacted[j] = link
+			incoming[link.Target] = append(incoming[link.Target], link)
+		}
+		outgoing[in.intern(source)] = compacted
+	}
+	l.outgoing, l.incoming = outgoing, incoming
+}
+
+// footprint returns the number of label definitions and an estimate of the label index size
+func (l *LabelIndex) footprint() (int, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	count, size := 0, 0
+	for _, byFile := range []map[string][]LabelLocation{l.definitions, l.usages} {
+		for filename, locations := range byFile {
+			size += mapEntryOverhead + stringsSize(filename) + len(locations)*int(unsafe.Sizeof(LabelLocation{}))
+			for _, location := range locations {
+				size += len(location.Label) + len(location.Filename)
+			}
+		}
+	}
+	for _, locations := range l.definitions {
+		count += len(locations)
+	}
+	return count, size
+}
+
+// compact rebuilds the label maps with interned strings
+func (l *LabelIndex) compact(in interner) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	compactLocations := func(byFile map[string][]LabelLocation) map[string][]LabelLocation {
+		compacted := make(map[string][]LabelLocation, len(byFile))
+		for filename, locations := range byFile {
+			copied := make([]LabelLocation, len(locations))
+			for j, location := range locations {
+				location.Label = in.intern(location.Label)
+				location.Filename = in.intern(location.Filename)
+				copied[j] = location
+			}
+			compacted[in.intern(filename)] = copied
+		}
+		return compacted
+	}
+	l.definitions = compactLocations(l.definitions)
+	l.usages = compactLocations(l.usages)
+}
+
+// footprint returns the number of TODO markers and an estimate of their size
+func (t *TodoIndex) footprint() (int, int) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	count, size := 0, 0
+	for slug, todos := range t.todos {
+		count += len(todos)
+		size += mapEntryOverhead + stringsSize(slug) + len(todos)*int(unsafe.Sizeof(Todo{}))
+		for _, todo := range todos {
+			size += len(todo.Filename) + len(todo.Text)
+		}
+	}
+	return count, size
+}
+
+// compact rebuilds the TODO map with interned strings
+func (t *TodoIndex) compact(in interner) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	todos := make(map[string][]Todo, len(t.todos))
+	for slug, markers := range t.todos {
+		copied := make([]Todo, len(markers))
+		for j, todo := range markers {
+			todo.Filename = in.intern(todo.Filename)
+			todo.Text = strings.Clone(todo.Text)
+			copied[j] = todo
+		}
+		todos[in.intern(slug)] = copied
+	}
+	t.todos = todos
+}
+
+// footprint returns the number of indexed terms and an estimate of the full-text index size
+func (x *SearchIndex) footprint() (int, int) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	size := 0
+	for token, postings := range x.postings {
+		size += mapEntryOverhead + stringsSize(token) + len(postings)*mapEntryOverhead
+	}
+	for slug, tokens := range x.docs {
+		size += mapEntryOverhead + stringsSize(slug) + len(tokens)*mapEntryOverhead
+	}
+	return len(x.postings), size
+}
+
+// compact rebuilds the full-text maps with interned tokens and slugs
+func (x *SearchIndex) compact(in interner) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	postings := make(map[string]map[string]int, len(x.postings))
+	docs := make(map[string]map[string]int, len(x.docs))
+	for slug, tokens := range x.docs {
+		slug = in.intern(slug)
+		compacted := make(map[string]int, len(tokens))
+		for token, count := range tokens {
+			token = in.intern(token)
+			compacted[token] = count
+			if postings[token] == nil {
+				postings[token] = make(map[string]int)
+			}
+			postings[token][slug] = count
+		}
+		docs[slug] = compacted
+	}
+	x.postings, x.docs = postings, docs
+}
+
+// footprint returns an estimate of the body fingerprint index size
+func (c *ContentHashIndex) footprint() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	size := 0
+	for slug, hashes := range c.hashes {
+		size += mapEntryOverhead + stringsSize(slug, hashes.exact, hashes.near)
+	}
+	return size
+}
+
+// compact rebuilds the fingerprint map with interned slugs
+func (c *ContentHashIndex) compact(in interner) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hashes := make(map[string]contentHashes, len(c.hashes))
+	for slug, fingerprints := range c.hashes {
+		hashes[in.intern(slug)] = fingerprints
+	}
+	c.hashes = hashes
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestIndexInfo tests reporting index sizes and compacting the index
+func TestIndexInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	notes := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n% tags: math\n\\section{Trees}\\label{trees}\nVertices and edges. \\todo{draw}",
+		"20240102-paper.tex":  "%% Metadata\n% title: Paper\n\\ref{graphs} and \\ref{trees}",
+	}
+	for name, content := range notes {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.RebuildIndex(context.Background())
+
+	info, err := ls.indexInfoCommand(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("indexInfoCommand failed: %v", err)
+	}
+	if info.Notes != 2 || info.Links != 2 || info.Labels != 1 || info.Todos != 1 || info.SearchTerms == 0 {
+		t.Errorf("unexpected counts %+v", info)
+	}
+	if info.EstimatedBytes == 0 || info.HeapBytes == 0 || info.Compacted {
+		t.Errorf("unexpected sizes %+v", info)
+	}
+	if cache, ok := info.Caches["macroPatterns"]; !ok || cache.Hits+cache.Misses == 0 {
+		t.Errorf("expected macro pattern lookups to be counted, got %+v", info.Caches)
+	}
+
+	compacted, err := ls.indexInfoCommand(context.Background(), []interface{}{"compact"})
+	if err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	if !compacted.Compacted || compacted.Notes != info.Notes || compacted.Links != info.Links || compacted.SearchTerms != info.SearchTerms {
+		t.Errorf("expected compaction to keep the index, got %+v", compacted)
+	}
+	if compacted.Caches["macroPatterns"].Entries != 0 {
+		t.Errorf("expected the pattern cache to be dropped, got %+v", compacted.Caches["macroPatterns"])
+	}
+
+	// The compacted index still answers queries
+	if incoming := ls.index.Links().Incoming("graphs"); len(incoming) != 1 || incoming[0].Source != "paper" {
+		t.Errorf("unexpected incoming links %+v", incoming)
+	}
+	if scores := ls.index.Search().Search("vertices"); scores["graphs"] == 0 {
+		t.Errorf("expected search to find graphs, got %v", scores)
+	}
+	if note, ok := ls.index.Get("graphs"); !ok || note.Title != "Graphs" || len(note.Tags) != 1 {
+		t.Errorf("unexpected note %+v", note)
+	}
+
+	if _, err := ls.indexInfoCommand(context.Background(), []interface{}{"shrink"}); err == nil {
+		t.Error("expected unknown action to fail")
+	}
+}
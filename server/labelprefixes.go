@@ -0,0 +1,253 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeLabelPrefix marks labels whose prefix does not match their environment
+const diagnosticCodeLabelPrefix = "LX012"
+
+var (
+	// labelCompletionPattern matches a line prefix inside \label{...}
+	labelCompletionPattern = regexp.MustCompile(`\\label\{([^}]*)$`)
+
+	// captionPattern matches \caption[short]{text}
+	captionPattern = regexp.MustCompile(`\\caption(?:\[[^\]]*\])?\{([^}]*)\}`)
+)
+
+// sectionLabelContext is the label context of labels following a sectioning command outside any environment
+const sectionLabelContext = "section"
+
+// defaultLabelPrefixes are the label prefixes required inside common environments
+// Theorem-like environments use their conventional prefixes from theoremLabelPrefixes
+func defaultLabelPrefixes() map[string]string {
+	prefixes := map[string]string{
+		"figure":   "fig",
+		"table":    "tab",
+		"equation": "eq",
+		"align":    "eq",
+	}
+	for env, prefix := range theoremLabelPrefixes {
+		prefixes[env] = prefix
+	}
+	return prefixes
+}
+
+func init() {
+	registerQuickFix(diagnosticCodeLabelPrefix, fixLabelPrefix)
+}
+
+// labelContext returns the innermost environment open at a position, without a trailing *
+// Outside environments, labels directly after a \section or similar get sectionLabelContext
+func labelContext(lines []string, line, character int) string {
+	contexts := &labelContexts{lines: lines}
+	return contexts.at(line, character)
+}
+
+// labelContexts finds the label context of many positions of a document in one pass over it,
+// keeping the environments open so far instead of scanning from the top for every position
+// Positions are looked up in document order; an earlier one starts the scan over
+type labelContexts struct {
+	lines []string
+	next  int      // first line not scanned yet
+	open  []string // environments open before line next
+}
+
+// at returns the label context of a position, as labelContext does
+func (c *labelContexts) at(line, character int) string {
+	if line < c.next {
+		c.next, c.open = 0, nil
+	}
+	for ; c.next < line && c.next < len(c.lines); c.next++ {
+		c.open = openEnvironments(c.open, c.lines[c.next])
+	}
+	open := c.open
+	if line < len(c.lines) {
+		text := c.lines[line]
+		if character <= len(text) {
+			text = text[:character]
+		}
+		open = openEnvironments(append([]string(nil), c.open...), text)
+	}
+	if len(open) > 0 {
+		return open[len(open)-1]
+	}
+
+	// The label's own line, or the closest non-blank line before it, holds the heading
+	lines := c.lines
+	if line < len(lines) && character <= len(lines[line]) && sectionPattern.MatchString(lines[line][:character]) {
+		return sectionLabelContext
+	}
+	for lineNum := line - 1; lineNum >= 0; lineNum-- {
+		if trimmed := strings.TrimSpace(lines[lineNum]); trimmed != "" {
+			if sectionPattern.MatchString(lines[lineNum]) && !strings.HasPrefix(trimmed, "%") {
+				return sectionLabelContext
+			}
+			break
+		}
+	}
+	return ""
+}
+
+// openEnvironments applies the \begin and \end commands of a line to the environments open before it
+func openEnvironments(open []string, text string) []string {
+	if strings.HasPrefix(strings.TrimSpace(text), "%") {
+		return open
+	}
+	for _, match := range environmentPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimSuffix(match[2], "*")
+		if unindentedEnvironments[name] {
+			continue
+		}
+		if match[1] == "begin" {
+			open = append(open, name)
+		} else if len(open) > 0 && open[len(open)-1] == name {
+			open = open[:len(open)-1]
+		}
+	}
+	return open
+}
+
+// labelPrefixDiagnostics reports labels missing the prefix the labelPrefixes policy requires for their environment
+func (s *LanguageServer) labelPrefixDiagnostics(content string) []protocol.Diagnostic {
+	prefixes := s.settings().LabelPrefixes
+
+	diagnostics := []protocol.Diagnostic{}
+	lines := s.documentLines(content)
+	contexts := &labelContexts{lines: lines}
+	for lineNum, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		for _, match := range labelPattern.FindAllStringSubmatchIndex(line, -1) {
+			context := contexts.at(lineNum, match[0])
+			prefix := prefixes[context]
+			label := line[match[2]:match[3]]
+			if prefix == "" || strings.HasPrefix(label, prefix+":") {
+				continue
+			}
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    lineRange(lineNum, match[2], match[3]),
+				Severity: protocol.DiagnosticSeverityWarning,
+				Code:     diagnosticCodeLabelPrefix,
+				Message:  fmt.Sprintf("Label '%s' in %s should start with '%s:'", label, context, prefix),
+				Source:   "lx-ls",
+			})
+		}
+	}
+	return diagnostics
+}
+
+// prefixedLabel swaps the prefix of a label, keeping its key
+func prefixedLabel(label, prefix string) string {
+	if colon := strings.Index(label, ":"); colon >= 0 {
+		label = label[colon+1:]
+	}
+	return prefix + ":" + label
+}
+
+// fixLabelPrefix renames a label to the required prefix, along with its references across the vault
+func fixLabelPrefix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	lines := strings.Split(req.Content, "\n")
+	line := int(diag.Range.Start.Line)
+	if line >= len(lines) || int(diag.Range.End.Character) > len(lines[line]) {
+		return nil
+	}
+	label := lines[line][diag.Range.Start.Character:diag.Range.End.Character]
+	prefix := s.settings().LabelPrefixes[labelContext(lines, line, int(diag.Range.Start.Character))]
+	if prefix == "" {
+		return nil
+	}
+	renamed := prefixedLabel(label, prefix)
+
+	edit, err := s.labelRenameEdit(filepath.Base(uriToPath(req.URI)), label, renamed)
+	if err != nil {
+		return nil
+	}
+	// The open buffer may hold a label the index has not seen yet
+	if len(edit.Changes[req.URI]) == 0 {
+		edit.Changes[req.URI] = []protocol.TextEdit{{Range: diag.Range, NewText: renamed}}
+	}
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf("Rename label to '%s'", renamed),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: true,
+			Edit:        edit,
+		},
+	}
+}
+
+// labelCompletions suggests keys inside \label{...} from the label's context: the prefix of its
+// environment and a key from the figure or table caption, or the section heading
+func (s *LanguageServer) labelCompletions(lines []string, line, character int, typed string) []protocol.CompletionItem {
+	context := labelContext(lines, line, character)
+	prefix := s.settings().LabelPrefixes[context]
+	if prefix == "" && (context == sectionLabelContext || context == "") {
+		prefix = "sec"
+	}
+	if prefix == "" {
+		prefix = context
+	}
+
+	name := currentSectionHeading(lines, line)
+	if context != sectionLabelContext && context != "" {
+		name = environmentCaption(lines, line, context)
+	}
+
+	candidates := []string{prefix + ":"}
+	if key := kebabCase(name); key != "" {
+		candidates = append([]string{prefix + ":" + key}, candidates...)
+	}
+
+	detail := "Label"
+	if context != "" {
+		detail = "Label for " + context
+	}
+
+	var items []protocol.CompletionItem
+	for i, candidate := range candidates {
+		if !strings.HasPrefix(candidate, typed) || candidate == typed {
+			continue
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:    candidate,
+			Kind:     protocol.CompletionItemKindReference,
+			Detail:   detail,
+			SortText: fmt.Sprintf("%d", i),
+		})
+	}
+	return items
+}
+
+// environmentCaption returns the caption of the environment enclosing a line, looking both ways
+// until the environment's \begin and \end
+func environmentCaption(lines []string, line int, env string) string {
+	var captions []string
+	for lineNum := line; lineNum >= 0; lineNum-- {
+		if match := captionPattern.FindStringSubmatch(lines[lineNum]); match != nil {
+			captions = append(captions, match[1])
+		}
+		if strings.Contains(lines[lineNum], `\begin{`+env) {
+			break
+		}
+	}
+	for lineNum := line + 1; lineNum < len(lines); lineNum++ {
+		if strings.Contains(lines[lineNum], `\end{`+env) {
+			break
+		}
+		if match := captionPattern.FindStringSubmatch(lines[lineNum]); match != nil {
+			captions = append(captions, match[1])
+		}
+	}
+	if len(captions) == 0 {
+		return ""
+	}
+	return captions[0]
+}
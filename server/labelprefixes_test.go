@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestLabelContext tests finding the environment or heading a label belongs to
+func TestLabelContext(t *testing.T) {
+	lines := strings.Split("\\begin{document}\n\\section{Intro}\n\\label{sec:intro}\n\\begin{figure*}\n\\begin{center}\n\\end{center}\n\\label{fig:x}\n\\end{figure*}\ntext \\label{free}\n", "\n")
+	tests := map[int]string{2: "section", 6: "figure", 8: ""}
+	for line, want := range tests {
+		if got := labelContext(lines, line, strings.Index(lines[line], "\\label")); got != want {
+			t.Errorf("line %d: got context %q, want %q", line, got, want)
+		}
+	}
+
+	// One pass over the document finds the same contexts, and going back starts it over
+	contexts := &labelContexts{lines: lines}
+	for _, line := range []int{2, 6, 8, 2} {
+		character := strings.Index(lines[line], "\\label")
+		if got, want := contexts.at(line, character), labelContext(lines, line, character); got != want {
+			t.Errorf("line %d: one pass found context %q, want %q", line, got, want)
+		}
+	}
+}
+
+// TestLabelPrefixes tests the label prefix policy, its quick fix and \label completion
+func TestLabelPrefixes(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "%% Metadata\n% title: Graphs\n\\section{Degree Sequences}\n\\label{\n\\begin{figure}\n\\caption{Degree distribution}\n\\label{degrees}\n\\end{figure}\n\\begin{lemma}\n\\label{lem:handshake}\n\\end{lemma}\n"
+	os.WriteFile(filepath.Join(tempDir, "20240101-graphs.tex"), []byte(content), 0644)
+	os.WriteFile(filepath.Join(tempDir, "20240102-trees.tex"), []byte("See \\ref{degrees}.\n"), 0644)
+
+	config := DefaultConfig()
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), config: &config, documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	diagnostics := ls.labelPrefixDiagnostics(content)
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnosticCodeLabelPrefix || diagnostics[0].Range != lineRange(6, 7, 14) {
+		t.Fatalf("expected the figure label to be reported, got %+v", diagnostics)
+	}
+
+	uri := pathToURI(filepath.Join(tempDir, "20240101-graphs.tex"))
+	actions := fixLabelPrefix(ls, &codeActionRequest{URI: uri, Content: content}, diagnostics[0])
+	if len(actions) != 1 || actions[0].Title != "Rename label to 'fig:degrees'" || len(actions[0].Edit.Changes) != 2 {
+		t.Fatalf("expected a vault-wide rename, got %+v", actions)
+	}
+
+	// Labels in an environment offer the prefix and a key from the caption
+	lines := strings.Split(content, "\n")
+	items := ls.labelCompletions(lines, 6, 7, "fig:")
+	if len(items) != 1 || items[0].Label != "fig:degree-distribution" {
+		t.Errorf("unexpected figure label completions %+v", items)
+	}
+	items = ls.labelCompletions(lines, 3, 7, "")
+	if len(items) != 2 || items[0].Label != "sec:degree-sequences" || items[1].Label != "sec:" {
+		t.Errorf("unexpected section label completions %+v", items)
+	}
+
+	// Dropping an environment from the policy drops its diagnostics
+	config.LabelPrefixes = map[string]string{"figure": ""}
+	if diagnostics := ls.labelPrefixDiagnostics(content); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %+v", diagnostics)
+	}
+}
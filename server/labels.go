@@ -0,0 +1,205 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// labelRefPattern matches commands referring to \label targets; the argument may be a comma list
+var labelRefPattern = regexp.MustCompile(`\\(?:ref|eqref|cref|Cref|autoref|pageref)\{([^}]+)\}`)
+
+// LabelLocation is a single occurrence of a label inside a note
+type LabelLocation struct {
+	Label    string
+	Filename string
+	Range    protocol.Range
+}
+
+// LabelIndex is a cross-note index of \label definitions and their usages
+type LabelIndex struct {
+	mu          sync.RWMutex
+	definitions map[string][]LabelLocation // filename -> \label occurrences
+	usages      map[string][]LabelLocation // filename -> \ref/\eqref/\cref occurrences
+}
+
+func NewLabelIndex() *LabelIndex {
+	return &LabelIndex{
+		definitions: make(map[string][]LabelLocation),
+		usages:      make(map[string][]LabelLocation),
+	}
+}
+
+// Set replaces the label definitions and usages of a note
+func (l *LabelIndex) Set(filename string, definitions, usages []LabelLocation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.definitions[filename] = definitions
+	l.usages[filename] = usages
+}
+
+// Delete removes a note from the label index
+func (l *LabelIndex) Delete(filename string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.definitions, filename)
+	delete(l.usages, filename)
+}
+
+// Definitions returns every \label occurrence of label across the vault
+func (l *LabelIndex) Definitions(label string) []LabelLocation {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return collectLabel(l.definitions, label)
+}
+
+// Usages returns every reference to label across the vault
+func (l *LabelIndex) Usages(label string) []LabelLocation {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return collectLabel(l.usages, label)
+}
+
+// collectLabel gathers the occurrences of label from a per-file map
+func collectLabel(byFile map[string][]LabelLocation, label string) []LabelLocation {
+	var locations []LabelLocation
+	for _, locs := range byFile {
+		for _, loc := range locs {
+			if loc.Label == label {
+				locations = append(locations, loc)
+			}
+		}
+	}
+	return locations
+}
+
+// extractLabels scans note content for \label definitions and label references
+func extractLabels(filename, content string) ([]LabelLocation, []LabelLocation) {
+	var definitions, usages []LabelLocation
+
+	lines := strings.Split(content, "\n")
+	for lineNum, line := range lines {
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+
+		for _, match := range labelPattern.FindAllStringSubmatchIndex(line, -1) {
+			definitions = append(definitions, LabelLocation{
+				Label:    line[match[2]:match[3]],
+				Filename: filename,
+				Range:    lineRange(lineNum, match[2], match[3]),
+			})
+		}
+
+		for _, match := range labelRefPattern.FindAllStringSubmatchIndex(line, -1) {
+			usages = append(usages, splitLabelList(filename, line, lineNum, match[2], match[3])...)
+		}
+	}
+
+	return definitions, usages
+}
+
+// splitLabelList splits a comma-separated label argument into individual locations
+func splitLabelList(filename, line string, lineNum, start, end int) []LabelLocation {
+	var locations []LabelLocation
+
+	offset := start
+	for _, part := range strings.Split(line[start:end], ",") {
+		trimmed := strings.TrimSpace(part)
+		if trimmed != "" {
+			partStart := offset + strings.Index(part, trimmed)
+			locations = append(locations, LabelLocation{
+				Label:    trimmed,
+				Filename: filename,
+				Range:    lineRange(lineNum, partStart, partStart+len(trimmed)),
+			})
+		}
+		offset += len(part) + 1
+	}
+
+	return locations
+}
+
+// getLabelAtPosition returns the label under the cursor, either on its \label
+// definition or on a reference to a label known to the index
+func (s *LanguageServer) getLabelAtPosition(content string, pos protocol.Position) string {
+	lines := s.documentLines(content)
+	if int(pos.Line) >= len(lines) {
+		return ""
+	}
+
+	definitions, usages := extractLabels("", lines[pos.Line])
+	for _, loc := range definitions {
+		if pos.Character >= loc.Range.Start.Character && pos.Character <= loc.Range.End.Character {
+			return loc.Label
+		}
+	}
+	for _, loc := range usages {
+		if pos.Character >= loc.Range.Start.Character && pos.Character <= loc.Range.End.Character {
+			if len(s.index.Labels().Definitions(loc.Label)) > 0 {
+				return loc.Label
+			}
+		}
+	}
+
+	return ""
+}
+
+// labelOwner returns the note whose label a reference or definition in filename names: the note
+// itself when it defines the label, in content or the index, else the only other note defining it
+// Returns "" when several other notes define the label, leaving the reference ambiguous
+func (s *LanguageServer) labelOwner(filename, content, label string) string {
+	definitions, _ := extractLabels(filename, content)
+	for _, def := range definitions {
+		if def.Label == label {
+			return filename
+		}
+	}
+
+	owner := ""
+	for _, def := range s.index.Labels().Definitions(label) {
+		switch {
+		case def.Filename == filename:
+			// The index holds an older version of this note, which no longer defines the label
+		case owner == "" || owner == def.Filename:
+			owner = def.Filename
+		default:
+			return ""
+		}
+	}
+	return owner
+}
+
+// labelRenameEdit rewrites the label owner defines along with the references resolving to it: those
+// inside owner, and those of notes that do not define the label when owner is the only note that does
+// Same-named labels of other notes and their references are left alone
+func (s *LanguageServer) labelRenameEdit(owner, label, newLabel string) (*protocol.WorkspaceEdit, error) {
+	if strings.ContainsAny(newLabel, "{},") || strings.TrimSpace(newLabel) == "" {
+		return nil, fmt.Errorf("invalid label name: %q", newLabel)
+	}
+
+	definers := make(map[string]bool)
+	for _, loc := range s.index.Labels().Definitions(label) {
+		definers[loc.Filename] = true
+	}
+	shared := len(definers) > 1 || (len(definers) == 1 && !definers[owner])
+
+	changes := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	occurrences := append(s.index.Labels().Definitions(label), s.index.Labels().Usages(label)...)
+	for _, loc := range occurrences {
+		if loc.Filename != owner && (shared || definers[loc.Filename]) {
+			continue
+		}
+		uri := pathToURI(s.notePath(loc.Filename))
+		changes[uri] = append(changes[uri], protocol.TextEdit{
+			Range:   loc.Range,
+			NewText: newLabel,
+		})
+	}
+
+	return &protocol.WorkspaceEdit{Changes: changes}, nil
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestRename_Label tests renaming a label with its usages across the vault
+func TestRename_Label(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	definingFile := filepath.Join(notesPath, "20240101-equations.tex")
+	usingFile := filepath.Join(notesPath, "20240102-proofs.tex")
+
+	testNotes := map[string]string{
+		definingFile: "\\begin{equation}\\label{eq:foo}\\end{equation}\nBy \\eqref{eq:foo}.",
+		usingFile:    "See \\cref{eq:bar, eq:foo} and \\ref{equations}.\n% \\ref{eq:foo}",
+	}
+	for path, content := range testNotes {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test note: %v", err)
+		}
+	}
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath},
+		index: NewIndex(),
+	}
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	// Rename from a usage in another note
+	params := &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(usingFile)},
+			Position:     protocol.Position{Line: 0, Character: 21}, // Inside "eq:foo"
+		},
+		NewName: "eq:baz",
+	}
+
+	edit, err := ls.Rename(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	definingEdits := edit.Changes[pathToURI(definingFile)]
+	if len(definingEdits) != 2 {
+		t.Errorf("expected label and eqref edits in defining note, got %d", len(definingEdits))
+	}
+
+	usingEdits := edit.Changes[pathToURI(usingFile)]
+	if len(usingEdits) != 1 {
+		t.Fatalf("expected 1 edit in using note, got %d", len(usingEdits))
+	}
+	want := protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 18},
+		End:   protocol.Position{Line: 0, Character: 24},
+	}
+	if usingEdits[0].Range != want || usingEdits[0].NewText != "eq:baz" {
+		t.Errorf("unexpected edit %+v", usingEdits[0])
+	}
+
+	// Invalid names are rejected
+	params.NewName = "eq:a,b"
+	if _, err := ls.Rename(context.Background(), params); err == nil {
+		t.Error("expected error for invalid label name")
+	}
+
+	// Once another note defines the same label, renaming stays within the note renamed from
+	otherFile := filepath.Join(notesPath, "20240103-other.tex")
+	os.WriteFile(otherFile, []byte("\\label{eq:foo}\nAs in \\eqref{eq:foo}."), 0644)
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	params.TextDocument.URI = pathToURI(definingFile)
+	params.Position = protocol.Position{Line: 0, Character: 25} // Inside the \label
+	params.NewName = "eq:baz"
+	edit, err = ls.Rename(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if len(edit.Changes) != 1 || len(edit.Changes[pathToURI(definingFile)]) != 2 {
+		t.Errorf("expected the label and its local reference only, got %+v", edit.Changes)
+	}
+
+	// A reference from a note defining neither is ambiguous
+	params.TextDocument.URI = pathToURI(usingFile)
+	params.Position = protocol.Position{Line: 0, Character: 21}
+	if _, err := ls.Rename(context.Background(), params); err == nil {
+		t.Error("expected error renaming an ambiguous reference")
+	}
+}
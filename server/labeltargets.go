@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeDuplicateLabel marks a \label defined more than once in the vault
+const diagnosticCodeDuplicateLabel = "LX013"
+
+// labelRefCompletionPattern matches a line prefix inside the argument of a label-only reference command
+var labelRefCompletionPattern = regexp.MustCompile(`\\(?:eqref|cref|Cref|autoref|pageref)\{([^}]*)$`)
+
+// All returns every \label definition across the vault, sorted by label, then file
+func (l *LabelIndex) All() []LabelLocation {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var locations []LabelLocation
+	for _, locs := range l.definitions {
+		locations = append(locations, locs...)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Label != locations[j].Label {
+			return locations[i].Label < locations[j].Label
+		}
+		return locations[i].Filename < locations[j].Filename
+	})
+	return locations
+}
+
+// labelNoteTitle names the note a label is defined in
+func (s *LanguageServer) labelNoteTitle(loc LabelLocation) string {
+	if note, exists := s.index.Get(s.parseFilenameToSlug(loc.Filename)); exists {
+		return note.Title
+	}
+	return loc.Filename
+}
+
+// labelTargetCompletions offers the labels of the vault as reference targets
+// The labels of the document being edited come first, read from its content so unsaved ones are
+// offered too, with the environment they label; all of them sort after notes, which remain the
+// usual target of \ref
+func (s *LanguageServer) labelTargetCompletions(uri protocol.DocumentURI, content string) []protocol.CompletionItem {
+	filename := ""
+	if uri != "" {
+		filename = filepath.Base(uriToPath(uri))
+	}
+
+	seen := make(map[string]bool)
+	var items []protocol.CompletionItem
+	lines := s.documentLines(content)
+	contexts := &labelContexts{lines: lines}
+	definitions, _ := extractLabels(filename, content)
+	for _, loc := range definitions {
+		if seen[loc.Label] {
+			continue
+		}
+		seen[loc.Label] = true
+		item := protocol.CompletionItem{
+			Label:    loc.Label,
+			Kind:     protocol.CompletionItemKindReference,
+			Detail:   "Label in this note",
+			SortText: "~0" + loc.Label,
+		}
+		if env := contexts.at(int(loc.Range.Start.Line), int(loc.Range.Start.Character)); env != "" {
+			item.Detail += " (" + env + ")"
+			if caption := environmentCaption(lines, int(loc.Range.Start.Line), env); env != sectionLabelContext && caption != "" {
+				item.Documentation = caption
+			}
+		}
+		items = append(items, item)
+	}
+
+	for _, loc := range s.index.Labels().All() {
+		// The index may hold an older version of this note's labels
+		if seen[loc.Label] || (filename != "" && loc.Filename == filename) {
+			continue
+		}
+		seen[loc.Label] = true
+		items = append(items, protocol.CompletionItem{
+			Label:    loc.Label,
+			Kind:     protocol.CompletionItemKindReference,
+			Detail:   "Label in " + s.labelNoteTitle(loc),
+			SortText: "~1" + loc.Label,
+		})
+	}
+	return items
+}
+
+// labelDefinitions returns where the label under the cursor is defined
+// Definitions in the document itself come from its content, so unsaved labels are found too
+func (s *LanguageServer) labelDefinitions(uri protocol.DocumentURI, content string, pos protocol.Position) []protocol.Location {
+	label := s.getLabelAtPosition(content, pos)
+	if label == "" {
+		// References to labels only this document defines are not in the index yet
+		label = s.labelUsageAt(content, pos)
+	}
+	if label == "" {
+		return nil
+	}
+
+	filename := filepath.Base(uriToPath(uri))
+	var locations []protocol.Location
+	definitions, _ := extractLabels(filename, content)
+	for _, def := range definitions {
+		if def.Label == label {
+			locations = append(locations, protocol.Location{URI: uri, Range: def.Range})
+		}
+	}
+	for _, def := range s.index.Labels().Definitions(label) {
+		if def.Filename == filename {
+			continue
+		}
+		locations = append(locations, protocol.Location{URI: pathToURI(s.notePath(def.Filename)), Range: def.Range})
+	}
+	return locations
+}
+
+// labelUsageAt returns the label referenced under the cursor, whether or not it is defined
+func (s *LanguageServer) labelUsageAt(content string, pos protocol.Position) string {
+	lines := s.documentLines(content)
+	if int(pos.Line) >= len(lines) {
+		return ""
+	}
+	_, usages := extractLabels("", lines[pos.Line])
+	for _, loc := range usages {
+		if pos.Character >= loc.Range.Start.Character && pos.Character <= loc.Range.End.Character {
+			return loc.Label
+		}
+	}
+	return ""
+}
+
+// duplicateLabelDiagnostics reports labels defined twice in a note, or also defined in another note,
+// which leaves references to them ambiguous
+// filename is the note's own, whose indexed labels are the ones in content; without it only
+// duplicates within content are reported
+func (s *LanguageServer) duplicateLabelDiagnostics(filename, content string) []protocol.Diagnostic {
+	definitions, _ := extractLabels(filename, content)
+	counts := make(map[string]int)
+	for _, def := range definitions {
+		counts[def.Label]++
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, def := range definitions {
+		var elsewhere []string
+		if filename != "" {
+			notes := make(map[string]bool)
+			for _, other := range s.index.Labels().Definitions(def.Label) {
+				if title := s.labelNoteTitle(other); other.Filename != filename && !notes[title] {
+					notes[title] = true
+					elsewhere = append(elsewhere, title)
+				}
+			}
+		}
+		sort.Strings(elsewhere)
+
+		var message string
+		switch {
+		case len(elsewhere) > 0:
+			message = fmt.Sprintf("Label '%s' is also defined in %s", def.Label, strings.Join(elsewhere, ", "))
+		case counts[def.Label] > 1:
+			message = fmt.Sprintf("Label '%s' is defined %d times in this note", def.Label, counts[def.Label])
+		default:
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    def.Range,
+			Severity: protocol.DiagnosticSeverityWarning,
+			Code:     diagnosticCodeDuplicateLabel,
+			Message:  message,
+			Source:   "lx-ls",
+		})
+	}
+	return diagnostics
+}
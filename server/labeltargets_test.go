@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestLabelTargets tests completing, resolving and checking labels defined inside notes
+func TestLabelTargets(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n\\begin{equation}\\label{eq:euler}\\end{equation}\n\\label{sec:intro}",
+		"20240102-trees.tex":  "%% Metadata\n% title: Trees\n\\label{sec:intro}\nSee \\eqref{eq:e} and \\ref{eq:euler}.\n\\label{tab:x}\n\\label{tab:x}",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+	uri := pathToURI(filepath.Join(tempDir, "20240102-trees.tex"))
+
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 3, Character: 15},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Label != "eq:euler" || list.Items[0].Detail != "Label in Graphs" {
+		t.Errorf("expected only the eq:euler label inside \\eqref, got %+v", list.Items)
+	}
+
+	// Labels of the note being edited come first, unsaved ones included, and say what they label
+	content := files["20240102-trees.tex"] + "\n\\begin{figure}\\caption{A tree}\\label{fig:tree}\\end{figure}\nSee \\ref{"
+	docURI := pathToURI(filepath.Join(tempDir, "20240102-trees.tex"))
+	ls.documents[docURI] = content
+	list, err = ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: docURI},
+			Position:     protocol.Position{Line: 7, Character: 9},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	var labels []string
+	var tree protocol.CompletionItem
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].SortText < list.Items[j].SortText })
+	for _, item := range list.Items {
+		if strings.Contains(item.Label, ":") {
+			labels = append(labels, item.Label)
+		}
+		if item.Label == "fig:tree" {
+			tree = item
+		}
+	}
+	if strings.Join(labels, " ") != "fig:tree sec:intro tab:x eq:euler" {
+		t.Errorf("expected this note's labels before the others, got %v", labels)
+	}
+	if tree.Detail != "Label in this note (figure)" || tree.Documentation != "A tree" {
+		t.Errorf("unexpected fig:tree item %+v", tree)
+	}
+	delete(ls.documents, docURI)
+
+	locations, err := ls.Definition(context.Background(), &protocol.DefinitionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 3, Character: 30},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Definition failed: %v", err)
+	}
+	if len(locations) != 1 || !strings.HasSuffix(string(locations[0].URI), "20240101-graphs.tex") || locations[0].Range != lineRange(2, 23, 31) {
+		t.Errorf("expected the label inside graphs, got %+v", locations)
+	}
+
+	var messages []string
+	for _, diag := range ls.analyzeNoteDiagnostics(uri, files["20240102-trees.tex"]) {
+		if diag.Code == diagnosticCodeDuplicateLabel {
+			messages = append(messages, diag.Message)
+		}
+	}
+	want := []string{
+		"Label 'sec:intro' is also defined in Graphs",
+		"Label 'tab:x' is defined 2 times in this note",
+		"Label 'tab:x' is defined 2 times in this note",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected duplicate label diagnostics %q", messages)
+	}
+}
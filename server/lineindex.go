@@ -0,0 +1,83 @@
+package server
+
+import (
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// lineIndex holds the lines of a document and where each starts, built once per change
+// Lines are substrings of the content, so indexing costs one pass and no copies
+type lineIndex struct {
+	content string
+	lines   []string
+	starts  []int // byte offset of the start of each line
+}
+
+func newLineIndex(content string) *lineIndex {
+	count := strings.Count(content, "\n") + 1
+	idx := &lineIndex{
+		content: content,
+		lines:   make([]string, 0, count),
+		starts:  make([]int, 0, count),
+	}
+	start := 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			idx.lines = append(idx.lines, content[start:i])
+			idx.starts = append(idx.starts, start)
+			start = i + 1
+		}
+	}
+	idx.lines = append(idx.lines, content[start:])
+	idx.starts = append(idx.starts, start)
+	return idx
+}
+
+// Lines returns the lines of the document, as strings.Split on "\n" would
+// The slice is shared, callers must not modify it
+func (idx *lineIndex) Lines() []string {
+	return idx.lines
+}
+
+// Line returns a line of the document
+func (idx *lineIndex) Line(line int) (string, bool) {
+	if line < 0 || line >= len(idx.lines) {
+		return "", false
+	}
+	return idx.lines[line], true
+}
+
+// Offset returns the byte offset of a position, clamped to its line
+func (idx *lineIndex) Offset(pos protocol.Position) int {
+	line := int(pos.Line)
+	if line >= len(idx.starts) {
+		return len(idx.content)
+	}
+	return idx.starts[line] + min(int(pos.Character), len(idx.lines[line]))
+}
+
+// indexLines rebuilds the line index of an open document
+// Callers hold s.mu
+func (s *LanguageServer) indexLines(uri protocol.DocumentURI, content string) {
+	if s.lineIndexes == nil {
+		s.lineIndexes = make(map[protocol.DocumentURI]*lineIndex)
+	}
+	s.lineIndexes[uri] = newLineIndex(content)
+}
+
+// documentLines splits content into lines, reusing the line index when content is an open document
+// The text handed out by GetDocument shares its memory with the index, so the comparison
+// stops at the pointer check instead of comparing the text
+// The slice may be shared, callers must not modify it
+func (s *LanguageServer) documentLines(content string) []string {
+	s.mu.RLock()
+	for _, idx := range s.lineIndexes {
+		if len(idx.content) == len(content) && idx.content == content {
+			s.mu.RUnlock()
+			return idx.lines
+		}
+	}
+	s.mu.RUnlock()
+	return strings.Split(content, "\n")
+}
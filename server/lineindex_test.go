@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestLineIndex tests that the line index splits like strings.Split and maps positions to offsets
+func TestLineIndex(t *testing.T) {
+	for _, content := range []string{"", "one", "one\ntwo", "one\n\nthree\n"} {
+		idx := newLineIndex(content)
+		if want := strings.Split(content, "\n"); !reflect.DeepEqual(idx.Lines(), want) {
+			t.Errorf("lines of %q: expected %q, got %q", content, want, idx.Lines())
+		}
+	}
+
+	idx := newLineIndex("ab\ncdef\ng")
+	tests := []struct {
+		pos  protocol.Position
+		want int
+	}{
+		{protocol.Position{Line: 0, Character: 1}, 1},
+		{protocol.Position{Line: 1, Character: 2}, 5},
+		{protocol.Position{Line: 1, Character: 99}, 7},
+		{protocol.Position{Line: 9, Character: 0}, 9},
+	}
+	for _, tt := range tests {
+		if got := idx.Offset(tt.pos); got != tt.want {
+			t.Errorf("Offset(%v) = %d, expected %d", tt.pos, got, tt.want)
+		}
+	}
+	if line, ok := idx.Line(2); !ok || line != "g" {
+		t.Errorf("expected line 2 to be g, got %q", line)
+	}
+}
+
+// TestDocumentLines tests that open documents reuse their line index until closed
+func TestDocumentLines(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	uri := protocol.DocumentURI("file:///notes/20240101-graphs.tex")
+	ls.storeDocument(uri, "first\nsecond")
+
+	content, _ := ls.GetDocument(uri)
+	lines := ls.documentLines(content)
+	if len(lines) != 2 || &lines[0] != &ls.documentLines(content)[0] {
+		t.Fatalf("expected the open document's lines to be shared, got %q", lines)
+	}
+
+	ls.storeDocument(uri, "first\nsecond\nthird")
+	content, _ = ls.GetDocument(uri)
+	if lines := ls.documentLines(content); len(lines) != 3 {
+		t.Errorf("expected the index to follow the change, got %q", lines)
+	}
+
+	ls.DidClose(context.Background(), &protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if len(ls.lineIndexes) != 0 {
+		t.Errorf("expected closing to drop the line index, got %d", len(ls.lineIndexes))
+	}
+}
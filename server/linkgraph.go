@@ -0,0 +1,233 @@
+package server
+
+import (
+	"sort"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// LinkEdge is the set of references from one note to another
+type LinkEdge struct {
+	Source string
+	Target string
+	Ranges []protocol.Range // locations of the referenced slug in the source note
+}
+
+// Graph is the note graph: adjacency lists of slugs, with the positions of the references behind
+// each edge. The link index keeps the edges and the note index the nodes, so references to notes
+// that do not exist yet become edges as soon as the note appears
+// Queries only follow edges between indexed notes, and references of a note to itself are no edges
+type Graph struct {
+	mu    sync.RWMutex
+	nodes map[string]bool                        // slugs of the indexed notes
+	out   map[string]map[string][]protocol.Range // source -> target -> reference ranges
+	in    map[string]map[string]bool             // target -> sources
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		nodes: make(map[string]bool),
+		out:   make(map[string]map[string][]protocol.Range),
+		in:    make(map[string]map[string]bool),
+	}
+}
+
+// addNode adds an indexed note
+func (g *Graph) addNode(slug string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nodes[slug] = true
+}
+
+// removeNode drops a note no longer indexed; the edges of its references stay until they change
+func (g *Graph) removeNode(slug string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.nodes, slug)
+}
+
+// setEdges replaces the edges of source with those of its links
+func (g *Graph) setEdges(source string, links []Link) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.removeEdgesLocked(source)
+	targets := make(map[string][]protocol.Range)
+	for _, link := range links {
+		if link.Target != source {
+			targets[link.Target] = append(targets[link.Target], link.Range)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	g.out[source] = targets
+	for target := range targets {
+		if g.in[target] == nil {
+			g.in[target] = make(map[string]bool)
+		}
+		g.in[target][source] = true
+	}
+}
+
+// removeEdges drops the edges of source
+func (g *Graph) removeEdges(source string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.removeEdgesLocked(source)
+}
+
+// removeEdgesLocked drops the edges of source
+// Caller must hold the write lock
+func (g *Graph) removeEdgesLocked(source string) {
+	for target := range g.out[source] {
+		delete(g.in[target], source)
+		if len(g.in[target]) == 0 {
+			delete(g.in, target)
+		}
+	}
+	delete(g.out, source)
+}
+
+// Has reports whether slug is a note of the graph
+func (g *Graph) Has(slug string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.nodes[slug]
+}
+
+// Outgoing returns the edges from slug to other notes, by target
+func (g *Graph) Outgoing(slug string) []LinkEdge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var edges []LinkEdge
+	for target, ranges := range g.out[slug] {
+		if g.nodes[target] {
+			edges = append(edges, LinkEdge{Source: slug, Target: target, Ranges: append([]protocol.Range(nil), ranges...)})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].Target < edges[j].Target })
+	return edges
+}
+
+// Incoming returns the edges from other notes to slug, by source
+func (g *Graph) Incoming(slug string) []LinkEdge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var edges []LinkEdge
+	for source := range g.in[slug] {
+		if g.nodes[source] {
+			edges = append(edges, LinkEdge{Source: source, Target: slug, Ranges: append([]protocol.Range(nil), g.out[source][slug]...)})
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].Source < edges[j].Source })
+	return edges
+}
+
+// Neighbors returns the notes slug references or is referenced by, sorted
+func (g *Graph) Neighbors(slug string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.neighborsLocked(slug)
+}
+
+// neighborsLocked returns the neighbors of slug
+// Caller must hold the read lock
+func (g *Graph) neighborsLocked(slug string) []string {
+	seen := make(map[string]bool)
+	for target := range g.out[slug] {
+		if g.nodes[target] {
+			seen[target] = true
+		}
+	}
+	for source := range g.in[slug] {
+		if g.nodes[source] {
+			seen[source] = true
+		}
+	}
+	neighbors := make([]string, 0, len(seen))
+	for neighbor := range seen {
+		neighbors = append(neighbors, neighbor)
+	}
+	sort.Strings(neighbors)
+	return neighbors
+}
+
+// ShortestPath returns the notes on a shortest path from one note to another, both included,
+// following references in either direction. Returns nil when no path connects them
+// Among paths of the same length, the one through the first slugs in sort order is returned
+func (g *Graph) ShortestPath(from, to string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if !g.nodes[from] || !g.nodes[to] {
+		return nil
+	}
+
+	previous := map[string]string{from: ""}
+	frontier := []string{from}
+	for len(frontier) > 0 {
+		if _, reached := previous[to]; reached {
+			break
+		}
+		var next []string
+		for _, slug := range frontier {
+			for _, neighbor := range g.neighborsLocked(slug) {
+				if _, visited := previous[neighbor]; visited {
+					continue
+				}
+				previous[neighbor] = slug
+				next = append(next, neighbor)
+			}
+		}
+		frontier = next
+	}
+	if _, reached := previous[to]; !reached {
+		return nil
+	}
+
+	path := []string{to}
+	for slug := to; slug != from; {
+		slug = previous[slug]
+		path = append(path, slug)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Components returns the connected components of the graph, references followed in either
+// direction; notes without links form components of their own
+// Each component is sorted, and the components go from the largest to the smallest
+func (g *Graph) Components() [][]string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	slugs := make([]string, 0, len(g.nodes))
+	for slug := range g.nodes {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	visited := make(map[string]bool)
+	var components [][]string
+	for _, start := range slugs {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		component := []string{start}
+		for queue := []string{start}; len(queue) > 0; queue = queue[1:] {
+			for _, neighbor := range g.neighborsLocked(queue[0]) {
+				if !visited[neighbor] {
+					visited[neighbor] = true
+					component = append(component, neighbor)
+					queue = append(queue, neighbor)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	return components
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestGraph tests the note graph kept by the index and the related notes built on it
+func TestGraph(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-a.tex": "\\ref{b} \\ref{b} \\ref{gone} \\ref{a}",
+		"20240102-b.tex": "\\ref{c}",
+		"20240103-c.tex": "",
+		"20240104-d.tex": "\\ref{c}",
+		"20240105-e.tex": "",
+		"20240106-f.tex": "\\ref{later}",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+	graph := ls.index.Graph()
+
+	// References to missing notes and to the note itself are no edges
+	if edges := graph.Outgoing("a"); len(edges) != 1 || edges[0].Target != "b" || len(edges[0].Ranges) != 2 {
+		t.Errorf("unexpected edges of a %+v", edges)
+	}
+	if neighbors := fmt.Sprint(graph.Neighbors("c")); neighbors != "[b d]" {
+		t.Errorf("unexpected neighbors of c %s", neighbors)
+	}
+	if path := fmt.Sprint(graph.ShortestPath("a", "d")); path != "[a b c d]" {
+		t.Errorf("unexpected path %s", path)
+	}
+	if path := graph.ShortestPath("a", "e"); path != nil {
+		t.Errorf("expected no path to an unlinked note, got %v", path)
+	}
+	if components := fmt.Sprint(graph.Components()); components != "[[a b c d] [e] [f]]" {
+		t.Errorf("unexpected components %s", components)
+	}
+
+	// A note appearing picks up the references written before it existed
+	os.WriteFile(filepath.Join(tempDir, "20240107-later.tex"), []byte(""), 0644)
+	ls.updateIndexForFile(filepath.Join(tempDir, "20240107-later.tex"))
+	if components := fmt.Sprint(graph.Components()); components != "[[a b c d] [f later] [e]]" {
+		t.Errorf("unexpected components after adding a note %s", components)
+	}
+
+	related, err := ls.relatedNotesCommand(context.Background(), []interface{}{"b"})
+	if err != nil {
+		t.Fatalf("relatedNotesCommand failed: %v", err)
+	}
+	var got []string
+	for _, note := range related {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%v", note.Slug, note.Distance, note.Shared, note.Path))
+	}
+	if fmt.Sprint(got) != "[a:1:0:[b a] c:1:0:[b c] d:2:1:[b c d]]" {
+		t.Errorf("unexpected related notes %v", got)
+	}
+
+	var orphans []string
+	for _, orphan := range ls.orphans() {
+		orphans = append(orphans, orphan.Slug)
+	}
+	if fmt.Sprint(orphans) != "[a d e f]" {
+		t.Errorf("unexpected orphans %v", orphans)
+	}
+
+	ls.dropNote("c", "", "20240103-c.tex")
+	if path := graph.ShortestPath("a", "d"); path != nil {
+		t.Errorf("expected removing c to disconnect a and d, got %v", path)
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandFixDanglingReferences removes (or rewrites) every reference to a note across the vault
+// Arguments: [slug] to remove references, [slug, newSlug] to retarget them
+const commandFixDanglingReferences = "lx.fixDanglingReferences"
+
+// diagnosticCodeBrokenRef marks diagnostics for references to notes missing from the index
+const diagnosticCodeBrokenRef = "LX001"
+
+func init() {
+	registerCommand(commandFixDanglingReferences, withoutResult((*LanguageServer).fixDanglingReferences))
+	registerQuickFix(diagnosticCodeBrokenRef, removeReferenceFix)
+	registerQuickFix(diagnosticCodeBrokenRef, removeAllReferencesFix)
+}
+
+// publishBacklinkDiagnostics re-runs diagnostics on every note referencing slug
+// Used when a note appears or disappears so dangling references are flagged vault-wide
+func (s *LanguageServer) publishBacklinkDiagnostics(ctx context.Context, slug string) {
+	seen := make(map[string]bool)
+	for _, link := range s.index.Links().Incoming(slug) {
+		if seen[link.Filename] {
+			continue
+		}
+		seen[link.Filename] = true
+
+		uri := pathToURI(s.notePath(link.Filename))
+		content, err := s.GetDocument(uri)
+		if err != nil {
+			continue
+		}
+		s.publishDiagnostics(ctx, uri, content)
+	}
+}
+
+// danglingReferencesEdit builds a vault-wide edit removing references to slug,
+// or retargeting them to newSlug when it is non-empty
+func (s *LanguageServer) danglingReferencesEdit(slug, newSlug string) *protocol.WorkspaceEdit {
+	changes := make(map[protocol.DocumentURI][]protocol.TextEdit)
+
+	for _, link := range s.index.Links().Incoming(slug) {
+		if link.Source == slug {
+			continue // References inside the note itself go away with it
+		}
+		uri := pathToURI(s.notePath(link.Filename))
+		edit := protocol.TextEdit{Range: link.Full, NewText: ""}
+		if newSlug != "" {
+			edit = protocol.TextEdit{Range: link.Range, NewText: newSlug}
+		}
+		changes[uri] = append(changes[uri], edit)
+	}
+
+	return &protocol.WorkspaceEdit{Changes: changes}
+}
+
+// fixDanglingReferences applies danglingReferencesEdit through the client
+func (s *LanguageServer) fixDanglingReferences(ctx context.Context, args []interface{}) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s requires a slug argument", commandFixDanglingReferences)
+	}
+	slug, ok := args[0].(string)
+	if !ok || slug == "" {
+		return fmt.Errorf("%s: invalid slug argument", commandFixDanglingReferences)
+	}
+
+	newSlug := ""
+	if len(args) > 1 {
+		newSlug, _ = args[1].(string)
+	}
+
+	edit := s.danglingReferencesEdit(slug, newSlug)
+	if len(edit.Changes) == 0 {
+		return nil
+	}
+
+	// Half-applied cleanups are worse than none, so a cancelled fix touches nothing
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s cancelled: %w", commandFixDanglingReferences, err)
+	}
+
+	label := fmt.Sprintf("Remove references to '%s'", slug)
+	if newSlug != "" {
+		label = fmt.Sprintf("Retarget references from '%s' to '%s'", slug, newSlug)
+	}
+
+	if err := s.applyEdit(ctx, label, edit); err != nil {
+		return err
+	}
+	// The references are gone from backlinks right away, not once the watcher catches up
+	for uri := range edit.Changes {
+		s.reindexDocument(uri)
+	}
+	s.recordActivity(ctx, "references.fix", label)
+
+	return nil
+}
+
+// removeAllReferencesFix offers the vault-wide cleanup for a broken-reference diagnostic
+func removeAllReferencesFix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	slug := s.getSlugAtPosition(req.Content, diag.Range.Start)
+	if slug == "" {
+		return nil
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf("Remove all references to '%s' in vault", slug),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Command: &protocol.Command{
+				Title:     "Remove dangling references",
+				Command:   commandFixDanglingReferences,
+				Arguments: []interface{}{slug},
+			},
+		},
+	}
+}
+
+// removeReferenceFix offers to delete or comment out the single broken reference under a diagnostic
+func removeReferenceFix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	var link *Link
+	for _, candidate := range extractLinks(s.linkPattern(), "", "", req.Content) {
+		if candidate.Range.Start == diag.Range.Start {
+			link = &candidate
+			break
+		}
+	}
+	if link == nil {
+		return nil
+	}
+
+	lines := strings.Split(req.Content, "\n")
+	line := lines[link.Full.Start.Line]
+	command := line[link.Full.Start.Character:link.Full.End.Character]
+
+	// A % comments out the rest of the line, so text following the reference moves to the next line
+	// The comment also swallows that line break, leaving the paragraph intact
+	commented := "%" + command
+	if strings.TrimSpace(line[link.Full.End.Character:]) != "" {
+		commented += "\n"
+	}
+
+	edit := func(text string) *protocol.WorkspaceEdit {
+		return &protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				req.URI: {{Range: link.Full, NewText: text}},
+			},
+		}
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title:       "Remove reference",
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Edit:        edit(""),
+		},
+		{
+			Title:       "Comment out reference",
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Edit:        edit(commented),
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// builtinLinkMacros are the commands whose argument always names another note
+var builtinLinkMacros = []string{"ref", "cite", "input", "include"}
+
+// macroNamePattern matches a bare LaTeX command name
+var macroNamePattern = regexp.MustCompile(`^[A-Za-z@]+$`)
+
+// macroPatterns caches compiled macro patterns by macro list and argument suffix
+var macroPatterns sync.Map
+
+// macroPatternStats counts lookups of macroPatterns
+var macroPatternStats cacheCounter
+
+// macroPattern matches any of the given commands followed by suffix, which captures the argument
+func macroPattern(macros []string, suffix string) *regexp.Regexp {
+	key := strings.Join(macros, "|") + suffix
+	if cached, ok := macroPatterns.Load(key); ok {
+		macroPatternStats.hit()
+		return cached.(*regexp.Regexp)
+	}
+	macroPatternStats.miss()
+	quoted := make([]string, len(macros))
+	for i, macro := range macros {
+		quoted[i] = regexp.QuoteMeta(macro)
+	}
+	pattern := regexp.MustCompile(`\\(?:` + strings.Join(quoted, "|") + `)` + suffix)
+	macroPatterns.Store(key, pattern)
+	return pattern
+}
+
+// referenceMacros returns the configured custom reference commands as bare names
+// Accepts "lxlink", "\lxlink" and "\lxlink{}"; anything else is ignored
+func (s *LanguageServer) referenceMacros() []string {
+	var macros []string
+	for _, macro := range s.settings().ReferenceMacros {
+		name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(macro), "\\"), "{}")
+		if macroNamePattern.MatchString(name) {
+			macros = append(macros, name)
+		}
+	}
+	return macros
+}
+
+// linkPattern matches note references that point at another note, including custom reference macros
+func (s *LanguageServer) linkPattern() *regexp.Regexp {
+	return macroPattern(append(append([]string{}, builtinLinkMacros...), s.referenceMacros()...), `\{([^}]+)\}`)
+}
+
+// Link represents a single reference from one note to another
+type Link struct {
+	Source   string         // slug of the note containing the reference
+	Filename string         // filename of the note containing the reference
+	Target   string         // slug being referenced
+	Alias    string         // alias the reference names Target by, empty when it names the slug
+	Anchor   string         // section anchor of slug#section references, empty otherwise
+	Range    protocol.Range // location of the slug inside the source note
+	Full     protocol.Range // location of the whole reference command
+}
+
+// LinkIndex is a reverse-link index mapping notes to the references pointing at them
+type LinkIndex struct {
+	mu       sync.RWMutex
+	outgoing map[string][]Link // source slug -> links
+	incoming map[string][]Link // target slug -> links
+	graph    *Graph            // note graph, its edges following the links
+}
+
+func NewLinkIndex() *LinkIndex {
+	return &LinkIndex{
+		outgoing: make(map[string][]Link),
+		incoming: make(map[string][]Link),
+		graph:    NewGraph(),
+	}
+}
+
+// Graph returns the note graph built from the links
+func (l *LinkIndex) Graph() *Graph {
+	return l.graph
+}
+
+// Set replaces all outgoing links of a source note
+func (l *LinkIndex) Set(source string, links []Link) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeLocked(source)
+	l.graph.setEdges(source, links)
+	if len(links) == 0 {
+		return
+	}
+	l.outgoing[source] = links
+	for _, link := range links {
+		l.incoming[link.Target] = append(l.incoming[link.Target], link)
+	}
+}
+
+// Delete removes all outgoing links of a source note
+func (l *LinkIndex) Delete(source string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeLocked(source)
+	l.graph.removeEdges(source)
+}
+
+// removeLocked drops the outgoing links of source from both maps
+// Caller must hold the write lock
+func (l *LinkIndex) removeLocked(source string) {
+	for _, link := range l.outgoing[source] {
+		kept := l.incoming[link.Target][:0]
+		for _, in := range l.incoming[link.Target] {
+			if in.Source != source {
+				kept = append(kept, in)
+			}
+		}
+		if len(kept) == 0 {
+			delete(l.incoming, link.Target)
+		} else {
+			l.incoming[link.Target] = kept
+		}
+	}
+	delete(l.outgoing, source)
+}
+
+// Incoming returns every link pointing at the target slug
+func (l *LinkIndex) Incoming(target string) []Link {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	links := make([]Link, len(l.incoming[target]))
+	copy(links, l.incoming[target])
+	return links
+}
+
+// IncomingSections returns the links pointing at sections of the target slug, by anchor
+func (l *LinkIndex) IncomingSections(target string) map[string][]Link {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	sections := make(map[string][]Link)
+	for _, link := range l.incoming[target] {
+		if link.Anchor != "" {
+			sections[link.Anchor] = append(sections[link.Anchor], link)
+		}
+	}
+	return sections
+}
+
+// Outgoing returns every link contained in the source note
+func (l *LinkIndex) Outgoing(source string) []Link {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	links := make([]Link, len(l.outgoing[source]))
+	copy(links, l.outgoing[source])
+	return links
+}
+
+// extractLinks scans note content for references to other notes
+func extractLinks(pattern *regexp.Regexp, source, filename, content string) []Link {
+	var links []Link
+
+	lines := strings.Split(content, "\n")
+	for lineNum, line := range lines {
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+
+		matches := pattern.FindAllStringSubmatchIndex(line, -1)
+		for _, match := range matches {
+			// The range covers the slug only, so rewriting it keeps a #section anchor and a vault/ qualifier
+			start, end := match[2], match[3]
+			if hash := strings.Index(line[start:end], "#"); hash >= 0 {
+				end = start + hash
+			}
+			_, anchor := splitAnchor(line[match[2]:match[3]])
+			root, target := splitQualified(normalizeSlug(line[match[2]:match[3]]))
+			if root != "" {
+				start += len(root) + 1
+			}
+			links = append(links, Link{
+				Source:   source,
+				Filename: filename,
+				Target:   target,
+				Anchor:   anchor,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: uint32(lineNum), Character: uint32(start)},
+					End:   protocol.Position{Line: uint32(lineNum), Character: uint32(end)},
+				},
+				Full: protocol.Range{
+					Start: protocol.Position{Line: uint32(lineNum), Character: uint32(match[0])},
+					End:   protocol.Position{Line: uint32(lineNum), Character: uint32(match[1])},
+				},
+			})
+		}
+	}
+
+	return links
+}
+
+// normalizeSlug converts a raw reference argument into a note slug, dropping a #section anchor
+func normalizeSlug(raw string) string {
+	slug, _ := splitAnchor(raw)
+	slug = strings.TrimSuffix(slug, ".tex")
+	slug = strings.TrimPrefix(slug, "../notes/")
+	return slug
+}
+
+// splitAnchor splits a reference argument of the form slug#section into the raw slug and the anchor
+func splitAnchor(raw string) (string, string) {
+	slug, anchor, _ := strings.Cut(strings.TrimSpace(raw), "#")
+	return strings.TrimSpace(slug), strings.TrimSpace(anchor)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// logLevels maps the logLevel setting to the most verbose message type sent to the client
+var logLevels = map[string]protocol.MessageType{
+	"off":     0,
+	"error":   protocol.MessageTypeError,
+	"warning": protocol.MessageTypeWarning,
+	"info":    protocol.MessageTypeInfo,
+	"debug":   protocol.MessageTypeLog,
+}
+
+// defaultLogLevel applies when logLevel is unset or unknown
+const defaultLogLevel = "info"
+
+// logLevel returns the most verbose message type the client asked for
+func (s *LanguageServer) logLevel() protocol.MessageType {
+	if level, ok := logLevels[strings.ToLower(s.settings().LogLevel)]; ok {
+		return level
+	}
+	return logLevels[defaultLogLevel]
+}
+
+// logf sends a message to the client's log through window/logMessage, if the log level lets it through
+// Message types are ordered from error (1) to log (4), so lower types are more severe
+func (s *LanguageServer) logf(messageType protocol.MessageType, format string, args ...interface{}) {
+	if s.conn == nil || messageType > s.logLevel() {
+		return
+	}
+	s.conn.Notify(context.Background(), protocol.MethodWindowLogMessage, &protocol.LogMessageParams{
+		Type:    messageType,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
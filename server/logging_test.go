@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// logClient connects a server to a client recording its window/logMessage notifications
+func logClient(t *testing.T, ls *LanguageServer) <-chan protocol.LogMessageParams {
+	serverSide, clientSide := net.Pipe()
+	messages := make(chan protocol.LogMessageParams, 16)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.LogMessageParams
+		if req.Method() == protocol.MethodWindowLogMessage && json.Unmarshal(req.Params(), &params) == nil {
+			messages <- params
+		}
+		return reply(ctx, nil, nil)
+	})
+	ls.conn = jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide))
+	t.Cleanup(func() {
+		client.Close()
+		serverSide.Close()
+	})
+	return messages
+}
+
+// TestLogf tests that messages below the configured level are dropped
+func TestLogf(t *testing.T) {
+	config := DefaultConfig()
+	config.LogLevel = "warning"
+	ls := &LanguageServer{index: NewIndex(), config: &config}
+	messages := logClient(t, ls)
+
+	ls.logf(protocol.MessageTypeInfo, "dropped")
+	ls.logf(protocol.MessageTypeError, "failed: %d", 1)
+	if message := <-messages; message.Type != protocol.MessageTypeError || message.Message != "failed: 1" {
+		t.Errorf("unexpected message %+v", message)
+	}
+
+	config.LogLevel = "off"
+	ls.logf(protocol.MessageTypeError, "dropped")
+	config.LogLevel = "DEBUG"
+	ls.logf(protocol.MessageTypeLog, "detail")
+	if message := <-messages; message.Type != protocol.MessageTypeLog || message.Message != "detail" {
+		t.Errorf("expected only the debug message after the level changed, got %+v", message)
+	}
+
+	// Without a client there is nowhere to log to
+	(&LanguageServer{}).logf(protocol.MessageTypeError, "ignored")
+}
+
+// TestLogUnreadableNote tests that notes failing to load are reported instead of skipped silently
+func TestLogUnreadableNote(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "20240101-a.tex"), []byte("%% Metadata\n% title: A\n"), 0644)
+	os.Symlink(filepath.Join(tempDir, "missing"), filepath.Join(tempDir, "20240102-broken.tex"))
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex()}
+	messages := logClient(t, ls)
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	message := <-messages
+	if message.Type != protocol.MessageTypeWarning || !strings.Contains(message.Message, "20240102-broken.tex") {
+		t.Errorf("unexpected message %+v", message)
+	}
+	if ls.index.Count() != 1 {
+		t.Errorf("expected the readable note to be indexed, got %d notes", ls.index.Count())
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandUnlinkedMentions lists the places other notes mention a note's title without referencing it
+// Arguments: [slug]
+const commandUnlinkedMentions = "lx.unlinkedMentions"
+
+func init() {
+	registerCommand(commandUnlinkedMentions, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("%s requires a slug", commandUnlinkedMentions)
+		}
+		slug, ok := args[0].(string)
+		if !ok || slug == "" {
+			return nil, fmt.Errorf("%s: invalid slug argument", commandUnlinkedMentions)
+		}
+		return s.unlinkedMentions(slug)
+	})
+}
+
+// UnlinkedMention is a line of another note naming a note without referencing it
+type UnlinkedMention struct {
+	Slug  string               `json:"slug"`
+	Title string               `json:"title"`
+	URI   protocol.DocumentURI `json:"uri"`
+	Range protocol.Range       `json:"range"`
+	Line  string               `json:"line"`
+}
+
+// unlinkedMentions finds the title of a note in notes that do not reference it
+// Candidates come from the full-text index; only whole-word matches of the full title are kept
+func (s *LanguageServer) unlinkedMentions(slug string) ([]UnlinkedMention, error) {
+	note, exists := s.index.Get(slug)
+	if !exists {
+		return nil, fmt.Errorf("note '%s' not found", slug)
+	}
+
+	words := strings.Fields(note.Title)
+	if len(tokenList(note.Title)) == 0 {
+		return []UnlinkedMention{}, nil
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	pattern := regexp.MustCompile(`(?i)\b` + strings.Join(words, `\s+`) + `\b`)
+
+	linked := make(map[string]bool)
+	for _, link := range s.index.Links().Incoming(slug) {
+		linked[link.Source] = true
+	}
+
+	mentions := []UnlinkedMention{}
+	for candidate := range s.index.Search().Search(note.Title) {
+		if candidate == slug || linked[candidate] {
+			continue
+		}
+		source, exists := s.index.Get(candidate)
+		if !exists {
+			continue
+		}
+		uri := pathToURI(s.notePath(source.Filename))
+		content, err := s.GetDocument(uri)
+		if err != nil {
+			continue
+		}
+
+		blockStart, blockEnd, hasBlock := s.metadataParser().FindBlock(content)
+		for lineNum, line := range strings.Split(content, "\n") {
+			if (hasBlock && lineNum >= blockStart && lineNum <= blockEnd) || strings.HasPrefix(strings.TrimSpace(line), "%") {
+				continue
+			}
+			for _, match := range pattern.FindAllStringIndex(line, -1) {
+				mentions = append(mentions, UnlinkedMention{
+					Slug:  candidate,
+					Title: source.Title,
+					URI:   uri,
+					Range: lineRange(lineNum, match[0], match[1]),
+					Line:  strings.TrimSpace(line),
+				})
+			}
+		}
+	}
+
+	sort.Slice(mentions, func(i, j int) bool {
+		a, b := mentions[i], mentions[j]
+		if a.Slug != b.Slug {
+			return a.Slug < b.Slug
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Character < b.Range.Start.Character
+	})
+	return mentions, nil
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestUnlinkedMentions tests finding a note's title in notes that do not reference it
+func TestUnlinkedMentions(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graph-theory.tex": "%% Metadata\n% title: Graph Theory\n\nGraph theory studies graphs.\n",
+		"20240102-trees.tex":        "%% Metadata\n% title: Trees\n\nTrees are studied in graph\ntheory, as in \\ref{graph-theory}.\n",
+		"20240103-paths.tex":        "%% Metadata\n% title: Paths\n\nPaths come from Graph  Theory.\n% graph theory in a comment\nNot graph theorymatic.\n",
+		"20240104-sets.tex":         "%% Metadata\n% title: Sets\n\nSets, graphs, theory.\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	mentions, err := ls.unlinkedMentions("graph-theory")
+	if err != nil {
+		t.Fatalf("unlinkedMentions failed: %v", err)
+	}
+	if len(mentions) != 1 || mentions[0].Slug != "paths" || mentions[0].Range != lineRange(3, 16, 29) {
+		t.Fatalf("expected only the mention in paths, got %+v", mentions)
+	}
+
+	// Mentions typed in an open buffer count before saving
+	uri := pathToURI(filepath.Join(tempDir, "20240104-sets.tex"))
+	ls.documents[uri] = files["20240104-sets.tex"] + "See graph theory.\n"
+	ls.reindexDocument(uri)
+	if mentions, _ := ls.unlinkedMentions("graph-theory"); len(mentions) != 2 || mentions[1].Slug != "sets" {
+		t.Errorf("expected the unsaved mention, got %+v", mentions)
+	}
+
+	if _, err := ls.unlinkedMentions("missing"); err == nil {
+		t.Error("expected an error for an unknown note")
+	}
+}
@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// commandMergeNotes merges one note into another and deletes it
+// Arguments: [sourceSlug, targetSlug] or [sourceSlug, targetSlug, {field: value}] to resolve conflicts
+// Conflicting metadata is returned as a MergeResult instead of picking a side; the client
+// prompts the user and calls the command again with the resolutions
+const commandMergeNotes = "lx.mergeNotes"
+
+func init() {
+	registerCommand(commandMergeNotes, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.mergeNotesCommand(ctx, args)
+	})
+}
+
+// MergeConflict is a metadata field the two notes disagree on
+type MergeConflict struct {
+	Field   string   `json:"field"`
+	Source  string   `json:"source"`
+	Target  string   `json:"target"`
+	Options []string `json:"options"`
+}
+
+// MergeResult is returned by lx.mergeNotes
+type MergeResult struct {
+	Merged    bool            `json:"merged"`
+	Source    string          `json:"source"`
+	Target    string          `json:"target"`
+	Conflicts []MergeConflict `json:"conflicts,omitempty"`
+}
+
+// mergeNotesCommand handles lx.mergeNotes
+func (s *LanguageServer) mergeNotesCommand(ctx context.Context, args []interface{}) (*MergeResult, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s requires source and target slugs", commandMergeNotes)
+	}
+	sourceSlug, _ := args[0].(string)
+	targetSlug, _ := args[1].(string)
+	if sourceSlug == "" || targetSlug == "" || sourceSlug == targetSlug {
+		return nil, fmt.Errorf("%s: invalid source or target", commandMergeNotes)
+	}
+
+	resolutions := make(map[string]string)
+	if len(args) > 2 {
+		raw, ok := args[2].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: resolutions must be an object", commandMergeNotes)
+		}
+		for field, value := range raw {
+			resolutions[field] = fmt.Sprint(value)
+		}
+	}
+
+	source, exists := s.index.Get(sourceSlug)
+	if !exists {
+		return nil, fmt.Errorf("note '%s' not found", sourceSlug)
+	}
+	target, exists := s.index.Get(targetSlug)
+	if !exists {
+		return nil, fmt.Errorf("note '%s' not found", targetSlug)
+	}
+
+	edit, conflicts, err := s.mergeEdit(source, target, resolutions)
+	if err != nil {
+		return nil, err
+	}
+	result := &MergeResult{Source: sourceSlug, Target: targetSlug, Conflicts: conflicts}
+	if len(conflicts) > 0 {
+		return result, nil
+	}
+
+	label := fmt.Sprintf("Merge '%s' into '%s'", sourceSlug, targetSlug)
+	if err := s.applyEdit(ctx, label, edit); err != nil {
+		return nil, err
+	}
+	if err := s.deleteNote(ctx, source); err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, "note.merge", label)
+
+	result.Merged = true
+	return result, nil
+}
+
+// mergeEdit builds the edit merging source into target
+// Unresolved conflicts are returned instead of an edit
+func (s *LanguageServer) mergeEdit(source, target *NoteHeader, resolutions map[string]string) (*protocol.WorkspaceEdit, []MergeConflict, error) {
+	sourceURI := pathToURI(s.notePath(source.Filename))
+	targetURI := pathToURI(s.notePath(target.Filename))
+
+	sourceText, err := s.GetDocument(sourceURI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read note: %w", err)
+	}
+	targetText, err := s.GetDocument(targetURI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read note: %w", err)
+	}
+
+	sourceMeta, err := s.metadataParser().Parse(sourceText)
+	if err != nil {
+		return nil, nil, fmt.Errorf("note '%s': %w", source.Slug, err)
+	}
+	targetMeta, err := s.metadataParser().Parse(targetText)
+	if err != nil {
+		return nil, nil, fmt.Errorf("note '%s': %w", target.Slug, err)
+	}
+
+	merged, conflicts, err := mergeMetadata(sourceMeta.Metadata, targetMeta.Metadata, source.Slug, resolutions)
+	if err != nil || len(conflicts) > 0 {
+		return nil, conflicts, err
+	}
+
+	lines := strings.Split(targetText, "\n")
+	changes := map[protocol.DocumentURI][]protocol.TextEdit{
+		targetURI: {{
+			Range:   protocol.Range{End: lineEnd(lines, len(lines)-1)},
+			NewText: mergeContent(s.linkPattern(), targetText, sourceText, merged, source.Slug, target.Slug),
+		}},
+	}
+
+	// Other notes now point at the target; the merged notes were rewritten above
+	for uri, edits := range s.danglingReferencesEdit(source.Slug, target.Slug).Changes {
+		if uri != sourceURI && uri != targetURI {
+			changes[uri] = edits
+		}
+	}
+
+	return &protocol.WorkspaceEdit{Changes: changes}, nil, nil
+}
+
+// mergeMetadata combines the metadata of two notes, keeping the target's title and the union of tags
+// and aliases; the source's slug becomes an alias too, so references the merge does not rewrite
+// still resolve. Dates, statuses and compile profiles that differ need a resolution
+func mergeMetadata(source, target *metadata.Metadata, sourceSlug string, resolutions map[string]string) (*metadata.Metadata, []MergeConflict, error) {
+	merged := &metadata.Metadata{
+		Title:       target.Title,
+		Date:        target.Date,
+		Tags:        append([]string{}, target.Tags...),
+		Status:      target.Status,
+		Engine:      target.Engine,
+		CompileArgs: target.CompileArgs,
+	}
+	// The merged note was last edited whenever either of them was
+	merged.Modified = target.Modified
+	if source.Modified > merged.Modified {
+		merged.Modified = source.Modified
+	}
+
+	seen := make(map[string]bool)
+	for _, tag := range target.Tags {
+		seen[strings.ToLower(tag)] = true
+	}
+	for _, tag := range source.Tags {
+		if !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			merged.Tags = append(merged.Tags, tag)
+		}
+	}
+
+	aliases := make(map[string]bool)
+	for _, alias := range append(append(append([]string{}, target.Aliases...), source.Aliases...), sourceSlug) {
+		if alias != "" && !aliases[strings.ToLower(alias)] {
+			aliases[strings.ToLower(alias)] = true
+			merged.Aliases = append(merged.Aliases, alias)
+		}
+	}
+
+	fields := []struct {
+		name           string
+		source, target string
+		value          *string
+	}{
+		{"date", source.Date, target.Date, &merged.Date},
+		{"status", source.Status, target.Status, &merged.Status},
+		{"engine", source.Engine, target.Engine, &merged.Engine},
+		{"compileargs", source.CompileArgs, target.CompileArgs, &merged.CompileArgs},
+	}
+
+	var conflicts []MergeConflict
+	for _, field := range fields {
+		switch {
+		case field.source == field.target || field.source == "":
+			continue
+		case field.target == "":
+			*field.value = field.source
+			continue
+		}
+
+		resolution, resolved := resolutions[field.name]
+		if !resolved {
+			conflicts = append(conflicts, MergeConflict{
+				Field:   field.name,
+				Source:  field.source,
+				Target:  field.target,
+				Options: []string{field.target, field.source},
+			})
+			continue
+		}
+		if resolution != field.source && resolution != field.target {
+			return nil, nil, fmt.Errorf("%s: '%s' is not a valid %s, expected '%s' or '%s'", commandMergeNotes, resolution, field.name, field.target, field.source)
+		}
+		*field.value = resolution
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Field < conflicts[j].Field })
+	return merged, conflicts, nil
+}
+
+// mergeContent appends the body of source to target under the merged metadata
+// References to the source inside either note are retargeted
+func mergeContent(pattern *regexp.Regexp, targetText, sourceText string, merged *metadata.Metadata, sourceSlug, targetSlug string) string {
+	text := metadata.Update(targetText, merged)
+	section := fmt.Sprintf("%% Merged from %s\n%s", sourceSlug, noteBody(sourceText))
+
+	lines := strings.Split(text, "\n")
+	insertAt := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), `\end{document}`) {
+			insertAt = i
+			break
+		}
+	}
+	if insertAt == len(lines) {
+		text = strings.TrimRight(text, "\n") + "\n\n" + section + "\n"
+	} else {
+		text = strings.Join(lines[:insertAt], "\n") + "\n\n" + section + "\n" + strings.Join(lines[insertAt:], "\n")
+	}
+
+	return retargetLinks(pattern, text, sourceSlug, targetSlug)
+}
+
+// noteBody returns the content of a note between \begin{document} and \end{document}
+// Notes without a document environment contribute everything after the metadata block
+func noteBody(content string) string {
+	lines := strings.Split(content, "\n")
+	start, end := 0, len(lines)
+	if _, blockEnd, found := metadata.FindBlock(content); found {
+		start = blockEnd + 1
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, `\begin{document}`) {
+			start = i + 1
+		} else if strings.HasPrefix(trimmed, `\end{document}`) {
+			end = i
+		}
+	}
+	if start > end {
+		return ""
+	}
+	return strings.Trim(strings.Join(lines[start:end], "\n"), "\n")
+}
+
+// retargetLinks rewrites the references to one slug in text
+func retargetLinks(pattern *regexp.Regexp, text, from, to string) string {
+	lines := strings.Split(text, "\n")
+	links := extractLinks(pattern, "", "", text)
+	// Back to front so earlier ranges on the same line stay valid
+	for i := len(links) - 1; i >= 0; i-- {
+		link := links[i]
+		if link.Target != from {
+			continue
+		}
+		line := lines[link.Range.Start.Line]
+		lines[link.Range.Start.Line] = line[:link.Range.Start.Character] + to + line[link.Range.End.Character:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestMergeNotes tests conflict reporting and the merge edit
+func TestMergeNotes(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	files := map[string]string{
+		"20240101-graphs.tex":   "%% Metadata\n%% title: Graphs\n%% date: 2024-01-01\n%% tags: math\n%% aliases: g, vert\n%% status: final\n\n\\begin{document}\nGraphs, see \\ref{vertices}.\n\\end{document}",
+		"20240102-vertices.tex": "%% Metadata\n%% title: Vertices\n%% date: 2024-01-02\n%% tags: Math, nodes\n%% aliases: vert, v\n%% status: draft\n\n\\begin{document}\nA vertex, unlike \\ref{graphs}.\n\\end{document}",
+		"20240103-other.tex":    "%% Metadata\n%% title: Other\n\nSee \\ref{vertices}.",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(notesPath, name), []byte(content), 0644)
+	}
+
+	ls := &LanguageServer{vault: &vault.Vault{RootPath: tempDir, NotesPath: notesPath}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	// Without resolutions the conflicts come back and nothing changes
+	result, err := ls.mergeNotesCommand(context.Background(), []interface{}{"vertices", "graphs"})
+	if err != nil {
+		t.Fatalf("mergeNotesCommand failed: %v", err)
+	}
+	if result.Merged || len(result.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts and no merge, got %+v", result)
+	}
+	date := result.Conflicts[0]
+	if date.Field != "date" || date.Source != "2024-01-02" || date.Target != "2024-01-01" {
+		t.Errorf("unexpected date conflict %+v", date)
+	}
+	if _, err := os.Stat(filepath.Join(notesPath, "20240102-vertices.tex")); err != nil {
+		t.Error("expected source note to remain until conflicts are resolved")
+	}
+
+	// Resolutions must pick one side
+	source, _ := ls.index.Get("vertices")
+	target, _ := ls.index.Get("graphs")
+	if _, _, err := ls.mergeEdit(source, target, map[string]string{"date": "2024-01-02", "status": "review"}); err == nil {
+		t.Error("expected error for a resolution matching neither side")
+	}
+
+	edit, conflicts, err := ls.mergeEdit(source, target, map[string]string{"date": "2024-01-02", "status": "final"})
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("expected resolved merge, got %v, %+v", err, conflicts)
+	}
+	if len(edit.Changes) != 2 {
+		t.Fatalf("expected edits to the target and the referencing note, got %d", len(edit.Changes))
+	}
+
+	merged := edit.Changes[pathToURI(filepath.Join(notesPath, "20240101-graphs.tex"))][0].NewText
+	expected := "%% Metadata\n%% title: Graphs\n%% date: 2024-01-02\n%% tags: math, nodes\n%% aliases: g, vert, v, vertices\n%% status: final\n\\begin{document}\nGraphs, see \\ref{graphs}.\n\n% Merged from vertices\nA vertex, unlike \\ref{graphs}.\n\\end{document}"
+	if merged != expected {
+		t.Errorf("unexpected merged note:\n%s\nwant:\n%s", merged, expected)
+	}
+
+	other := edit.Changes[pathToURI(filepath.Join(notesPath, "20240103-other.tex"))]
+	if len(other) != 1 || other[0].NewText != "graphs" {
+		t.Errorf("expected reference in other note to be retargeted, got %+v", other)
+	}
+}
+
+// TestNoteBody tests body extraction for merging
+func TestNoteBody(t *testing.T) {
+	if body := noteBody("%% Metadata\n%% title: A\n\nLoose text\n"); strings.TrimSpace(body) != "Loose text" {
+		t.Errorf("expected text after metadata, got %q", body)
+	}
+	if body := noteBody("\\documentclass{article}\n\\begin{document}\nInside\n\\end{document}"); body != "Inside" {
+		t.Errorf("expected document body, got %q", body)
+	}
+}
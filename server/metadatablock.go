@@ -0,0 +1,75 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+func init() {
+	registerCodeActionProvider(moveMetadataBlockAction)
+}
+
+// metadataParser returns a lenient parser honouring the configured metadata scope and date formats
+func (s *LanguageServer) metadataParser() *metadata.Parser {
+	scope, err := metadata.ParseScope(s.settings().MetadataScope)
+	if err != nil {
+		scope = metadata.ScopePreamble
+	}
+	parser := metadata.NewParser(false).WithScope(scope)
+	parser, _ = parser.WithDateFormats(s.settings().DateFormats...) // Validated by parseConfig
+	return parser
+}
+
+// moveMetadataBlockAction offers to move a metadata block found further down to the top of the file
+// Only offered when the requested range touches the block
+func moveMetadataBlockAction(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction {
+	start, end, found := s.metadataParser().FindBlock(req.Content)
+	if !found || int(req.Range.End.Line) < start || int(req.Range.Start.Line) > end {
+		return nil
+	}
+
+	lines := strings.Split(req.Content, "\n")
+	if firstContentLine(lines) >= start {
+		return nil // Already at the top
+	}
+
+	// Take a blank line following the block along with it
+	removeEnd := end + 1
+	if removeEnd < len(lines) && strings.TrimSpace(lines[removeEnd]) == "" {
+		removeEnd++
+	}
+	block := strings.Join(lines[start:end+1], "\n") + "\n\n"
+
+	return []protocol.CodeAction{
+		{
+			Title: "Move metadata block to top of file",
+			Kind:  protocol.RefactorRewrite,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					req.URI: {
+						{Range: lineRange(0, 0, 0), NewText: block},
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: uint32(start), Character: 0},
+								End:   protocol.Position{Line: uint32(removeEnd), Character: 0},
+							},
+							NewText: "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// firstContentLine returns the index of the first line that is not blank
+func firstContentLine(lines []string) int {
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return i
+		}
+	}
+	return len(lines)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestMoveMetadataBlockAction tests moving a mid-file metadata block to the top
+func TestMoveMetadataBlockAction(t *testing.T) {
+	ls := &LanguageServer{index: NewIndex()}
+
+	content := "\\documentclass{article}\n%% Metadata\n% title: Late\n\n\\begin{document}\n\\end{document}"
+	req := &codeActionRequest{
+		URI:     "file:///note.tex",
+		Content: content,
+		Range:   lineRange(2, 0, 0),
+	}
+
+	actions := moveMetadataBlockAction(ls, req)
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(actions))
+	}
+
+	edits := actions[0].Edit.Changes[req.URI]
+	if len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got %d", len(edits))
+	}
+	if edits[0].NewText != "%% Metadata\n% title: Late\n\n" {
+		t.Errorf("unexpected inserted block %q", edits[0].NewText)
+	}
+	expected := protocol.Range{
+		Start: protocol.Position{Line: 1, Character: 0},
+		End:   protocol.Position{Line: 4, Character: 0},
+	}
+	if edits[1].Range != expected {
+		t.Errorf("expected block and blank line removed, got %+v", edits[1].Range)
+	}
+
+	// Not offered away from the block
+	req.Range = lineRange(5, 0, 0)
+	if actions := moveMetadataBlockAction(ls, req); len(actions) != 0 {
+		t.Errorf("expected no action outside the block, got %d", len(actions))
+	}
+
+	// Not offered when the block is already at the top
+	req.Content = "%% Metadata\n% title: Top\n\\documentclass{article}"
+	req.Range = lineRange(0, 0, 0)
+	if actions := moveMetadataBlockAction(ls, req); len(actions) != 0 {
+		t.Errorf("expected no action for top block, got %d", len(actions))
+	}
+}
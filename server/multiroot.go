@@ -0,0 +1,189 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeAmbiguousRef marks a reference to a slug that notes of several vaults use
+const diagnosticCodeAmbiguousRef = "LX015"
+
+// noteDirOf returns the Dir of the note at path, "" for the top of the vault's notes directory
+func (s *LanguageServer) noteDirOf(path string) string {
+	if filepath.Dir(path) == filepath.Clean(s.vault.NotesPath) {
+		return ""
+	}
+	return filepath.Dir(path)
+}
+
+// headerPath returns the path of a note, including notes whose slug a note of another directory holds
+func (s *LanguageServer) headerPath(note *NoteHeader) string {
+	if note.Dir != "" {
+		return filepath.Join(note.Dir, note.Filename)
+	}
+	return s.vault.GetNotePath(note.Filename)
+}
+
+// notesDirName names the vault or folder owning a notes directory: the vault's folder for the
+// notes directory of an lx vault, the folder itself otherwise
+func notesDirName(dir string) string {
+	dir = filepath.Clean(dir)
+	if vaultAt(filepath.Dir(dir)).NotesPath == dir {
+		return filepath.Base(filepath.Dir(dir))
+	}
+	return filepath.Base(dir)
+}
+
+// rootName names the vault or workspace folder a directory of notes belongs to, "" meaning the vault's own notes
+func (s *LanguageServer) rootName(dir string) string {
+	root := s.vault.NotesPath
+	if dir != "" {
+		for _, folder := range s.workspaceFolders() {
+			if inTree(dir, folder) && len(folder) > len(root) {
+				root = folder
+			}
+		}
+	}
+	return notesDirName(root)
+}
+
+// noteRoot names the vault or workspace folder a note belongs to
+func (s *LanguageServer) noteRoot(note *NoteHeader) string {
+	return s.rootName(note.Dir)
+}
+
+// documentRoot names the vault or workspace folder of an open document
+func (s *LanguageServer) documentRoot(uri protocol.DocumentURI) string {
+	return s.rootName(s.noteDirOf(uriToPath(uri)))
+}
+
+// multiRoot reports whether notes of more than one vault or folder are managed
+func (s *LanguageServer) multiRoot() bool {
+	return len(s.workspaceFolders()) > 0
+}
+
+// splitQualified splits a vault/slug reference; unqualified references return an empty vault
+// Notes of vaults opened together as workspace folders may share a slug, and naming the vault
+// tells which of them a reference means
+func splitQualified(ref string) (string, string) {
+	if root, slug, ok := strings.Cut(ref, "/"); ok {
+		return root, slug
+	}
+	return "", ref
+}
+
+// resolveNote finds the note a reference argument names, already normalized by normalizeSlug
+// A qualified reference only matches the note of its vault; a bare slug shared by several vaults
+// prefers the vault of from, when given. Bare references matching no slug may name a note by alias
+func (s *LanguageServer) resolveNote(ref string, from protocol.DocumentURI) (*NoteHeader, bool) {
+	note, exists := s.resolveSlug(ref, from)
+	if !exists && !strings.Contains(ref, "/") {
+		return s.aliasTarget(ref)
+	}
+	return note, exists
+}
+
+// resolveSlug finds the note using the slug a reference argument names
+func (s *LanguageServer) resolveSlug(ref string, from protocol.DocumentURI) (*NoteHeader, bool) {
+	root, slug := splitQualified(ref)
+	if root == "" && from == "" {
+		return s.index.Get(slug)
+	}
+
+	variants := s.index.Variants(slug)
+	if root == "" {
+		if len(variants) > 1 {
+			root = s.documentRoot(from)
+		} else {
+			return s.index.Get(slug)
+		}
+	}
+	for _, note := range variants {
+		if s.noteRoot(note) == root {
+			return note, true
+		}
+	}
+	if ref == slug {
+		return s.index.Get(slug)
+	}
+	return nil, false
+}
+
+// sharedSlugRoots names the vaults whose notes use slug, sorted, when there are several
+func (s *LanguageServer) sharedSlugRoots(slug string) []string {
+	variants := s.index.Variants(slug)
+	if len(variants) < 2 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var roots []string
+	for _, note := range variants {
+		if root := s.noteRoot(note); !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	sort.Strings(roots)
+	if len(roots) < 2 {
+		return nil
+	}
+	return roots
+}
+
+// qualifiedRefCompletions offers one item per vault for a slug notes of several vaults use, as
+// vault/slug, the vault of the document being edited first
+// Returns nil for slugs only one vault uses
+func (s *LanguageServer) qualifiedRefCompletions(note *NoteHeader, sortPrefix, ownRoot string) []protocol.CompletionItem {
+	if s.sharedSlugRoots(note.Slug) == nil {
+		return nil
+	}
+	var items []protocol.CompletionItem
+	for _, variant := range s.index.Variants(note.Slug) {
+		root := s.noteRoot(variant)
+		rank := 1
+		if root == ownRoot {
+			rank = 0
+		}
+		label := root + "/" + variant.Slug
+		items = append(items, protocol.CompletionItem{
+			Label:      label,
+			Kind:       protocol.CompletionItemKindReference,
+			Detail:     fmt.Sprintf("%s (%s)", variant.Title, root),
+			InsertText: label,
+			FilterText: variant.Slug,
+			SortText:   fmt.Sprintf("%s-%d-%s", sortPrefix, rank, root),
+		})
+	}
+	return items
+}
+
+// ambiguousRefDiagnostic tells which vault a bare reference to a shared slug goes to
+func (s *LanguageServer) ambiguousRefDiagnostic(uri protocol.DocumentURI, ref string, r protocol.Range) (protocol.Diagnostic, bool) {
+	root, slug := splitQualified(ref)
+	roots := s.sharedSlugRoots(slug)
+	if root != "" || roots == nil {
+		return protocol.Diagnostic{}, false
+	}
+	note, exists := s.resolveNote(ref, uri)
+	if !exists {
+		return protocol.Diagnostic{}, false
+	}
+	target := s.noteRoot(note)
+	var others []string
+	for _, other := range roots {
+		if other != target {
+			others = append(others, other+"/"+slug)
+		}
+	}
+	return protocol.Diagnostic{
+		Range:    r,
+		Severity: protocol.DiagnosticSeverityInformation,
+		Code:     diagnosticCodeAmbiguousRef,
+		Message:  fmt.Sprintf("'%s' is used in %s; this reference goes to the note in %s, write %s for the other", slug, strings.Join(roots, ", "), target, strings.Join(others, " or ")),
+		Source:   "lx-ls",
+	}, true
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestSharedSlugs tests telling apart notes of two vaults using the same slug
+func TestSharedSlugs(t *testing.T) {
+	tempDir := t.TempDir()
+	mathNotes := filepath.Join(tempDir, "math", "notes")
+	physicsNotes := filepath.Join(tempDir, "physics", "notes")
+	os.MkdirAll(mathNotes, 0755)
+	os.MkdirAll(physicsNotes, 0755)
+	content := "%% Metadata\n% title: Work\n\\ref{energy} \\ref{physics/energy} \\ref{physics/missing}\n\\ref{ene"
+	os.WriteFile(filepath.Join(mathNotes, "20240101-energy.tex"), []byte("%% Metadata\n% title: Energy Functionals\n"), 0644)
+	os.WriteFile(filepath.Join(mathNotes, "20240102-work.tex"), []byte(content), 0644)
+	os.WriteFile(filepath.Join(physicsNotes, "20240103-energy.tex"), []byte("%% Metadata\n% title: Energy\n"), 0644)
+	os.WriteFile(filepath.Join(physicsNotes, "20240104-motion.tex"), []byte("%% Metadata\n% title: Motion\n\\ref{energy}"), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: mathNotes},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.setWorkspaceFolders([]protocol.WorkspaceFolder{{URI: string(pathToURI(filepath.Dir(physicsNotes))), Name: "physics"}})
+	ls.RebuildIndex(context.Background())
+
+	if variants := ls.index.Variants("energy"); len(variants) != 2 {
+		t.Fatalf("expected both energy notes to be indexed, got %+v", variants)
+	}
+	workURI := pathToURI(filepath.Join(mathNotes, "20240102-work.tex"))
+	motionURI := pathToURI(filepath.Join(physicsNotes, "20240104-motion.tex"))
+	for _, tc := range []struct {
+		ref   string
+		from  protocol.DocumentURI
+		title string
+	}{
+		{"energy", workURI, "Energy Functionals"},
+		{"energy", motionURI, "Energy"},
+		{"physics/energy", workURI, "Energy"},
+		{"math/energy", "", "Energy Functionals"},
+	} {
+		note, exists := ls.resolveNote(tc.ref, tc.from)
+		if !exists || note.Title != tc.title {
+			t.Errorf("expected %s from %s to resolve to %s, got %+v", tc.ref, tc.from, tc.title, note)
+		}
+	}
+
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: workURI},
+			Position:     protocol.Position{Line: 3, Character: 8},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Label != "math/energy" || list.Items[1].Label != "physics/energy" ||
+		list.Items[0].SortText >= list.Items[1].SortText || list.Items[1].Detail != "Energy (physics)" {
+		t.Errorf("expected qualified items, the own vault first, got %+v", list.Items)
+	}
+
+	var messages []string
+	for _, diag := range ls.analyzeNoteDiagnostics(workURI, content) {
+		if diag.Code == diagnosticCodeAmbiguousRef || diag.Code == diagnosticCodeBrokenRef {
+			messages = append(messages, diag.Message)
+		}
+	}
+	want := []string{
+		"'energy' is used in math, physics; this reference goes to the note in math, write physics/energy for the other",
+		"Note 'missing' not found in physics",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics %q", messages)
+	}
+
+	hover, _ := ls.Hover(context.Background(), &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: workURI},
+			Position:     protocol.Position{Line: 2, Character: 25},
+		},
+	})
+	if hover == nil || !strings.Contains(hover.Contents.Value, "**Energy**") || !strings.Contains(hover.Contents.Value, "Vault: physics") {
+		t.Errorf("expected the physics note in the hover, got %+v", hover)
+	}
+
+	// Removing the note holding the slug hands it to the other vault's
+	ls.dropNote("energy", "", "20240101-energy.tex")
+	if note, exists := ls.index.Get("energy"); !exists || note.Title != "Energy" {
+		t.Errorf("expected the physics note to take the slug over, got %+v", note)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// codeActionKindNormalizeLineEndings lets clients run the normalization as a save action
+const codeActionKindNormalizeLineEndings protocol.CodeActionKind = "source.normalizeLineEndings"
+
+func init() {
+	registerCodeActionProvider(normalizeLineEndingsAction)
+}
+
+// storeDocument keeps the normalized text of an open document and returns it
+// Whether the client's copy still has a BOM or CR line endings is remembered for the save action
+func (s *LanguageServer) storeDocument(uri protocol.DocumentURI, text string) string {
+	normalized := metadata.Normalize(text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.documents[uri] = normalized
+	s.indexLines(uri, normalized)
+	if normalized != text {
+		if s.unnormalized == nil {
+			s.unnormalized = make(map[protocol.DocumentURI]bool)
+		}
+		s.unnormalized[uri] = true
+	} else {
+		delete(s.unnormalized, uri)
+	}
+
+	return normalized
+}
+
+// normalizeLineEndingsAction rewrites a document with a BOM or CR line endings to plain LF
+func normalizeLineEndingsAction(s *LanguageServer, req *codeActionRequest) []protocol.CodeAction {
+	s.mu.RLock()
+	unnormalized := s.unnormalized[req.URI]
+	s.mu.RUnlock()
+	if !unnormalized {
+		return nil
+	}
+
+	// Ending at the start of the line after the last one covers the whole document whatever its endings
+	lineCount := strings.Count(req.Content, "\n") + 1
+
+	return []protocol.CodeAction{
+		{
+			Title: "Normalize line endings to LF",
+			Kind:  codeActionKindNormalizeLineEndings,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					req.URI: {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 0},
+								End:   protocol.Position{Line: uint32(lineCount), Character: 0},
+							},
+							NewText: req.Content,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestStoreDocument_Normalization tests that open documents are normalized and offered a save action
+func TestStoreDocument_Normalization(t *testing.T) {
+	ls := &LanguageServer{
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+
+	uri := protocol.DocumentURI("file:///vault/notes/windows.tex")
+	text := ls.storeDocument(uri, "\ufeffFirst\r\nSecond \\todo{x}\r\n")
+	if text != "First\nSecond \\todo{x}\n" {
+		t.Errorf("unexpected normalized text %q", text)
+	}
+
+	content, _ := ls.GetDocument(uri)
+	req := &codeActionRequest{URI: uri, Content: content}
+	actions := normalizeLineEndingsAction(ls, req)
+	if len(actions) != 1 {
+		t.Fatalf("expected normalize action, got %d", len(actions))
+	}
+	edit := actions[0].Edit.Changes[uri][0]
+	if edit.NewText != content || edit.Range.End.Line != 3 {
+		t.Errorf("unexpected edit %+v", edit)
+	}
+
+	// Diagnostic ranges are computed on the normalized text
+	diagnostics := ls.analyzeDiagnostics(content)
+	if len(diagnostics) != 1 || diagnostics[0].Range.End.Character != 15 {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+
+	// Once the client saves LF text, the action disappears
+	ls.storeDocument(uri, content)
+	if actions := normalizeLineEndingsAction(ls, req); len(actions) != 0 {
+		t.Errorf("expected no action for normalized document, got %d", len(actions))
+	}
+
+	ls.DidClose(context.Background(), &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if ls.unnormalized[uri] {
+		t.Error("expected close to forget the document")
+	}
+}
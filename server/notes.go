@@ -0,0 +1,408 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+// commandCreateNote creates a note for a slug that is referenced but missing
+// Arguments: [slug]
+const commandCreateNote = "lx.createNote"
+
+// commandDeleteNote deletes a note, guarding against leaving dangling references
+// Arguments: [slug] to ask the user when the note has backlinks,
+// [slug, "strip"] or [slug, "retarget", newSlug] to fix references first, [slug, "force"] to delete regardless
+const commandDeleteNote = "lx.deleteNote"
+
+// commandNewNote creates a note from a title and opens it in the editor
+// Arguments: [title], [title, template] or [title, template, [tags]]; template names a .sty in the vault's templates
+const commandNewNote = "lx.newNote"
+
+// commandOpenDailyNote finds or creates today's daily note
+// Arguments: none; the note loads the vault's daily template when there is one
+const commandOpenDailyNote = "lx.openDailyNote"
+
+// dailyTemplate is the vault template daily notes load when it exists
+const dailyTemplate = "daily"
+
+// Reference handling modes of lx.deleteNote
+const (
+	deleteModeAsk      = ""
+	deleteModeStrip    = "strip"
+	deleteModeRetarget = "retarget"
+	deleteModeForce    = "force"
+)
+
+// slugPattern matches slugs as generated by lx-cli ("graph-theory-notes")
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+func init() {
+	registerCommand(commandCreateNote, withoutResult((*LanguageServer).createNoteCommand))
+	registerCommand(commandDeleteNote, withoutResult((*LanguageServer).deleteNoteCommand))
+	registerCommand(commandNewNote, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.newNoteCommand(ctx, args)
+	})
+	registerCommand(commandOpenDailyNote, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.openDailyNoteCommand(ctx)
+	})
+	registerQuickFix(diagnosticCodeBrokenRef, createMissingNoteFix)
+}
+
+// createMissingNoteFix offers to create the note a broken reference points at
+func createMissingNoteFix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	slug := s.getSlugAtPosition(req.Content, diag.Range.Start)
+	if !slugPattern.MatchString(slug) {
+		return nil
+	}
+	if _, trashed := s.trashedNotes()[slug]; trashed {
+		return nil // Restoring is offered instead
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf("Create note '%s'", slug),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: true,
+			Command: &protocol.Command{
+				Title:     "Create note",
+				Command:   commandCreateNote,
+				Arguments: []interface{}{slug},
+			},
+		},
+	}
+}
+
+// createNoteCommand handles lx.createNote
+func (s *LanguageServer) createNoteCommand(ctx context.Context, args []interface{}) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s requires a slug argument", commandCreateNote)
+	}
+	slug, ok := args[0].(string)
+	if !ok || !slugPattern.MatchString(slug) {
+		return fmt.Errorf("%s: invalid slug argument", commandCreateNote)
+	}
+
+	header, err := s.createNote(ctx, titleFromSlug(slug), slug, nil, "")
+	if err != nil {
+		return err
+	}
+	s.recordActivity(ctx, "note.create", header.Filename)
+
+	return nil
+}
+
+// NewNoteResult is returned by lx.newNote
+// Clients without window/showDocument open the note at URI themselves
+type NewNoteResult struct {
+	Slug string               `json:"slug"`
+	URI  protocol.DocumentURI `json:"uri"`
+}
+
+// newNoteCommand handles lx.newNote
+func (s *LanguageServer) newNoteCommand(ctx context.Context, args []interface{}) (*NewNoteResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a title argument", commandNewNote)
+	}
+	title, ok := args[0].(string)
+	title = strings.TrimSpace(title)
+	if !ok || title == "" {
+		return nil, fmt.Errorf("%s: invalid title argument", commandNewNote)
+	}
+	slug := kebabCase(title)
+	if !slugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("%s: cannot derive a slug from '%s'", commandNewNote, title)
+	}
+
+	template := ""
+	if len(args) > 1 && args[1] != nil {
+		if template, ok = args[1].(string); !ok {
+			return nil, fmt.Errorf("%s: invalid template argument", commandNewNote)
+		}
+	}
+	if template != "" {
+		if _, err := os.Stat(filepath.Join(s.vault.TemplatesPath, template+".sty")); err != nil {
+			return nil, fmt.Errorf("template '%s' not found", template)
+		}
+	}
+
+	var tags []string
+	if len(args) > 2 {
+		list, ok := args[2].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid tags argument", commandNewNote)
+		}
+		for _, item := range list {
+			if tag, ok := item.(string); ok && strings.TrimSpace(tag) != "" {
+				tags = append(tags, strings.TrimSpace(tag))
+			}
+		}
+	}
+
+	header, err := s.createNote(ctx, title, slug, tags, template)
+	if err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, "note.create", header.Filename)
+
+	// The note exists from here on, so failing to open it is reported without failing the command
+	uri := pathToURI(s.vault.GetNotePath(header.Filename))
+	if s.conn != nil && s.showDocument {
+		var result protocol.ShowDocumentResult
+		if _, err := s.conn.Call(ctx, protocol.MethodShowDocument, &protocol.ShowDocumentParams{
+			URI:       protocol.URI(uri),
+			TakeFocus: true,
+		}, &result); err != nil {
+			s.logf(protocol.MessageTypeWarning, "Created %s but failed to open it: %v", header.Filename, err)
+		}
+	}
+
+	return &NewNoteResult{Slug: header.Slug, URI: uri}, nil
+}
+
+// DailyNoteResult is returned by lx.openDailyNote
+type DailyNoteResult struct {
+	Slug    string               `json:"slug"`
+	URI     protocol.DocumentURI `json:"uri"`
+	Created bool                 `json:"created"`
+}
+
+// dailySlug returns the slug of the daily note of a day, e.g. "daily-2024-01-15"
+func dailySlug(day time.Time) string {
+	return "daily-" + day.Format("2006-01-02")
+}
+
+// openDailyNoteCommand handles lx.openDailyNote
+// The client opens the returned URI
+func (s *LanguageServer) openDailyNoteCommand(ctx context.Context) (*DailyNoteResult, error) {
+	today := time.Now()
+	slug := dailySlug(today)
+	if note, exists := s.index.Get(slug); exists {
+		return &DailyNoteResult{Slug: slug, URI: pathToURI(s.notePath(note.Filename))}, nil
+	}
+
+	template := ""
+	if _, err := os.Stat(filepath.Join(s.vault.TemplatesPath, dailyTemplate+".sty")); err == nil {
+		template = dailyTemplate
+	}
+
+	header, err := s.createNote(ctx, today.Format("2006-01-02"), slug, []string{"daily"}, template)
+	if err != nil {
+		return nil, err
+	}
+	s.recordActivity(ctx, "note.create", header.Filename)
+
+	return &DailyNoteResult{Slug: slug, URI: pathToURI(s.vault.GetNotePath(header.Filename)), Created: true}, nil
+}
+
+// createNote writes a new note with a metadata block into the vault and indexes it
+// References to the new note are no longer broken, so the notes containing them are re-checked
+func (s *LanguageServer) createNote(ctx context.Context, title, slug string, tags []string, template string) (*NoteHeader, error) {
+	if _, exists := s.index.Get(slug); exists {
+		return nil, fmt.Errorf("note '%s' already exists", slug)
+	}
+
+	now := time.Now()
+	header := &NoteHeader{
+		Title:    title,
+		Date:     now.Format("2006-01-02"),
+		Tags:     tags,
+		Slug:     slug,
+		Filename: fmt.Sprintf("%s-%s.tex", now.Format("20060102"), slug),
+	}
+	if header.Tags == nil {
+		header.Tags = []string{}
+	}
+
+	path := s.vault.GetNotePath(header.Filename)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("file %s already exists", header.Filename)
+	}
+
+	if err := s.fileOperation(ctx, func() error {
+		if err := os.WriteFile(path, []byte(renderNoteContent(header, template)), 0644); err != nil {
+			return fmt.Errorf("failed to write note: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if indexed, exists := s.index.Get(slug); exists {
+		header = indexed
+	}
+
+	return header, nil
+}
+
+// deleteNoteCommand handles lx.deleteNote
+func (s *LanguageServer) deleteNoteCommand(ctx context.Context, args []interface{}) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s requires a slug argument", commandDeleteNote)
+	}
+	slug, ok := args[0].(string)
+	if !ok || slug == "" {
+		return fmt.Errorf("%s: invalid slug argument", commandDeleteNote)
+	}
+	mode := deleteModeAsk
+	if len(args) > 1 {
+		mode, _ = args[1].(string)
+	}
+	newSlug := ""
+	if len(args) > 2 {
+		newSlug, _ = args[2].(string)
+	}
+
+	note, exists := s.index.Get(slug)
+	if !exists {
+		return fmt.Errorf("note '%s' not found", slug)
+	}
+
+	references, notes := s.backlinkCounts(slug)
+	if mode == deleteModeAsk {
+		if references == 0 {
+			mode = deleteModeForce // Nothing to protect
+		} else {
+			choice, err := s.askDeleteMode(ctx, slug, references, notes)
+			if err != nil {
+				return err
+			}
+			mode = choice
+		}
+	}
+
+	switch mode {
+	case deleteModeAsk:
+		return nil // Cancelled
+	case deleteModeStrip, deleteModeRetarget:
+		if mode == deleteModeRetarget {
+			if newSlug == "" {
+				return fmt.Errorf("%s: retarget requires a new slug", commandDeleteNote)
+			}
+			if _, exists := s.index.Get(newSlug); !exists {
+				return fmt.Errorf("note '%s' not found", newSlug)
+			}
+		}
+		if references > 0 {
+			if err := s.fixDanglingReferences(ctx, []interface{}{slug, newSlug}); err != nil {
+				return err
+			}
+		}
+	case deleteModeForce:
+	default:
+		return fmt.Errorf("%s: unknown mode '%s'", commandDeleteNote, mode)
+	}
+
+	if err := s.deleteNote(ctx, note); err != nil {
+		return err
+	}
+	s.recordActivity(ctx, "note.delete", note.Filename)
+
+	return nil
+}
+
+// backlinkCounts returns how many references point at slug and from how many other notes
+func (s *LanguageServer) backlinkCounts(slug string) (int, int) {
+	references := 0
+	notes := make(map[string]bool)
+	for _, link := range s.index.Links().Incoming(slug) {
+		if link.Source == slug {
+			continue // References inside the note itself go away with it
+		}
+		references++
+		notes[link.Source] = true
+	}
+	return references, len(notes)
+}
+
+// askDeleteMode asks the user what to do with the references to a note about to be deleted
+// Returns deleteModeAsk when the user cancels
+func (s *LanguageServer) askDeleteMode(ctx context.Context, slug string, references, notes int) (string, error) {
+	const (
+		stripAction  = "Remove references and delete"
+		forceAction  = "Delete anyway"
+		cancelAction = "Cancel"
+	)
+
+	var choice *protocol.MessageActionItem
+	if _, err := s.conn.Call(ctx, protocol.MethodWindowShowMessageRequest, &protocol.ShowMessageRequestParams{
+		Type:    protocol.MessageTypeWarning,
+		Message: fmt.Sprintf("Note '%s' is referenced %d time(s) from %d note(s).", slug, references, notes),
+		Actions: []protocol.MessageActionItem{{Title: stripAction}, {Title: forceAction}, {Title: cancelAction}},
+	}, &choice); err != nil {
+		return deleteModeAsk, fmt.Errorf("failed to confirm deletion: %w", err)
+	}
+
+	if choice == nil {
+		return deleteModeAsk, nil
+	}
+	switch choice.Title {
+	case stripAction:
+		return deleteModeStrip, nil
+	case forceAction:
+		return deleteModeForce, nil
+	default:
+		return deleteModeAsk, nil
+	}
+}
+
+// deleteNote moves a note file to the trash and drops it from the index
+// Notes still referencing it are re-checked so their references show up as broken
+func (s *LanguageServer) deleteNote(ctx context.Context, note *NoteHeader) error {
+	return s.fileOperation(ctx, func() error {
+		return s.trashNote(s.notePath(note.Filename))
+	})
+}
+
+// renderNoteContent generates the initial LaTeX source of a note, matching lx-cli's layout
+// Notes without a date are dated today
+func renderNoteContent(header *NoteHeader, template string) string {
+	var builder strings.Builder
+
+	date := header.Date
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	builder.WriteString(metadata.Format(&metadata.Metadata{Title: header.Title, Date: date, Tags: header.Tags}))
+	builder.WriteString("\n")
+
+	builder.WriteString("\\documentclass[12pt]{article}\n\n")
+	if template != "" {
+		builder.WriteString(fmt.Sprintf("\\usepackage{%s}\n", template))
+	}
+	builder.WriteString("\\usepackage[utf8]{inputenc}\n")
+	builder.WriteString("\\usepackage[T1]{fontenc}\n")
+	builder.WriteString("\\usepackage{amsmath}\n")
+	builder.WriteString("\\usepackage{amssymb}\n")
+	builder.WriteString("\\usepackage{geometry}\n")
+	builder.WriteString("\\geometry{margin=1in}\n\n")
+
+	builder.WriteString(fmt.Sprintf("\\title{%s}\n", header.Title))
+	builder.WriteString(fmt.Sprintf("\\date{%s}\n\n", date))
+
+	builder.WriteString("\\begin{document}\n\n")
+	builder.WriteString("\\maketitle\n\n")
+	builder.WriteString("% Your notes go here\n\n")
+	builder.WriteString("\\end{document}\n")
+
+	return builder.String()
+}
+
+// titleFromSlug turns "graph-theory" into "Graph Theory"
+func titleFromSlug(slug string) string {
+	words := strings.Split(slug, "-")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
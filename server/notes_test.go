@@ -0,0 +1,343 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestCreateMissingNote tests the quick fix and command that create a note for a broken reference
+func TestCreateMissingNote(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath},
+		index: NewIndex(),
+	}
+
+	content := `See \ref{graph-theory} and \ref{eq:main}.`
+	req := &codeActionRequest{Content: content}
+
+	var createActions []protocol.CodeAction
+	for _, action := range ls.collectCodeActions(req, ls.analyzeDiagnostics(content)) {
+		if action.Command != nil && action.Command.Command == commandCreateNote {
+			createActions = append(createActions, action)
+		}
+	}
+	// Only valid slugs get a create action
+	if len(createActions) != 1 {
+		t.Fatalf("expected 1 create note action, got %d", len(createActions))
+	}
+	if createActions[0].Command.Arguments[0] != "graph-theory" {
+		t.Errorf("expected slug argument 'graph-theory', got %v", createActions[0].Command.Arguments)
+	}
+
+	if err := ls.createNoteCommand(context.Background(), createActions[0].Command.Arguments); err != nil {
+		t.Fatalf("createNoteCommand failed: %v", err)
+	}
+
+	note, exists := ls.index.Get("graph-theory")
+	if !exists {
+		t.Fatal("expected created note to be indexed")
+	}
+	if note.Title != "Graph Theory" {
+		t.Errorf("expected title 'Graph Theory', got %q", note.Title)
+	}
+	if !strings.HasSuffix(note.Filename, "-graph-theory.tex") {
+		t.Errorf("unexpected filename %q", note.Filename)
+	}
+
+	data, err := os.ReadFile(filepath.Join(notesPath, note.Filename))
+	if err != nil {
+		t.Fatalf("failed to read created note: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "%% Metadata\n%% title: Graph Theory\n") {
+		t.Errorf("expected metadata block, got:\n%s", data)
+	}
+
+	// The reference is no longer broken
+	for _, diag := range ls.analyzeDiagnostics(content) {
+		if strings.Contains(diag.Message, "graph-theory") {
+			t.Errorf("unexpected diagnostic after creation: %s", diag.Message)
+		}
+	}
+
+	// Creating it again fails
+	if err := ls.createNoteCommand(context.Background(), []interface{}{"graph-theory"}); err == nil {
+		t.Error("expected error when note already exists")
+	}
+}
+
+// TestNewNote tests creating a note from a title with a template and tags
+func TestNewNote(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	os.MkdirAll(v.TemplatesPath, 0755)
+	os.WriteFile(filepath.Join(v.TemplatesPath, "lecture.sty"), []byte("\\ProvidesPackage{lecture}\n"), 0644)
+
+	ls := &LanguageServer{vault: v, index: NewIndex()}
+
+	result, err := ls.newNoteCommand(context.Background(), []interface{}{"Graph Theory Basics", "lecture", []interface{}{"math", "graphs"}})
+	if err != nil {
+		t.Fatalf("newNoteCommand failed: %v", err)
+	}
+	if result.Slug != "graph-theory-basics" {
+		t.Errorf("expected slug 'graph-theory-basics', got %q", result.Slug)
+	}
+
+	note, exists := ls.index.Get("graph-theory-basics")
+	if !exists {
+		t.Fatal("expected new note to be indexed")
+	}
+	if note.Title != "Graph Theory Basics" || strings.Join(note.Tags, ",") != "math,graphs" {
+		t.Errorf("unexpected note %+v", note)
+	}
+	if result.URI != pathToURI(filepath.Join(v.NotesPath, note.Filename)) {
+		t.Errorf("unexpected URI %s", result.URI)
+	}
+	data, _ := os.ReadFile(filepath.Join(v.NotesPath, note.Filename))
+	if !strings.Contains(string(data), "\\usepackage{lecture}") {
+		t.Errorf("expected template to be loaded, got:\n%s", data)
+	}
+	parsed, err := metadata.NewParser(true).Parse(string(data))
+	if err != nil || len(parsed.Errors) > 0 {
+		t.Fatalf("expected the metadata block to parse, got %v %+v", err, parsed)
+	}
+	if parsed.Metadata.Title != "Graph Theory Basics" || parsed.Metadata.Date != note.Date || strings.Join(parsed.Metadata.Tags, ",") != "math,graphs" {
+		t.Errorf("unexpected metadata %+v", parsed.Metadata)
+	}
+	if block := strings.SplitN(string(data), "\n\n", 2)[0]; strings.Contains(block, "\n% ") {
+		t.Errorf("expected %%%% fields only, got:\n%s", block)
+	}
+
+	if _, err := ls.newNoteCommand(context.Background(), []interface{}{"Graph Theory Basics"}); err == nil {
+		t.Error("expected existing note to be rejected")
+	}
+	if _, err := ls.newNoteCommand(context.Background(), []interface{}{"Other", "missing"}); err == nil {
+		t.Error("expected unknown template to be rejected")
+	}
+	if _, err := ls.newNoteCommand(context.Background(), []interface{}{"  "}); err == nil {
+		t.Error("expected empty title to be rejected")
+	}
+}
+
+// TestNewNoteShowDocument tests that new notes are only shown to clients supporting it, and that a
+// failure to show one does not fail the command
+func TestNewNoteShowDocument(t *testing.T) {
+	v := vaultAt(t.TempDir())
+	os.MkdirAll(v.NotesPath, 0755)
+
+	serverSide, clientSide := net.Pipe()
+	shown := make(chan protocol.DocumentURI, 2)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.ShowDocumentParams
+		if req.Method() == protocol.MethodShowDocument && json.Unmarshal(req.Params(), &params) == nil {
+			shown <- protocol.DocumentURI(params.URI)
+			return reply(ctx, nil, fmt.Errorf("no editor window"))
+		}
+		return reply(ctx, nil, nil)
+	})
+	defer func() {
+		client.Close()
+		serverSide.Close()
+	}()
+
+	ls := &LanguageServer{vault: v, index: NewIndex(), conn: jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide))}
+	ls.conn.Go(context.Background(), jsonrpc2.MethodNotFoundHandler)
+
+	if _, err := ls.newNoteCommand(context.Background(), []interface{}{"Hidden"}); err != nil {
+		t.Fatalf("newNoteCommand failed: %v", err)
+	}
+	if len(shown) != 0 {
+		t.Error("expected no window/showDocument without the client capability")
+	}
+
+	ls.showDocument = true
+	result, err := ls.newNoteCommand(context.Background(), []interface{}{"Shown"})
+	if err != nil || result.Slug != "shown" {
+		t.Fatalf("expected the note to be created despite the failed show, got %+v, %v", result, err)
+	}
+	if len(shown) != 1 || <-shown != result.URI {
+		t.Error("expected the new note to be shown")
+	}
+}
+
+// TestTitleFromSlug tests title derivation from slugs
+func TestTitleFromSlug(t *testing.T) {
+	tests := map[string]string{
+		"graph-theory":  "Graph Theory",
+		"single":        "Single",
+		"lecture-2-ode": "Lecture 2 Ode",
+	}
+	for slug, expected := range tests {
+		if got := titleFromSlug(slug); got != expected {
+			t.Errorf("titleFromSlug(%q) = %q, want %q", slug, got, expected)
+		}
+	}
+}
+
+// TestDeleteNote tests the reference-aware delete command
+func TestDeleteNote(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	files := map[string]string{
+		"20240101-target.tex": "%% Metadata\n% title: Target\n\nSee \\ref{target} itself.",
+		"20240102-first.tex":  "%% Metadata\n% title: First\n\n\\ref{target} and \\ref{target}",
+		"20240103-second.tex": "%% Metadata\n% title: Second\n\n\\ref{target}",
+		"20240104-lonely.tex": "%% Metadata\n% title: Lonely\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(notesPath, name), []byte(content), 0644)
+	}
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{RootPath: tempDir, NotesPath: notesPath},
+		index: NewIndex(),
+	}
+	ls.RebuildIndex(context.Background())
+
+	// Self-references do not count
+	references, notes := ls.backlinkCounts("target")
+	if references != 3 || notes != 2 {
+		t.Errorf("expected 3 references from 2 notes, got %d from %d", references, notes)
+	}
+
+	// Notes without backlinks are deleted straight away
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"lonely"}); err != nil {
+		t.Fatalf("deleteNoteCommand failed: %v", err)
+	}
+	if _, exists := ls.index.Get("lonely"); exists {
+		t.Error("expected deleted note to leave the index")
+	}
+	if _, err := os.Stat(filepath.Join(notesPath, "20240104-lonely.tex")); !os.IsNotExist(err) {
+		t.Error("expected note file to be removed")
+	}
+
+	// Retargeting needs an existing replacement
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"target", deleteModeRetarget}); err == nil {
+		t.Error("expected error for retarget without a new slug")
+	}
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"target", deleteModeRetarget, "missing"}); err == nil {
+		t.Error("expected error for retarget to a missing note")
+	}
+
+	// The note's own references are left alone, it goes away with them
+	if _, ok := ls.danglingReferencesEdit("target", "").Changes[pathToURI(filepath.Join(notesPath, "20240101-target.tex"))]; ok {
+		t.Error("expected no edit of the note being deleted")
+	}
+
+	// Forcing deletes despite the backlinks, which stay indexed as dangling
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"target", deleteModeForce}); err != nil {
+		t.Fatalf("forced delete failed: %v", err)
+	}
+	if _, exists := ls.index.Get("target"); exists {
+		t.Error("expected forced delete to remove the note")
+	}
+	if len(ls.index.Links().Incoming("target")) != 3 {
+		t.Errorf("expected dangling references to remain indexed")
+	}
+}
+
+// TestDeleteNoteStrip tests that stripping references before a delete leaves no backlinks behind
+func TestDeleteNoteStrip(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-target.tex"), []byte("%% Metadata\n% title: Target\n\nSee \\ref{target}."), 0644)
+	os.WriteFile(filepath.Join(notesPath, "20240102-first.tex"), []byte("%% Metadata\n% title: First\n\nSee \\ref{target}."), 0644)
+
+	// The client writes the edits to disk, as editors do for notes that are not open
+	serverSide, clientSide := net.Pipe()
+	var edited []protocol.DocumentURI
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params protocol.ApplyWorkspaceEditParams
+		if req.Method() == protocol.MethodWorkspaceApplyEdit && json.Unmarshal(req.Params(), &params) == nil {
+			for uri, edits := range params.Edit.Changes {
+				data, _ := os.ReadFile(uriToPath(uri))
+				os.WriteFile(uriToPath(uri), []byte(applyTextEdits(string(data), edits)), 0644)
+				edited = append(edited, uri)
+			}
+		}
+		return reply(ctx, &protocol.ApplyWorkspaceEditResponse{Applied: true}, nil)
+	})
+	defer func() {
+		client.Close()
+		serverSide.Close()
+	}()
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{RootPath: tempDir, NotesPath: notesPath},
+		index: NewIndex(),
+		conn:  jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide)),
+	}
+	ls.conn.Go(context.Background(), jsonrpc2.MethodNotFoundHandler)
+	ls.RebuildIndex(context.Background())
+
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"target", deleteModeStrip}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if len(edited) != 1 || edited[0] != pathToURI(filepath.Join(notesPath, "20240102-first.tex")) {
+		t.Errorf("expected only the referencing note to be edited, got %v", edited)
+	}
+	if incoming := ls.index.Links().Incoming("target"); len(incoming) != 0 {
+		t.Errorf("expected the stripped references to leave the index, got %+v", incoming)
+	}
+}
+
+// TestOpenDailyNote tests that the daily note is created once and then found
+func TestOpenDailyNote(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	os.MkdirAll(v.TemplatesPath, 0755)
+	os.WriteFile(filepath.Join(v.TemplatesPath, "daily.sty"), []byte("\\ProvidesPackage{daily}\n"), 0644)
+
+	ls := &LanguageServer{vault: v, index: NewIndex()}
+
+	created, err := ls.openDailyNoteCommand(context.Background())
+	if err != nil {
+		t.Fatalf("openDailyNoteCommand failed: %v", err)
+	}
+	today := time.Now().Format("2006-01-02")
+	if !created.Created || created.Slug != "daily-"+today {
+		t.Errorf("expected today's daily note to be created, got %+v", created)
+	}
+
+	note, exists := ls.index.Get(created.Slug)
+	if !exists {
+		t.Fatal("expected daily note to be indexed")
+	}
+	if note.Date != today || strings.Join(note.Tags, ",") != "daily" {
+		t.Errorf("unexpected metadata %+v", note)
+	}
+	data, _ := os.ReadFile(uriToPath(created.URI))
+	if !strings.Contains(string(data), "\\usepackage{daily}") {
+		t.Errorf("expected daily template to be loaded, got:\n%s", data)
+	}
+
+	found, err := ls.openDailyNoteCommand(context.Background())
+	if err != nil {
+		t.Fatalf("openDailyNoteCommand failed: %v", err)
+	}
+	if found.Created || found.URI != created.URI {
+		t.Errorf("expected existing daily note to be returned, got %+v", found)
+	}
+}
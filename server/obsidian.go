@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandExportObsidianGraph writes the note graph as a JSON Canvas file, which Obsidian opens as a canvas
+// Arguments: [path], [path, folder] or [path, folder, overwrite], folder being where the Markdown mirror
+// of each note lives in the Markdown vault, as folder/slug.md; without it the mirrors are expected at its root
+// The path lies inside the vault, relative paths starting at its root, and an existing file is only
+// replaced when overwrite is true
+const commandExportObsidianGraph = "lx.exportObsidianGraph"
+
+// graphFormatCanvas is the format reported by lx.exportObsidianGraph
+const graphFormatCanvas = "canvas"
+
+// Size and spacing of the note cards laid out on the canvas
+const (
+	canvasNodeWidth  = 400
+	canvasNodeHeight = 300
+	canvasGap        = 100
+)
+
+func init() {
+	registerCommand(commandExportObsidianGraph, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.exportObsidianGraphCommand(ctx, args)
+	})
+}
+
+// CanvasNode is a file node of a JSON Canvas (jsoncanvas.org), pointing at the Markdown mirror of a note
+// Metadata carries the note's metadata block; canvas readers ignore fields they do not know
+type CanvasNode struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	File     string         `json:"file"`
+	X        int            `json:"x"`
+	Y        int            `json:"y"`
+	Width    int            `json:"width"`
+	Height   int            `json:"height"`
+	Metadata CanvasMetadata `json:"metadata"`
+}
+
+// CanvasMetadata is the metadata of a note as exported on its canvas node
+type CanvasMetadata struct {
+	Slug  string   `json:"slug"`
+	Title string   `json:"title"`
+	Date  string   `json:"date,omitempty"`
+	Tags  []string `json:"tags"`
+}
+
+// CanvasEdge is a link between two notes on the canvas
+type CanvasEdge struct {
+	ID       string `json:"id"`
+	FromNode string `json:"fromNode"`
+	ToNode   string `json:"toNode"`
+	ToEnd    string `json:"toEnd"`
+	Label    string `json:"label,omitempty"`
+}
+
+// Canvas is a JSON Canvas document
+type Canvas struct {
+	Nodes []CanvasNode `json:"nodes"`
+	Edges []CanvasEdge `json:"edges"`
+}
+
+// exportObsidianGraphCommand handles lx.exportObsidianGraph
+func (s *LanguageServer) exportObsidianGraphCommand(ctx context.Context, args []interface{}) (*ExportGraphResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a path argument", commandExportObsidianGraph)
+	}
+	target, ok := args[0].(string)
+	if !ok || target == "" {
+		return nil, fmt.Errorf("%s: invalid path argument", commandExportObsidianGraph)
+	}
+	if strings.HasPrefix(target, "file://") {
+		target = uriToPath(protocol.DocumentURI(target))
+	}
+	folder := ""
+	if len(args) > 1 && args[1] != nil {
+		if folder, ok = args[1].(string); !ok {
+			return nil, fmt.Errorf("%s: invalid folder argument", commandExportObsidianGraph)
+		}
+	}
+	overwrite := false
+	if len(args) > 2 {
+		if overwrite, ok = args[2].(bool); !ok {
+			return nil, fmt.Errorf("%s: invalid overwrite argument", commandExportObsidianGraph)
+		}
+	}
+	target, err := s.vaultTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := s.noteCanvas(folder)
+	encoded, err := json.MarshalIndent(canvas, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode canvas: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(target, flags, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s already exists, pass overwrite to replace it", target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write canvas: %w", err)
+	}
+	_, err = file.Write(append(encoded, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write canvas: %w", err)
+	}
+	s.recordActivity(ctx, "graph.export", target)
+
+	return &ExportGraphResult{Path: target, Format: graphFormatCanvas, Nodes: len(canvas.Nodes), Edges: len(canvas.Edges)}, nil
+}
+
+// vaultTarget resolves a path to write to, relative paths starting at the vault root, and refuses
+// paths outside the vault, following symbolic links that could lead out of it
+func (s *LanguageServer) vaultTarget(target string) (string, error) {
+	root, err := filepath.Abs(s.vault.RootPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve vault root: %w", err)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+	target = filepath.Clean(target)
+
+	resolved, err := filepath.EvalSymlinks(target)
+	if os.IsNotExist(err) {
+		resolved, err = filepath.EvalSymlinks(filepath.Dir(target))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", target, err)
+	}
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = realRoot
+	}
+	if !inTree(resolved, root) {
+		return "", fmt.Errorf("%s is outside the vault", target)
+	}
+	return target, nil
+}
+
+// noteCanvas lays the note graph out on a canvas, in a square grid of notes sorted by slug
+// Mirrors are named after slugs, as Markdown vaults link by file name: folder/graph-theory.md
+func (s *LanguageServer) noteCanvas(folder string) *Canvas {
+	graph := s.noteGraph()
+	columns := int(math.Ceil(math.Sqrt(float64(len(graph.Nodes)))))
+	folder = strings.Trim(strings.ReplaceAll(folder, "\\", "/"), "/")
+
+	canvas := &Canvas{Nodes: []CanvasNode{}, Edges: []CanvasEdge{}}
+	for i, node := range graph.Nodes {
+		date := ""
+		if note, exists := s.index.Get(node.Slug); exists {
+			date = note.Date
+		}
+		canvas.Nodes = append(canvas.Nodes, CanvasNode{
+			ID:     node.Slug,
+			Type:   "file",
+			File:   path.Join(folder, node.Slug+".md"),
+			X:      (i % columns) * (canvasNodeWidth + canvasGap),
+			Y:      (i / columns) * (canvasNodeHeight + canvasGap),
+			Width:  canvasNodeWidth,
+			Height: canvasNodeHeight,
+			Metadata: CanvasMetadata{
+				Slug:  node.Slug,
+				Title: node.Title,
+				Date:  date,
+				Tags:  node.Tags,
+			},
+		})
+	}
+	for _, edge := range graph.Edges {
+		label := ""
+		if edge.Weight > 1 {
+			label = fmt.Sprintf("%d references", edge.Weight)
+		}
+		canvas.Edges = append(canvas.Edges, CanvasEdge{
+			ID:       edge.Source + "->" + edge.Target,
+			FromNode: edge.Source,
+			ToNode:   edge.Target,
+			ToEnd:    "arrow",
+			Label:    label,
+		})
+	}
+	return canvas
+}
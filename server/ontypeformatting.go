@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+var (
+	// environmentPattern matches \begin{env} and \end{env}; group 1 is begin or end, group 2 the name
+	environmentPattern = regexp.MustCompile(`\\(begin|end)\{([^}]+)\}`)
+
+	// openedEnvironmentPattern matches a line prefix ending in a just-closed \begin{env}
+	openedEnvironmentPattern = regexp.MustCompile(`\\begin\{([^}]+)\}$`)
+)
+
+// unindentedEnvironments wrap the whole note, so their bodies stay at the left margin
+var unindentedEnvironments = map[string]bool{
+	"document": true,
+}
+
+// Handle OnTypeFormatting request
+// Closing \begin{env} with "}" inserts the matching \end{env}; "}" and newlines reindent the line
+func (s *LanguageServer) OnTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	if s.settings().Coexist || !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	return onTypeEdits(content, params.Position, params.Ch, params.Options), nil
+}
+
+// onTypeEdits computes the edits for a trigger character typed before pos
+func onTypeEdits(content string, pos protocol.Position, ch string, options protocol.FormattingOptions) []protocol.TextEdit {
+	lines := strings.Split(content, "\n")
+	lineNum := int(pos.Line)
+	if lineNum >= len(lines) || int(pos.Character) > len(lines[lineNum]) {
+		return nil
+	}
+	line := lines[lineNum]
+	unit := indentUnit(options)
+
+	edits := []protocol.TextEdit{}
+	if reindent, ok := reindentLine(lines, lineNum, unit); ok {
+		edits = append(edits, reindent)
+	}
+
+	if ch != "}" {
+		return edits
+	}
+
+	match := openedEnvironmentPattern.FindStringSubmatch(line[:pos.Character])
+	if match == nil || !environmentUnclosed(lines, match[1]) {
+		return edits
+	}
+
+	// The new line takes the indentation the \begin line will have once reindented
+	indent := strings.Repeat(unit, environmentDepth(lines, lineNum))
+	edits = append(edits, protocol.TextEdit{
+		Range:   protocol.Range{Start: lineEnd(lines, lineNum), End: lineEnd(lines, lineNum)},
+		NewText: "\n" + indent + `\end{` + match[1] + `}`,
+	})
+	return edits
+}
+
+// indentUnit returns one level of indentation under the client's formatting options
+func indentUnit(options protocol.FormattingOptions) string {
+	if options.InsertSpaces && options.TabSize > 0 {
+		return strings.Repeat(" ", int(options.TabSize))
+	}
+	return "\t"
+}
+
+// reindentLine replaces the leading whitespace of a line with its environment depth
+func reindentLine(lines []string, lineNum int, unit string) (protocol.TextEdit, bool) {
+	line := lines[lineNum]
+	trimmed := strings.TrimLeft(line, " \t")
+
+	depth := environmentDepth(lines, lineNum)
+	if match := environmentPattern.FindStringSubmatch(trimmed); match != nil && strings.HasPrefix(trimmed, match[0]) &&
+		match[1] == "end" && !unindentedEnvironments[match[2]] && depth > 0 {
+		depth--
+	}
+
+	indent := strings.Repeat(unit, depth)
+	current := line[:len(line)-len(trimmed)]
+	if current == indent {
+		return protocol.TextEdit{}, false
+	}
+	return protocol.TextEdit{Range: lineRange(lineNum, 0, len(current)), NewText: indent}, true
+}
+
+// environmentDepth counts the environments open at the start of a line
+func environmentDepth(lines []string, lineNum int) int {
+	depth := 0
+	for _, line := range lines[:lineNum] {
+		for _, match := range environmentPattern.FindAllStringSubmatch(stripInlineComment(line), -1) {
+			if unindentedEnvironments[match[2]] {
+				continue
+			}
+			if match[1] == "begin" {
+				depth++
+			} else if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return depth
+}
+
+// environmentUnclosed reports whether env has more \begin than \end in the document
+func environmentUnclosed(lines []string, env string) bool {
+	balance := 0
+	for _, line := range lines {
+		for _, match := range environmentPattern.FindAllStringSubmatch(stripInlineComment(line), -1) {
+			if match[2] != env {
+				continue
+			}
+			if match[1] == "begin" {
+				balance++
+			} else {
+				balance--
+			}
+		}
+	}
+	return balance > 0
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestOnTypeEdits tests environment auto-closing and reindentation
+func TestOnTypeEdits(t *testing.T) {
+	tabs := protocol.FormattingOptions{}
+	spaces := protocol.FormattingOptions{InsertSpaces: true, TabSize: 2}
+
+	tests := []struct {
+		name     string
+		content  string
+		pos      protocol.Position
+		ch       string
+		options  protocol.FormattingOptions
+		expected string
+	}{
+		{
+			name:     "closes new environment",
+			content:  "\\begin{document}\n\\begin{itemize}\n\\end{document}",
+			pos:      protocol.Position{Line: 1, Character: 15},
+			ch:       "}",
+			options:  tabs,
+			expected: "\\begin{document}\n\\begin{itemize}\n\\end{itemize}\n\\end{document}",
+		},
+		{
+			name:     "nested environment is indented",
+			content:  "\\begin{itemize}\n\\begin{enumerate}\n\\end{itemize}",
+			pos:      protocol.Position{Line: 1, Character: 17},
+			ch:       "}",
+			options:  spaces,
+			expected: "\\begin{itemize}\n  \\begin{enumerate}\n  \\end{enumerate}\n\\end{itemize}",
+		},
+		{
+			name:     "already closed environment",
+			content:  "\\begin{align}\n\\end{align}",
+			pos:      protocol.Position{Line: 0, Character: 13},
+			ch:       "}",
+			options:  tabs,
+			expected: "\\begin{align}\n\\end{align}",
+		},
+		{
+			name:     "newline indents inside environment",
+			content:  "\\begin{itemize}\n\n\\end{itemize}",
+			pos:      protocol.Position{Line: 1, Character: 0},
+			ch:       "\n",
+			options:  tabs,
+			expected: "\\begin{itemize}\n\t\n\\end{itemize}",
+		},
+		{
+			name:     "end dedents to its begin",
+			content:  "\\begin{itemize}\n\t\\item a\n\t\\end{itemize}",
+			pos:      protocol.Position{Line: 2, Character: 14},
+			ch:       "}",
+			options:  tabs,
+			expected: "\\begin{itemize}\n\t\\item a\n\\end{itemize}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := onTypeEdits(tt.content, tt.pos, tt.ch, tt.options)
+			if got := applyTextEdits(tt.content, edits); got != tt.expected {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// workDone reports progress of a long-running request to the client
+// A nil token means the client did not ask for progress; reporting is then a no-op
+type workDone struct {
+	s      *LanguageServer
+	token  *protocol.ProgressToken
+	cancel context.CancelFunc
+}
+
+// progressKey carries the progress of the running request in its context
+type progressKey struct{}
+
+// beginProgress starts cancellable progress reporting under the client's token
+// The returned context is cancelled when the client sends window/workDoneProgress/cancel,
+// and carries the progress for progressFrom
+func (s *LanguageServer) beginProgress(ctx context.Context, token *protocol.ProgressToken, title string) (context.Context, *workDone) {
+	ctx, cancel := context.WithCancel(ctx)
+	progress := &workDone{s: s, token: token, cancel: cancel}
+	ctx = context.WithValue(ctx, progressKey{}, progress)
+	if token == nil {
+		return ctx, progress
+	}
+
+	s.mu.Lock()
+	if s.progress == nil {
+		s.progress = make(map[protocol.ProgressToken]context.CancelFunc)
+	}
+	s.progress[*token] = cancel
+	s.mu.Unlock()
+
+	progress.notify(ctx, &protocol.WorkDoneProgressBegin{
+		Kind:        protocol.WorkDoneProgressKindBegin,
+		Title:       title,
+		Cancellable: true,
+	})
+	return ctx, progress
+}
+
+// createProgress starts progress under a server-created token, for work no request asked for
+// Clients that do not support window/workDoneProgress/create get no-op reporting
+func (s *LanguageServer) createProgress(ctx context.Context, token, title string) (context.Context, *workDone) {
+	if !s.workDoneProgress || s.conn == nil {
+		return s.beginProgress(ctx, nil, title)
+	}
+
+	progressToken := protocol.NewProgressToken(token)
+	if _, err := s.conn.Call(ctx, protocol.MethodWorkDoneProgressCreate, &protocol.WorkDoneProgressCreateParams{Token: *progressToken}, nil); err != nil {
+		return s.beginProgress(ctx, nil, title)
+	}
+	return s.beginProgress(ctx, progressToken, title)
+}
+
+// progressFrom returns the progress of the request running under ctx
+// Outside of beginProgress it returns no-op reporting
+func (s *LanguageServer) progressFrom(ctx context.Context) *workDone {
+	if progress, ok := ctx.Value(progressKey{}).(*workDone); ok {
+		return progress
+	}
+	return &workDone{s: s, cancel: func() {}}
+}
+
+// Report sends an intermediate message, with percentage in [0, 100]
+func (p *workDone) Report(ctx context.Context, message string, percentage uint32) {
+	p.notify(ctx, &protocol.WorkDoneProgressReport{
+		Kind:        protocol.WorkDoneProgressKindReport,
+		Cancellable: true,
+		Message:     message,
+		Percentage:  percentage,
+	})
+}
+
+// End finishes the progress and releases its context
+func (p *workDone) End(ctx context.Context, message string) {
+	defer p.cancel()
+	if p.token == nil {
+		return
+	}
+
+	p.s.mu.Lock()
+	delete(p.s.progress, *p.token)
+	p.s.mu.Unlock()
+
+	// The request context may already be cancelled, but the client still needs the end notification
+	p.notify(context.WithoutCancel(ctx), &protocol.WorkDoneProgressEnd{
+		Kind:    protocol.WorkDoneProgressKindEnd,
+		Message: message,
+	})
+}
+
+func (p *workDone) notify(ctx context.Context, value interface{}) {
+	if p.token == nil || p.s.conn == nil {
+		return
+	}
+	p.s.conn.Notify(ctx, protocol.MethodProgress, &protocol.ProgressParams{Token: *p.token, Value: value})
+}
+
+// cancelProgress cancels the request running under token, if any
+func (s *LanguageServer) cancelProgress(token protocol.ProgressToken) {
+	s.mu.RLock()
+	cancel, ok := s.progress[token]
+	s.mu.RUnlock()
+	if ok {
+		cancel()
+	}
+}
+
+// cancelHandler handles window/workDoneProgress/cancel ahead of the async queue
+// AsyncHandler holds each message until the previous request replied, so a cancel
+// queued behind the request it targets would only arrive once that request finished
+func (s *LanguageServer) cancelHandler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if req.Method() != protocol.MethodWorkDoneProgressCancel {
+			return next(ctx, reply, req)
+		}
+
+		var params protocol.WorkDoneProgressCancelParams
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return reply(ctx, nil, err)
+		}
+		s.cancelProgress(params.Token)
+		return reply(ctx, nil, nil)
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestWorkDoneProgressCancel tests that cancelling a progress token cancels its request
+func TestWorkDoneProgressCancel(t *testing.T) {
+	ls := &LanguageServer{index: NewIndex()}
+	token := protocol.NewProgressToken("scan-1")
+
+	ctx, progress := ls.beginProgress(context.Background(), token, "Scanning")
+	if ctx.Err() != nil {
+		t.Fatal("expected live context")
+	}
+	if ls.progressFrom(ctx) != progress {
+		t.Error("expected the context to carry its progress")
+	}
+
+	// The cancel notification bypasses the async queue
+	var forwarded bool
+	handler := ls.cancelHandler(func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		forwarded = true
+		return reply(ctx, nil, nil)
+	})
+	params, _ := json.Marshal(map[string]string{"token": "scan-1"})
+	req, _ := jsonrpc2.NewNotification(protocol.MethodWorkDoneProgressCancel, json.RawMessage(params))
+	handler(context.Background(), func(context.Context, interface{}, error) error { return nil }, req)
+
+	if forwarded {
+		t.Error("expected cancel to be handled before the queue")
+	}
+	if ctx.Err() == nil {
+		t.Error("expected context to be cancelled")
+	}
+
+	progress.End(ctx, "Cancelled")
+	if len(ls.progress) != 0 {
+		t.Error("expected token to be released")
+	}
+
+	// Requests without a token are still cancellable through their parent
+	ctx, progress = ls.beginProgress(context.Background(), nil, "Scanning")
+	progress.End(ctx, "")
+	if ctx.Err() == nil {
+		t.Error("expected context to be released on end")
+	}
+}
+
+// TestCancelledCommands tests that cancelled commands stop without side effects
+func TestCancelledCommands(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-a.tex"), []byte("\\ref{gone} and more"), 0644)
+	os.WriteFile(filepath.Join(notesPath, "20240102-b.tex"), []byte("\\ref{gone}"), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: notesPath}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ls.ExecuteCommand(ctx, &protocol.ExecuteCommandParams{
+		Command:   commandFixDanglingReferences,
+		Arguments: []interface{}{"gone"},
+	}); err == nil {
+		t.Error("expected cancelled fix to fail")
+	}
+
+	matches, err := ls.Grep(ctx, &GrepParams{Pattern: "gone"})
+	if err != nil || len(matches) != 0 {
+		t.Errorf("expected cancelled grep to return no matches, got %v, %v", matches, err)
+	}
+}
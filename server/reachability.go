@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandTransitiveRefs lists every note reachable from a root note through references and includes
+// Arguments: [slug] or [slug, maxDepth]; a maxDepth of 0 means unlimited
+const commandTransitiveRefs = "lx.transitiveRefs"
+
+func init() {
+	registerCommand(commandTransitiveRefs, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.transitiveRefsCommand(ctx, args)
+	})
+}
+
+// ReachableNote is a note in the dependency closure of the root
+type ReachableNote struct {
+	Slug  string               `json:"slug"`
+	Title string               `json:"title"`
+	URI   protocol.DocumentURI `json:"uri"`
+	Depth int                  `json:"depth"` // 1 for notes the root references directly
+	Via   string               `json:"via"`   // the note through which it was first reached
+}
+
+// TransitiveRefsResult is returned by lx.transitiveRefs
+type TransitiveRefsResult struct {
+	Root    string          `json:"root"`
+	Notes   []ReachableNote `json:"notes"`   // by depth, then slug; the root is not included
+	Missing []string        `json:"missing"` // referenced slugs with no note and no \label behind them
+}
+
+// transitiveRefsCommand handles lx.transitiveRefs
+func (s *LanguageServer) transitiveRefsCommand(ctx context.Context, args []interface{}) (*TransitiveRefsResult, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a slug argument", commandTransitiveRefs)
+	}
+	root, ok := args[0].(string)
+	if !ok || root == "" {
+		return nil, fmt.Errorf("%s: invalid slug argument", commandTransitiveRefs)
+	}
+	maxDepth := 0
+	if len(args) > 1 {
+		// JSON numbers decode as float64
+		depth, ok := args[1].(float64)
+		if !ok || depth < 0 {
+			return nil, fmt.Errorf("%s: invalid depth argument", commandTransitiveRefs)
+		}
+		maxDepth = int(depth)
+	}
+
+	if _, exists := s.index.Get(root); !exists {
+		return nil, fmt.Errorf("note '%s' not found", root)
+	}
+	return s.transitiveRefs(ctx, root, maxDepth)
+}
+
+// transitiveRefs walks the link graph breadth-first from root, so each note gets its shortest depth
+func (s *LanguageServer) transitiveRefs(ctx context.Context, root string, maxDepth int) (*TransitiveRefsResult, error) {
+	result := &TransitiveRefsResult{Root: root, Notes: []ReachableNote{}, Missing: []string{}}
+
+	visited := map[string]bool{root: true}
+	missing := make(map[string]bool)
+	frontier := []string{root}
+	for depth := 1; len(frontier) > 0 && (maxDepth == 0 || depth <= maxDepth); depth++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var next []string
+		for _, source := range frontier {
+			for _, link := range s.index.Links().Outgoing(source) {
+				if visited[link.Target] || missing[link.Target] {
+					continue
+				}
+				note, exists := s.index.Get(link.Target)
+				if !exists {
+					// \ref{} also points at labels, which are not part of the note graph
+					if len(s.index.Labels().Definitions(link.Target)) == 0 {
+						missing[link.Target] = true
+						result.Missing = append(result.Missing, link.Target)
+					}
+					continue
+				}
+				visited[link.Target] = true
+				next = append(next, link.Target)
+				result.Notes = append(result.Notes, ReachableNote{
+					Slug:  note.Slug,
+					Title: note.Title,
+					URI:   pathToURI(s.notePath(note.Filename)),
+					Depth: depth,
+					Via:   source,
+				})
+			}
+		}
+		frontier = next
+	}
+
+	sort.SliceStable(result.Notes, func(i, j int) bool {
+		if result.Notes[i].Depth != result.Notes[j].Depth {
+			return result.Notes[i].Depth < result.Notes[j].Depth
+		}
+		return result.Notes[i].Slug < result.Notes[j].Slug
+	})
+	sort.Strings(result.Missing)
+	return result, nil
+}
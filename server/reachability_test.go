@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestTransitiveRefs tests the dependency closure of a note with depths
+func TestTransitiveRefs(t *testing.T) {
+	tempDir := t.TempDir()
+	notes := map[string]string{
+		"20240101-paper.tex":     "\\input{intro}\n\\ref{methods}\n\\ref{eq:main}",
+		"20240102-intro.tex":     "\\ref{methods} and \\ref{gone}",
+		"20240103-methods.tex":   "\\include{appendix}\n\\label{eq:main}",
+		"20240104-appendix.tex":  "\\ref{paper}",
+		"20240105-unrelated.tex": "\\ref{paper}",
+	}
+	for name, content := range notes {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	result, err := ls.transitiveRefsCommand(context.Background(), []interface{}{"paper"})
+	if err != nil {
+		t.Fatalf("transitiveRefsCommand failed: %v", err)
+	}
+
+	want := []ReachableNote{
+		{Slug: "intro", Depth: 1, Via: "paper"},
+		{Slug: "methods", Depth: 1, Via: "paper"},
+		{Slug: "appendix", Depth: 2, Via: "methods"},
+	}
+	if len(result.Notes) != len(want) {
+		t.Fatalf("expected %d notes, got %+v", len(want), result.Notes)
+	}
+	for i, note := range result.Notes {
+		if note.Slug != want[i].Slug || note.Depth != want[i].Depth || note.Via != want[i].Via {
+			t.Errorf("note %d: expected %+v, got %+v", i, want[i], note)
+		}
+	}
+	// Labels are not missing notes
+	if len(result.Missing) != 1 || result.Missing[0] != "gone" {
+		t.Errorf("expected missing [gone], got %v", result.Missing)
+	}
+
+	limited, err := ls.transitiveRefsCommand(context.Background(), []interface{}{"paper", float64(1)})
+	if err != nil {
+		t.Fatalf("transitiveRefsCommand failed: %v", err)
+	}
+	if len(limited.Notes) != 2 {
+		t.Errorf("expected depth 1 only, got %+v", limited.Notes)
+	}
+
+	if _, err := ls.transitiveRefsCommand(context.Background(), []interface{}{"nope"}); err == nil {
+		t.Error("expected unknown root to be rejected")
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// MethodRecentNotes is the custom request returning the most recently opened notes
+const MethodRecentNotes = "lx/recentNotes"
+
+// recentFilename is the file in the vault cache keeping when notes were last opened
+const recentFilename = "recent.json"
+
+// recentSaveDelay is how long opens settle before the history is written, so switching through
+// many notes writes the file once
+const recentSaveDelay = 2 * time.Second
+
+// RecentNotesParams optionally caps the number of notes returned
+// A zero limit returns the whole history
+type RecentNotesParams struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// RecentNote is a note in the lx/recentNotes result
+type RecentNote struct {
+	Slug   string               `json:"slug"`
+	Title  string               `json:"title"`
+	URI    protocol.DocumentURI `json:"uri"`
+	Opened time.Time            `json:"opened"`
+}
+
+// recentHistory tracks when notes were last opened, persisted as slug -> time
+type recentHistory struct {
+	mu     sync.Mutex
+	path   string // empty keeps the history in memory only
+	opened map[string]time.Time
+	save   *time.Timer // pending write of the history, nil when it is saved
+}
+
+// loadRecentHistory reads the history at path, starting empty when it is missing or unreadable
+func loadRecentHistory(path string) *recentHistory {
+	history := &recentHistory{path: path, opened: make(map[string]time.Time)}
+	if path == "" {
+		return history
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &history.opened)
+	}
+	return history
+}
+
+// touch records a note being opened, keeping only the size most recent notes
+// The history is written once opens settle for recentSaveDelay, reporting the outcome to saved
+func (h *recentHistory) touch(slug string, at time.Time, size int, saved func(error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.opened[slug] = at
+	if size > 0 && len(h.opened) > size {
+		for _, stale := range h.sorted()[size:] {
+			delete(h.opened, stale)
+		}
+	}
+
+	if h.path == "" {
+		return
+	}
+	if h.save != nil {
+		h.save.Stop()
+	}
+	h.save = time.AfterFunc(recentSaveDelay, func() {
+		saved(h.flush())
+	})
+}
+
+// flush writes a pending change of the history now
+func (h *recentHistory) flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.save == nil {
+		return nil
+	}
+	h.save.Stop()
+	h.save = nil
+
+	data, err := json.Marshal(h.opened)
+	if err != nil {
+		return fmt.Errorf("failed to encode recent notes: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(h.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recent notes: %w", err)
+	}
+	return nil
+}
+
+// list returns the slugs and open times, most recent first
+func (h *recentHistory) list() ([]string, map[string]time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	opened := make(map[string]time.Time, len(h.opened))
+	for slug, at := range h.opened {
+		opened[slug] = at
+	}
+	return h.sorted(), opened
+}
+
+// sorted returns the slugs most recently opened first, by slug on ties
+// Callers hold h.mu
+func (h *recentHistory) sorted() []string {
+	slugs := make([]string, 0, len(h.opened))
+	for slug := range h.opened {
+		slugs = append(slugs, slug)
+	}
+	sort.Slice(slugs, func(i, j int) bool {
+		a, b := h.opened[slugs[i]], h.opened[slugs[j]]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return slugs[i] < slugs[j]
+	})
+	return slugs
+}
+
+// recentNotes returns the open history of the vault, loading it on first use
+func (s *LanguageServer) recentNotes() *recentHistory {
+	s.recentOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.recent == nil {
+			s.recent = loadRecentHistory(s.recentPath())
+		}
+	})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recent
+}
+
+// flushRecent writes the open history right away, before the server stops or leaves the vault
+func (s *LanguageServer) flushRecent() {
+	s.mu.RLock()
+	recent := s.recent
+	s.mu.RUnlock()
+	if recent == nil {
+		return
+	}
+	if err := recent.flush(); err != nil {
+		s.logf(protocol.MessageTypeWarning, "Failed to save recent notes: %v", err)
+	}
+}
+
+// recentPath returns where the open history of the vault is kept
+func (s *LanguageServer) recentPath() string {
+	if s.vault == nil || s.vault.CachePath == "" {
+		return ""
+	}
+	return filepath.Join(s.vault.CachePath, recentFilename)
+}
+
+// recordOpen notes that a document was opened, for lx/recentNotes
+// Only notes of the vault are recorded, not other LaTeX files the editor opens
+func (s *LanguageServer) recordOpen(uri protocol.DocumentURI) {
+	if !s.IsManaged(uri) {
+		return
+	}
+	slug := s.parseFilenameToSlug(filepath.Base(uriToPath(uri)))
+	s.recentNotes().touch(slug, time.Now(), s.settings().RecentNotesSize, func(err error) {
+		if err != nil {
+			s.logf(protocol.MessageTypeWarning, "Failed to save recent notes: %v", err)
+		}
+	})
+}
+
+// Handle lx/recentNotes request
+// Notes that no longer exist are left out
+func (s *LanguageServer) RecentNotes(ctx context.Context, params *RecentNotesParams) ([]RecentNote, error) {
+	slugs, opened := s.recentNotes().list()
+
+	notes := []RecentNote{}
+	for _, slug := range slugs {
+		if params.Limit > 0 && len(notes) == params.Limit {
+			break
+		}
+		note, exists := s.index.Get(slug)
+		if !exists {
+			continue
+		}
+		notes = append(notes, RecentNote{
+			Slug:   slug,
+			Title:  note.Title,
+			URI:    pathToURI(s.notePath(note.Filename)),
+			Opened: opened[slug],
+		})
+	}
+	return notes, nil
+}
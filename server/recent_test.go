@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestRecentNotes tests that opened notes are listed most recent first and survive a restart
+func TestRecentNotes(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	for _, slug := range []string{"first", "second", "third"} {
+		os.WriteFile(filepath.Join(v.NotesPath, "20240101-"+slug+".tex"), []byte("%% Metadata\n% title: "+slug+"\n"), 0644)
+	}
+
+	config := DefaultConfig()
+	config.RecentNotesSize = 2
+	ls := &LanguageServer{vault: v, index: NewIndex(), config: &config, documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	for _, slug := range []string{"first", "second", "third", "second"} {
+		uri := pathToURI(filepath.Join(v.NotesPath, "20240101-"+slug+".tex"))
+		ls.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: uri, Text: "%% Metadata\n"},
+		})
+		time.Sleep(time.Millisecond) // Distinct open times
+	}
+
+	notes, err := ls.RecentNotes(context.Background(), &RecentNotesParams{})
+	if err != nil {
+		t.Fatalf("RecentNotes failed: %v", err)
+	}
+	if len(notes) != 2 || notes[0].Slug != "second" || notes[1].Slug != "third" || notes[0].Title != "second" {
+		t.Fatalf("unexpected recent notes %+v", notes)
+	}
+
+	// The history is written once opens settle, or right away on shutdown
+	if _, err := os.Stat(filepath.Join(v.CachePath, recentFilename)); !os.IsNotExist(err) {
+		t.Errorf("expected the history write to wait for opens to settle, got %v", err)
+	}
+	ls.flushRecent()
+
+	// A new server picks the history up from the cache
+	restarted := &LanguageServer{vault: v, index: ls.index}
+	notes, _ = restarted.RecentNotes(context.Background(), &RecentNotesParams{Limit: 1})
+	if len(notes) != 1 || notes[0].Slug != "second" {
+		t.Errorf("expected the persisted history, got %+v", notes)
+	}
+
+	// Files outside the notes directory are not recorded
+	ls.recordOpen(pathToURI(filepath.Join(v.TemplatesPath, "macros.tex")))
+	if _, opened := ls.recentNotes().list(); len(opened) != 2 {
+		t.Errorf("expected only notes to be recorded, got %v", opened)
+	}
+
+	// Notes deleted since are left out
+	ls.index.Delete("second")
+	notes, _ = ls.RecentNotes(context.Background(), &RecentNotesParams{})
+	if len(notes) != 1 || notes[0].Slug != "third" {
+		t.Errorf("expected deleted notes to be skipped, got %+v", notes)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+
+	"go.lsp.dev/protocol"
+)
+
+// Handle References request (Find References)
+// Returns every reference across the vault pointing at the note under the cursor,
+// or at the current note when the cursor is not on a reference
+func (s *LanguageServer) References(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	slug := s.getSlugAtPosition(content, params.Position)
+	if slug == "" {
+		// Not on a reference: find backlinks of the current note
+		slug = s.parseFilenameToSlug(filepath.Base(uriToPath(params.TextDocument.URI)))
+	} else if note, exists := s.resolveNote(slug, params.TextDocument.URI); exists {
+		slug = note.Slug // References by alias are indexed under the slug
+	}
+
+	links := s.index.Links().Incoming(slug)
+	locations := make([]protocol.Location, 0, len(links)+1)
+
+	if params.Context.IncludeDeclaration {
+		if note, exists := s.index.Get(slug); exists {
+			locations = append(locations, protocol.Location{
+				URI: pathToURI(s.notePath(note.Filename)),
+			})
+		}
+	}
+
+	for _, link := range links {
+		locations = append(locations, protocol.Location{
+			URI:   pathToURI(s.notePath(link.Filename)),
+			Range: link.Range,
+		})
+	}
+
+	return locations, nil
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestReferences_Backlinks tests finding references to a note across the vault
+func TestReferences_Backlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	testNotes := map[string]string{
+		"20240101-graph-theory.tex":   "%% Metadata\n%% title: Graph Theory\n\nTrees are graphs.",
+		"20240102-linear-algebra.tex": "%% Metadata\n%% title: Linear Algebra\n\nSee \\ref{graph-theory}.\n% \\ref{graph-theory} commented out",
+		"20240103-topology.tex":       "%% Metadata\n%% title: Topology\n\n\\cite{graph-theory} and \\ref{linear-algebra}",
+	}
+	for filename, content := range testNotes {
+		if err := os.WriteFile(filepath.Join(notesPath, filename), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test note: %v", err)
+		}
+	}
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath},
+		index: NewIndex(),
+	}
+
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	// Cursor in the target note itself (not on a reference)
+	params := &protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: pathToURI(filepath.Join(notesPath, "20240101-graph-theory.tex")),
+			},
+			Position: protocol.Position{Line: 3, Character: 0},
+		},
+	}
+
+	locations, err := ls.References(context.Background(), params)
+	if err != nil {
+		t.Fatalf("References failed: %v", err)
+	}
+	if len(locations) != 2 {
+		t.Fatalf("expected 2 references to graph-theory, got %d", len(locations))
+	}
+
+	// Cursor on a reference inside another note, including the declaration
+	params.TextDocument.URI = pathToURI(filepath.Join(notesPath, "20240103-topology.tex"))
+	params.Position = protocol.Position{Line: 3, Character: 30}
+	params.Context.IncludeDeclaration = true
+
+	locations, err = ls.References(context.Background(), params)
+	if err != nil {
+		t.Fatalf("References failed: %v", err)
+	}
+	if len(locations) != 2 {
+		t.Fatalf("expected declaration and 1 reference for linear-algebra, got %d locations", len(locations))
+	}
+	expectedURI := pathToURI(filepath.Join(notesPath, "20240102-linear-algebra.tex"))
+	if locations[0].URI != expectedURI {
+		t.Errorf("expected URI %s, got %s", expectedURI, locations[0].URI)
+	}
+}
+
+// TestLinkIndex_WatcherUpdates tests that the reverse-link index follows file changes
+func TestLinkIndex_WatcherUpdates(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath},
+		index: NewIndex(),
+	}
+
+	testFile := filepath.Join(notesPath, "20240101-source.tex")
+	if err := os.WriteFile(testFile, []byte("\\ref{target}"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	ls.updateIndexForFile(testFile)
+
+	if got := len(ls.index.Links().Incoming("target")); got != 1 {
+		t.Fatalf("expected 1 incoming link, got %d", got)
+	}
+
+	// Modify: reference moves to another note
+	if err := os.WriteFile(testFile, []byte("\\ref{other}"), 0644); err != nil {
+		t.Fatalf("failed to modify test file: %v", err)
+	}
+	ls.updateIndexForFile(testFile)
+
+	if got := len(ls.index.Links().Incoming("target")); got != 0 {
+		t.Errorf("expected 0 incoming links after modification, got %d", got)
+	}
+	if got := len(ls.index.Links().Incoming("other")); got != 1 {
+		t.Errorf("expected 1 incoming link to 'other', got %d", got)
+	}
+
+	// Delete
+	os.Remove(testFile)
+	ls.updateIndexForFile(testFile)
+
+	if got := len(ls.index.Links().Incoming("other")); got != 0 {
+		t.Errorf("expected 0 incoming links after deletion, got %d", got)
+	}
+}
+
+// TestDanglingReferences tests the vault-wide cleanup after a note is deleted
+func TestDanglingReferences(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	testNotes := map[string]string{
+		"20240101-first.tex":  "See \\ref{deleted} here.",
+		"20240102-second.tex": "\\cite{deleted} and \\ref{deleted}",
+	}
+	for filename, content := range testNotes {
+		if err := os.WriteFile(filepath.Join(notesPath, filename), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test note: %v", err)
+		}
+	}
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath},
+		index: NewIndex(),
+	}
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	// Removal strips the whole command
+	edit := ls.danglingReferencesEdit("deleted", "")
+	if len(edit.Changes) != 2 {
+		t.Fatalf("expected edits in 2 files, got %d", len(edit.Changes))
+	}
+	firstEdits := edit.Changes[pathToURI(filepath.Join(notesPath, "20240101-first.tex"))]
+	if len(firstEdits) != 1 || firstEdits[0].NewText != "" || firstEdits[0].Range.Start.Character != 4 {
+		t.Errorf("unexpected removal edit: %+v", firstEdits)
+	}
+
+	// Retargeting replaces only the slug
+	edit = ls.danglingReferencesEdit("deleted", "replacement")
+	secondEdits := edit.Changes[pathToURI(filepath.Join(notesPath, "20240102-second.tex"))]
+	if len(secondEdits) != 2 {
+		t.Fatalf("expected 2 edits in second note, got %d", len(secondEdits))
+	}
+	for _, e := range secondEdits {
+		if e.NewText != "replacement" {
+			t.Errorf("expected retarget to 'replacement', got %q", e.NewText)
+		}
+	}
+
+	// Broken-reference diagnostics carry the bulk quick fix
+	content := testNotes["20240101-first.tex"]
+	req := &codeActionRequest{Content: content}
+	found := false
+	for _, action := range ls.collectCodeActions(req, ls.analyzeDiagnostics(content)) {
+		if action.Command != nil && action.Command.Command == commandFixDanglingReferences {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a %s code action", commandFixDanglingReferences)
+	}
+}
+
+// TestRemoveReferenceFix tests removing and commenting out a single broken reference
+func TestRemoveReferenceFix(t *testing.T) {
+	ls := &LanguageServer{index: NewIndex()}
+
+	tests := []struct {
+		name      string
+		content   string
+		commented string
+	}{
+		{"reference mid-line", `See \ref{gone} for details.`, "%\\ref{gone}\n"},
+		{"reference at end of line", `See \ref{gone}`, "%\\ref{gone}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &codeActionRequest{URI: "file:///note.tex", Content: tt.content}
+			diagnostics := ls.analyzeDiagnostics(tt.content)
+			if len(diagnostics) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+			}
+
+			actions := removeReferenceFix(ls, req, diagnostics[0])
+			if len(actions) != 2 {
+				t.Fatalf("expected 2 actions, got %d", len(actions))
+			}
+
+			remove := actions[0].Edit.Changes[req.URI][0]
+			if remove.NewText != "" || remove.Range.Start.Character != 4 || remove.Range.End.Character != 14 {
+				t.Errorf("unexpected remove edit %+v", remove)
+			}
+
+			comment := actions[1].Edit.Changes[req.URI][0]
+			if comment.NewText != tt.commented || comment.Range != remove.Range {
+				t.Errorf("unexpected comment edit %+v", comment)
+			}
+		})
+	}
+}
+
+// TestReferenceMacros tests that configured custom macros are treated like \ref
+func TestReferenceMacros(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	content := "%% Metadata\n% title: Trees\n\n\\lxlink{graph-theory} and \\seealso{missing}\n\\other{graph-theory}\n\\lxlink{gr"
+	files := map[string]string{
+		"20240101-graph-theory.tex": "%% Metadata\n% title: Graph Theory\n",
+		"20240102-trees.tex":        content,
+	}
+	for name, text := range files {
+		os.WriteFile(filepath.Join(notesPath, name), []byte(text), 0644)
+	}
+
+	config := DefaultConfig()
+	config.ReferenceMacros = []string{"lxlink", "\\seealso{}", "not a macro"}
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+		config:    &config,
+	}
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	if macros := ls.referenceMacros(); len(macros) != 2 || macros[0] != "lxlink" || macros[1] != "seealso" {
+		t.Errorf("expected macros [lxlink seealso], got %v", macros)
+	}
+
+	// Backlinks, definition and hover all resolve through the custom macro
+	if incoming := ls.index.Links().Incoming("graph-theory"); len(incoming) != 1 {
+		t.Errorf("expected 1 backlink through \\lxlink, got %d", len(incoming))
+	}
+	if slug := ls.getSlugAtPosition(content, protocol.Position{Line: 3, Character: 10}); slug != "graph-theory" {
+		t.Errorf("expected slug 'graph-theory' under \\lxlink, got %q", slug)
+	}
+	if slug := ls.getSlugAtPosition(content, protocol.Position{Line: 4, Character: 10}); slug != "" {
+		t.Errorf("expected unconfigured macro to be ignored, got %q", slug)
+	}
+
+	// Broken references through a custom macro are reported
+	broken := 0
+	for _, diag := range ls.analyzeDiagnostics(content) {
+		if diag.Code == diagnosticCodeBrokenRef {
+			broken++
+			if diag.Message != "Note 'missing' not found" {
+				t.Errorf("unexpected diagnostic: %s", diag.Message)
+			}
+		}
+	}
+	if broken != 1 {
+		t.Errorf("expected 1 broken reference, got %d", broken)
+	}
+
+	// Slugs complete inside the custom macro
+	uri := pathToURI(filepath.Join(notesPath, "20240102-trees.tex"))
+	ls.documents[uri] = content
+	list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 5, Character: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Label != "graph-theory" {
+		t.Errorf("expected completion 'graph-theory', got %v", list.Items)
+	}
+}
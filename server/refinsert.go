@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// What accepting a reference completion inserts, see CompletionConfig.RefInsert
+const (
+	refInsertSlug       = "slug"       // the slug alone
+	refInsertCloseBrace = "closeBrace" // the slug and the closing brace, unless it is already there
+	refInsertFull       = "full"       // as closeBrace, and [[ is completed into a whole \ref{slug}
+)
+
+var refInsertModes = map[string]bool{refInsertSlug: true, refInsertCloseBrace: true, refInsertFull: true}
+
+// wikiRefPattern matches a line prefix inside [[...
+var wikiRefPattern = regexp.MustCompile(`\[\[([^\[\]]*)$`)
+
+// slugTail returns how many characters of a slug follow the cursor, as when completing in the middle of one
+func slugTail(rest string) int {
+	for i := 0; i < len(rest); i++ {
+		if c := rest[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return i
+		}
+	}
+	return len(rest)
+}
+
+// closeRefEdits makes reference items inside \ref{ replace the typed slug and close the brace
+// The rest of a slug after the cursor is removed through additionalTextEdits
+func closeRefEdits(items []protocol.CompletionItem, line string, lineNum, start, cursor int) {
+	rest := line[cursor:]
+	tail := slugTail(rest)
+	closed := strings.HasPrefix(rest[tail:], "}")
+
+	var cleanup []protocol.TextEdit
+	if tail > 0 {
+		cleanup = []protocol.TextEdit{{Range: lineRange(lineNum, cursor, cursor+tail)}}
+	}
+	for i := range items {
+		newText := items[i].Label
+		if !closed {
+			newText += "}"
+		}
+		items[i].InsertText = ""
+		items[i].TextEdit = &protocol.TextEdit{Range: lineRange(lineNum, start, cursor), NewText: newText}
+		items[i].AdditionalTextEdits = cleanup
+	}
+}
+
+// wikiRefCompletions completes [[slug into \ref{slug}, dropping the brackets the client may have closed
+func (s *LanguageServer) wikiRefCompletions(lines []string, lineNum, cursor int, ownRoot string) []protocol.CompletionItem {
+	line := lines[lineNum]
+	match := wikiRefPattern.FindStringSubmatchIndex(line[:cursor])
+	if match == nil {
+		return nil
+	}
+	items := filterCompletions(s.getRefCompletions(currentSectionHeading(lines, lineNum), ownRoot), line[match[2]:match[3]])
+
+	rest := line[cursor:]
+	tail := slugTail(rest)
+	tail += len(rest[tail:]) - len(strings.TrimPrefix(strings.TrimPrefix(rest[tail:], "]"), "]"))
+
+	var cleanup []protocol.TextEdit
+	if tail > 0 {
+		cleanup = []protocol.TextEdit{{Range: lineRange(lineNum, cursor, cursor+tail)}}
+	}
+	for i := range items {
+		items[i].InsertText = ""
+		items[i].FilterText = "[[" + items[i].Label
+		items[i].TextEdit = &protocol.TextEdit{
+			Range:   lineRange(lineNum, match[0], cursor),
+			NewText: fmt.Sprintf("\\ref{%s}", items[i].Label),
+		}
+		items[i].AdditionalTextEdits = cleanup
+	}
+	return items
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestRefInsert tests the edits of reference completions for each completion.refInsert mode
+func TestRefInsert(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "20240101-a.tex")
+	content := "See \\ref{gra}.\nSee \\ref{graph-th\nSee [[gra]] too."
+	os.WriteFile(testFile, []byte(content), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.index.Set("graph-theory", &NoteHeader{Slug: "graph-theory", Title: "Graph Theory", Filename: "20240102-graph-theory.tex"})
+	complete := func(mode string, line, character uint32) protocol.CompletionItem {
+		t.Helper()
+		ls.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{
+			Settings: map[string]interface{}{"completion": map[string]interface{}{"refInsert": mode}},
+		})
+		list, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+				Position:     protocol.Position{Line: line, Character: character},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Completion failed: %v", err)
+		}
+		if len(list.Items) != 1 {
+			t.Fatalf("%s: expected one item, got %+v", mode, list.Items)
+		}
+		return list.Items[0]
+	}
+
+	if item := complete(refInsertSlug, 0, 12); item.InsertText != "graph-theory" || item.TextEdit != nil {
+		t.Errorf("slug: expected the slug alone, got %+v", item)
+	}
+
+	// The brace is already closed, so only the typed slug is replaced
+	item := complete(refInsertCloseBrace, 0, 11)
+	if item.TextEdit == nil || item.TextEdit.NewText != "graph-theory" || item.TextEdit.Range != lineRange(0, 9, 11) {
+		t.Errorf("closeBrace: unexpected edit %+v", item.TextEdit)
+	}
+	if len(item.AdditionalTextEdits) != 1 || item.AdditionalTextEdits[0].Range != lineRange(0, 11, 12) {
+		t.Errorf("closeBrace: expected the rest of the slug to be removed, got %+v", item.AdditionalTextEdits)
+	}
+
+	if item := complete(refInsertCloseBrace, 1, 17); item.TextEdit == nil || item.TextEdit.NewText != "graph-theory}" {
+		t.Errorf("closeBrace: expected the brace to be closed, got %+v", item.TextEdit)
+	}
+
+	item = complete(refInsertFull, 2, 9)
+	if item.TextEdit == nil || item.TextEdit.NewText != "\\ref{graph-theory}" || item.TextEdit.Range != lineRange(2, 4, 9) {
+		t.Errorf("full: unexpected edit %+v", item.TextEdit)
+	}
+	if len(item.AdditionalTextEdits) != 1 || item.AdditionalTextEdits[0].Range != lineRange(2, 9, 11) {
+		t.Errorf("full: expected the closing brackets to be removed, got %+v", item.AdditionalTextEdits)
+	}
+
+	if _, err := parseConfig(map[string]interface{}{"completion": map[string]interface{}{"refInsert": "brace"}}, DefaultConfig()); err == nil {
+		t.Error("expected an unknown refInsert mode to be rejected")
+	}
+}
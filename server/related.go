@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandRelatedNotes lists the notes closest to a note in the note graph
+// Arguments: [slug] or [slug, limit]
+const commandRelatedNotes = "lx.relatedNotes"
+
+// defaultRelatedLimit caps lx.relatedNotes when no limit is given
+const defaultRelatedLimit = 10
+
+func init() {
+	registerCommand(commandRelatedNotes, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.relatedNotesCommand(ctx, args)
+	})
+}
+
+// RelatedNote is a note in the lx.relatedNotes result
+type RelatedNote struct {
+	Slug     string               `json:"slug"`
+	Title    string               `json:"title"`
+	URI      protocol.DocumentURI `json:"uri"`
+	Distance int                  `json:"distance"` // 1 for notes linked with the note, 2 for notes linked through another
+	Shared   int                  `json:"shared"`   // notes both are linked with
+	Path     []string             `json:"path"`     // slugs from the note to this one
+}
+
+// relatedNotesCommand handles lx.relatedNotes
+func (s *LanguageServer) relatedNotesCommand(ctx context.Context, args []interface{}) ([]RelatedNote, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a slug argument", commandRelatedNotes)
+	}
+	slug, ok := args[0].(string)
+	if !ok || slug == "" {
+		return nil, fmt.Errorf("%s: invalid slug argument", commandRelatedNotes)
+	}
+	limit := defaultRelatedLimit
+	if len(args) > 1 {
+		// JSON numbers decode as float64
+		value, ok := args[1].(float64)
+		if !ok || value < 1 {
+			return nil, fmt.Errorf("%s: invalid limit argument", commandRelatedNotes)
+		}
+		limit = int(value)
+	}
+
+	if _, exists := s.index.Get(slug); !exists {
+		return nil, fmt.Errorf("note '%s' not found", slug)
+	}
+	return s.relatedNotes(slug, limit), nil
+}
+
+// relatedNotes ranks the notes within two links of slug, references followed in either direction:
+// nearer notes first, then notes sharing more neighbors with it
+func (s *LanguageServer) relatedNotes(slug string, limit int) []RelatedNote {
+	graph := s.index.Graph()
+	neighbors := graph.Neighbors(slug)
+	linked := make(map[string]bool, len(neighbors))
+	for _, neighbor := range neighbors {
+		linked[neighbor] = true
+	}
+
+	candidates := make(map[string]int) // slug -> distance
+	for _, neighbor := range neighbors {
+		candidates[neighbor] = 1
+		for _, next := range graph.Neighbors(neighbor) {
+			if next != slug && !linked[next] {
+				candidates[next] = 2
+			}
+		}
+	}
+
+	related := make([]RelatedNote, 0, len(candidates))
+	for candidate, distance := range candidates {
+		shared := 0
+		for _, neighbor := range graph.Neighbors(candidate) {
+			if linked[neighbor] {
+				shared++
+			}
+		}
+		related = append(related, RelatedNote{Slug: candidate, Distance: distance, Shared: shared})
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Distance != related[j].Distance {
+			return related[i].Distance < related[j].Distance
+		}
+		if related[i].Shared != related[j].Shared {
+			return related[i].Shared > related[j].Shared
+		}
+		return related[i].Slug < related[j].Slug
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+
+	for i := range related {
+		if note, exists := s.index.Get(related[i].Slug); exists {
+			related[i].Title = note.Title
+			related[i].URI = pathToURI(s.notePath(note.Filename))
+		}
+		related[i].Path = graph.ShortestPath(slug, related[i].Slug)
+	}
+	return related
+}
@@ -0,0 +1,329 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// MethodSearch is the custom request querying the full-text index
+	MethodSearch = "lx/search"
+
+	// MethodGrep is the custom request matching a regular expression against note contents
+	MethodGrep = "lx/grep"
+)
+
+// reindexDebounce is how long edits to an open document settle before the search and link indexes catch up
+const reindexDebounce = 300 * time.Millisecond
+
+// defaultSearchLimit caps results when a request does not set a limit
+const defaultSearchLimit = 50
+
+// commandNamePattern matches LaTeX command names, which are not indexed as words
+var commandNamePattern = regexp.MustCompile(`\\[A-Za-z@]+`)
+
+// SearchIndex is an inverted index from word tokens to the notes containing them
+type SearchIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]int // token -> slug -> occurrences
+	docs     map[string]map[string]int // slug -> token -> occurrences
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		postings: make(map[string]map[string]int),
+		docs:     make(map[string]map[string]int),
+	}
+}
+
+// Set replaces the indexed content of a note
+// Only tokens whose counts changed touch the postings, so small edits stay cheap
+func (x *SearchIndex) Set(slug, content string) {
+	tokens := tokenize(content)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	previous := x.docs[slug]
+	for token := range previous {
+		if _, kept := tokens[token]; !kept {
+			x.removePostingLocked(token, slug)
+		}
+	}
+	for token, count := range tokens {
+		if previous[token] == count {
+			continue
+		}
+		if x.postings[token] == nil {
+			x.postings[token] = make(map[string]int)
+		}
+		x.postings[token][slug] = count
+	}
+
+	if len(tokens) == 0 {
+		delete(x.docs, slug)
+		return
+	}
+	x.docs[slug] = tokens
+}
+
+// Delete removes a note from the index
+func (x *SearchIndex) Delete(slug string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for token := range x.docs[slug] {
+		x.removePostingLocked(token, slug)
+	}
+	delete(x.docs, slug)
+}
+
+// removePostingLocked drops slug from the posting list of token
+// Caller must hold the write lock
+func (x *SearchIndex) removePostingLocked(token, slug string) {
+	delete(x.postings[token], slug)
+	if len(x.postings[token]) == 0 {
+		delete(x.postings, token)
+	}
+}
+
+// Search returns the notes containing every token of the query, best matches first
+// The last query token also matches as a prefix, so results appear while typing
+func (x *SearchIndex) Search(query string) map[string]int {
+	terms := tokenList(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var scores map[string]int
+	for i, term := range terms {
+		matches := make(map[string]int)
+		for slug, count := range x.postings[term] {
+			matches[slug] += count
+		}
+		if i == len(terms)-1 {
+			for token, slugs := range x.postings {
+				if token != term && strings.HasPrefix(token, term) {
+					for slug, count := range slugs {
+						matches[slug] += count
+					}
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = matches
+			continue
+		}
+		for slug := range scores {
+			if count, ok := matches[slug]; ok {
+				scores[slug] += count
+			} else {
+				delete(scores, slug)
+			}
+		}
+	}
+	return scores
+}
+
+// tokenize counts the words of content, ignoring LaTeX command names
+func tokenize(content string) map[string]int {
+	counts := make(map[string]int)
+	for _, token := range tokenList(commandNamePattern.ReplaceAllString(content, " ")) {
+		counts[token]++
+	}
+	return counts
+}
+
+// tokenList splits text into lowercase words of at least two letters or digits
+func tokenList(text string) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 2 {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// scheduleReindex re-indexes an open document once edits have settled
+func (s *LanguageServer) scheduleReindex(uri protocol.DocumentURI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reindexTimers == nil {
+		s.reindexTimers = make(map[protocol.DocumentURI]*time.Timer)
+	}
+	if timer, ok := s.reindexTimers[uri]; ok {
+		timer.Stop()
+	}
+	s.reindexTimers[uri] = time.AfterFunc(reindexDebounce, func() {
+		s.mu.Lock()
+		delete(s.reindexTimers, uri)
+		s.mu.Unlock()
+		s.reindexDocument(uri)
+	})
+}
+
+// cancelReindex drops a pending update, e.g. when the document closes
+func (s *LanguageServer) cancelReindex(uri protocol.DocumentURI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if timer, ok := s.reindexTimers[uri]; ok {
+		timer.Stop()
+		delete(s.reindexTimers, uri)
+	}
+}
+
+// reindexDocument indexes the text and links of what the user currently sees: the open buffer, or the file once closed
+// Backlinks then follow references as they are typed, not only once saved
+func (s *LanguageServer) reindexDocument(uri protocol.DocumentURI) {
+	slug := s.parseFilenameToSlug(filepath.Base(uriToPath(uri)))
+	note, exists := s.index.Get(slug)
+	if !exists {
+		return
+	}
+	content, err := s.GetDocument(uri)
+	if err != nil {
+		s.index.Search().Delete(slug)
+		s.index.Links().Delete(slug)
+		return
+	}
+	s.index.Search().Set(slug, content)
+	s.index.Links().Set(slug, s.noteLinks(slug, note.Filename, content))
+}
+
+// SearchParams is a full-text query
+type SearchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// SearchHit is a note matching a query
+type SearchHit struct {
+	Slug  string               `json:"slug"`
+	Title string               `json:"title"`
+	URI   protocol.DocumentURI `json:"uri"`
+	Score int                  `json:"score"`
+}
+
+// Handle lx/search request
+func (s *LanguageServer) Search(ctx context.Context, params *SearchParams) ([]SearchHit, error) {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	hits := []SearchHit{}
+	for slug, score := range s.index.Search().Search(params.Query) {
+		note, exists := s.index.Get(slug)
+		if !exists {
+			continue
+		}
+		hits = append(hits, SearchHit{
+			Slug:  slug,
+			Title: note.Title,
+			URI:   pathToURI(s.notePath(note.Filename)),
+			Score: score,
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Slug < hits[j].Slug
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// GrepParams is a regular expression to match against note contents
+type GrepParams struct {
+	protocol.WorkDoneProgressParams
+
+	Pattern       string `json:"pattern"`
+	CaseSensitive bool   `json:"caseSensitive,omitempty"`
+	Limit         int    `json:"limit,omitempty"`
+}
+
+// GrepMatch is a single matching line
+type GrepMatch struct {
+	Slug  string               `json:"slug"`
+	URI   protocol.DocumentURI `json:"uri"`
+	Range protocol.Range       `json:"range"`
+	Line  string               `json:"line"`
+}
+
+// Handle lx/grep request
+// Open documents are matched as currently edited, not as last saved
+func (s *LanguageServer) Grep(ctx context.Context, params *GrepParams) ([]GrepMatch, error) {
+	expr := params.Pattern
+	if !params.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	ctx, progress := s.beginProgress(ctx, params.WorkDoneToken, "Searching notes")
+	defer func() { progress.End(ctx, "") }()
+
+	notes := s.index.All()
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Filename < notes[j].Filename })
+
+	matches := []GrepMatch{}
+	reported := uint32(0)
+	for i, note := range notes {
+		// A cancelled grep returns what it found so far
+		if err := ctx.Err(); err != nil {
+			return matches, nil
+		}
+		if percentage := uint32(i * 100 / len(notes)); percentage > reported {
+			progress.Report(ctx, note.Filename, percentage)
+			reported = percentage
+		}
+
+		uri := pathToURI(s.notePath(note.Filename))
+		content, err := s.GetDocument(uri)
+		if err != nil {
+			continue
+		}
+
+		for lineNum, line := range strings.Split(content, "\n") {
+			for _, loc := range pattern.FindAllStringIndex(line, -1) {
+				matches = append(matches, GrepMatch{
+					Slug:  note.Slug,
+					URI:   uri,
+					Range: lineRange(lineNum, loc[0], loc[1]),
+					Line:  line,
+				})
+				if len(matches) >= limit {
+					return matches, nil
+				}
+			}
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestSearchIndex tests token diffing in the inverted index
+func TestSearchIndex(t *testing.T) {
+	index := NewSearchIndex()
+	index.Set("graphs", `\section{Graphs} A graph has vertices and edges. \textbf{Graph} coloring.`)
+	index.Set("groups", `A group has an identity.`)
+
+	if scores := index.Search("graph"); scores["graphs"] != 3 || len(scores) != 1 {
+		t.Errorf("expected prefix match on graphs only, got %v", scores)
+	}
+	if scores := index.Search("textbf"); len(scores) != 0 {
+		t.Errorf("expected command names to be skipped, got %v", scores)
+	}
+	if scores := index.Search("has identity"); len(scores) != 1 || scores["groups"] == 0 {
+		t.Errorf("expected AND query to match groups only, got %v", scores)
+	}
+
+	// Dropped tokens leave the postings
+	index.Set("graphs", `A tree has no cycles.`)
+	if scores := index.Search("vertices"); len(scores) != 0 {
+		t.Errorf("expected stale token to be removed, got %v", scores)
+	}
+	if scores := index.Search("has"); len(scores) != 2 {
+		t.Errorf("expected shared token in both notes, got %v", scores)
+	}
+
+	index.Delete("graphs")
+	if _, exists := index.postings["cycles"]; exists {
+		t.Error("expected empty posting lists to be pruned")
+	}
+}
+
+// TestSearchTracksBuffers tests that search and grep reflect unsaved edits
+func TestSearchTracksBuffers(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	notePath := filepath.Join(notesPath, "20240101-graphs.tex")
+	os.WriteFile(notePath, []byte("%% Metadata\n% title: Graphs\n\nVertices and edges."), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.RebuildIndex(context.Background())
+	uri := pathToURI(notePath)
+
+	ls.DidChange(context.Background(), &protocol.DidChangeTextDocumentParams{
+		TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri}},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "%% Metadata\n% title: Graphs\n\nMatchings and edges."}},
+	})
+
+	// Grep reads buffers directly
+	matches, err := ls.Grep(context.Background(), &GrepParams{Pattern: "match\\w+"})
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Range.Start.Line != 3 || matches[0].Range.End.Character != 9 {
+		t.Errorf("unexpected grep matches: %+v", matches)
+	}
+
+	// The index catches up once edits settle
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hits, _ := ls.Search(context.Background(), &SearchParams{Query: "matchings"})
+		if len(hits) == 1 && hits[0].Slug == "graphs" && hits[0].Title == "Graphs" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("search did not pick up the buffer, got %+v", hits)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Closing discards the buffer
+	ls.DidClose(context.Background(), &protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if hits, _ := ls.Search(context.Background(), &SearchParams{Query: "matchings"}); len(hits) != 0 {
+		t.Errorf("expected search to fall back to the file, got %+v", hits)
+	}
+	if hits, _ := ls.Search(context.Background(), &SearchParams{Query: "vertices"}); len(hits) != 1 {
+		t.Errorf("expected saved content to be searchable, got %+v", hits)
+	}
+
+	if _, err := ls.Grep(context.Background(), &GrepParams{Pattern: "("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
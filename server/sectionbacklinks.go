@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// sectionBacklinks maps the heading lines of a note to the references from other notes
+// pointing at them through slug#section anchors, matched like findSectionAnchor by label or heading
+func (s *LanguageServer) sectionBacklinks(docURI protocol.DocumentURI, content string) map[int][]Link {
+	slug := s.parseFilenameToSlug(filepath.Base(uriToPath(docURI)))
+	byAnchor := s.index.Links().IncomingSections(slug)
+	if len(byAnchor) == 0 {
+		return nil
+	}
+
+	sections := make(map[int][]Link)
+	current := -1
+	for lineNum, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+		if match := sectionPattern.FindStringSubmatchIndex(line); match != nil {
+			current = lineNum
+			heading := kebabCase(strings.TrimSpace(line[match[4]:match[5]]))
+			for anchor, links := range byAnchor {
+				if kebabCase(anchor) == heading {
+					sections[current] = append(sections[current], links...)
+					delete(byAnchor, anchor) // Each reference counts for one section only
+				}
+			}
+		}
+		if current < 0 {
+			continue
+		}
+		for _, match := range labelPattern.FindAllStringSubmatch(line, -1) {
+			label := strings.TrimSpace(match[1])
+			if links, ok := byAnchor[label]; ok {
+				sections[current] = append(sections[current], links...)
+				delete(byAnchor, label)
+			}
+		}
+	}
+
+	// References from within the note are not backlinks
+	for line, links := range sections {
+		kept := links[:0]
+		for _, link := range links {
+			if link.Source != slug {
+				kept = append(kept, link)
+			}
+		}
+		if len(kept) == 0 {
+			delete(sections, line)
+		} else {
+			sections[line] = kept
+		}
+	}
+	return sections
+}
+
+// sectionBacklinkSources returns the distinct notes among links, sorted
+func sectionBacklinkSources(links []Link) []string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, link := range links {
+		if !seen[link.Source] {
+			seen[link.Source] = true
+			sources = append(sources, link.Source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// backlinkCount describes the number of references, e.g. "1 backlink" or "3 backlinks"
+func backlinkCount(links []Link) string {
+	return pluralBacklinks(len(links))
+}
+
+// annotateSectionSymbols adds the backlink count of linked sections to their symbol detail
+func annotateSectionSymbols(symbols []protocol.DocumentSymbol, sections map[int][]Link) {
+	for i := range symbols {
+		if symbols[i].Kind == protocol.SymbolKindModule {
+			if links, ok := sections[int(symbols[i].Range.Start.Line)]; ok {
+				symbols[i].Detail = fmt.Sprintf("%s — %s", symbols[i].Detail, backlinkCount(links))
+			}
+		}
+		annotateSectionSymbols(symbols[i].Children, sections)
+	}
+}
+
+// sectionBacklinkLenses labels linked section headings with the notes linking to them
+func (s *LanguageServer) sectionBacklinkLenses(docURI protocol.DocumentURI, content string) []protocol.CodeLens {
+	sections := s.sectionBacklinks(docURI, content)
+	lines := s.documentLines(content)
+
+	var lenses []protocol.CodeLens
+	for lineNum := range lines {
+		links, ok := sections[lineNum]
+		if !ok {
+			continue
+		}
+		lenses = append(lenses, protocol.CodeLens{
+			// An empty command renders as a plain label
+			Range:   lineRange(lineNum, 0, len(lines[lineNum])),
+			Command: &protocol.Command{Title: fmt.Sprintf("%s from %s", backlinkCount(links), strings.Join(sectionBacklinkSources(links), ", "))},
+		})
+	}
+	return lenses
+}
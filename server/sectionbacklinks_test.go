@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestSectionBacklinks tests that anchored references are attributed to the sections they point at
+func TestSectionBacklinks(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n\n\\section{Planar Graphs}\n\\section{Coloring}\n\\label{sec:colors}\n\\section{Trees}\nSee \\ref{graphs#trees}.",
+		"20240102-first.tex":  "\\ref{graphs#planar-graphs} and \\ref{graphs#sec:colors}",
+		"20240103-second.tex": "\\ref{graphs#Planar Graphs}, \\ref{graphs#missing} and \\ref{graphs}",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	uri := pathToURI(filepath.Join(tempDir, "20240101-graphs.tex"))
+	sections := ls.sectionBacklinks(uri, files["20240101-graphs.tex"])
+	if len(sections) != 2 || len(sections[3]) != 2 || len(sections[4]) != 1 {
+		t.Fatalf("unexpected section backlinks %+v", sections)
+	}
+
+	lenses, err := ls.CodeLens(context.Background(), &protocol.CodeLensParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if err != nil {
+		t.Fatalf("CodeLens failed: %v", err)
+	}
+	var titles []string
+	for _, lens := range lenses {
+		if lens.Range.Start.Line == 3 || lens.Range.Start.Line == 4 {
+			titles = append(titles, lens.Command.Title)
+		}
+	}
+	if len(titles) != 2 || titles[0] != "2 backlinks from first, second" || titles[1] != "1 backlink from first" {
+		t.Errorf("unexpected lenses %q", titles)
+	}
+
+	symbols, err := ls.DocumentSymbol(context.Background(), &protocol.DocumentSymbolParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if err != nil {
+		t.Fatalf("DocumentSymbol failed: %v", err)
+	}
+	details := make(map[string]string)
+	for _, symbol := range symbols[0].Children {
+		details[symbol.Name] = symbol.Detail
+	}
+	// The self-reference to Trees is not a backlink
+	if details["Planar Graphs"] != "section — 2 backlinks" || details["Coloring"] != "section — 1 backlink" || details["Trees"] != "section" {
+		t.Errorf("unexpected symbol details %v", details)
+	}
+}
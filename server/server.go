@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/kamal-hamza/lx-cli/pkg/vault"
@@ -24,6 +25,8 @@ type NoteHeader struct {
 	Tags     []string
 	Slug     string
 	Filename string
+	Modified time.Time // file modification time
+	Dir      string    // notes directory of a workspace folder, empty for the vault's own notes
 }
 
 type LanguageServer struct {
@@ -32,37 +35,364 @@ type LanguageServer struct {
 	conn      jsonrpc2.Conn
 	watcher   *fsnotify.Watcher
 	documents map[protocol.DocumentURI]string // <--- In-memory document store
+	journal   *Journal                        // activity journal, nil disables it
+	config    *Config                         // active settings, nil means DefaultConfig
 	mu        sync.RWMutex
+
+	unnormalized map[protocol.DocumentURI]bool       // open documents the client holds with a BOM or CR line endings
+	lineIndexes  map[protocol.DocumentURI]*lineIndex // lines of open documents, rebuilt on every change
+
+	folders []string // notes directories of workspace folders, besides the vault's
+
+	lazySymbols         bool // client resolves workspace symbol ranges on demand
+	pullDiagnostics     bool // client pulls diagnostics, so the notes that are not open can be reported
+	dynamicCompletion   bool // client registers completion dynamically
+	dynamicWatchedFiles bool // client reports file changes once asked to
+	configurationPull   bool // client answers workspace/configuration, and may send didChangeConfiguration without settings
+
+	reindexTimers map[protocol.DocumentURI]*time.Timer // pending search and link index updates per open document
+	fileEvents    map[string]*pendingFileChange        // watcher events waiting for the file to settle, by path
+
+	progress map[protocol.ProgressToken]context.CancelFunc // cancellable requests by work done token
+
+	openFiles      map[protocol.DocumentURI]os.FileInfo          // file identity of open documents, to follow renames on disk
+	movedDocuments map[protocol.DocumentURI]protocol.DocumentURI // URI an open document was opened as -> where it lives now
+
+	workDoneProgress bool          // client accepts server-initiated progress
+	showDocument     bool          // client opens documents the server asks it to show
+	indexReady       chan struct{} // closed once the initial index is built, nil when built synchronously
+	warmUpOnce       sync.Once
+
+	fileOps fileOperations // sequences watcher events with the server's own file changes
+
+	titles     *titleFetcher // cached, rate-limited page titles for \href, created on first use
+	titlesOnce sync.Once
+
+	recent     *recentHistory // when notes were last opened, loaded on first use
+	recentOnce sync.Once
+
+	doctorTimer *time.Timer // next background lx.doctor run, nil when they are off
+
+	background sync.WaitGroup // commands still running after they replied, see inBackground
+
+	linkScanTimer *time.Timer                   // pending background broken reference scan
+	linkScanned   map[protocol.DocumentURI]bool // closed notes the last scan published broken references for
+
+	stopped bool // shutdown was requested, so background runs are no longer scheduled
 }
 
 type Index struct {
-	mu    sync.RWMutex
-	notes map[string]*NoteHeader // slug -> header
+	mu        sync.RWMutex
+	notes     map[string]noteRef   // slug -> note the slug resolves to
+	shared    map[string][]noteRef // slug -> the notes using it, by directory then filename
+	headers   *HeaderStore         // headers of every note, by noteKey, within the memory budget
+	dirs      map[string]string    // filename -> notes directory, for notes outside the vault
+	links     *LinkIndex           // reverse-link index
+	labels    *LabelIndex          // cross-note label index
+	todos     *TodoIndex           // open TODO markers per note
+	search    *SearchIndex         // full-text index, tracking unsaved buffers
+	hashes    *ContentHashIndex    // body fingerprints, for duplicate detection
+	tags      *TagIndex            // notes per tag, following the note headers
+	aliases   *AliasIndex          // alternative names of notes, from their metadata
+	assets    *AssetIndex          // files of the assets directory
+	templates *TemplateIndex       // templates of the vault, read when first needed
+	bib       *BibIndex            // entries of the BibTeX files read so far
+}
+
+// noteRef locates the note file using a slug among those of every managed directory
+type noteRef struct {
+	Slug     string
+	Dir      string
+	Filename string
+}
+
+// refOf returns the location of a note using slug
+func refOf(slug string, note *NoteHeader) noteRef {
+	return noteRef{Slug: slug, Dir: note.Dir, Filename: note.Filename}
+}
+
+// key identifies the note in the header store
+func (r noteRef) key() string {
+	return r.Slug + ":" + filepath.Join(r.Dir, r.Filename)
+}
+
+// sameDir reports whether two notes live in the same directory
+func (r noteRef) sameDir(other noteRef) bool {
+	return filepath.Clean(r.Dir) == filepath.Clean(other.Dir)
 }
 
 func NewIndex() *Index {
 	return &Index{
-		notes: make(map[string]*NoteHeader),
+		notes:     make(map[string]noteRef),
+		shared:    make(map[string][]noteRef),
+		headers:   NewHeaderStore(),
+		dirs:      make(map[string]string),
+		links:     NewLinkIndex(),
+		labels:    NewLabelIndex(),
+		todos:     NewTodoIndex(),
+		search:    NewSearchIndex(),
+		hashes:    NewContentHashIndex(),
+		tags:      NewTagIndex(),
+		aliases:   NewAliasIndex(),
+		assets:    NewAssetIndex(),
+		templates: NewTemplateIndex(),
+		bib:       NewBibIndex(),
 	}
 }
 
+// Assets returns the index of the vault's assets directory
+func (i *Index) Assets() *AssetIndex {
+	return i.assets
+}
+
+// Templates returns the template cache of the vault
+func (i *Index) Templates() *TemplateIndex {
+	return i.templates
+}
+
+// Bib returns the cache of BibTeX entries
+func (i *Index) Bib() *BibIndex {
+	return i.bib
+}
+
+// Links returns the reverse-link index of the vault
+func (i *Index) Links() *LinkIndex {
+	return i.links
+}
+
+// Labels returns the cross-note label index of the vault
+func (i *Index) Labels() *LabelIndex {
+	return i.labels
+}
+
+// Todos returns the TODO marker index of the vault
+func (i *Index) Todos() *TodoIndex {
+	return i.todos
+}
+
+// Search returns the full-text index of the vault
+func (i *Index) Search() *SearchIndex {
+	return i.search
+}
+
+// Tags returns the tag index of the vault
+func (i *Index) Tags() *TagIndex {
+	return i.tags
+}
+
+// Aliases returns the note alias index of the vault
+func (i *Index) Aliases() *AliasIndex {
+	return i.aliases
+}
+
+// Graph returns the note graph of the vault
+func (i *Index) Graph() *Graph {
+	return i.links.Graph()
+}
+
+// Hashes returns the body fingerprint index of the vault
+func (i *Index) Hashes() *ContentHashIndex {
+	return i.hashes
+}
+
 func (i *Index) Get(slug string) (*NoteHeader, bool) {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
-	note, exists := i.notes[slug]
-	return note, exists
+	ref, exists := i.notes[slug]
+	if !exists {
+		return nil, false
+	}
+	return i.headers.Get(ref.key())
+}
+
+// Headers returns the store holding the note headers
+func (i *Index) Headers() *HeaderStore {
+	return i.headers
 }
 
-func (i *Index) Set(slug string, header *NoteHeader) {
+// Set indexes the note using slug, replacing the earlier header of the same file, and reports
+// whether the note holds the slug
+// When notes of other directories use the slug too, the note already holding it keeps it and the
+// others are only reachable through Variants. Files of one directory resolving to the same slug,
+// such as 20240101-foo.tex and 20240202-foo.tex, collide: the first filename in sort order holds
+// the slug, whatever order the files are indexed in, and Collisions lists them
+func (i *Index) Set(slug string, header *NoteHeader) bool {
 	i.mu.Lock()
 	defer i.mu.Unlock()
-	i.notes[slug] = header
+	ref := refOf(slug, header)
+	i.headers.Put(ref.key(), header)
+	i.setVariant(slug, ref)
+	if old, exists := i.notes[slug]; exists && (!old.sameDir(ref) || old.Filename < ref.Filename) {
+		return false
+	}
+	i.setMain(slug, header)
+	return true
 }
 
 func (i *Index) Delete(slug string) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	if old, exists := i.notes[slug]; exists {
+		delete(i.dirs, old.Filename)
+	}
+	for _, ref := range i.shared[slug] {
+		i.headers.Delete(ref.key())
+	}
+	delete(i.notes, slug)
+	delete(i.shared, slug)
+	i.tags.Delete(slug)
+	i.aliases.Delete(slug)
+	i.links.Graph().removeNode(slug)
+}
+
+// Remove drops the note of one directory using slug; when another note uses the slug too, it takes
+// the slug over and is returned
+func (i *Index) Remove(slug, dir, filename string) (*NoteHeader, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	removed := noteRef{Slug: slug, Dir: dir, Filename: filename}
+	isRemoved := func(ref noteRef) bool {
+		return ref.sameDir(removed) && ref.Filename == filename
+	}
+	variants := i.shared[slug][:0]
+	for _, ref := range i.shared[slug] {
+		if isRemoved(ref) {
+			i.headers.Delete(ref.key())
+		} else {
+			variants = append(variants, ref)
+		}
+	}
+	if len(variants) == 0 {
+		delete(i.shared, slug)
+	} else {
+		i.shared[slug] = variants
+	}
+
+	if old, exists := i.notes[slug]; exists && !isRemoved(old) {
+		return i.headers.Get(old.key())
+	}
+	if old, exists := i.notes[slug]; exists {
+		delete(i.dirs, old.Filename)
+	}
 	delete(i.notes, slug)
+	i.tags.Delete(slug)
+	i.links.Graph().removeNode(slug)
+	if len(variants) == 0 {
+		return nil, false
+	}
+	// A colliding file of the same directory comes before the notes of other directories
+	next := variants[0]
+	for _, ref := range variants {
+		if ref.sameDir(removed) {
+			next = ref
+			break
+		}
+	}
+	header, ok := i.headers.Get(next.key())
+	if !ok {
+		return nil, false
+	}
+	i.setMain(slug, header)
+	return header, true
+}
+
+// Has reports whether header is the indexed header of its note, or a copy of it read back from
+// the header store
+func (i *Index) Has(header *NoteHeader) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ref := refOf(header.Slug, header)
+	for _, variant := range i.shared[header.Slug] {
+		if variant.sameDir(ref) && variant.Filename == ref.Filename {
+			indexed, ok := i.headers.Peek(ref.key())
+			return ok && sameHeader(indexed, header)
+		}
+	}
+	return false
+}
+
+// Variants returns every indexed note using slug, from all managed directories
+// More than one means the slug is shared, e.g. by notes of two vaults opened as workspace folders
+func (i *Index) Variants(slug string) []*NoteHeader {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.peekAll(i.shared[slug])
+}
+
+// Collisions returns the other files of header's directory resolving to its slug, sorted by filename
+func (i *Index) Collisions(header *NoteHeader) []*NoteHeader {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	ref := refOf(header.Slug, header)
+	var others []noteRef
+	for _, variant := range i.shared[header.Slug] {
+		if variant.sameDir(ref) && variant.Filename != ref.Filename {
+			others = append(others, variant)
+		}
+	}
+	return i.peekAll(others)
+}
+
+// peekAll returns the headers of notes without marking them used
+// Callers hold i.mu
+func (i *Index) peekAll(refs []noteRef) []*NoteHeader {
+	keys := make([]string, len(refs))
+	for n, ref := range refs {
+		keys[n] = ref.key()
+	}
+	return i.headers.PeekMany(keys)
+}
+
+// setMain makes header the note slug resolves to
+// Callers hold i.mu
+func (i *Index) setMain(slug string, header *NoteHeader) {
+	if old, exists := i.notes[slug]; exists {
+		delete(i.dirs, old.Filename)
+	}
+	i.notes[slug] = refOf(slug, header)
+	if header.Dir != "" {
+		i.dirs[header.Filename] = header.Dir
+	}
+	i.tags.Set(slug, header.Tags)
+	i.links.Graph().addNode(slug)
+}
+
+// setVariant records a note among the notes using slug, unless it is recorded already
+// Notes of one directory stay together, sorted by filename
+// Callers hold i.mu
+func (i *Index) setVariant(slug string, ref noteRef) {
+	variants := i.shared[slug]
+	at := len(variants)
+	for n, variant := range variants {
+		if !variant.sameDir(ref) {
+			continue
+		}
+		if variant.Filename == ref.Filename {
+			variants[n] = ref
+			return
+		}
+		if variant.Filename > ref.Filename {
+			at = n
+			break
+		}
+		at = n + 1
+	}
+	variants = append(variants, noteRef{})
+	copy(variants[at+1:], variants[at:])
+	variants[at] = ref
+	i.shared[slug] = variants
+}
+
+// noteKey identifies a note file among those of every managed directory
+func noteKey(note *NoteHeader) string {
+	return filepath.Join(note.Dir, note.Filename)
+}
+
+// Dir returns the notes directory of a note outside the vault, or "" for the vault's own notes
+func (i *Index) Dir(filename string) string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.dirs[filename]
 }
 
 func (i *Index) Count() int {
@@ -74,11 +404,22 @@ func (i *Index) Count() int {
 func (i *Index) All() []*NoteHeader {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
-	notes := make([]*NoteHeader, 0, len(i.notes))
-	for _, note := range i.notes {
-		notes = append(notes, note)
+	refs := make([]noteRef, 0, len(i.notes))
+	for _, ref := range i.notes {
+		refs = append(refs, ref)
+	}
+	return i.peekAll(refs)
+}
+
+// AllVariants returns every indexed note, including those whose slug a note of another directory holds
+func (i *Index) AllVariants() []*NoteHeader {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var refs []noteRef
+	for _, variants := range i.shared {
+		refs = append(refs, variants...)
 	}
-	return notes
+	return i.peekAll(refs)
 }
 
 func NewLanguageServer() (*LanguageServer, error) {
@@ -97,12 +438,17 @@ func NewLanguageServer() (*LanguageServer, error) {
 		vault:     v,
 		index:     NewIndex(),
 		documents: make(map[protocol.DocumentURI]string), // <--- Initialize map
+		journal:   NewJournal(filepath.Join(v.RootPath, activityFilename)),
 	}, nil
 }
 
 // GetDocument returns the content of a document (from memory or disk)
 func (s *LanguageServer) GetDocument(uri protocol.DocumentURI) (string, error) {
 	s.mu.RLock()
+	// Open documents renamed on disk are found under the URI they were opened with too
+	if moved, ok := s.movedDocuments[uri]; ok {
+		uri = moved
+	}
 	content, ok := s.documents[uri]
 	s.mu.RUnlock()
 
@@ -116,114 +462,312 @@ func (s *LanguageServer) GetDocument(uri protocol.DocumentURI) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return string(data), nil
+	return metadata.Normalize(string(data)), nil
 }
 
 func (s *LanguageServer) Run(ctx context.Context) error {
 	// Set up JSON-RPC connection over stdio
-	stream := jsonrpc2.NewStream(
+	// Oversized or malformed messages are reported and skipped instead of dropping the connection
+	stream := newFramedStream(
 		struct {
 			io.Reader
 			io.WriteCloser
 		}{os.Stdin, os.Stdout},
+		maxMessageSize,
+		func(err error) {
+			fmt.Fprintf(os.Stderr, "lx-ls: %v\n", err)
+			s.logf(protocol.MessageTypeError, "%v", err)
+		},
 	)
 
-	conn := jsonrpc2.NewConn(stream)
-	conn.Go(ctx, s.handler())
-	s.conn = conn
-
-	// Build initial index
-	if err := s.RebuildIndex(ctx); err != nil {
-		return fmt.Errorf("failed to build initial index: %w", err)
-	}
-
 	// --- Start File Watcher ---
+	// Before serving, so workspace folders added by Initialize are watched too
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return fmt.Errorf("failed to create watcher: %w", err)
 	}
 	s.watcher = watcher
-	defer s.watcher.Close()
+	defer watcher.Close()
 
-	// Watch Notes directory
-	if err := s.watcher.Add(s.vault.NotesPath); err != nil {
+	// Watch Notes directory and the folders organizing it
+	if err := watchTree(s.watcher, s.vault.NotesPath); err != nil {
 		return fmt.Errorf("failed to watch notes directory: %w", err)
 	}
+	// The assets and templates directories are optional, vaults without figures may not have one
+	watchTree(s.watcher, s.vault.AssetsPath)
+	s.watcher.Add(s.vault.TemplatesPath)
 
 	// Handle events in background
-	go s.handleFileEvents(ctx)
+	go s.handleFileEvents(ctx, watcher)
 	// --------------------------
 
+	conn := jsonrpc2.NewConn(stream)
+	// Async so handlers can call back into the client (applyEdit, registerCapability)
+	// without blocking the read loop that delivers the client's response
+	// The initial index is built in the background after Initialized, see warmUp
+	s.indexReady = make(chan struct{})
+	conn.Go(ctx, s.cancelHandler(jsonrpc2.AsyncHandler(s.followRenamesHandler(s.handler()))))
+	s.conn = conn
+
 	// Wait for connection to close
 	<-conn.Done()
+	s.stopSchedules()
+	s.flushRecent()
 	return conn.Err()
 }
 
 // handleFileEvents watches for changes in the notes directory
-func (s *LanguageServer) handleFileEvents(ctx context.Context) {
+// Returns once the watcher is closed
+func (s *LanguageServer) handleFileEvents(ctx context.Context, watcher *fsnotify.Watcher) {
 	for {
 		select {
-		case event, ok := <-s.watcher.Events:
+		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
-			// Only care about .tex files
-			if strings.HasSuffix(event.Name, ".tex") {
-				// Update index for this specific file
-				s.updateIndexForFile(event.Name)
+			s.assetEvent(ctx, watcher, event)
+			s.templateEvent(ctx, event)
+			s.dirEvent(ctx, watcher, event)
+			s.scheduleFileChange(ctx, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
 			}
+			s.logf(protocol.MessageTypeError, "File watcher error: %v", err)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// stopWatcher closes the fsnotify watcher when file watching is disabled
+func (s *LanguageServer) stopWatcher() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.watcher != nil {
+		s.watcher.Close()
+		s.watcher = nil
+	}
+}
+
+// fileChanged updates the index for a note changed on disk, whether reported by fsnotify or the client
+// Waits for server file operations in progress; changes they made and indexed already are skipped
+func (s *LanguageServer) fileChanged(ctx context.Context, path string, created bool) {
+	// Only care about .tex files
+	if !strings.HasSuffix(path, ".tex") {
+		return
+	}
+
+	s.fileOps.mu.Lock()
+	defer s.fileOps.mu.Unlock()
+	if s.fileOps.isSettled(path, time.Now()) {
+		return
+	}
+	s.reconcileFile(ctx, path, created)
+}
+
+// reconcileFile brings the index in line with a note file as it is on disk
+func (s *LanguageServer) reconcileFile(ctx context.Context, path string, created bool) {
+	slug := s.parseFilenameToSlug(filepath.Base(path))
+	_, existed := s.index.Get(slug)
+	variants := len(s.index.Variants(slug))
+
+	// Follow open documents renamed outside the editor before indexing picks up the buffer
+	if created {
+		s.detectExternalRename(ctx, path)
+	}
+
+	// Update index for this specific file
+	s.updateIndexForFile(path)
+
+	// Note appeared or disappeared: refresh diagnostics of notes linking to it
+	if _, exists := s.index.Get(slug); exists != existed {
+		s.publishBacklinkDiagnostics(ctx, slug)
+		s.scheduleLinkScan()
+	}
+	// Files using the slug appeared or disappeared: refresh the slug collisions of open notes
+	if len(s.index.Variants(slug)) != variants {
+		s.publishCollisionDiagnostics(ctx, slug)
+	}
+}
+
 // updateIndexForFile updates a single entry in the index
 func (s *LanguageServer) updateIndexForFile(path string) {
 	// 1. Check if file was deleted
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		slug := s.parseFilenameToSlug(filepath.Base(path))
-		s.index.Delete(slug)
+		s.dropNote(s.parseFilenameToSlug(filepath.Base(path)), s.noteDirOf(path), filepath.Base(path))
 		return
 	}
 
 	// 2. Parse and Update
-	header, err := s.parseNoteHeader(filepath.Base(path))
-	if err == nil {
-		s.index.Set(header.Slug, header)
+	header, err := s.parseNoteHeader(path)
+	if err != nil {
+		s.logf(protocol.MessageTypeWarning, "Skipping %s: %v", path, err)
+		return
 	}
+	s.setNote(header)
 }
 
-// RebuildIndex scans all notes and rebuilds the index
+// setNote indexes a note and, unless a note of another directory holds its slug, its content
+func (s *LanguageServer) setNote(header *NoteHeader) {
+	holds := s.index.Set(header.Slug, header)
+	s.warnSlugCollisions(header)
+	if holds {
+		s.indexContent(header)
+		s.relinkSlug(header.Slug)
+	}
+}
+
+// dropNote removes a note from every index
+// Another note using the same slug, from a different directory, takes the slug over
+func (s *LanguageServer) dropNote(slug, dir, filename string) {
+	if note, ok := s.index.Remove(slug, dir, filename); ok {
+		s.index.Labels().Delete(filename)
+		s.indexContent(note)
+		return
+	}
+	s.index.Links().Delete(slug)
+	s.index.Labels().Delete(filename)
+	s.index.Todos().Delete(slug)
+	s.index.Search().Delete(slug)
+	s.index.Hashes().Delete(slug)
+	s.setAliases(slug, nil)
+	s.relinkSlug(slug)
+}
+
+// indexContent refreshes the links and labels of a note in the cross-note indexes
+func (s *LanguageServer) indexContent(header *NoteHeader) {
+	content, err := os.ReadFile(s.notePath(header.Filename))
+	if err != nil {
+		s.index.Links().Delete(header.Slug)
+		s.index.Labels().Delete(header.Filename)
+		s.index.Todos().Delete(header.Slug)
+		s.index.Search().Delete(header.Slug)
+		s.index.Hashes().Delete(header.Slug)
+		s.setAliases(header.Slug, nil)
+		return
+	}
+	text := metadata.Normalize(string(content))
+	s.indexAliases(header.Slug, text)
+	s.index.Hashes().Set(header.Slug, s.hashContent(text))
+	definitions, usages := extractLabels(header.Filename, text)
+	s.index.Labels().Set(header.Filename, definitions, usages)
+	s.index.Todos().Set(header.Slug, extractTodos(header.Filename, text))
+
+	// An open buffer wins over the file so search and backlinks match what the user sees
+	s.mu.RLock()
+	if buffer, open := s.documents[pathToURI(s.notePath(header.Filename))]; open {
+		text = buffer
+	}
+	s.mu.RUnlock()
+	s.index.Links().Set(header.Slug, s.noteLinks(header.Slug, header.Filename, text))
+	s.index.Search().Set(header.Slug, text)
+}
+
+// RebuildIndex scans all notes and brings the index up to date
+// Notes whose files kept their modification time are not parsed again, and notes whose files
+// disappeared are dropped. Progress is reported to the progress carried by ctx, if any
 func (s *LanguageServer) RebuildIndex(ctx context.Context) error {
-	headers, err := s.listNoteHeaders(ctx)
+	return s.rebuildIndex(ctx, false)
+}
+
+// rebuildIndex re-parses every note when full is set, for settings that change how notes are read
+func (s *LanguageServer) rebuildIndex(ctx context.Context, full bool) error {
+	progress := s.progressFrom(ctx)
+	progress.Report(ctx, "Reading notes", 0)
+	headers, err := s.listNoteHeaders(ctx, full)
 	if err != nil {
 		return err
 	}
 
-	for _, header := range headers {
-		s.index.Set(header.Slug, header)
+	reported := uint32(0)
+	seen := make(map[string]bool, len(headers))
+	for i, header := range headers {
+		seen[noteKey(header)] = true
+		// Unchanged notes come back as the indexed header itself, or a copy from the header store
+		if full || !s.index.Has(header) {
+			s.setNote(header)
+		}
+
+		// At most one report per percent, so large vaults do not flood the client
+		if percentage := uint32((i + 1) * 100 / len(headers)); percentage > reported || i+1 == len(headers) {
+			reported = percentage
+			progress.Report(ctx, fmt.Sprintf("Indexing vault: %d/%d notes", i+1, len(headers)), percentage)
+		}
 	}
 
+	for _, note := range s.index.AllVariants() {
+		if !seen[noteKey(note)] {
+			s.dropNote(note.Slug, note.Dir, note.Filename)
+		}
+	}
+
+	s.indexAssets()
+	s.index.Templates().Invalidate()
 	return nil
 }
 
-// listNoteHeaders reads all .tex files in notes directory and parses metadata
-func (s *LanguageServer) listNoteHeaders(ctx context.Context) ([]*NoteHeader, error) {
+// listNoteHeaders reads all .tex files in the notes directory and workspace folders and parses metadata
+// Notes unchanged since they were indexed keep their header, unless full is set; notes unchanged
+// since the last `lx reindex` take their metadata from the CLI's index instead
+func (s *LanguageServer) listNoteHeaders(ctx context.Context, full bool) ([]*NoteHeader, error) {
+	headers, err := s.scanDirHeaders(s.vault.NotesPath, s.loadWarmStart(), !full)
+	if err != nil {
+		return nil, err
+	}
+
+	// The CLI index only covers the top of the notes directory
+	for _, dir := range s.noteDirsTree() {
+		if dir == s.vault.NotesPath {
+			continue
+		}
+		folderHeaders, err := s.scanDirHeaders(dir, nil, !full)
+		if err != nil {
+			s.logf(protocol.MessageTypeWarning, "Skipping folder %s: %v", dir, err)
+			continue // A folder may disappear while the workspace is open
+		}
+		headers = append(headers, folderHeaders...)
+	}
+
+	return headers, nil
+}
+
+// listDirHeaders parses the notes of a single directory
+func (s *LanguageServer) listDirHeaders(dir string, cached *warmStart) ([]*NoteHeader, error) {
+	return s.scanDirHeaders(dir, cached, false)
+}
+
+// scanDirHeaders parses the notes of a single directory, reusing the indexed headers of unchanged notes when reuse is set
+func (s *LanguageServer) scanDirHeaders(dir string, cached *warmStart, reuse bool) ([]*NoteHeader, error) {
 	var headers []*NoteHeader
 
-	entries, err := os.ReadDir(s.vault.NotesPath)
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
+	parser := s.metadataParser()
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tex") {
 			continue
 		}
 
-		header, err := s.parseNoteHeader(entry.Name())
+		if info, err := entry.Info(); err == nil {
+			if header := s.unchangedHeader(dir, entry.Name(), info.ModTime()); reuse && header != nil {
+				headers = append(headers, header)
+				continue
+			}
+			if header := cached.header(s.parseFilenameToSlug(entry.Name()), entry.Name(), info.ModTime()); header != nil {
+				// The CLI index keeps dates as written, legacy formats included
+				header.Date, _, _ = parser.NormalizeDate(header.Date)
+				headers = append(headers, header)
+				continue
+			}
+		}
+
+		header, err := s.parseNoteHeader(filepath.Join(dir, entry.Name()))
 		if err != nil {
+			s.logf(protocol.MessageTypeWarning, "Skipping %s: %v", filepath.Join(dir, entry.Name()), err)
 			continue // Skip malformed files
 		}
 
@@ -233,18 +777,43 @@ func (s *LanguageServer) listNoteHeaders(ctx context.Context) ([]*NoteHeader, er
 	return headers, nil
 }
 
+// unchangedHeader returns the indexed header of a note if its file still has the modification time it was indexed with
+func (s *LanguageServer) unchangedHeader(dir, filename string, modified time.Time) *NoteHeader {
+	if modified.IsZero() {
+		return nil
+	}
+	for _, note := range s.index.Variants(s.parseFilenameToSlug(filename)) {
+		noteDir := note.Dir
+		if noteDir == "" {
+			noteDir = s.vault.NotesPath
+		}
+		if note.Filename == filename && note.Modified.Equal(modified) && filepath.Clean(noteDir) == filepath.Clean(dir) {
+			return note
+		}
+	}
+	return nil
+}
+
 // parseNoteHeader extracts metadata from a note file using robust metadata parser
-func (s *LanguageServer) parseNoteHeader(filename string) (*NoteHeader, error) {
-	path := s.vault.GetNotePath(filename)
+func (s *LanguageServer) parseNoteHeader(path string) (*NoteHeader, error) {
+	filename := filepath.Base(path)
+	dir := s.noteDirOf(path)
+
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	var modified time.Time
+	if info, err := os.Stat(path); err == nil {
+		modified = info.ModTime()
+	}
+
 	// Use non-strict parser for reading existing files
 	// This allows recovery from minor metadata issues
-	meta, err := metadata.Extract(string(content))
+	result, err := s.metadataParser().Parse(string(content))
 	if err != nil {
+		s.logf(protocol.MessageTypeWarning, "Failed to parse metadata of %s, indexing it by filename: %v", path, err)
 		// Fallback: create minimal header from filename
 		slug := s.parseFilenameToSlug(filename)
 		return &NoteHeader{
@@ -253,8 +822,14 @@ func (s *LanguageServer) parseNoteHeader(filename string) (*NoteHeader, error) {
 			Title:    slug,
 			Date:     "",
 			Tags:     []string{},
+			Modified: modified,
+			Dir:      dir,
 		}, nil
 	}
+	for _, parseErr := range result.Errors {
+		s.logf(protocol.MessageTypeLog, "%s:%d: %s", path, parseErr.Line, parseErr.Message)
+	}
+	meta := result.Metadata
 
 	header := &NoteHeader{
 		Filename: filename,
@@ -262,6 +837,8 @@ func (s *LanguageServer) parseNoteHeader(filename string) (*NoteHeader, error) {
 		Title:    meta.Title,
 		Date:     meta.Date,
 		Tags:     meta.Tags,
+		Modified: modified,
+		Dir:      dir,
 	}
 
 	// Ensure tags is never nil
@@ -310,12 +887,6 @@ func (s *LanguageServer) IsManaged(uri protocol.DocumentURI) bool {
 		return false
 	}
 
-	// Check if path is within vault notes directory
-	notesPath, err := filepath.Abs(s.vault.NotesPath)
-	if err != nil {
-		return false
-	}
-
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return false
@@ -326,7 +897,14 @@ func (s *LanguageServer) IsManaged(uri protocol.DocumentURI) bool {
 		return false
 	}
 
-	return strings.HasPrefix(absPath, notesPath)
+	// Check if path is within the vault notes directory or a workspace folder's
+	for _, dir := range s.notesDirs() {
+		notesPath, err := filepath.Abs(dir)
+		if err == nil && strings.HasPrefix(absPath, notesPath) {
+			return true
+		}
+	}
+	return false
 }
 
 // uriToPath converts a URI to a file path
@@ -343,6 +921,48 @@ func uriToPath(uri protocol.DocumentURI) string {
 	return path
 }
 
+// pathToURI converts a file path to a URI
+func pathToURI(path string) protocol.DocumentURI {
+	return protocol.DocumentURI("file://" + path)
+}
+
+// applyEdit asks the client to apply a workspace edit
+// Edits over maxEditSize are sent in chunks of whole documents, labelled "label (1/3)" and so on
+// Chunked edits are not atomic: a chunk the client rejects stops the rest, but the chunks applied
+// before it stay applied
+func (s *LanguageServer) applyEdit(ctx context.Context, label string, edit *protocol.WorkspaceEdit) error {
+	chunks := splitEdit(edit, maxEditSize)
+	for i, chunk := range chunks {
+		chunkLabel := label
+		if len(chunks) > 1 {
+			chunkLabel = fmt.Sprintf("%s (%d/%d)", label, i+1, len(chunks))
+		}
+		var result protocol.ApplyWorkspaceEditResponse
+		if _, err := s.conn.Call(ctx, protocol.MethodWorkspaceApplyEdit, &protocol.ApplyWorkspaceEditParams{
+			Label: chunkLabel,
+			Edit:  *chunk,
+		}, &result); err != nil {
+			return fmt.Errorf("failed to apply edit %q: %w", chunkLabel, err)
+		}
+		if !result.Applied {
+			return fmt.Errorf("client rejected edit %q: %s", chunkLabel, result.FailureReason)
+		}
+	}
+	return nil
+}
+
+// deliverEdit returns an edit to hand back as a request result
+// Edits too large for one response are applied in chunks through applyEdit instead, leaving an empty result
+func (s *LanguageServer) deliverEdit(ctx context.Context, label string, edit *protocol.WorkspaceEdit) (*protocol.WorkspaceEdit, error) {
+	if editSize(edit) <= maxEditSize {
+		return edit, nil
+	}
+	if err := s.applyEdit(ctx, label, edit); err != nil {
+		return nil, err
+	}
+	return &protocol.WorkspaceEdit{}, nil
+}
+
 // handler returns the JSON-RPC handler for LSP methods
 func (s *LanguageServer) handler() jsonrpc2.Handler {
 	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -352,11 +972,26 @@ func (s *LanguageServer) handler() jsonrpc2.Handler {
 			if err := json.Unmarshal(req.Params(), &params); err != nil {
 				return reply(ctx, nil, err)
 			}
+			s.lazySymbols = supportsSymbolResolve(req.Params())
+			s.pullDiagnostics = supportsPullDiagnostics(req.Params())
 			result, err := s.Initialize(ctx, &params)
 			return reply(ctx, result, err)
 
 		case protocol.MethodInitialized:
-			return reply(ctx, nil, nil)
+			var params protocol.InitializedParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			err := s.Initialized(ctx, &params)
+			return reply(ctx, nil, err)
+
+		case protocol.MethodWorkspaceDidChangeConfiguration:
+			var params protocol.DidChangeConfigurationParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			err := s.DidChangeConfiguration(ctx, &params)
+			return reply(ctx, nil, err)
 
 		case protocol.MethodTextDocumentDidOpen:
 			var params protocol.DidOpenTextDocumentParams
@@ -382,6 +1017,14 @@ func (s *LanguageServer) handler() jsonrpc2.Handler {
 			err := s.DidClose(ctx, &params)
 			return reply(ctx, nil, err)
 
+		case protocol.MethodTextDocumentDidSave:
+			var params protocol.DidSaveTextDocumentParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			err := s.DidSave(ctx, &params)
+			return reply(ctx, nil, err)
+
 		case protocol.MethodTextDocumentCompletion:
 			var params protocol.CompletionParams
 			if err := json.Unmarshal(req.Params(), &params); err != nil {
@@ -406,6 +1049,62 @@ func (s *LanguageServer) handler() jsonrpc2.Handler {
 			result, err := s.Hover(ctx, &params)
 			return reply(ctx, result, err)
 
+		case protocol.MethodTextDocumentReferences:
+			var params protocol.ReferenceParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.References(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentDocumentSymbol:
+			var params protocol.DocumentSymbolParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.DocumentSymbol(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentDocumentHighlight:
+			var params protocol.DocumentHighlightParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.DocumentHighlight(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentDocumentLink:
+			var params protocol.DocumentLinkParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.DocumentLink(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentCodeLens:
+			var params protocol.CodeLensParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.CodeLens(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentCodeAction:
+			var params protocol.CodeActionParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.CodeAction(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodWorkspaceExecuteCommand:
+			var params protocol.ExecuteCommandParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.ExecuteCommand(ctx, &params)
+			return reply(ctx, result, err)
+
 		case protocol.MethodTextDocumentRename:
 			var params protocol.RenameParams
 			if err := json.Unmarshal(req.Params(), &params); err != nil {
@@ -414,10 +1113,153 @@ func (s *LanguageServer) handler() jsonrpc2.Handler {
 			result, err := s.Rename(ctx, &params)
 			return reply(ctx, result, err)
 
+		case protocol.MethodTextDocumentFormatting:
+			var params protocol.DocumentFormattingParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.Formatting(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentOnTypeFormatting:
+			var params protocol.DocumentOnTypeFormattingParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.OnTypeFormatting(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentSignatureHelp:
+			var params protocol.SignatureHelpParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.SignatureHelp(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentWillSaveWaitUntil:
+			var params protocol.WillSaveTextDocumentParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.WillSaveWaitUntil(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodWorkspaceDidChangeWorkspaceFolders:
+			var params protocol.DidChangeWorkspaceFoldersParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			err := s.DidChangeWorkspaceFolders(ctx, &params)
+			return reply(ctx, nil, err)
+
+		case protocol.MethodWorkspaceDidChangeWatchedFiles:
+			var params protocol.DidChangeWatchedFilesParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			err := s.DidChangeWatchedFiles(ctx, &params)
+			return reply(ctx, nil, err)
+
+		case protocol.MethodWorkspaceSymbol:
+			var params protocol.WorkspaceSymbolParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.WorkspaceSymbol(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodWorkspaceSymbolResolve:
+			var params WorkspaceSymbol
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.WorkspaceSymbolResolve(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodTextDocumentDiagnostic:
+			var params DocumentDiagnosticParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.DocumentDiagnostic(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodWorkspaceDiagnostic:
+			var params WorkspaceDiagnosticParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.WorkspaceDiagnostic(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodDiffOutline:
+			var params DiffOutlineParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.DiffOutline(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodHeatmap:
+			var params HeatmapParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.Heatmap(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodRecentNotes:
+			var params RecentNotesParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.RecentNotes(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodNotesByTag:
+			var params NotesByTagParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.NotesByTag(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodStats:
+			// Clients may leave out the parameters lx/stats does not take
+			var params StatsParams
+			if len(req.Params()) > 0 {
+				if err := json.Unmarshal(req.Params(), &params); err != nil {
+					return reply(ctx, nil, err)
+				}
+			}
+			result, err := s.Stats(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodSearch:
+			var params SearchParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.Search(ctx, &params)
+			return reply(ctx, result, err)
+
+		case MethodGrep:
+			var params GrepParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, err)
+			}
+			result, err := s.Grep(ctx, &params)
+			return reply(ctx, result, err)
+
 		case protocol.MethodShutdown:
+			s.stopSchedules()
+			s.index.Headers().Close()
+			s.flushRecent()
 			return reply(ctx, nil, nil)
 
 		case protocol.MethodExit:
+			s.stopSchedules()
 			return nil
 
 		default:
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kamal-hamza/lx-cli/pkg/vault"
 	"go.lsp.dev/protocol"
@@ -168,6 +169,42 @@ func TestBuildIndex(t *testing.T) {
 	}
 }
 
+// TestRebuildIndex_Incremental tests that a rebuild re-reads only changed notes and drops deleted ones
+func TestRebuildIndex_Incremental(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"graphs", "trees", "paths"} {
+		os.WriteFile(filepath.Join(tempDir, "20240101-"+name+".tex"), []byte("%% Metadata\n% title: "+name+"\n"), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+	graphs, _ := ls.index.Get("graphs")
+	trees, _ := ls.index.Get("trees")
+
+	// Change trees with a new modification time, delete paths
+	treesPath := filepath.Join(tempDir, "20240101-trees.tex")
+	os.WriteFile(treesPath, []byte("%% Metadata\n% title: Forests\n\\ref{graphs}\n"), 0644)
+	later := trees.Modified.Add(time.Second)
+	os.Chtimes(treesPath, later, later)
+	os.Remove(filepath.Join(tempDir, "20240101-paths.tex"))
+	ls.index.Links().Set("paths", []Link{{Source: "paths", Target: "graphs"}})
+
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if current, _ := ls.index.Get("graphs"); current != graphs {
+		t.Error("expected the unchanged note to keep its header")
+	}
+	if current, _ := ls.index.Get("trees"); current == trees || current.Title != "Forests" {
+		t.Errorf("expected the changed note to be parsed again, got %+v", current)
+	}
+	if _, exists := ls.index.Get("paths"); exists {
+		t.Error("expected the deleted note to be dropped")
+	}
+	if incoming := ls.index.Links().Incoming("graphs"); len(incoming) != 1 || incoming[0].Source != "trees" {
+		t.Errorf("expected only the link from trees, got %+v", incoming)
+	}
+}
+
 // TestCompletion_References tests reference completion
 func TestCompletion_References(t *testing.T) {
 	ls := &LanguageServer{
@@ -427,6 +464,16 @@ func TestHover(t *testing.T) {
 	if !strings.Contains(content, "math") {
 		t.Errorf("expected tags in hover, got: %s", content)
 	}
+	if !strings.Contains(content, "✓ no TODOs") {
+		t.Errorf("expected TODO status in hover, got: %s", content)
+	}
+
+	// Open TODOs in the target note show up in the status line
+	ls.index.Todos().Set("graph-theory", extractTodos("graph-theory.tex", "\\todo{proofs}\n\\todo[inline]{figures}"))
+	hover, _ = ls.Hover(context.Background(), params)
+	if !strings.Contains(hover.Contents.Value, "⚠ 2 open TODOs") {
+		t.Errorf("expected open TODO count in hover, got: %s", hover.Contents.Value)
+	}
 }
 
 // TestRename tests the rename functionality
@@ -614,6 +661,37 @@ func TestLiveIndexing_FileModification(t *testing.T) {
 	}
 }
 
+// TestDidSave tests that saving a note re-indexes it without waiting for the watcher
+func TestDidSave(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	testFile := filepath.Join(notesPath, "20240101-test-note.tex")
+	os.WriteFile(testFile, []byte("%% Metadata\n%% title: Original Title\n"), 0644)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.RebuildIndex(context.Background())
+
+	os.WriteFile(testFile, []byte("%% Metadata\n%% title: Saved Title\n\n\\ref{other}"), 0644)
+	params := &protocol.DidSaveTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)}}
+	if err := ls.DidSave(context.Background(), params); err != nil {
+		t.Fatalf("DidSave failed: %v", err)
+	}
+
+	note, exists := ls.index.Get("test-note")
+	if !exists || note.Title != "Saved Title" {
+		t.Errorf("expected re-indexed title 'Saved Title', got %+v", note)
+	}
+	if len(ls.index.Links().Incoming("other")) != 1 {
+		t.Error("expected links of the saved note to be re-indexed")
+	}
+}
+
 // TestLiveIndexing_FileDeletion tests index updates on file deletion
 func TestLiveIndexing_FileDeletion(t *testing.T) {
 	tempDir := t.TempDir()
@@ -729,3 +807,56 @@ func TestIndex_ThreadSafety(t *testing.T) {
 		t.Errorf("expected 10 notes in index, got %d", index.Count())
 	}
 }
+
+// TestCompletion_SectionProximity tests ranking \ref candidates by the enclosing section
+func TestCompletion_SectionProximity(t *testing.T) {
+	ls := &LanguageServer{
+		index: NewIndex(),
+	}
+
+	ls.index.Set("linear-algebra", &NoteHeader{
+		Title: "Linear Algebra",
+		Slug:  "linear-algebra",
+	})
+	ls.index.Set("trees", &NoteHeader{
+		Title: "Trees",
+		Slug:  "trees",
+		Tags:  []string{"graphs"},
+	})
+
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	testFile := filepath.Join(notesPath, "test.tex")
+	content := "\\section{Graphs and Networks}\nSome text.\nSee \\ref{"
+	os.WriteFile(testFile, []byte(content), 0644)
+
+	ls.vault = &vault.Vault{NotesPath: notesPath}
+
+	params := &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentURI("file://" + testFile),
+			},
+			Position: protocol.Position{Line: 2, Character: 9},
+		},
+	}
+
+	result, err := ls.Completion(context.Background(), params)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("expected 2 completion items, got %d", len(result.Items))
+	}
+
+	sortText := make(map[string]string)
+	for _, item := range result.Items {
+		sortText[item.Label] = item.SortText
+	}
+	if sortText["trees"] >= sortText["linear-algebra"] {
+		t.Errorf("expected 'trees' (tagged graphs) to sort before 'linear-algebra', got %q vs %q", sortText["trees"], sortText["linear-algebra"])
+	}
+}
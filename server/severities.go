@@ -0,0 +1,49 @@
+package server
+
+import (
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeTodo marks diagnostics for \todo{} markers
+const diagnosticCodeTodo = "LX002"
+
+// severityOff drops the diagnostics of a code altogether
+const severityOff = "off"
+
+// severityNames maps the severities setting to LSP severities
+var severityNames = map[string]protocol.DiagnosticSeverity{
+	"error":       protocol.DiagnosticSeverityError,
+	"warning":     protocol.DiagnosticSeverityWarning,
+	"information": protocol.DiagnosticSeverityInformation,
+	"info":        protocol.DiagnosticSeverityInformation,
+	"hint":        protocol.DiagnosticSeverityHint,
+}
+
+// applySeverities overrides the severity of diagnostics by code as configured, dropping those turned off
+// Settings may name a rule by its code or its name; unknown severity names leave the rule's own severity
+func (s *LanguageServer) applySeverities(diagnostics []protocol.Diagnostic) []protocol.Diagnostic {
+	severities := s.settings().Severities
+	if len(severities) == 0 {
+		return diagnostics
+	}
+
+	kept := diagnostics[:0]
+	for _, diag := range diagnostics {
+		code, _ := diag.Code.(string)
+		setting, ok := severities[code]
+		if rule, known := diagnosticRuleByCode(code); !ok && known {
+			setting = severities[rule.Name]
+		}
+		name := strings.ToLower(setting)
+		if name == severityOff {
+			continue
+		}
+		if severity, ok := severityNames[name]; ok {
+			diag.Severity = severity
+		}
+		kept = append(kept, diag)
+	}
+	return kept
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// openCallPattern matches a command whose last argument group is still open at the end of the line prefix
+// Group 1 is the command name, group 2 the complete groups before it, group 3 the open group
+var openCallPattern = regexp.MustCompile(`\\([A-Za-z]+\*?)((?:\[[^\]]*\]|\{[^}]*\})*)(\[[^\]]*|\{[^}]*)$`)
+
+// argumentGroupPattern splits complete argument groups
+var argumentGroupPattern = regexp.MustCompile(`\[[^\]]*\]|\{[^}]*\}`)
+
+// commandParameter documents one argument of a LaTeX command
+type commandParameter struct {
+	Label         string // as written in the signature label, e.g. "{slug}"
+	Documentation string
+	Optional      bool // [] argument
+}
+
+// commandSignature documents a LaTeX command for signature help
+type commandSignature struct {
+	Label         string
+	Documentation string
+	Parameters    []commandParameter
+}
+
+// slugParameter documents the lx convention for note references
+var slugParameter = commandParameter{
+	Label:         "{slug}",
+	Documentation: "Slug of the target note: lowercase kebab-case without the date prefix or `.tex`, e.g. `graph-theory`.",
+}
+
+// commandSignatures are the commands signature help knows about
+var commandSignatures = map[string]commandSignature{
+	"ref": {
+		Label:         `\ref{slug}`,
+		Documentation: "Link to another note in the vault.",
+		Parameters:    []commandParameter{slugParameter},
+	},
+	"cite": {
+		Label:         `\cite{slug}`,
+		Documentation: "Cite another note in the vault.",
+		Parameters:    []commandParameter{slugParameter},
+	},
+	"input": {
+		Label:         `\input{slug}`,
+		Documentation: "Inline the content of another note.",
+		Parameters:    []commandParameter{slugParameter},
+	},
+	"include": {
+		Label:         `\include{slug}`,
+		Documentation: "Include another note on a new page.",
+		Parameters:    []commandParameter{slugParameter},
+	},
+	"includegraphics": {
+		Label:         `\includegraphics[options]{file}`,
+		Documentation: "Insert an image.",
+		Parameters: []commandParameter{
+			{Label: "[options]", Documentation: "Key-value options such as `width=0.8\\linewidth`, `height=`, `scale=` or `angle=`.", Optional: true},
+			{Label: "{file}", Documentation: "Image file, relative to the vault assets directory."},
+		},
+	},
+	"usepackage": {
+		Label:         `\usepackage[options]{template}`,
+		Documentation: "Load a package or a vault template.",
+		Parameters: []commandParameter{
+			{Label: "[options]", Documentation: "Package options.", Optional: true},
+			{Label: "{template}", Documentation: "Template name from the vault templates directory, without `.sty`; several can be separated by commas."},
+		},
+	},
+	"label": {
+		Label:         `\label{key}`,
+		Documentation: "Name the current section, equation or environment so it can be referenced.",
+		Parameters: []commandParameter{
+			{Label: "{key}", Documentation: "Label key with a kind prefix, e.g. `sec:intro`, `eq:main`, `fig:plot` or `thm:euler`."},
+		},
+	},
+	"todo": {
+		Label:         `\todo{description}`,
+		Documentation: "Mark open work; TODOs are reported as diagnostics and counted in hovers.",
+		Parameters: []commandParameter{
+			{Label: "{description}", Documentation: "What is left to do."},
+		},
+	},
+	"begin": {
+		Label:         `\begin{environment}`,
+		Documentation: "Open an environment; close it with `\\end{environment}`.",
+		Parameters: []commandParameter{
+			{Label: "{environment}", Documentation: "Environment name, e.g. `itemize`, `align` or a theorem environment from the note's templates."},
+		},
+	},
+}
+
+// Handle SignatureHelp request
+func (s *LanguageServer) SignatureHelp(ctx context.Context, params *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error) {
+	if s.settings().Coexist || !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	lines := s.documentLines(content)
+	if int(params.Position.Line) >= len(lines) {
+		return nil, nil
+	}
+	line := lines[params.Position.Line]
+	if int(params.Position.Character) > len(line) {
+		return nil, nil
+	}
+
+	return signatureHelp(line[:params.Position.Character]), nil
+}
+
+// signatureHelp returns help for the command whose argument the line prefix ends in
+func signatureHelp(prefix string) *protocol.SignatureHelp {
+	match := openCallPattern.FindStringSubmatch(prefix)
+	if match == nil {
+		return nil
+	}
+	signature, ok := commandSignatures[strings.TrimSuffix(match[1], "*")]
+	if !ok {
+		return nil
+	}
+
+	// Walk the argument groups, matching [ to optional and { to required parameters
+	groups := append(argumentGroupPattern.FindAllString(match[2], -1), match[3])
+	active, next := -1, 0
+	for _, group := range groups {
+		optional := strings.HasPrefix(group, "[")
+		active = -1
+		for i := next; i < len(signature.Parameters); i++ {
+			if signature.Parameters[i].Optional == optional {
+				active = i
+				break
+			}
+		}
+		if active < 0 {
+			return nil
+		}
+		next = active + 1
+	}
+
+	parameters := make([]protocol.ParameterInformation, 0, len(signature.Parameters))
+	for _, param := range signature.Parameters {
+		parameters = append(parameters, protocol.ParameterInformation{
+			Label:         param.Label,
+			Documentation: protocol.MarkupContent{Kind: protocol.Markdown, Value: param.Documentation},
+		})
+	}
+
+	return &protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{
+			{
+				Label:         signature.Label,
+				Documentation: protocol.MarkupContent{Kind: protocol.Markdown, Value: signature.Documentation},
+				Parameters:    parameters,
+			},
+		},
+		ActiveParameter: uint32(active),
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import "testing"
+
+// TestSignatureHelp tests command and parameter detection from the line prefix
+func TestSignatureHelp(t *testing.T) {
+	tests := []struct {
+		prefix    string
+		signature string
+		active    uint32
+	}{
+		{`See \ref{gra`, `\ref{slug}`, 0},
+		{`\includegraphics[width=`, `\includegraphics[options]{file}`, 0},
+		{`\includegraphics{`, `\includegraphics[options]{file}`, 1},
+		{`\includegraphics[scale=2]{plo`, `\includegraphics[options]{file}`, 1},
+		{`\usepackage{amsmath, lec`, `\usepackage[options]{template}`, 1},
+		{`\section*{Intro} \label{sec:`, `\label{key}`, 0},
+	}
+	for _, tt := range tests {
+		help := signatureHelp(tt.prefix)
+		if help == nil {
+			t.Errorf("%q: expected signature help", tt.prefix)
+			continue
+		}
+		if help.Signatures[0].Label != tt.signature || help.ActiveParameter != tt.active {
+			t.Errorf("%q: got %s with parameter %d, want %s with %d", tt.prefix, help.Signatures[0].Label, help.ActiveParameter, tt.signature, tt.active)
+		}
+	}
+
+	for _, prefix := range []string{`\ref{done}`, `\section{Intro`, `\ref{a}{b`, `plain text {`} {
+		if help := signatureHelp(prefix); help != nil {
+			t.Errorf("%q: expected no signature help, got %s", prefix, help.Signatures[0].Label)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeMissingStructure marks notes lacking structure their template requires
+const diagnosticCodeMissingStructure = "LX007"
+
+var (
+	// templateRequirePattern matches "% lx-requires: \lecture{}" doc comments in templates
+	templateRequirePattern = regexp.MustCompile(`^\s*%+\s*lx-requires:\s*(.+?)\s*$`)
+
+	// requirementPattern splits a requirement into command name and optional argument
+	requirementPattern = regexp.MustCompile(`^\\([A-Za-z@]+\*?)(?:\{([^}]*)\})?$`)
+)
+
+// skeletonRequirement is a piece of structure a template expects in notes that load it
+type skeletonRequirement struct {
+	Text     string // as declared, e.g. "\section{Summary}"
+	Template string
+	pattern  *regexp.Regexp
+}
+
+// parseRequirement compiles a declared requirement
+// "\lecture" and "\lecture{}" require the command; "\section{Summary}" also requires the argument
+func parseRequirement(template, text string) (skeletonRequirement, bool) {
+	match := requirementPattern.FindStringSubmatch(text)
+	if match == nil {
+		return skeletonRequirement{}, false
+	}
+
+	name := regexp.QuoteMeta(strings.TrimSuffix(match[1], "*"))
+	expr := `\\` + name + `\*?(?:\[[^\]]*\])?\{`
+	if argument := strings.TrimSpace(match[2]); argument != "" {
+		expr += `\s*` + regexp.QuoteMeta(argument) + `\s*\}`
+	}
+	if match[2] == "" && !strings.HasSuffix(text, "}") {
+		expr = `\\` + name + `\b`
+	}
+
+	return skeletonRequirement{
+		Text:     text,
+		Template: template,
+		pattern:  regexp.MustCompile(expr),
+	}, true
+}
+
+// templateRequirements reads the lx-requires doc comments of a template
+func templateRequirements(template, content string) []skeletonRequirement {
+	var requirements []skeletonRequirement
+	for _, line := range strings.Split(content, "\n") {
+		match := templateRequirePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if requirement, ok := parseRequirement(template, match[1]); ok {
+			requirements = append(requirements, requirement)
+		}
+	}
+	return requirements
+}
+
+func init() {
+	registerQuickFix(diagnosticCodeMissingStructure, insertSkeletonFix)
+}
+
+// skeletonDiagnostics reports required template structure missing from a note
+// Diagnostics sit on the template name in \usepackage so the cause is visible
+func (s *LanguageServer) skeletonDiagnostics(content string) []protocol.Diagnostic {
+	ignored := make(map[string]bool)
+	for _, template := range s.settings().SkeletonIgnore {
+		ignored[template] = true
+	}
+
+	// Match against prose only, so commented-out structure does not count
+	var body strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		body.WriteString(stripInlineComment(line))
+		body.WriteString("\n")
+	}
+
+	diagnostics := []protocol.Diagnostic{}
+	for lineNum, line := range strings.Split(content, "\n") {
+		line = stripInlineComment(line)
+		for _, match := range usepackagePattern.FindAllStringSubmatchIndex(line, -1) {
+			offset := match[2]
+			for _, part := range strings.Split(line[match[2]:match[3]], ",") {
+				template := strings.TrimSpace(part)
+				column := offset + strings.Index(part, template)
+				offset += len(part) + 1
+
+				if template == "" || ignored[template] {
+					continue
+				}
+				data, ok := s.templateContent(template)
+				if !ok {
+					continue // Not a vault template
+				}
+
+				for _, requirement := range templateRequirements(template, data) {
+					if requirement.pattern.MatchString(body.String()) {
+						continue
+					}
+					diagnostics = append(diagnostics, protocol.Diagnostic{
+						Range:    lineRange(lineNum, column, column+len(template)),
+						Severity: protocol.DiagnosticSeverityWarning,
+						Code:     diagnosticCodeMissingStructure,
+						Message:  fmt.Sprintf("Template '%s' requires %s", template, requirement.Text),
+						Source:   "lx-ls",
+					})
+				}
+			}
+		}
+	}
+	return diagnostics
+}
+
+// insertSkeletonFix adds the missing structure just before \end{document}, or at the end of the note
+func insertSkeletonFix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	_, text, found := strings.Cut(diag.Message, " requires ")
+	if !found {
+		return nil
+	}
+	if strings.HasPrefix(text, `\`) && !strings.Contains(text, "{") {
+		text += "{}"
+	}
+
+	lines := strings.Split(req.Content, "\n")
+	insertAt := lineEnd(lines, len(lines)-1)
+	newText := "\n" + text
+	for lineNum, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), `\end{document}`) {
+			insertAt = protocol.Position{Line: uint32(lineNum)}
+			newText = text + "\n"
+			break
+		}
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf("Insert %s", text),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					req.URI: {{Range: protocol.Range{Start: insertAt, End: insertAt}, NewText: newText}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestSkeletonDiagnostics tests required template structure checks
+func TestSkeletonDiagnostics(t *testing.T) {
+	tempDir := t.TempDir()
+	templatesPath := filepath.Join(tempDir, "templates")
+	os.MkdirAll(templatesPath, 0755)
+	os.WriteFile(filepath.Join(templatesPath, "lecture.sty"), []byte(`% Lecture notes
+% lx-requires: \lecture{}
+% lx-requires: \section{Summary}
+% lx-requires: not a command
+\newcommand{\lecture}[1]{\section*{Lecture #1}}
+`), 0644)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: filepath.Join(tempDir, "notes"), TemplatesPath: templatesPath},
+		index: NewIndex(),
+	}
+
+	content := "\\usepackage{amsmath, lecture}\n\\begin{document}\n% \\lecture{1}\n\\section*{ Summary }\n\\end{document}"
+	diagnostics := ls.skeletonDiagnostics(content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %+v", len(diagnostics), diagnostics)
+	}
+	if diagnostics[0].Message != `Template 'lecture' requires \lecture{}` {
+		t.Errorf("unexpected message %q", diagnostics[0].Message)
+	}
+	if diagnostics[0].Range != lineRange(0, 21, 28) {
+		t.Errorf("expected range on the template name, got %+v", diagnostics[0].Range)
+	}
+
+	req := &codeActionRequest{URI: "file:///note.tex", Content: content}
+	actions := insertSkeletonFix(ls, req, diagnostics[0])
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 fix, got %d", len(actions))
+	}
+	edit := actions[0].Edit.Changes[protocol.DocumentURI("file:///note.tex")][0]
+	if edit.Range.Start.Line != 4 || edit.NewText != "\\lecture{}\n" {
+		t.Errorf("expected insertion before \\end{document}, got %+v", edit)
+	}
+
+	// Ignored templates are not checked
+	config := DefaultConfig()
+	config.SkeletonIgnore = []string{"lecture"}
+	ls.config = &config
+	if diagnostics := ls.skeletonDiagnostics(content); len(diagnostics) != 0 {
+		t.Errorf("expected ignored template to be skipped, got %+v", diagnostics)
+	}
+}
+
+// TestParseRequirement tests requirement matching
+func TestParseRequirement(t *testing.T) {
+	tests := []struct {
+		requirement string
+		content     string
+		matches     bool
+	}{
+		{`\lecture`, `\lecture{3}`, true},
+		{`\lecture`, `\lectures`, false},
+		{`\lecture{}`, `\lecture`, false},
+		{`\section{Summary}`, `\section*{Summary}`, true},
+		{`\section{Summary}`, `\section{Summary of results}`, false},
+	}
+	for _, tt := range tests {
+		requirement, ok := parseRequirement("t", tt.requirement)
+		if !ok {
+			t.Fatalf("failed to parse %q", tt.requirement)
+		}
+		if got := requirement.pattern.MatchString(tt.content); got != tt.matches {
+			t.Errorf("%s against %q = %v, want %v", tt.requirement, tt.content, got, tt.matches)
+		}
+	}
+
+	if _, ok := parseRequirement("t", "not a command"); ok {
+		t.Error("expected free text to be rejected")
+	}
+}
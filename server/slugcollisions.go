@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeDuplicateSlug reports note files resolving to the same slug
+const diagnosticCodeDuplicateSlug = "LX011"
+
+// slugCollisionDiagnostics reports the other files of a note's directory resolving to its slug, on the
+// first line of the note, naming the file references to the slug go to
+// Files of other directories using the slug are reported by workspace diagnostics only, as qualified
+// references tell them apart
+func (s *LanguageServer) slugCollisionDiagnostics(uri protocol.DocumentURI) []protocol.Diagnostic {
+	path := uriToPath(uri)
+	if !strings.HasSuffix(path, ".tex") {
+		return nil
+	}
+	note := &NoteHeader{Slug: s.parseFilenameToSlug(filepath.Base(path)), Filename: filepath.Base(path), Dir: s.noteDirOf(path)}
+	collisions := s.index.Collisions(note)
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	target := collisionTarget(note, collisions)
+	var diagnostics []protocol.Diagnostic
+	for _, other := range collisions {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange(0, 0, 0),
+			Severity: protocol.DiagnosticSeverityError,
+			Code:     diagnosticCodeDuplicateSlug,
+			Message:  fmt.Sprintf("Slug '%s' is also used by %s; references to it go to %s", note.Slug, other.Filename, target),
+			Source:   "lx-ls",
+		})
+	}
+	return diagnostics
+}
+
+// warnSlugCollisions logs the files of a note's directory that resolve to its slug as well
+func (s *LanguageServer) warnSlugCollisions(header *NoteHeader) {
+	collisions := s.index.Collisions(header)
+	if len(collisions) == 0 {
+		return
+	}
+	target := collisionTarget(header, collisions)
+	for _, other := range collisions {
+		s.logf(protocol.MessageTypeWarning, "Slug '%s' of %s is also used by %s; references to it go to %s", header.Slug, header.Filename, other.Filename, target)
+	}
+}
+
+// collisionTarget returns the filename references to a slug several files of a directory use resolve
+// to: the first in sort order, as the index keeps it
+func collisionTarget(note *NoteHeader, collisions []*NoteHeader) string {
+	target := note.Filename
+	for _, other := range collisions {
+		if other.Filename < target {
+			target = other.Filename
+		}
+	}
+	return target
+}
+
+// publishCollisionDiagnostics refreshes the diagnostics of the open notes using slug, when files
+// resolving to it appeared or disappeared
+func (s *LanguageServer) publishCollisionDiagnostics(ctx context.Context, slug string) {
+	for _, note := range s.index.Variants(slug) {
+		uri := pathToURI(s.headerPath(note))
+		s.mu.RLock()
+		content, open := s.documents[uri]
+		s.mu.RUnlock()
+		if open {
+			s.publishDiagnostics(ctx, uri, content)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestSlugCollisions tests files of one directory resolving to the same slug
+func TestSlugCollisions(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240202-foo.tex": "%% Metadata\n%% title: Later Foo\n",
+		"20240101-foo.tex": "%% Metadata\n%% title: Earlier Foo\n",
+		"20240103-bar.tex": "%% Metadata\n%% title: Bar\n\\ref{foo}\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	// The first filename holds the slug, whatever order the files are indexed in
+	if note, _ := ls.index.Get("foo"); note == nil || note.Filename != "20240101-foo.tex" {
+		t.Fatalf("expected 20240101-foo.tex to hold the slug, got %+v", note)
+	}
+	for _, order := range [][]string{{"20240101-foo.tex", "20240202-foo.tex"}, {"20240202-foo.tex", "20240101-foo.tex"}} {
+		index := NewIndex()
+		for _, name := range order {
+			index.Set("foo", &NoteHeader{Slug: "foo", Filename: name})
+		}
+		if note, _ := index.Get("foo"); note.Filename != "20240101-foo.tex" {
+			t.Errorf("indexing %v: expected 20240101-foo.tex to hold the slug, got %s", order, note.Filename)
+		}
+		if variants := index.Variants("foo"); len(variants) != 2 || variants[0].Filename != "20240101-foo.tex" {
+			t.Errorf("indexing %v: expected both files sorted, got %+v", order, variants)
+		}
+	}
+
+	// Both files are reported, naming the other and the file references go to
+	for name, other := range map[string]string{"20240101-foo.tex": "20240202-foo.tex", "20240202-foo.tex": "20240101-foo.tex"} {
+		uri := pathToURI(filepath.Join(tempDir, name))
+		var found []protocol.Diagnostic
+		for _, diag := range ls.analyzeNoteDiagnostics(uri, files[name]) {
+			if diag.Code == diagnosticCodeDuplicateSlug {
+				found = append(found, diag)
+			}
+		}
+		if len(found) != 1 || found[0].Severity != protocol.DiagnosticSeverityError ||
+			!strings.Contains(found[0].Message, other) || !strings.Contains(found[0].Message, "go to 20240101-foo.tex") {
+			t.Errorf("%s: expected a duplicate slug error naming %s, got %+v", name, other, found)
+		}
+	}
+	barURI := pathToURI(filepath.Join(tempDir, "20240103-bar.tex"))
+	for _, diag := range ls.analyzeNoteDiagnostics(barURI, files["20240103-bar.tex"]) {
+		if diag.Code == diagnosticCodeDuplicateSlug {
+			t.Errorf("bar: unexpected duplicate slug %+v", diag)
+		}
+	}
+
+	// Once the file holding the slug is gone, the other takes it over and the collision clears
+	os.Remove(filepath.Join(tempDir, "20240101-foo.tex"))
+	ls.updateIndexForFile(filepath.Join(tempDir, "20240101-foo.tex"))
+	if note, _ := ls.index.Get("foo"); note == nil || note.Filename != "20240202-foo.tex" || note.Title != "Later Foo" {
+		t.Fatalf("expected 20240202-foo.tex to take the slug over, got %+v", note)
+	}
+	if collisions := ls.index.Collisions(&NoteHeader{Slug: "foo", Filename: "20240202-foo.tex"}); len(collisions) != 0 {
+		t.Errorf("expected no collisions left, got %+v", collisions)
+	}
+}
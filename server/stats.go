@@ -0,0 +1,55 @@
+package server
+
+import "context"
+
+// MethodStats is the custom request returning vault statistics, for dashboards in editor plugins
+const MethodStats = "lx/stats"
+
+// StatsParams is empty; lx/stats takes no parameters
+type StatsParams struct{}
+
+// StatsResult summarizes the vault from the index
+// Words are counted like the full-text index splits them: runs of at least two letters or digits,
+// without LaTeX command names, and from the open buffer of notes being edited
+type StatsResult struct {
+	Notes   int            `json:"notes"`
+	Tags    map[string]int `json:"tags"` // lowercased tag -> notes carrying it
+	Links   int            `json:"links"`
+	Orphans int            `json:"orphans"`
+	Words   int            `json:"words"`
+}
+
+// Handle lx/stats request
+func (s *LanguageServer) Stats(ctx context.Context, params *StatsParams) (*StatsResult, error) {
+	return &StatsResult{
+		Notes:   s.index.Count(),
+		Tags:    s.index.Tags().Counts(),
+		Links:   s.index.Links().Count(),
+		Orphans: len(s.orphans()),
+		Words:   s.index.Search().Words(),
+	}, nil
+}
+
+// Count returns the number of links in the vault
+func (l *LinkIndex) Count() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	count := 0
+	for _, links := range l.outgoing {
+		count += len(links)
+	}
+	return count
+}
+
+// Words returns the number of indexed words in the vault, repeated words counted every time
+func (x *SearchIndex) Words() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	words := 0
+	for _, tokens := range x.docs {
+		for _, count := range tokens {
+			words += count
+		}
+	}
+	return words
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestStats tests the vault statistics of lx/stats
+func TestStats(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n% tags: math\n\\ref{trees} graph theory\n",
+		"20240102-trees.tex":  "%% Metadata\n% title: Trees\n% tags: Math, trees\n\\ref{graphs} \\ref{graphs} a\n",
+		"20240103-sets.tex":   "%% Metadata\n% title: Sets\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	stats, err := ls.Stats(context.Background(), &StatsParams{})
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Notes != 3 || stats.Links != 3 || stats.Orphans != 1 || stats.Words != 19 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if len(stats.Tags) != 2 || stats.Tags["math"] != 2 || stats.Tags["trees"] != 1 {
+		t.Errorf("unexpected tag counts %v", stats.Tags)
+	}
+}
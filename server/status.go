@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandSetStatus rewrites the status field of a note's metadata
+// Arguments: [uri, status]
+const commandSetStatus = "lx.setStatus"
+
+func init() {
+	registerCommand(commandSetStatus, withoutResult((*LanguageServer).setStatus))
+}
+
+// reviewStatuses is the order notes move through review
+var reviewStatuses = []string{"draft", "review", "final"}
+
+// statusFieldPattern matches a "%% status: value" metadata line; group 1 is the value
+var statusFieldPattern = regexp.MustCompile(`^\s*%+\s*status\s*:\s*(.*?)\s*$`)
+
+// statusLine locates the status field inside the metadata block
+// Returns the line number and the column range of the value
+func (s *LanguageServer) statusLine(content string) (int, int, int, bool) {
+	start, end, found := s.metadataParser().FindBlock(content)
+	if !found {
+		return 0, 0, 0, false
+	}
+
+	lines := strings.Split(content, "\n")
+	for lineNum := start; lineNum <= end && lineNum < len(lines); lineNum++ {
+		if match := statusFieldPattern.FindStringSubmatchIndex(lines[lineNum]); match != nil {
+			return lineNum, match[2], match[3], true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// statusLenses shows the review status of a note with commands to move it along
+func (s *LanguageServer) statusLenses(docURI protocol.DocumentURI, content string) []protocol.CodeLens {
+	lineNum, valueStart, valueEnd, found := s.statusLine(content)
+	if !found {
+		return nil
+	}
+
+	line := strings.Split(content, "\n")[lineNum]
+	status := strings.ToLower(line[valueStart:valueEnd])
+	lensRange := lineRange(lineNum, 0, len(line))
+
+	lenses := []protocol.CodeLens{
+		{
+			// An empty command renders as a plain label
+			Range:   lensRange,
+			Command: &protocol.Command{Title: fmt.Sprintf("Status: %s", status)},
+		},
+	}
+
+	for _, next := range nextStatuses(status) {
+		title := fmt.Sprintf("Mark as %s", next)
+		if next == reviewStatuses[0] {
+			title = fmt.Sprintf("Back to %s", next)
+		}
+		lenses = append(lenses, protocol.CodeLens{
+			Range: lensRange,
+			Command: &protocol.Command{
+				Title:     title,
+				Command:   commandSetStatus,
+				Arguments: []interface{}{string(docURI), next},
+			},
+		})
+	}
+
+	return lenses
+}
+
+// nextStatuses returns where a note can go from its current status
+// Notes advance one step at a time; final notes can be reopened as drafts
+func nextStatuses(status string) []string {
+	for i, candidate := range reviewStatuses {
+		if candidate != status {
+			continue
+		}
+		if i == len(reviewStatuses)-1 {
+			return []string{reviewStatuses[0]}
+		}
+		return []string{reviewStatuses[i+1]}
+	}
+	// Unknown statuses can be brought back into the workflow
+	return []string{reviewStatuses[0]}
+}
+
+// setStatus handles lx.setStatus
+func (s *LanguageServer) setStatus(ctx context.Context, args []interface{}) error {
+	notePath, err := notePathArgument(commandSetStatus, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("%s requires a status argument", commandSetStatus)
+	}
+	status, ok := args[1].(string)
+	if !ok || strings.TrimSpace(status) == "" {
+		return fmt.Errorf("%s: invalid status argument", commandSetStatus)
+	}
+
+	docURI := pathToURI(notePath)
+	content, err := s.GetDocument(docURI)
+	if err != nil {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+
+	edit, err := s.statusEdit(docURI, content, status)
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyEdit(ctx, fmt.Sprintf("Mark as %s", status), edit); err != nil {
+		return err
+	}
+	s.recordActivity(ctx, "note.status", fmt.Sprintf("%s: %s", filepath.Base(notePath), status))
+
+	return nil
+}
+
+// statusEdit builds the edit replacing the status value of a note
+func (s *LanguageServer) statusEdit(docURI protocol.DocumentURI, content, status string) (*protocol.WorkspaceEdit, error) {
+	lineNum, valueStart, valueEnd, found := s.statusLine(content)
+	if !found {
+		return nil, fmt.Errorf("note has no status field")
+	}
+
+	return &protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			docURI: {{Range: lineRange(lineNum, valueStart, valueEnd), NewText: status}},
+		},
+	}, nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+)
+
+// TestStatusLenses tests the review status lens and its advance commands
+func TestStatusLenses(t *testing.T) {
+	ls := &LanguageServer{index: NewIndex()}
+	uri := pathToURI("/vault/notes/20240101-test.tex")
+
+	content := "%% Metadata\n% title: Test\n%% status: Review\n\n\\documentclass{article}"
+	lenses := ls.statusLenses(uri, content)
+	if len(lenses) != 2 {
+		t.Fatalf("expected 2 lenses, got %d", len(lenses))
+	}
+	if lenses[0].Command.Title != "Status: review" || lenses[0].Command.Command != "" {
+		t.Errorf("unexpected status label %+v", lenses[0].Command)
+	}
+	if lenses[1].Command.Command != commandSetStatus || lenses[1].Command.Arguments[1] != "final" {
+		t.Errorf("expected advance to final, got %+v", lenses[1].Command)
+	}
+	if lenses[0].Range.Start.Line != 2 {
+		t.Errorf("expected lens on status line, got %d", lenses[0].Range.Start.Line)
+	}
+
+	edit, err := ls.statusEdit(uri, content, "final")
+	if err != nil {
+		t.Fatalf("statusEdit failed: %v", err)
+	}
+	change := edit.Changes[uri][0]
+	if change.NewText != "final" || change.Range.Start.Character != 11 || change.Range.End.Character != 17 {
+		t.Errorf("unexpected status edit %+v", change)
+	}
+
+	// Notes without a status get no lenses
+	if lenses := ls.statusLenses(uri, "%% Metadata\n% title: Test\n"); len(lenses) != 0 {
+		t.Errorf("expected no lenses, got %d", len(lenses))
+	}
+}
+
+// TestNextStatuses tests the review workflow transitions
+func TestNextStatuses(t *testing.T) {
+	tests := map[string]string{
+		"draft":   "review",
+		"review":  "final",
+		"final":   "draft",
+		"unknown": "draft",
+	}
+	for status, expected := range tests {
+		if next := nextStatuses(status); len(next) != 1 || next[0] != expected {
+			t.Errorf("nextStatuses(%q) = %v, want [%s]", status, next, expected)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// maxMessageSize caps the body of a single JSON-RPC message in either direction
+	// Clients drop or choke on larger messages, so they are refused with an error instead
+	maxMessageSize = 64 << 20
+
+	// maxEditSize is the encoded size above which workspace edits are sent in chunks
+	maxEditSize = 8 << 20
+)
+
+// errMessageTooLarge is returned when writing a request or notification over maxMessageSize
+type errMessageTooLarge struct {
+	kind string
+	size int
+}
+
+func (e *errMessageTooLarge) Error() string {
+	return fmt.Sprintf("%s of %s exceeds the %s message limit", e.kind, formatBytes(e.size), formatBytes(maxMessageSize))
+}
+
+// framedStream is a jsonrpc2.Stream over Content-Length framed messages, like jsonrpc2.NewStream,
+// that survives bad input: oversized or malformed messages are skipped and reported through
+// onDrop rather than failing the connection, and each frame goes out in a single write
+// Requests among them are answered with an error, so the client does not wait for a reply
+type framedStream struct {
+	in      *bufio.Reader
+	out     io.WriteCloser
+	writeMu sync.Mutex // Read answers skipped requests alongside the connection's writes
+	maxSize int
+	onDrop  func(error)
+}
+
+// newFramedStream frames messages over rwc, refusing bodies over maxSize bytes
+func newFramedStream(rwc io.ReadWriteCloser, maxSize int, onDrop func(error)) *framedStream {
+	return &framedStream{
+		in:      bufio.NewReader(rwc),
+		out:     rwc,
+		maxSize: maxSize,
+		onDrop:  onDrop,
+	}
+}
+
+// Read returns the next message that fits the size limit and decodes
+func (s *framedStream) Read(ctx context.Context) (jsonrpc2.Message, int64, error) {
+	var total int64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, total, ctx.Err()
+		default:
+		}
+
+		length, headerSize, err := s.readHeader()
+		total += headerSize
+		if err != nil {
+			return nil, total, err
+		}
+
+		if length > int64(s.maxSize) {
+			body := &io.LimitedReader{R: s.in, N: length}
+			id, isCall := requestID(body)
+			io.Copy(io.Discard, body)
+			total += length - body.N
+			if body.N > 0 {
+				return nil, total, fmt.Errorf("discarding oversized message: %w", io.ErrUnexpectedEOF)
+			}
+			tooLarge := &errMessageTooLarge{kind: "incoming message", size: int(length)}
+			s.drop(tooLarge)
+			if isCall {
+				s.replyError(ctx, id, jsonrpc2.NewError(jsonrpc2.InvalidRequest, tooLarge.Error()))
+			}
+			continue
+		}
+
+		data := make([]byte, length)
+		n, err := io.ReadFull(s.in, data)
+		total += int64(n)
+		if err != nil {
+			return nil, total, fmt.Errorf("reading message body: %w", err)
+		}
+
+		msg, err := jsonrpc2.DecodeMessage(data)
+		if err != nil {
+			s.drop(fmt.Errorf("skipping malformed message: %w", err))
+			if id, isCall := requestID(bytes.NewReader(data)); isCall {
+				code := jsonrpc2.InvalidRequest
+				if !json.Valid(data) {
+					code = jsonrpc2.ParseError
+				}
+				s.replyError(ctx, id, jsonrpc2.NewError(code, fmt.Sprintf("malformed message: %v", err)))
+			}
+			continue
+		}
+		return msg, total, nil
+	}
+}
+
+// requestID reads the id of a request from a message body token by token, so it is found in
+// bodies too large to hold or that fail to decode past the id
+// Reports false for notifications and responses, which get no reply, and when the body breaks off
+// before both the id and the method were read
+func requestID(body io.Reader) (jsonrpc2.ID, bool) {
+	var id jsonrpc2.ID
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return id, false
+	}
+	hasID, hasMethod := false, false
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if key == "id" {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				break
+			}
+			hasID = string(raw) != "null" && id.UnmarshalJSON(raw) == nil
+			continue
+		}
+		hasMethod = hasMethod || key == "method"
+		if skipJSONValue(decoder) != nil {
+			break
+		}
+	}
+	return id, hasID && hasMethod
+}
+
+// skipJSONValue reads past the next value of decoder, without holding on to it
+func skipJSONValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// replyError answers a request the stream skipped
+func (s *framedStream) replyError(ctx context.Context, id jsonrpc2.ID, replyErr *jsonrpc2.Error) {
+	response, err := jsonrpc2.NewResponse(id, nil, replyErr)
+	if err == nil {
+		_, err = s.Write(ctx, response)
+	}
+	if err != nil {
+		s.drop(fmt.Errorf("answering skipped request %v: %w", id, err))
+	}
+}
+
+// readHeader reads the header block of a frame and returns its Content-Length
+func (s *framedStream) readHeader() (length, size int64, err error) {
+	length = -1
+	for {
+		line, err := s.in.ReadString('\n')
+		size += int64(len(line))
+		if err != nil {
+			return 0, size, fmt.Errorf("reading header: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return 0, size, fmt.Errorf("invalid header line %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64); err != nil || length < 0 {
+				return 0, size, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return 0, size, fmt.Errorf("missing Content-Length header")
+	}
+	return length, size, nil
+}
+
+// Write frames a message
+// A response over the size limit is replaced by an error response for the same request, so the
+// client is told why instead of waiting forever; other oversized messages are not sent
+func (s *framedStream) Write(ctx context.Context, msg jsonrpc2.Message) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("marshaling message: %w", err)
+	}
+
+	if len(data) > s.maxSize {
+		tooLarge := &errMessageTooLarge{kind: "message", size: len(data)}
+		response, ok := msg.(*jsonrpc2.Response)
+		if !ok {
+			if _, isCall := msg.(*jsonrpc2.Call); isCall {
+				tooLarge.kind = "request"
+			} else {
+				tooLarge.kind = "notification"
+			}
+			return 0, tooLarge
+		}
+		tooLarge.kind = "response"
+		s.drop(tooLarge)
+		replacement, err := jsonrpc2.NewResponse(response.ID(), nil, jsonrpc2.NewError(jsonrpc2.InternalError, tooLarge.Error()))
+		if err != nil {
+			return 0, err
+		}
+		if data, err = json.Marshal(replacement); err != nil {
+			return 0, fmt.Errorf("marshaling message: %w", err)
+		}
+	}
+
+	// Header and body in one write, so a failed write never leaves a header without its body
+	frame := make([]byte, 0, len(data)+32)
+	frame = append(frame, "Content-Length: "...)
+	frame = strconv.AppendInt(frame, int64(len(data)), 10)
+	frame = append(frame, "\r\n\r\n"...)
+	frame = append(frame, data...)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	n, err := s.out.Write(frame)
+	return int64(n), err
+}
+
+// Close closes the underlying output
+func (s *framedStream) Close() error {
+	return s.out.Close()
+}
+
+func (s *framedStream) drop(err error) {
+	if s.onDrop != nil {
+		s.onDrop(err)
+	}
+}
+
+// formatBytes formats a size in bytes with a binary unit, e.g. "12.5 MiB"
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
+
+// editSize is the encoded size of a workspace edit
+func editSize(edit *protocol.WorkspaceEdit) int {
+	data, err := json.Marshal(edit)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// splitEdit breaks a workspace edit into edits of at most limit encoded bytes, keeping each
+// document's edits together; a single document over the limit gets an edit of its own
+// Document changes keep their order, since later ones may depend on earlier ones
+func splitEdit(edit *protocol.WorkspaceEdit, limit int) []*protocol.WorkspaceEdit {
+	if editSize(edit) <= limit {
+		return []*protocol.WorkspaceEdit{edit}
+	}
+
+	var chunks []*protocol.WorkspaceEdit
+	current := &protocol.WorkspaceEdit{ChangeAnnotations: edit.ChangeAnnotations}
+	size := 0
+	add := func(docSize int, apply func(*protocol.WorkspaceEdit)) {
+		if size > 0 && size+docSize > limit {
+			chunks = append(chunks, current)
+			current = &protocol.WorkspaceEdit{ChangeAnnotations: edit.ChangeAnnotations}
+			size = 0
+		}
+		apply(current)
+		size += docSize
+	}
+
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, string(uri))
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		edits := edit.Changes[protocol.DocumentURI(uri)]
+		add(editSize(&protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{protocol.DocumentURI(uri): edits}}), func(chunk *protocol.WorkspaceEdit) {
+			if chunk.Changes == nil {
+				chunk.Changes = make(map[protocol.DocumentURI][]protocol.TextEdit)
+			}
+			chunk.Changes[protocol.DocumentURI(uri)] = edits
+		})
+	}
+	// Clients prefer documentChanges over changes when both are set, so they never share a chunk
+	if size > 0 && len(edit.DocumentChanges) > 0 {
+		chunks = append(chunks, current)
+		current = &protocol.WorkspaceEdit{ChangeAnnotations: edit.ChangeAnnotations}
+		size = 0
+	}
+	for _, change := range edit.DocumentChanges {
+		change := change
+		add(editSize(&protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{change}}), func(chunk *protocol.WorkspaceEdit) {
+			chunk.DocumentChanges = append(chunk.DocumentChanges, change)
+		})
+	}
+	if size > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// bufferCloser is an in-memory stream for framedStream
+type bufferCloser struct {
+	io.Reader
+	out bytes.Buffer
+}
+
+func (b *bufferCloser) Write(p []byte) (int, error) { return b.out.Write(p) }
+func (b *bufferCloser) Close() error                { return nil }
+
+func frame(body string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// TestFramedStream_SkipsBadMessages tests that oversized and malformed messages are skipped without ending the stream
+func TestFramedStream_SkipsBadMessages(t *testing.T) {
+	input := frame(`{"jsonrpc":"2.0","method":"big","params":"`+strings.Repeat("x", 200)+`"}`) +
+		frame(`{"jsonrpc":"2.0",`) +
+		frame(`{"jsonrpc":"2.0","id":1,"method":"ok"}`)
+	var dropped []error
+	stream := newFramedStream(&bufferCloser{Reader: strings.NewReader(input)}, 100, func(err error) { dropped = append(dropped, err) })
+
+	msg, _, err := stream.Read(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	call, ok := msg.(*jsonrpc2.Call)
+	if !ok || call.Method() != "ok" {
+		t.Fatalf("expected the well-formed call, got %#v", msg)
+	}
+	if len(dropped) != 2 || !strings.Contains(dropped[0].Error(), "exceeds the") {
+		t.Errorf("expected the oversized and malformed messages to be reported, got %v", dropped)
+	}
+
+	if _, _, err := stream.Read(context.Background()); err == nil {
+		t.Error("expected an error at the end of the input")
+	}
+}
+
+// TestFramedStream_OversizedResponse tests that a response over the limit becomes an error response for the same request
+func TestFramedStream_OversizedResponse(t *testing.T) {
+	out := &bufferCloser{Reader: strings.NewReader("")}
+	stream := newFramedStream(out, 100, nil)
+
+	response, _ := jsonrpc2.NewResponse(jsonrpc2.NewNumberID(7), strings.Repeat("x", 200), nil)
+	if _, err := stream.Write(context.Background(), response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reread := newFramedStream(&bufferCloser{Reader: bytes.NewReader(out.out.Bytes())}, maxMessageSize, nil)
+	msg, _, err := reread.Read(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replacement, ok := msg.(*jsonrpc2.Response)
+	if !ok || replacement.ID() != jsonrpc2.NewNumberID(7) || replacement.Err() == nil {
+		t.Fatalf("expected an error response for request 7, got %#v", msg)
+	}
+	if !strings.Contains(replacement.Err().Error(), "response of") {
+		t.Errorf("unexpected error message %q", replacement.Err())
+	}
+
+	notification, _ := jsonrpc2.NewNotification("big", strings.Repeat("x", 200))
+	if _, err := stream.Write(context.Background(), notification); err == nil {
+		t.Error("expected an oversized notification to be refused")
+	}
+}
+
+// TestSplitEdit tests that large edits are split by document within the limit
+func TestSplitEdit(t *testing.T) {
+	edit := &protocol.WorkspaceEdit{Changes: make(map[protocol.DocumentURI][]protocol.TextEdit)}
+	for i := 0; i < 10; i++ {
+		uri := protocol.DocumentURI(fmt.Sprintf("file:///notes/%02d.tex", i))
+		edit.Changes[uri] = []protocol.TextEdit{{NewText: strings.Repeat("y", 100)}}
+	}
+
+	if chunks := splitEdit(edit, editSize(edit)); len(chunks) != 1 {
+		t.Errorf("expected an edit within the limit to stay whole, got %d chunks", len(chunks))
+	}
+
+	limit := editSize(edit) / 3
+	chunks := splitEdit(edit, limit)
+	if len(chunks) < 3 {
+		t.Fatalf("expected at least 3 chunks, got %d", len(chunks))
+	}
+	seen := 0
+	for _, chunk := range chunks {
+		if size := editSize(chunk); size > limit {
+			t.Errorf("chunk of %d bytes exceeds limit %d", size, limit)
+		}
+		seen += len(chunk.Changes)
+	}
+	if seen != 10 {
+		t.Errorf("expected all 10 documents across the chunks, got %d", seen)
+	}
+}
+
+// TestFramedStream_AnswersSkippedRequests tests that oversized and malformed requests get an error
+// response for their id, and skipped notifications none
+func TestFramedStream_AnswersSkippedRequests(t *testing.T) {
+	input := frame(`{"jsonrpc":"2.0","method":"big","params":{"text":"`+strings.Repeat("x", 500)+`"},"id":3}`) +
+		frame(`{"jsonrpc":"2.0","method":"big","params":"`+strings.Repeat("x", 500)+`"}`) +
+		frame(`{"jsonrpc":"2.0","id":"four","method":"broken",`) +
+		frame(`{"jsonrpc":"2.0","id":5,"method":7}`) +
+		frame(`{"jsonrpc":"2.0","id":6,"method":"ok"}`)
+	out := &bufferCloser{Reader: strings.NewReader(input)}
+	stream := newFramedStream(out, 400, nil)
+	if msg, _, err := stream.Read(context.Background()); err != nil || msg.(*jsonrpc2.Call).ID() != jsonrpc2.NewNumberID(6) {
+		t.Fatalf("expected the well-formed call, got %#v, %v", msg, err)
+	}
+
+	replies := newFramedStream(&bufferCloser{Reader: bytes.NewReader(out.out.Bytes())}, maxMessageSize, nil)
+	for _, want := range []struct {
+		id   jsonrpc2.ID
+		code jsonrpc2.Code
+	}{
+		{jsonrpc2.NewNumberID(3), jsonrpc2.InvalidRequest},
+		{jsonrpc2.NewStringID("four"), jsonrpc2.ParseError},
+		{jsonrpc2.NewNumberID(5), jsonrpc2.InvalidRequest},
+	} {
+		msg, _, err := replies.Read(context.Background())
+		if err != nil {
+			t.Fatalf("expected a reply for %v: %v", want.id, err)
+		}
+		response, ok := msg.(*jsonrpc2.Response)
+		var replyErr *jsonrpc2.Error
+		if !ok || response.ID() != want.id || !errors.As(response.Err(), &replyErr) || replyErr.Code != want.code {
+			t.Errorf("expected error %d for %v, got %#v", want.code, want.id, msg)
+		}
+	}
+	if msg, _, err := replies.Read(context.Background()); err == nil {
+		t.Errorf("expected no reply to the notification, got %#v", msg)
+	}
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kamal-hamza/lx-lsp/pkg/metadata"
+	"go.lsp.dev/protocol"
+)
+
+var (
+	// sectionPattern matches sectioning commands, including starred variants
+	sectionPattern = regexp.MustCompile(`\\(chapter|section|subsection|subsubsection)\*?\{([^}]*)\}`)
+	// labelPattern matches \label targets
+	labelPattern = regexp.MustCompile(`\\label\{([^}]+)\}`)
+	// metadataFieldPattern matches a "% field: value" line inside the metadata block
+	metadataFieldPattern = regexp.MustCompile(`^\s*%+\s*(\w+):\s*(.*)$`)
+)
+
+// sectionLevels maps sectioning commands to their nesting depth
+var sectionLevels = map[string]int{
+	"chapter":       0,
+	"section":       1,
+	"subsection":    2,
+	"subsubsection": 3,
+}
+
+// symbolNode is an intermediate tree node used while building the outline
+type symbolNode struct {
+	level    int
+	symbol   protocol.DocumentSymbol
+	children []*symbolNode
+}
+
+// Handle DocumentSymbol request
+func (s *LanguageServer) DocumentSymbol(ctx context.Context, params *protocol.DocumentSymbolParams) ([]protocol.DocumentSymbol, error) {
+	if !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	return []protocol.DocumentSymbol{s.noteSymbol(params.TextDocument.URI, content)}, nil
+}
+
+// noteSymbol wraps the outline in a root symbol for the note itself
+// Editors with breadcrumbs then show e.g. "Graph Theory — 2024-01-01 — math" at the top of the path
+func (s *LanguageServer) noteSymbol(docURI protocol.DocumentURI, content string) protocol.DocumentSymbol {
+	filename := filepath.Base(uriToPath(docURI))
+	slug := s.parseFilenameToSlug(filename)
+	lines := s.documentLines(content)
+
+	title, date := slug, filenameDate(filename)
+	var tags []string
+	if result, err := s.metadataParser().Parse(content); err == nil {
+		if result.Metadata.Title != "" {
+			title = result.Metadata.Title
+		}
+		if result.Metadata.Date != "" {
+			date = result.Metadata.Date
+		}
+		tags = result.Metadata.Tags
+	}
+
+	parts := []string{title}
+	if date != "" {
+		parts = append(parts, date)
+	}
+	if len(tags) > 0 {
+		parts = append(parts, strings.Join(tags, ", "))
+	}
+
+	// Select the title field when there is one, so the breadcrumb jumps to it
+	selection, found := s.titleRange(content)
+	if !found {
+		selection = lineRange(0, 0, len(lines[0]))
+	}
+
+	children := documentSymbols(content)
+	annotateSectionSymbols(children, s.sectionBacklinks(docURI, content))
+
+	return protocol.DocumentSymbol{
+		Name:   strings.Join(parts, " — "),
+		Detail: slug,
+		Kind:   protocol.SymbolKindFile,
+		Range: protocol.Range{
+			End: lineEnd(lines, len(lines)-1),
+		},
+		SelectionRange: selection,
+		Children:       children,
+	}
+}
+
+// titleRange locates the value of the title field in the metadata block
+func (s *LanguageServer) titleRange(content string) (protocol.Range, bool) {
+	blockStart, blockEnd, found := s.metadataParser().FindBlock(content)
+	if !found {
+		return protocol.Range{}, false
+	}
+	lines := s.documentLines(content)
+	for lineNum := blockStart; lineNum <= blockEnd && lineNum < len(lines); lineNum++ {
+		match := metadataFieldPattern.FindStringSubmatchIndex(lines[lineNum])
+		if match != nil && strings.EqualFold(lines[lineNum][match[2]:match[3]], "title") {
+			return lineRange(lineNum, match[4], match[5]), true
+		}
+	}
+	return protocol.Range{}, false
+}
+
+// filenameDate returns the YYYYMMDD prefix of a note filename as YYYY-MM-DD
+func filenameDate(filename string) string {
+	prefix, _, found := strings.Cut(filename, "-")
+	if !found || len(prefix) != 8 {
+		return ""
+	}
+	date, err := time.Parse("20060102", prefix)
+	if err != nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// documentSymbols builds the outline of a note: metadata block, sections and labels
+func documentSymbols(content string) []protocol.DocumentSymbol {
+	lines := strings.Split(content, "\n")
+	root := &symbolNode{level: -1}
+	stack := []*symbolNode{root}
+
+	// closeUntil pops every open section at or below level, ending it before line
+	closeUntil := func(level, line int) {
+		for len(stack) > 1 && stack[len(stack)-1].level >= level {
+			node := stack[len(stack)-1]
+			node.symbol.Range.End = lineEnd(lines, line-1)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	blockStart, blockEnd, hasBlock := metadata.FindBlock(content)
+	if hasBlock {
+		root.children = append(root.children, metadataSymbol(lines, blockStart, blockEnd))
+	}
+
+	for lineNum, line := range lines {
+		if hasBlock && lineNum >= blockStart && lineNum <= blockEnd {
+			continue
+		}
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+
+		if match := sectionPattern.FindStringSubmatchIndex(line); match != nil {
+			level := sectionLevels[line[match[2]:match[3]]]
+			closeUntil(level, lineNum)
+
+			node := &symbolNode{
+				level: level,
+				symbol: protocol.DocumentSymbol{
+					Name:           strings.TrimSpace(line[match[4]:match[5]]),
+					Detail:         line[match[2]:match[3]],
+					Kind:           protocol.SymbolKindModule,
+					Range:          lineRange(lineNum, match[0], match[1]),
+					SelectionRange: lineRange(lineNum, match[4], match[5]),
+				},
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		}
+
+		for _, match := range labelPattern.FindAllStringSubmatchIndex(line, -1) {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, &symbolNode{
+				symbol: protocol.DocumentSymbol{
+					Name:           line[match[2]:match[3]],
+					Detail:         "label",
+					Kind:           protocol.SymbolKindKey,
+					Range:          lineRange(lineNum, match[0], match[1]),
+					SelectionRange: lineRange(lineNum, match[2], match[3]),
+				},
+			})
+		}
+	}
+
+	closeUntil(-1, len(lines))
+
+	return flattenSymbols(root.children)
+}
+
+// metadataSymbol returns the metadata block symbol with one child per field
+func metadataSymbol(lines []string, blockStart, blockEnd int) *symbolNode {
+	node := &symbolNode{
+		symbol: protocol.DocumentSymbol{
+			Name: "Metadata",
+			Kind: protocol.SymbolKindObject,
+			Range: protocol.Range{
+				Start: protocol.Position{Line: uint32(blockStart)},
+				End:   lineEnd(lines, blockEnd),
+			},
+			SelectionRange: lineRange(blockStart, 0, len(lines[blockStart])),
+		},
+	}
+
+	for lineNum := blockStart + 1; lineNum <= blockEnd; lineNum++ {
+		match := metadataFieldPattern.FindStringSubmatchIndex(lines[lineNum])
+		if match == nil {
+			continue
+		}
+		node.children = append(node.children, &symbolNode{
+			symbol: protocol.DocumentSymbol{
+				Name:           strings.ToLower(lines[lineNum][match[2]:match[3]]),
+				Detail:         strings.TrimSpace(lines[lineNum][match[4]:match[5]]),
+				Kind:           protocol.SymbolKindProperty,
+				Range:          lineRange(lineNum, 0, len(lines[lineNum])),
+				SelectionRange: lineRange(lineNum, match[2], match[3]),
+			},
+		})
+	}
+
+	return node
+}
+
+// flattenSymbols converts intermediate nodes into protocol symbols
+func flattenSymbols(nodes []*symbolNode) []protocol.DocumentSymbol {
+	symbols := make([]protocol.DocumentSymbol, 0, len(nodes))
+	for _, node := range nodes {
+		symbol := node.symbol
+		if len(node.children) > 0 {
+			symbol.Children = flattenSymbols(node.children)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// lineRange returns a range on a single line
+func lineRange(line, start, end int) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(line), Character: uint32(start)},
+		End:   protocol.Position{Line: uint32(line), Character: uint32(end)},
+	}
+}
+
+// utf16Column converts a byte offset in line to the UTF-16 code units LSP positions count
+func utf16Column(line string, offset int) int {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	column := 0
+	for _, r := range line[:offset] {
+		if r >= 0x10000 {
+			column += 2
+		} else {
+			column++
+		}
+	}
+	return column
+}
+
+// byteColumn converts a UTF-16 column of line to a byte offset, the end of the line when past it
+func byteColumn(line string, column int) int {
+	units := 0
+	for offset, r := range line {
+		if units >= column {
+			return offset
+		}
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	return len(line)
+}
+
+// textRange returns the range of line's bytes start to end, in UTF-16 columns
+func textRange(lineNum int, line string, start, end int) protocol.Range {
+	return lineRange(lineNum, utf16Column(line, start), utf16Column(line, end))
+}
+
+// lineEnd returns the position at the end of the given line
+func lineEnd(lines []string, line int) protocol.Position {
+	if line < 0 {
+		line = 0
+	}
+	if line >= len(lines) {
+		line = len(lines) - 1
+	}
+	return protocol.Position{Line: uint32(line), Character: uint32(len(lines[line]))}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestDocumentSymbols tests the outline built for a note
+func TestDocumentSymbols(t *testing.T) {
+	content := `%% Metadata
+%% title: Graph Theory
+%% date: 2024-01-01
+%% tags: math
+
+\documentclass{article}
+\begin{document}
+\section{Introduction}
+\label{sec:intro}
+\subsection{Definitions}
+A graph \label{def:graph} is a pair.
+% \section{Commented}
+\section*{Trees}
+\end{document}`
+
+	symbols := documentSymbols(content)
+
+	if len(symbols) != 3 {
+		t.Fatalf("expected 3 top-level symbols (metadata, 2 sections), got %d", len(symbols))
+	}
+
+	meta := symbols[0]
+	if meta.Name != "Metadata" || len(meta.Children) != 3 {
+		t.Errorf("expected metadata symbol with 3 fields, got %s with %d children", meta.Name, len(meta.Children))
+	}
+	if meta.Range.End.Line != 3 {
+		t.Errorf("expected metadata block to end on line 3, got %d", meta.Range.End.Line)
+	}
+
+	intro := symbols[1]
+	if intro.Name != "Introduction" || intro.Kind != protocol.SymbolKindModule {
+		t.Errorf("expected Introduction section, got %s (%v)", intro.Name, intro.Kind)
+	}
+	if len(intro.Children) != 2 {
+		t.Fatalf("expected label and subsection under Introduction, got %d children", len(intro.Children))
+	}
+	if intro.Children[0].Name != "sec:intro" || intro.Children[0].Kind != protocol.SymbolKindKey {
+		t.Errorf("expected sec:intro label, got %s", intro.Children[0].Name)
+	}
+	if intro.Range.End.Line != 11 {
+		t.Errorf("expected Introduction to end before Trees (line 11), got %d", intro.Range.End.Line)
+	}
+
+	defs := intro.Children[1]
+	if defs.Name != "Definitions" || len(defs.Children) != 1 || defs.Children[0].Name != "def:graph" {
+		t.Errorf("expected Definitions subsection with def:graph label, got %+v", defs)
+	}
+
+	if symbols[2].Name != "Trees" {
+		t.Errorf("expected starred section Trees, got %s", symbols[2].Name)
+	}
+}
+
+// TestNoteSymbol tests the root symbol shown in breadcrumbs
+func TestNoteSymbol(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: "/vault/notes"}, index: NewIndex()}
+
+	content := "%% Metadata\n%% title: Graph Theory\n%% date: 2024-01-01\n%% tags: math, graphs\n\n\\section{Intro}"
+	symbol := ls.noteSymbol(pathToURI("/vault/notes/20240101-graph-theory.tex"), content)
+	if symbol.Name != "Graph Theory — 2024-01-01 — math, graphs" {
+		t.Errorf("unexpected name %q", symbol.Name)
+	}
+	if symbol.Detail != "graph-theory" || symbol.Kind != protocol.SymbolKindFile {
+		t.Errorf("unexpected detail %q or kind %v", symbol.Detail, symbol.Kind)
+	}
+	if symbol.SelectionRange != lineRange(1, 10, 22) {
+		t.Errorf("expected title value to be selected, got %+v", symbol.SelectionRange)
+	}
+	if symbol.Range.End.Line != 5 || len(symbol.Children) != 2 {
+		t.Errorf("expected root to span the note with the outline as children, got %+v", symbol)
+	}
+
+	// Without metadata the filename provides slug and date
+	symbol = ls.noteSymbol(pathToURI("/vault/notes/20240315-scratch.tex"), "Just text")
+	if symbol.Name != "scratch — 2024-03-15" {
+		t.Errorf("unexpected fallback name %q", symbol.Name)
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// MethodNotesByTag is the custom request listing the notes carrying a tag
+const MethodNotesByTag = "lx/notesByTag"
+
+// TagIndex maps tags to the notes carrying them, kept in step with the note index
+// Tags are matched case-insensitively, like the metadata parser deduplicates them
+type TagIndex struct {
+	mu    sync.RWMutex
+	notes map[string]map[string]bool // lowercased tag -> slugs
+	tags  map[string][]string        // slug -> lowercased tags
+}
+
+func NewTagIndex() *TagIndex {
+	return &TagIndex{
+		notes: make(map[string]map[string]bool),
+		tags:  make(map[string][]string),
+	}
+}
+
+// tagKey is the form tags are indexed under
+func tagKey(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// Set replaces the tags of a note
+func (t *TagIndex) Set(slug string, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(slug)
+	var keys []string
+	for _, tag := range tags {
+		key := tagKey(tag)
+		if key == "" {
+			continue
+		}
+		if t.notes[key] == nil {
+			t.notes[key] = make(map[string]bool)
+		}
+		t.notes[key][slug] = true
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 {
+		t.tags[slug] = keys
+	}
+}
+
+// Delete removes a note from the tag index
+func (t *TagIndex) Delete(slug string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(slug)
+}
+
+// remove drops slug from the tags it carried
+// Callers hold t.mu
+func (t *TagIndex) remove(slug string) {
+	for _, key := range t.tags[slug] {
+		delete(t.notes[key], slug)
+		if len(t.notes[key]) == 0 {
+			delete(t.notes, key)
+		}
+	}
+	delete(t.tags, slug)
+}
+
+// Notes returns the slugs of the notes carrying tag, sorted
+func (t *TagIndex) Notes(tag string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	slugs := make([]string, 0, len(t.notes[tagKey(tag)]))
+	for slug := range t.notes[tagKey(tag)] {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+// Counts returns every tag in the vault with the number of notes carrying it
+func (t *TagIndex) Counts() map[string]int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	counts := make(map[string]int, len(t.notes))
+	for key, slugs := range t.notes {
+		counts[key] = len(slugs)
+	}
+	return counts
+}
+
+// NotesByTagParams names the tag to look up
+type NotesByTagParams struct {
+	Tag string `json:"tag"`
+}
+
+// TaggedNote is a note in the lx/notesByTag result
+type TaggedNote struct {
+	Slug  string               `json:"slug"`
+	Title string               `json:"title"`
+	URI   protocol.DocumentURI `json:"uri"`
+}
+
+// Handle lx/notesByTag request
+func (s *LanguageServer) NotesByTag(ctx context.Context, params *NotesByTagParams) ([]TaggedNote, error) {
+	if tagKey(params.Tag) == "" {
+		return nil, fmt.Errorf("%s requires a tag", MethodNotesByTag)
+	}
+	notes := []TaggedNote{}
+	for _, slug := range s.index.Tags().Notes(params.Tag) {
+		note, exists := s.index.Get(slug)
+		if !exists {
+			continue
+		}
+		notes = append(notes, TaggedNote{Slug: slug, Title: note.Title, URI: pathToURI(s.notePath(note.Filename))})
+	}
+	return notes, nil
+}
+
+// tagAtPosition returns the metadata tag under the cursor
+func (s *LanguageServer) tagAtPosition(content string, pos protocol.Position) (metadataTag, bool) {
+	for _, tag := range s.metadataTags(content) {
+		if tag.Range.Start.Line == pos.Line && tag.Range.Start.Character <= pos.Character && pos.Character <= tag.Range.End.Character {
+			return tag, true
+		}
+	}
+	return metadataTag{}, false
+}
+
+// tagHover lists the notes sharing the tag under the cursor
+func (s *LanguageServer) tagHover(tag string) *protocol.Hover {
+	slugs := s.index.Tags().Notes(tag)
+	text := fmt.Sprintf("Tag `%s`: %s", tag, noteCount(len(slugs)))
+	for _, slug := range slugs {
+		if note, exists := s.index.Get(slug); exists {
+			text += fmt.Sprintf("\n- %s (`%s`)", note.Title, slug)
+		}
+	}
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: text,
+		},
+	}
+}
+
+// noteCount formats a number of notes, e.g. "1 note" or "3 notes"
+func noteCount(n int) string {
+	if n == 1 {
+		return "1 note"
+	}
+	return fmt.Sprintf("%d notes", n)
+}
+
+// tagCompletions offers the vault's tags on a tags line of the metadata block
+// Tags already on the line are left out; the best matches of what is typed, then the most used tags,
+// sort first. While a tag the vault does not have yet is typed, the existing tags it nearly
+// duplicates, such as math for maths, come first, so tags are reused rather than multiplied
+func (s *LanguageServer) tagCompletions(content string, line int, linePrefix string) []protocol.CompletionItem {
+	match := metadataFieldPattern.FindStringSubmatchIndex(linePrefix)
+	if match == nil || !strings.EqualFold(linePrefix[match[2]:match[3]], "tags") {
+		return nil
+	}
+	blockStart, blockEnd, found := s.metadataParser().FindBlock(content)
+	if !found || line < blockStart || line > blockEnd {
+		return nil
+	}
+
+	written := strings.Split(linePrefix[match[4]:], ",")
+	fragment := strings.TrimLeft(written[len(written)-1], " \t")
+	prefix := tagKey(fragment)
+	typed := lineRange(line, len(linePrefix)-len(fragment), len(linePrefix))
+	present := make(map[string]bool)
+	for _, tag := range written[:len(written)-1] {
+		present[tagKey(tag)] = true
+	}
+
+	counts := s.index.Tags().Counts()
+	var tags []string
+	for tag := range counts {
+		if !present[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	var items, similar []protocol.CompletionItem
+	for i, tag := range tags {
+		item := protocol.CompletionItem{
+			Label:    tag,
+			Kind:     protocol.CompletionItemKindEnumMember,
+			Detail:   noteCount(counts[tag]),
+			SortText: fmt.Sprintf("%05d", i),
+			TextEdit: &protocol.TextEdit{Range: typed, NewText: tag},
+		}
+		if _, matches := fuzzyMatch(prefix, tag); !matches && counts[prefix] == 0 && similarTags(prefix, tag) {
+			// Kept by clients filtering on what is typed, which the tag itself does not match
+			item.Detail = fmt.Sprintf("%s, instead of '%s'", noteCount(counts[tag]), fragment)
+			item.FilterText = fragment
+			item.SortText = "000-" + item.SortText
+			similar = append(similar, item)
+			continue
+		}
+		items = append(items, item)
+	}
+	return append(similar, filterCompletions(items, prefix)...)
+}
+
+// similarTags reports whether two different tags are likely meant to be the same: one is the plural
+// of the other, they differ only in separators, or by a single typo once they are long enough
+func similarTags(a, b string) bool {
+	if a == b || len(a) < 3 || len(b) < 3 {
+		return false
+	}
+	for _, suffix := range []string{"s", "es"} {
+		if a+suffix == b || b+suffix == a {
+			return true
+		}
+	}
+	unseparated := strings.NewReplacer("-", "", "_", "", " ", "")
+	if unseparated.Replace(a) == unseparated.Replace(b) {
+		return true
+	}
+	return len(a) >= 5 && len(b) >= 5 && withinOneEdit(a, b)
+}
+
+// withinOneEdit reports whether a single insertion, deletion, substitution or swap of adjacent
+// characters turns a into b
+func withinOneEdit(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		if i < len(a)-1 && a[i] == b[i+1] && a[i+1] == b[i] && a[i+2:] == b[i+2:] {
+			return true
+		}
+		return i == len(a) || a[i+1:] == b[i+1:]
+	}
+	return a[i:] == b[i+1:]
+}
+
+// tagRenameEdit rewrites a tag on the tags lines of every note carrying it
+func (s *LanguageServer) tagRenameEdit(tag, newTag string) (*protocol.WorkspaceEdit, error) {
+	newTag = strings.TrimSpace(newTag)
+	if newTag == "" || strings.ContainsAny(newTag, ",\n") {
+		return nil, fmt.Errorf("invalid tag name: %q", newTag)
+	}
+
+	changes := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	for _, slug := range s.index.Tags().Notes(tag) {
+		note, exists := s.index.Get(slug)
+		if !exists {
+			continue
+		}
+		uri := pathToURI(s.notePath(note.Filename))
+		content, err := s.GetDocument(uri)
+		if err != nil {
+			continue
+		}
+		for _, written := range s.metadataTags(content) {
+			if tagKey(written.Name) == tagKey(tag) {
+				changes[uri] = append(changes[uri], protocol.TextEdit{Range: written.Range, NewText: newTag})
+			}
+		}
+	}
+
+	return &protocol.WorkspaceEdit{Changes: changes}, nil
+}
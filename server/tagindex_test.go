@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestTagIndex tests that the tag index follows note headers, case-insensitively
+func TestTagIndex(t *testing.T) {
+	index := NewIndex()
+	index.Set("graphs", &NoteHeader{Slug: "graphs", Filename: "20240101-graphs.tex", Tags: []string{"Math", "graphs"}})
+	index.Set("trees", &NoteHeader{Slug: "trees", Filename: "20240102-trees.tex", Tags: []string{"math"}})
+
+	if notes := index.Tags().Notes("MATH"); len(notes) != 2 || notes[0] != "graphs" || notes[1] != "trees" {
+		t.Errorf("expected both notes under math, got %v", notes)
+	}
+
+	// Retagging and deleting notes update the tags they carried
+	index.Set("graphs", &NoteHeader{Slug: "graphs", Filename: "20240101-graphs.tex", Tags: []string{"combinatorics"}})
+	index.Delete("trees")
+	counts := index.Tags().Counts()
+	if len(counts) != 1 || counts["combinatorics"] != 1 {
+		t.Errorf("unexpected tag counts %v", counts)
+	}
+}
+
+// TestTagFeatures tests tag completion, hover, rename and lx/notesByTag
+func TestTagFeatures(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n% tags: math, graphs\n",
+		"20240102-trees.tex":  "%% Metadata\n% title: Trees\n% tags: Math, trees, graphs\n",
+		"20240103-sets.tex":   "%% Metadata\n% title: Sets\n% tags: math\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	config := DefaultConfig()
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), config: &config, documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	notes, err := ls.NotesByTag(context.Background(), &NotesByTagParams{Tag: "graphs"})
+	if err != nil || len(notes) != 2 || notes[0].Slug != "graphs" || notes[1].Title != "Trees" {
+		t.Fatalf("unexpected notes by tag %+v (%v)", notes, err)
+	}
+
+	uri := pathToURI(filepath.Join(tempDir, "20240103-sets.tex"))
+	content := "%% Metadata\n% title: Sets\n% tags: math, "
+	ls.documents[uri] = content
+	list, _ := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 2, Character: uint32(len("% tags: math, "))},
+		},
+	})
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, ",") != "graphs,trees" {
+		t.Errorf("expected the other tags by usage, got %v", labels)
+	}
+
+	hover, _ := ls.Hover(context.Background(), &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 2, Character: 9},
+		},
+	})
+	if hover == nil || !strings.HasPrefix(hover.Contents.Value, "Tag `math`: 3 notes") {
+		t.Errorf("unexpected tag hover %+v", hover)
+	}
+
+	edit, err := ls.Rename(context.Background(), &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 2, Character: 9},
+		},
+		NewName: "mathematics",
+	})
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if len(edit.Changes) != 3 {
+		t.Fatalf("expected edits in 3 notes, got %+v", edit.Changes)
+	}
+	trees := edit.Changes[pathToURI(filepath.Join(tempDir, "20240102-trees.tex"))]
+	if len(trees) != 1 || trees[0].NewText != "mathematics" || trees[0].Range != lineRange(2, 8, 12) {
+		t.Errorf("unexpected edit of trees %+v", trees)
+	}
+
+	// A new tag close to an existing one offers the existing tag first, replacing what is typed
+	ls.documents[uri] = "%% Metadata\n% title: Sets\n% tags: maths"
+	list, _ = ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 2, Character: uint32(len("% tags: maths"))},
+		},
+	})
+	if len(list.Items) != 1 || list.Items[0].Label != "math" || list.Items[0].TextEdit == nil ||
+		list.Items[0].TextEdit.Range != lineRange(2, 8, 13) || list.Items[0].FilterText != "maths" {
+		t.Errorf("expected math to replace maths, got %+v", list.Items)
+	}
+	for _, pair := range [][2]string{{"math", "maths"}, {"machine-learning", "machinelearning"}, {"algebra", "algerba"}, {"graphs", "graph"}} {
+		if !similarTags(pair[0], pair[1]) {
+			t.Errorf("expected %q and %q to be similar", pair[0], pair[1])
+		}
+	}
+	for _, pair := range [][2]string{{"math", "myth"}, {"graphs", "graphs"}, {"set", "sat"}} {
+		if similarTags(pair[0], pair[1]) {
+			t.Errorf("expected %q and %q to differ", pair[0], pair[1])
+		}
+	}
+}
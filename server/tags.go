@@ -0,0 +1,197 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+)
+
+// diagnosticCodeTagPolicy marks metadata tags that break the configured tag policy
+const diagnosticCodeTagPolicy = "LX006"
+
+// TagPolicy keeps the tag namespace consistent across the vault
+type TagPolicy struct {
+	Lowercase    bool   `json:"lowercase"`
+	KebabCase    bool   `json:"kebabCase"`
+	MaxLength    int    `json:"maxLength,omitempty"`    // 0 disables the limit
+	AllowedChars string `json:"allowedChars,omitempty"` // regexp character class body, e.g. "a-z0-9-"
+}
+
+// allowedPattern compiles AllowedChars, returning nil when unset or invalid
+func (p TagPolicy) allowedPattern() *regexp.Regexp {
+	if p.AllowedChars == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(`^[` + p.AllowedChars + `]$`)
+	if err != nil {
+		return nil
+	}
+	return pattern
+}
+
+// violations lists how tag breaks the policy
+func (p TagPolicy) violations(tag string) []string {
+	var problems []string
+	if p.Lowercase && strings.ToLower(tag) != tag {
+		problems = append(problems, "must be lowercase")
+	}
+	if p.KebabCase && kebabCase(tag) != strings.ToLower(tag) {
+		problems = append(problems, "must be kebab-case")
+	}
+	if p.MaxLength > 0 && len([]rune(tag)) > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+	if allowed := p.allowedPattern(); allowed != nil {
+		for _, r := range tag {
+			if !allowed.MatchString(string(r)) {
+				problems = append(problems, fmt.Sprintf("must only use [%s]", p.AllowedChars))
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// normalize rewrites tag to satisfy the policy
+func (p TagPolicy) normalize(tag string) string {
+	// Kebab-casing first keeps the word breaks of camelCase tags
+	if p.KebabCase {
+		tag = kebabCase(tag)
+	}
+	if p.Lowercase {
+		tag = strings.ToLower(tag)
+	}
+	if allowed := p.allowedPattern(); allowed != nil {
+		var builder strings.Builder
+		for _, r := range tag {
+			if allowed.MatchString(string(r)) {
+				builder.WriteRune(r)
+			}
+		}
+		tag = builder.String()
+	}
+	if runes := []rune(tag); p.MaxLength > 0 && len(runes) > p.MaxLength {
+		tag = strings.TrimRight(string(runes[:p.MaxLength]), "-")
+	}
+	return tag
+}
+
+// kebabCase lowercases words and joins them with single dashes
+// Word breaks are separators and lower-to-upper case changes, so "GraphTheory" becomes "graph-theory"
+func kebabCase(tag string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(tag)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	return strings.Join(words, "-")
+}
+
+func init() {
+	registerQuickFix(diagnosticCodeTagPolicy, fixTagPolicy)
+}
+
+// tagPolicyDiagnostics reports metadata tags that break the policy
+func (s *LanguageServer) tagPolicyDiagnostics(content string) []protocol.Diagnostic {
+	policy := s.settings().TagPolicy
+
+	diagnostics := []protocol.Diagnostic{}
+	for _, tag := range s.metadataTags(content) {
+		problems := policy.violations(tag.Name)
+		if len(problems) == 0 {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    tag.Range,
+			Severity: protocol.DiagnosticSeverityWarning,
+			Code:     diagnosticCodeTagPolicy,
+			Message:  fmt.Sprintf("Tag '%s' %s", tag.Name, strings.Join(problems, ", ")),
+			Source:   "lx-ls",
+		})
+	}
+	return diagnostics
+}
+
+// metadataTag is a tag as written on a metadata tags line
+type metadataTag struct {
+	Name  string
+	Range protocol.Range
+}
+
+// metadataTags locates every tag on the tags lines of the metadata block
+func (s *LanguageServer) metadataTags(content string) []metadataTag {
+	start, end, found := s.metadataParser().FindBlock(content)
+	if !found {
+		return nil
+	}
+
+	var tags []metadataTag
+	lines := s.documentLines(content)
+	for lineNum := start; lineNum <= end && lineNum < len(lines); lineNum++ {
+		line := lines[lineNum]
+		match := metadataFieldPattern.FindStringSubmatchIndex(line)
+		if match == nil || !strings.EqualFold(line[match[2]:match[3]], "tags") {
+			continue
+		}
+
+		offset := match[4]
+		for _, part := range strings.Split(line[match[4]:match[5]], ",") {
+			name := strings.TrimSpace(part)
+			if name != "" {
+				column := offset + strings.Index(part, name)
+				tags = append(tags, metadataTag{Name: name, Range: lineRange(lineNum, column, column+len(name))})
+			}
+			offset += len(part) + 1
+		}
+	}
+	return tags
+}
+
+// fixTagPolicy rewrites a tag to its normalized form
+func fixTagPolicy(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	for _, tag := range s.metadataTags(req.Content) {
+		if tag.Range != diag.Range {
+			continue
+		}
+
+		normalized := s.settings().TagPolicy.normalize(tag.Name)
+		if normalized == "" || normalized == tag.Name {
+			return nil
+		}
+		return []protocol.CodeAction{
+			{
+				Title:       fmt.Sprintf("Change tag to '%s'", normalized),
+				Kind:        protocol.QuickFix,
+				Diagnostics: []protocol.Diagnostic{diag},
+				IsPreferred: true,
+				Edit: &protocol.WorkspaceEdit{
+					Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+						req.URI: {{Range: tag.Range, NewText: normalized}},
+					},
+				},
+			},
+		}
+	}
+	return nil
+}
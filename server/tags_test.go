@@ -0,0 +1,66 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestTagPolicy tests tag validation and normalization
+func TestTagPolicy(t *testing.T) {
+	policy := DefaultConfig().TagPolicy
+	policy.AllowedChars = "a-z0-9-"
+
+	tests := map[string]string{
+		"math":                                  "math",
+		"Graph Theory":                          "graph-theory",
+		"GraphTheory":                           "graph-theory",
+		"linear_algebra":                        "linear-algebra",
+		"--odd--":                               "odd",
+		"café":                                  "caf",
+		"a-very-long-tag-name-that-keeps-going": "a-very-long-tag-name-that-keeps",
+	}
+	for tag, expected := range tests {
+		if got := policy.normalize(tag); got != expected {
+			t.Errorf("normalize(%q) = %q, want %q", tag, got, expected)
+		}
+		if problems := policy.violations(expected); len(problems) != 0 {
+			t.Errorf("normalized tag %q still violates policy: %v", expected, problems)
+		}
+	}
+
+	if problems := policy.violations("Graph_Theory"); len(problems) != 3 {
+		t.Errorf("expected lowercase, kebab-case and charset violations, got %v", problems)
+	}
+
+	// Invalid character classes are ignored rather than rejecting every tag
+	policy.AllowedChars = "z-a"
+	if problems := policy.violations("math"); len(problems) != 0 {
+		t.Errorf("expected invalid charset to be ignored, got %v", problems)
+	}
+}
+
+// TestTagPolicyDiagnostics tests diagnostics and fixes on metadata tag lines
+func TestTagPolicyDiagnostics(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex()}
+
+	content := "%% Metadata\n%% title: Graphs\n%% tags: math,  Graph Theory, ok\n\nBody with tags: Not Checked"
+	diagnostics := ls.tagPolicyDiagnostics(content)
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+	}
+	if diagnostics[0].Range != lineRange(2, 16, 28) {
+		t.Errorf("unexpected range %+v", diagnostics[0].Range)
+	}
+
+	req := &codeActionRequest{URI: "file:///note.tex", Content: content}
+	actions := fixTagPolicy(ls, req, diagnostics[0])
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 fix, got %d", len(actions))
+	}
+	edit := actions[0].Edit.Changes[protocol.DocumentURI("file:///note.tex")][0]
+	if edit.NewText != "graph-theory" {
+		t.Errorf("expected 'graph-theory', got %q", edit.NewText)
+	}
+}
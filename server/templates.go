@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TemplateIndex caches the templates of the vault, their names and the contents read so far, so
+// \usepackage completion and the checks reading templates do not go to the disk on every request
+// Entries are checked against the modification times of the directory and the template files, so
+// the cache holds without a watcher and for a directory created later; the watcher drops the cache
+// too, for changes within the timestamp granularity
+type TemplateIndex struct {
+	mu         sync.RWMutex
+	root       string
+	loaded     bool
+	listed     time.Time // modification time of root when it was listed, zero when it did not exist
+	generation int       // bumped by Invalidate, so reads that started before it are not cached
+	names      []string  // sorted template names, without .sty
+	contents   map[string]templateFile
+}
+
+// templateFile is the cached content of a template and the file it was read from
+type templateFile struct {
+	content  string
+	modified time.Time
+	size     int64
+}
+
+func NewTemplateIndex() *TemplateIndex {
+	return &TemplateIndex{contents: make(map[string]templateFile)}
+}
+
+// load returns the templates of root, listing the directory unless it is unchanged since the last listing
+func (t *TemplateIndex) load(root string) []string {
+	var listed time.Time
+	if info, err := os.Stat(root); err == nil {
+		listed = info.ModTime()
+	}
+	t.mu.RLock()
+	names, cached := t.names, t.loaded && t.root == root && t.listed.Equal(listed)
+	generation := t.generation
+	t.mu.RUnlock()
+	if cached {
+		return names
+	}
+
+	names = nil
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sty") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".sty"))
+	}
+	sort.Strings(names)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.root != root {
+		t.contents = make(map[string]templateFile)
+		t.root = root
+	} else if t.generation != generation {
+		return names // Invalidated while listing, the listing may predate the change
+	}
+	t.names = names
+	t.listed = listed
+	t.loaded = true
+	return names
+}
+
+// Names returns the templates of the directory root
+func (t *TemplateIndex) Names(root string) []string {
+	return append([]string(nil), t.load(root)...)
+}
+
+// Content returns the content of a template of root, false when there is no such template
+func (t *TemplateIndex) Content(root, name string) (string, bool) {
+	names := t.load(root)
+	i := sort.SearchStrings(names, name)
+	if i == len(names) || names[i] != name {
+		return "", false
+	}
+	path := filepath.Join(root, name+".sty")
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+
+	t.mu.RLock()
+	file, cached := t.contents[name]
+	generation := t.generation
+	t.mu.RUnlock()
+	if cached && file.modified.Equal(info.ModTime()) && file.size == info.Size() {
+		return file.content, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.root == root && t.generation == generation {
+		t.contents[name] = templateFile{content: string(data), modified: info.ModTime(), size: info.Size()}
+	}
+	return string(data), true
+}
+
+// Invalidate drops the cache, so the templates are read again when next needed
+func (t *TemplateIndex) Invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.generation++
+	t.loaded = false
+	t.names = nil
+	t.contents = make(map[string]templateFile)
+}
+
+// listTemplates returns all available template names
+func (s *LanguageServer) listTemplates() []string {
+	return s.index.Templates().Names(s.vault.TemplatesPath)
+}
+
+// templateContent returns the content of a vault template, false when the vault has no such template
+func (s *LanguageServer) templateContent(name string) (string, bool) {
+	return s.index.Templates().Content(s.vault.TemplatesPath, name)
+}
+
+// templateEvent drops the template cache when a template is added, changed or removed
+// Open notes are checked again, since their required structure and theorems come from templates
+func (s *LanguageServer) templateEvent(ctx context.Context, event fsnotify.Event) {
+	if filepath.Dir(event.Name) != filepath.Clean(s.vault.TemplatesPath) || !strings.HasSuffix(event.Name, ".sty") || event.Op == fsnotify.Chmod {
+		return
+	}
+	s.index.Templates().Invalidate()
+	s.republishOpenDocuments(ctx)
+}
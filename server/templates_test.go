@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestTemplateCache tests serving templates from the cache until the watcher reports a change
+func TestTemplateCache(t *testing.T) {
+	tempDir := t.TempDir()
+	templatesPath := filepath.Join(tempDir, "templates")
+	os.MkdirAll(templatesPath, 0755)
+	os.WriteFile(filepath.Join(templatesPath, "lecture.sty"), []byte("% lx-requires: \\lecture{}\n"), 0644)
+	os.WriteFile(filepath.Join(templatesPath, "notes.txt"), []byte("not a template"), 0644)
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: tempDir, TemplatesPath: templatesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+
+	if names := strings.Join(ls.listTemplates(), ","); names != "lecture" {
+		t.Errorf("expected the lecture template, got %s", names)
+	}
+	if content, ok := ls.templateContent("lecture"); !ok || !strings.Contains(content, "lx-requires") {
+		t.Errorf("expected the content of lecture, got %q", content)
+	}
+	if _, ok := ls.templateContent("amsmath"); ok {
+		t.Error("expected packages that are not vault templates to be missing")
+	}
+
+	// Changes on disk are seen without the watcher, as when watching is off
+	os.WriteFile(filepath.Join(templatesPath, "lecture.sty"), []byte("% changed\n"), 0644)
+	os.WriteFile(filepath.Join(templatesPath, "paper.sty"), []byte(""), 0644)
+	if names := strings.Join(ls.listTemplates(), ","); names != "lecture,paper" {
+		t.Errorf("expected the new template, got %s", names)
+	}
+	if content, _ := ls.templateContent("lecture"); content != "% changed\n" {
+		t.Errorf("expected the changed content, got %q", content)
+	}
+
+	ls.templateEvent(context.Background(), fsnotify.Event{Name: filepath.Join(templatesPath, "paper.sty"), Op: fsnotify.Remove})
+	os.Remove(filepath.Join(templatesPath, "paper.sty"))
+	if names := strings.Join(ls.listTemplates(), ","); names != "lecture" {
+		t.Errorf("expected the removed template to be gone, got %s", names)
+	}
+}
+
+// TestTemplateCacheLateDirectory tests that a templates directory created after the first lookup is found
+func TestTemplateCacheLateDirectory(t *testing.T) {
+	templatesPath := filepath.Join(t.TempDir(), "templates")
+	templates := NewTemplateIndex()
+	if names := templates.Names(templatesPath); len(names) != 0 {
+		t.Fatalf("expected no templates, got %v", names)
+	}
+
+	os.MkdirAll(templatesPath, 0755)
+	os.WriteFile(filepath.Join(templatesPath, "lecture.sty"), []byte("% lecture\n"), 0644)
+	if names := templates.Names(templatesPath); len(names) != 1 || names[0] != "lecture" {
+		t.Errorf("expected the lecture template, got %v", names)
+	}
+	if content, ok := templates.Content(templatesPath, "lecture"); !ok || content != "% lecture\n" {
+		t.Errorf("expected the content of lecture, got %q", content)
+	}
+}
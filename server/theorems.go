@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+var (
+	// usepackagePattern matches \usepackage[options]{a,b}
+	usepackagePattern = regexp.MustCompile(`\\usepackage(?:\[[^\]]*\])?\{([^}]+)\}`)
+
+	// newtheoremPattern matches \newtheorem{env}[counter]{Name} and \newtheorem*{env}{Name}
+	newtheoremPattern = regexp.MustCompile(`\\newtheorem\*?\{([^}]+)\}(?:\[[^\]]*\])?\{([^}]+)\}`)
+)
+
+// theoremLabelPrefixes are the conventional label prefixes for common theorem-like environments
+var theoremLabelPrefixes = map[string]string{
+	"theorem":     "thm",
+	"lemma":       "lem",
+	"corollary":   "cor",
+	"proposition": "prop",
+	"definition":  "def",
+	"example":     "ex",
+	"remark":      "rem",
+	"conjecture":  "conj",
+}
+
+// TheoremEnvironment is a theorem-like environment declared by a template
+type TheoremEnvironment struct {
+	Name     string // environment name, e.g. "lemma"
+	Title    string // printed name, e.g. "Lemma"
+	Template string // template that declares it
+}
+
+// getTheoremCompletions returns environment snippets for the templates the note uses
+func (s *LanguageServer) getTheoremCompletions(content string) []protocol.CompletionItem {
+	environments := s.noteTheorems(content)
+	if len(environments) == 0 {
+		return nil
+	}
+
+	items := make([]protocol.CompletionItem, 0, len(environments)+1)
+	for _, env := range environments {
+		prefix, ok := theoremLabelPrefixes[env.Name]
+		if !ok {
+			prefix = env.Name
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:            env.Name,
+			Kind:             protocol.CompletionItemKindSnippet,
+			Detail:           fmt.Sprintf("%s environment (%s)", env.Title, env.Template),
+			InsertTextFormat: protocol.InsertTextFormatSnippet,
+			InsertText: fmt.Sprintf("\\begin{%s}[${1:title}]\n\t\\label{%s:${2:label}}\n\t$0\n\\end{%s}",
+				env.Name, prefix, env.Name),
+		})
+	}
+
+	// Theorem-like environments come with amsthm, which provides proof
+	items = append(items, protocol.CompletionItem{
+		Label:            "proof",
+		Kind:             protocol.CompletionItemKindSnippet,
+		Detail:           "Proof skeleton",
+		InsertTextFormat: protocol.InsertTextFormatSnippet,
+		InsertText:       "\\begin{proof}\n\t$0\n\\end{proof}",
+	})
+
+	return items
+}
+
+// noteTheorems collects theorem environments from every vault template the note loads
+func (s *LanguageServer) noteTheorems(content string) []TheoremEnvironment {
+	var environments []TheoremEnvironment
+	seen := make(map[string]bool)
+
+	for _, template := range usedPackages(content) {
+		data, ok := s.templateContent(template)
+		if !ok {
+			continue // Not a vault template
+		}
+		for _, env := range extractTheorems(template, data) {
+			if !seen[env.Name] {
+				seen[env.Name] = true
+				environments = append(environments, env)
+			}
+		}
+	}
+
+	return environments
+}
+
+// usedPackages returns the package names a note loads with \usepackage
+func usedPackages(content string) []string {
+	var packages []string
+	for _, line := range strings.Split(content, "\n") {
+		line = stripInlineComment(line)
+		for _, match := range usepackagePattern.FindAllStringSubmatch(line, -1) {
+			for _, name := range strings.Split(match[1], ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					packages = append(packages, name)
+				}
+			}
+		}
+	}
+	return packages
+}
+
+// extractTheorems parses the \newtheorem declarations of a template
+func extractTheorems(template, content string) []TheoremEnvironment {
+	var environments []TheoremEnvironment
+	for _, line := range strings.Split(content, "\n") {
+		line = stripInlineComment(line)
+		for _, match := range newtheoremPattern.FindAllStringSubmatch(line, -1) {
+			environments = append(environments, TheoremEnvironment{
+				Name:     strings.TrimSpace(match[1]),
+				Title:    strings.TrimSpace(match[2]),
+				Template: template,
+			})
+		}
+	}
+	return environments
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestTheoremCompletions tests snippets discovered from template \newtheorem declarations
+func TestTheoremCompletions(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	templatesPath := filepath.Join(tempDir, "templates")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(templatesPath, 0755)
+
+	os.WriteFile(filepath.Join(templatesPath, "math.sty"), []byte(`\RequirePackage{amsthm}
+\newtheorem{theorem}{Theorem}[section]
+\newtheorem{lemma}[theorem]{Lemma}
+% \newtheorem{hidden}{Hidden}
+\newtheorem*{claim}{Claim}
+`), 0644)
+
+	testFile := filepath.Join(notesPath, "test.tex")
+	content := "\\usepackage[utf8]{inputenc}\n\\usepackage{amsmath, math}\n\n"
+	os.WriteFile(testFile, []byte(content), 0644)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath, TemplatesPath: templatesPath},
+		index: NewIndex(),
+	}
+
+	result, err := ls.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: pathToURI(testFile)},
+			Position:     protocol.Position{Line: 2, Character: 0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+
+	snippets := make(map[string]protocol.CompletionItem)
+	for _, item := range result.Items {
+		snippets[item.Label] = item
+	}
+
+	for _, name := range []string{"theorem", "lemma", "claim", "proof"} {
+		if _, ok := snippets[name]; !ok {
+			t.Errorf("expected %s snippet", name)
+		}
+	}
+	if _, ok := snippets["hidden"]; ok {
+		t.Error("commented-out declaration should be ignored")
+	}
+	if !strings.Contains(snippets["lemma"].InsertText, `\label{lem:`) {
+		t.Errorf("expected lem: label prefix, got %q", snippets["lemma"].InsertText)
+	}
+
+	// Notes that do not load the template get no theorem snippets
+	if items := ls.getTheoremCompletions(`\usepackage{amsmath}`); len(items) != 0 {
+		t.Errorf("expected no theorem snippets, got %d", len(items))
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandListTodos lists the open TODOs of the vault, or of a single note
+// Arguments: none, or [uri] to list the TODOs of that note
+const commandListTodos = "lx.listTodos"
+
+func init() {
+	registerCommand(commandListTodos, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		var uri protocol.DocumentURI
+		if len(args) > 0 {
+			arg, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: invalid uri argument", commandListTodos)
+			}
+			uri = protocol.DocumentURI(arg)
+		}
+		return s.listTodos(uri), nil
+	})
+}
+
+// todoMarkerPattern matches \todo[options]{text}
+var todoMarkerPattern = regexp.MustCompile(`\\todo(?:\[[^\]]*\])?\{([^}]*)\}`)
+
+// Todo is an open \todo marker inside a note
+type Todo struct {
+	Filename string
+	Text     string
+	Range    protocol.Range
+}
+
+// TodoIndex tracks the open TODO markers of every note
+type TodoIndex struct {
+	mu    sync.RWMutex
+	todos map[string][]Todo // slug -> markers
+}
+
+func NewTodoIndex() *TodoIndex {
+	return &TodoIndex{
+		todos: make(map[string][]Todo),
+	}
+}
+
+// Set replaces the TODO markers of a note
+func (t *TodoIndex) Set(slug string, todos []Todo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(todos) == 0 {
+		delete(t.todos, slug)
+		return
+	}
+	t.todos[slug] = todos
+}
+
+// Delete removes a note from the TODO index
+func (t *TodoIndex) Delete(slug string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.todos, slug)
+}
+
+// Get returns a copy of the TODO markers of a note
+func (t *TodoIndex) Get(slug string) []Todo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]Todo(nil), t.todos[slug]...)
+}
+
+// All returns the TODO markers of every note with open TODOs
+func (t *TodoIndex) All() map[string][]Todo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	todos := make(map[string][]Todo, len(t.todos))
+	for slug, markers := range t.todos {
+		todos[slug] = append([]Todo(nil), markers...)
+	}
+	return todos
+}
+
+// extractTodos scans note content for \todo markers, skipping commented-out ones
+func extractTodos(filename, content string) []Todo {
+	var todos []Todo
+
+	lines := strings.Split(content, "\n")
+	for lineNum, line := range lines {
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(line), "%") {
+			continue
+		}
+
+		for _, match := range todoMarkerPattern.FindAllStringSubmatchIndex(line, -1) {
+			todos = append(todos, Todo{
+				Filename: filename,
+				Text:     strings.TrimSpace(line[match[2]:match[3]]),
+				Range:    lineRange(lineNum, match[0], match[1]),
+			})
+		}
+	}
+
+	return todos
+}
+
+// todoStatus summarizes the open TODOs of a note for hovers
+func todoStatus(count int) string {
+	switch count {
+	case 0:
+		return "✓ no TODOs"
+	case 1:
+		return "⚠ 1 open TODO"
+	default:
+		return fmt.Sprintf("⚠ %d open TODOs", count)
+	}
+}
+
+// TodoItem is an open TODO in the lx.listTodos result
+type TodoItem struct {
+	Slug  string               `json:"slug"`
+	Title string               `json:"title"`
+	URI   protocol.DocumentURI `json:"uri"`
+	Range protocol.Range       `json:"range"`
+	Text  string               `json:"text"`
+}
+
+// listTodos returns the open TODOs of the vault, or only those of uri when it is set
+// Sorted by note title, then position
+func (s *LanguageServer) listTodos(uri protocol.DocumentURI) []TodoItem {
+	items := []TodoItem{}
+	for slug, todos := range s.index.Todos().All() {
+		note, exists := s.index.Get(slug)
+		if !exists {
+			continue
+		}
+		noteURI := pathToURI(s.notePath(note.Filename))
+		if uri != "" && noteURI != uri {
+			continue
+		}
+		for _, todo := range todos {
+			items = append(items, TodoItem{Slug: slug, Title: note.Title, URI: noteURI, Range: todo.Range, Text: todo.Text})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		if a.Slug != b.Slug {
+			return a.Slug < b.Slug
+		}
+		return a.Range.Start.Line < b.Range.Start.Line || (a.Range.Start.Line == b.Range.Start.Line && a.Range.Start.Character < b.Range.Start.Character)
+	})
+	return items
+}
+
+// todoHover places the \todo under the cursor among the open TODOs of its note and of the vault,
+// with links running lx.listTodos for clients that allow command links in hovers
+// The note's own TODOs come from content, so unsaved markers count
+func (s *LanguageServer) todoHover(docURI protocol.DocumentURI, content string, pos protocol.Position) *protocol.Hover {
+	filename := filepath.Base(uriToPath(docURI))
+	todos := extractTodos(filename, content)
+
+	current := -1
+	for i, todo := range todos {
+		if todo.Range.Start.Line == pos.Line && todo.Range.Start.Character <= pos.Character && pos.Character <= todo.Range.End.Character {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		return nil
+	}
+
+	slug := s.parseFilenameToSlug(filename)
+	vault := len(todos)
+	for other, markers := range s.index.Todos().All() {
+		if other != slug {
+			vault += len(markers)
+		}
+	}
+
+	text := fmt.Sprintf("**TODO %d of %d in this note**", current+1, len(todos))
+	if todo := todos[current].Text; todo != "" {
+		text += "\n\n" + todo
+	}
+	text += fmt.Sprintf("\n\n%d open across the vault\n\n[Show TODOs in this note](%s) · [Show all TODOs](%s)",
+		vault, commandLink(commandListTodos, string(docURI)), commandLink(commandListTodos))
+
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: text,
+		},
+		Range: &todos[current].Range,
+	}
+}
+
+// commandLink builds a markdown link target running a command with arguments, as in command:name?["arg"]
+func commandLink(command string, args ...interface{}) string {
+	if len(args) == 0 {
+		return "command:" + command
+	}
+	encoded, _ := json.Marshal(args)
+	return "command:" + command + "?" + url.PathEscape(string(encoded))
+}
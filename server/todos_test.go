@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestExtractTodos tests TODO marker extraction
+func TestExtractTodos(t *testing.T) {
+	content := "\\todo{first}\n% \\todo{commented}\nText \\todo[color=red]{ second } here"
+
+	todos := extractTodos("note.tex", content)
+	if len(todos) != 2 {
+		t.Fatalf("expected 2 todos, got %d", len(todos))
+	}
+	if todos[1].Text != "second" || todos[1].Range.Start.Line != 2 || todos[1].Range.Start.Character != 5 {
+		t.Errorf("unexpected second todo %+v", todos[1])
+	}
+
+	index := NewTodoIndex()
+	index.Set("note", todos)
+	if len(index.Get("note")) != 2 {
+		t.Errorf("expected 2 indexed todos")
+	}
+	index.Get("note")[0].Text = "changed"
+	if index.Get("note")[0].Text != "first" || index.All()["note"][0].Text != "first" {
+		t.Errorf("expected callers to get a copy of the indexed todos")
+	}
+	index.Set("note", nil)
+	if len(index.Get("note")) != 0 {
+		t.Errorf("expected todos cleared")
+	}
+}
+
+// TestTodoStatus tests the hover status line
+func TestTodoStatus(t *testing.T) {
+	tests := map[int]string{
+		0: "✓ no TODOs",
+		1: "⚠ 1 open TODO",
+		3: "⚠ 3 open TODOs",
+	}
+	for count, expected := range tests {
+		if got := todoStatus(count); got != expected {
+			t.Errorf("todoStatus(%d) = %q, want %q", count, got, expected)
+		}
+	}
+}
+
+// TestTodoHover tests placing a TODO among the open TODOs of its note and the vault
+func TestTodoHover(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"20240101-graphs.tex": "%% Metadata\n% title: Graphs\n\\todo{define paths}\n",
+		"20240102-trees.tex":  "%% Metadata\n% title: Trees\n\\todo{first}\n\\todo{second}\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+
+	// An unsaved third TODO counts
+	uri := pathToURI(filepath.Join(tempDir, "20240102-trees.tex"))
+	ls.documents[uri] = files["20240102-trees.tex"] + "\\todo{third}\n"
+	hover, err := ls.Hover(context.Background(), &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 3, Character: 3},
+		},
+	})
+	if err != nil || hover == nil {
+		t.Fatalf("expected a hover, got %v", err)
+	}
+	for _, want := range []string{"**TODO 2 of 3 in this note**", "second", "4 open across the vault", "(command:lx.listTodos?%5B%22file:", "(command:lx.listTodos)"} {
+		if !strings.Contains(hover.Contents.Value, want) {
+			t.Errorf("expected hover to contain %q, got %q", want, hover.Contents.Value)
+		}
+	}
+
+	items := ls.listTodos(uri)
+	if len(items) != 2 || items[0].Text != "first" || items[1].Title != "Trees" {
+		t.Errorf("unexpected TODOs of trees %+v", items)
+	}
+	if items := ls.listTodos(""); len(items) != 3 || items[0].Slug != "graphs" {
+		t.Errorf("unexpected vault TODOs %+v", items)
+	}
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandListTrash lists the notes in the vault's trash
+// Arguments: none
+const commandListTrash = "lx.listTrash"
+
+// commandRestoreNote moves a trashed note back into the directory it was deleted from
+// Arguments: [slug]
+const commandRestoreNote = "lx.restoreNote"
+
+// trashDirname is the directory at the vault root deleted notes are moved to
+// It sits outside the notes directory, so trashed notes are never indexed
+const trashDirname = ".trash"
+
+// trashOriginSuffix names the file next to a trashed note holding the directory it was deleted from
+const trashOriginSuffix = ".origin"
+
+// trashCopySeparator separates a trashed note's name from the deletion time added to it when the
+// trash already holds a file of that name, as after deleting notes of the same name in two folders
+const trashCopySeparator = "~"
+
+func init() {
+	registerCommand(commandListTrash, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.listTrash()
+	})
+	registerCommand(commandRestoreNote, withoutResult((*LanguageServer).restoreNoteCommand))
+	registerQuickFix(diagnosticCodeBrokenRef, restoreTrashedNoteFix)
+}
+
+// TrashedNote describes a note in the trash, as returned by lx.listTrash
+type TrashedNote struct {
+	Slug      string    `json:"slug"`
+	Filename  string    `json:"filename"`
+	TrashFile string    `json:"trashFile"` // name of the file in the trash, Filename unless that was taken
+	Title     string    `json:"title"`
+	Deleted   time.Time `json:"deleted"`
+	Expires   time.Time `json:"expires,omitempty"` // zero when the trash is never emptied
+}
+
+// trashPath returns the trash directory of the vault
+func (s *LanguageServer) trashPath() string {
+	return filepath.Join(s.vault.RootPath, trashDirname)
+}
+
+// trashNote moves a note file into the trash, recording the directory it came from
+// The file's modification time is set to the deletion time, which retention counts from
+func (s *LanguageServer) trashNote(path string) error {
+	if err := os.MkdirAll(s.trashPath(), 0755); err != nil {
+		return fmt.Errorf("failed to create trash: %w", err)
+	}
+	now := time.Now()
+	filename := filepath.Base(path)
+	trashed := filepath.Join(s.trashPath(), filename)
+	if _, err := os.Lstat(trashed); err == nil {
+		stem := strings.TrimSuffix(filename, ".tex")
+		trashed = filepath.Join(s.trashPath(), fmt.Sprintf("%s%s%d.tex", stem, trashCopySeparator, now.UnixNano()))
+	}
+	if err := os.Rename(path, trashed); err != nil {
+		return fmt.Errorf("failed to move note to trash: %w", err)
+	}
+	os.Chtimes(trashed, now, now)
+	if err := os.WriteFile(trashed+trashOriginSuffix, []byte(filepath.Dir(path)), 0644); err != nil {
+		s.logf(protocol.MessageTypeWarning, "Failed to record where %s was deleted from: %v", filepath.Base(path), err)
+	}
+
+	s.purgeTrash(now)
+	return nil
+}
+
+// trashedNotes returns the notes in the trash by slug, without reading their content
+// Of several trashed notes with the same slug, the most recently deleted is returned
+func (s *LanguageServer) trashedNotes() map[string]TrashedNote {
+	entries := s.trashEntries()
+	if entries == nil {
+		return nil
+	}
+	notes := make(map[string]TrashedNote, len(entries))
+	for _, note := range entries {
+		if other, ok := notes[note.Slug]; !ok || note.Deleted.After(other.Deleted) {
+			notes[note.Slug] = note
+		}
+	}
+	return notes
+}
+
+// trashEntries returns every note in the trash, without reading their content
+func (s *LanguageServer) trashEntries() []TrashedNote {
+	if s.vault == nil {
+		return nil
+	}
+	entries, err := os.ReadDir(s.trashPath())
+	if err != nil {
+		return nil
+	}
+
+	retention := s.trashRetention()
+	notes := make([]TrashedNote, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tex") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		filename := trashedFilename(entry.Name())
+		note := TrashedNote{
+			Slug:      s.parseFilenameToSlug(filename),
+			Filename:  filename,
+			TrashFile: entry.Name(),
+			Deleted:   info.ModTime(),
+		}
+		if retention > 0 {
+			note.Expires = note.Deleted.Add(retention)
+		}
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// trashedFilename returns the name a file in the trash had before it was deleted
+func trashedFilename(name string) string {
+	stem := strings.TrimSuffix(name, ".tex")
+	i := strings.LastIndex(stem, trashCopySeparator)
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.ParseInt(stem[i+len(trashCopySeparator):], 10, 64); err != nil {
+		return name
+	}
+	return stem[:i] + ".tex"
+}
+
+// trashRetention returns how long trashed notes are kept, zero meaning forever
+func (s *LanguageServer) trashRetention() time.Duration {
+	return time.Duration(s.settings().TrashRetentionDays) * 24 * time.Hour
+}
+
+// purgeTrash deletes trashed notes older than the retention period
+func (s *LanguageServer) purgeTrash(now time.Time) {
+	for _, note := range s.trashEntries() {
+		if note.Expires.IsZero() || note.Expires.After(now) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.trashPath(), note.TrashFile)); err != nil {
+			s.logf(protocol.MessageTypeWarning, "Failed to purge %s from the trash: %v", note.TrashFile, err)
+			continue
+		}
+		os.Remove(filepath.Join(s.trashPath(), note.TrashFile+trashOriginSuffix))
+	}
+}
+
+// listTrash handles lx.listTrash
+// Expired notes are purged first; the rest are returned most recently deleted first
+func (s *LanguageServer) listTrash() ([]TrashedNote, error) {
+	s.purgeTrash(time.Now())
+
+	notes := []TrashedNote{}
+	for _, note := range s.trashedNotes() {
+		note.Title = note.Slug
+		if header, err := s.parseNoteHeader(filepath.Join(s.trashPath(), note.TrashFile)); err == nil {
+			note.Title = header.Title
+		}
+		notes = append(notes, note)
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].Deleted.Equal(notes[j].Deleted) {
+			return notes[i].Deleted.After(notes[j].Deleted)
+		}
+		return notes[i].Slug < notes[j].Slug
+	})
+
+	return notes, nil
+}
+
+// restoreNoteCommand handles lx.restoreNote
+func (s *LanguageServer) restoreNoteCommand(ctx context.Context, args []interface{}) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s requires a slug argument", commandRestoreNote)
+	}
+	slug, ok := args[0].(string)
+	if !ok || slug == "" {
+		return fmt.Errorf("%s: invalid slug argument", commandRestoreNote)
+	}
+
+	note, trashed := s.trashedNotes()[slug]
+	if !trashed {
+		return fmt.Errorf("note '%s' is not in the trash", slug)
+	}
+	if _, exists := s.index.Get(slug); exists {
+		return fmt.Errorf("note '%s' already exists", slug)
+	}
+
+	path := filepath.Join(s.trashOrigin(note.TrashFile), note.Filename)
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("cannot restore note '%s': %s already exists", slug, path)
+	}
+	// References to the note are no longer broken once it is indexed again
+	if err := s.fileOperation(ctx, func() error {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to restore note: %w", err)
+		}
+		if err := os.Rename(filepath.Join(s.trashPath(), note.TrashFile), path); err != nil {
+			return fmt.Errorf("failed to restore note: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(s.trashPath(), note.TrashFile+trashOriginSuffix))
+	s.recordActivity(ctx, "note.restore", note.Filename)
+
+	return nil
+}
+
+// trashOrigin returns the directory a trashed note was deleted from
+// Notes trashed before origins were recorded, or whose recorded origin is not a managed notes
+// directory or one of its subdirectories, go back to the vault's notes directory
+func (s *LanguageServer) trashOrigin(trashFile string) string {
+	data, err := os.ReadFile(filepath.Join(s.trashPath(), trashFile+trashOriginSuffix))
+	if dir := filepath.Clean(strings.TrimSpace(string(data))); err == nil && filepath.IsAbs(dir) {
+		for _, notesDir := range s.notesDirs() {
+			if inTree(dir, notesDir) {
+				return dir
+			}
+		}
+	}
+	return s.vault.NotesPath
+}
+
+// restoreTrashedNoteFix offers to restore the trashed note a broken reference points at
+func restoreTrashedNoteFix(s *LanguageServer, req *codeActionRequest, diag protocol.Diagnostic) []protocol.CodeAction {
+	slug := s.getSlugAtPosition(req.Content, diag.Range.Start)
+	if _, trashed := s.trashedNotes()[slug]; !trashed {
+		return nil
+	}
+
+	return []protocol.CodeAction{
+		{
+			Title:       fmt.Sprintf("Restore note '%s' from the trash", slug),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: true,
+			Command: &protocol.Command{
+				Title:     "Restore note",
+				Command:   commandRestoreNote,
+				Arguments: []interface{}{slug},
+			},
+		},
+	}
+}
+
+// brokenRefMessage describes a reference to a missing note, pointing out notes that are only in the trash
+// trash is loaded on first use, since most documents have no broken references
+func (s *LanguageServer) brokenRefMessage(slug string, trash *map[string]TrashedNote) string {
+	if *trash == nil {
+		*trash = s.trashedNotes()
+		if *trash == nil {
+			*trash = map[string]TrashedNote{}
+		}
+	}
+	if note, trashed := (*trash)[slug]; trashed {
+		return fmt.Sprintf("Note '%s' was moved to the trash on %s", slug, note.Deleted.Format("2006-01-02"))
+	}
+	if root, bare := splitQualified(slug); root != "" {
+		return fmt.Sprintf("Note '%s' not found in %s", bare, root)
+	}
+	return fmt.Sprintf("Note '%s' not found", slug)
+}
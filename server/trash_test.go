@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestTrash tests that deleted notes go to the trash and can be restored
+func TestTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	os.WriteFile(filepath.Join(v.NotesPath, "20240101-graphs.tex"), []byte("%% Metadata\n% title: Graphs\n"), 0644)
+	os.WriteFile(filepath.Join(v.NotesPath, "20240102-other.tex"), []byte("%% Metadata\n% title: Other\n\nSee \\ref{graphs}."), 0644)
+
+	ls := &LanguageServer{vault: v, index: NewIndex()}
+	ls.RebuildIndex(context.Background())
+
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"graphs", deleteModeForce}); err != nil {
+		t.Fatalf("deleteNoteCommand failed: %v", err)
+	}
+	if _, exists := ls.index.Get("graphs"); exists {
+		t.Error("expected trashed note to leave the index")
+	}
+
+	// Trashed notes are not picked up by a rebuild
+	ls.RebuildIndex(context.Background())
+	if _, exists := ls.index.Get("graphs"); exists {
+		t.Error("expected rebuild to skip the trash")
+	}
+
+	trash, err := ls.listTrash()
+	if err != nil {
+		t.Fatalf("listTrash failed: %v", err)
+	}
+	if len(trash) != 1 || trash[0].Slug != "graphs" || trash[0].Title != "Graphs" || trash[0].Expires.Sub(trash[0].Deleted) != 30*24*time.Hour {
+		t.Fatalf("unexpected trash %+v", trash)
+	}
+
+	// References to the trashed note say where it went and offer to restore it
+	content := "See \\ref{graphs}."
+	diagnostics := ls.analyzeDiagnostics(content)
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "moved to the trash") {
+		t.Fatalf("unexpected diagnostics %+v", diagnostics)
+	}
+	var commands []string
+	for _, action := range ls.collectCodeActions(&codeActionRequest{Content: content}, diagnostics) {
+		if action.Command != nil {
+			commands = append(commands, action.Command.Command)
+		}
+	}
+	if strings.Contains(strings.Join(commands, ","), commandCreateNote) || !strings.Contains(strings.Join(commands, ","), commandRestoreNote) {
+		t.Errorf("expected restore instead of create, got %v", commands)
+	}
+
+	if err := ls.restoreNoteCommand(context.Background(), []interface{}{"graphs"}); err != nil {
+		t.Fatalf("restoreNoteCommand failed: %v", err)
+	}
+	if note, exists := ls.index.Get("graphs"); !exists || note.Title != "Graphs" {
+		t.Error("expected restored note to be indexed")
+	}
+	if err := ls.restoreNoteCommand(context.Background(), []interface{}{"graphs"}); err == nil {
+		t.Error("expected error restoring a note that is not in the trash")
+	}
+}
+
+// TestPurgeTrash tests that notes past the retention period are deleted for good
+func TestPurgeTrash(t *testing.T) {
+	tempDir := t.TempDir()
+	config := DefaultConfig()
+	config.TrashRetentionDays = 7
+	ls := &LanguageServer{vault: vaultAt(tempDir), index: NewIndex(), config: &config}
+
+	os.MkdirAll(ls.trashPath(), 0755)
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	for _, name := range []string{"20240101-old.tex", "20240102-recent.tex"} {
+		os.WriteFile(filepath.Join(ls.trashPath(), name), []byte("%% Metadata\n"), 0644)
+	}
+	os.Chtimes(filepath.Join(ls.trashPath(), "20240101-old.tex"), old, old)
+
+	ls.purgeTrash(time.Now())
+	if _, err := os.Stat(filepath.Join(ls.trashPath(), "20240101-old.tex")); !os.IsNotExist(err) {
+		t.Error("expected expired note to be purged")
+	}
+	if _, trashed := ls.trashedNotes()["recent"]; !trashed {
+		t.Error("expected recent note to stay in the trash")
+	}
+
+	// Without retention nothing expires
+	config.TrashRetentionDays = 0
+	os.Chtimes(filepath.Join(ls.trashPath(), "20240102-recent.tex"), old, old)
+	ls.purgeTrash(time.Now())
+	if note, trashed := ls.trashedNotes()["recent"]; !trashed || !note.Expires.IsZero() {
+		t.Errorf("expected the note to be kept forever, got %+v", note)
+	}
+}
+
+// TestRestoreToOrigin tests that notes go back to the folder they were deleted from
+func TestRestoreToOrigin(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(filepath.Join(tempDir, "vault"))
+	plainNotes := filepath.Join(tempDir, "plain")
+	os.MkdirAll(v.NotesPath, 0755)
+	os.MkdirAll(plainNotes, 0755)
+	loosePath := filepath.Join(plainNotes, "20240103-loose.tex")
+	os.WriteFile(loosePath, []byte("%% Metadata\n%% title: Loose\n"), 0644)
+
+	ls := &LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.setWorkspaceFolders([]protocol.WorkspaceFolder{{URI: string(pathToURI(plainNotes)), Name: "plain"}})
+	ls.RebuildIndex(context.Background())
+
+	if err := ls.deleteNoteCommand(context.Background(), []interface{}{"loose", deleteModeForce}); err != nil {
+		t.Fatalf("deleteNoteCommand failed: %v", err)
+	}
+
+	// A file taking the note's place blocks the restore
+	os.WriteFile(loosePath, []byte("new"), 0644)
+	if err := ls.restoreNoteCommand(context.Background(), []interface{}{"loose"}); err == nil {
+		t.Error("expected restoring over an existing file to fail")
+	}
+	if data, _ := os.ReadFile(loosePath); string(data) != "new" {
+		t.Errorf("expected the file in the way to be left alone, got %q", data)
+	}
+	os.Remove(loosePath)
+
+	if err := ls.restoreNoteCommand(context.Background(), []interface{}{"loose"}); err != nil {
+		t.Fatalf("restoreNoteCommand failed: %v", err)
+	}
+	if _, err := os.Stat(loosePath); err != nil {
+		t.Errorf("expected the note back in its folder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(v.NotesPath, "20240103-loose.tex")); !os.IsNotExist(err) {
+		t.Error("expected nothing restored into the vault's notes directory")
+	}
+	if entries, _ := os.ReadDir(ls.trashPath()); len(entries) != 0 {
+		t.Errorf("expected an empty trash, got %v", entries)
+	}
+}
+
+// TestTrashSameFilename tests that trashing a note does not overwrite a trashed note of the same name
+func TestTrashSameFilename(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(filepath.Join(tempDir, "vault"))
+	ls := &LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+
+	first := filepath.Join(v.NotesPath, "20240101-graphs.tex")
+	second := filepath.Join(v.NotesPath, "math", "20240101-graphs.tex")
+	os.MkdirAll(filepath.Dir(second), 0755)
+	os.WriteFile(first, []byte("first"), 0644)
+	os.WriteFile(second, []byte("second"), 0644)
+	for _, path := range []string{first, second} {
+		if err := ls.trashNote(path); err != nil {
+			t.Fatalf("trashNote failed: %v", err)
+		}
+	}
+
+	entries := ls.trashEntries()
+	if len(entries) != 2 || entries[0].Slug != "graphs" || entries[1].Slug != "graphs" {
+		t.Fatalf("expected both notes in the trash, got %+v", entries)
+	}
+
+	// The most recently deleted note is restored first, to the folder it came from
+	if err := ls.restoreNoteCommand(context.Background(), []interface{}{"graphs"}); err != nil {
+		t.Fatalf("restoreNoteCommand failed: %v", err)
+	}
+	if data, _ := os.ReadFile(second); string(data) != "second" {
+		t.Errorf("expected the second note restored, got %q", data)
+	}
+	if note, trashed := ls.trashedNotes()["graphs"]; !trashed || note.Filename != "20240101-graphs.tex" {
+		t.Errorf("expected the first note to stay in the trash, got %+v", note)
+	}
+}
+
+// TestTrashOriginOutsideNotes tests that a recorded origin outside the notes directories is ignored
+func TestTrashOriginOutsideNotes(t *testing.T) {
+	tempDir := t.TempDir()
+	ls := &LanguageServer{vault: vaultAt(filepath.Join(tempDir, "vault"))}
+	os.MkdirAll(ls.trashPath(), 0755)
+	os.WriteFile(filepath.Join(ls.trashPath(), "20240101-a.tex"+trashOriginSuffix), []byte(filepath.Join(tempDir, "elsewhere")), 0644)
+	os.WriteFile(filepath.Join(ls.trashPath(), "20240102-b.tex"+trashOriginSuffix), []byte(filepath.Join(ls.vault.NotesPath, "math")), 0644)
+
+	if got := ls.trashOrigin("20240101-a.tex"); got != ls.vault.NotesPath {
+		t.Errorf("expected the notes directory, got %s", got)
+	}
+	if got := ls.trashOrigin("20240102-b.tex"); got != filepath.Join(ls.vault.NotesPath, "math") {
+		t.Errorf("expected the subdirectory, got %s", got)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+const (
+	// commandVersion reports the server build, the vault it serves and the size of its index
+	// Arguments: none
+	commandVersion = "lx.version"
+
+	// commandCheckUpdate compares the server version with the latest release and tells the user
+	// Arguments: none. Nothing is fetched unless the command is run, and then in the background:
+	// the command returns at once and the outcome is shown with window/showMessage
+	commandCheckUpdate = "lx.checkUpdate"
+
+	// updateCheckTimeout bounds the request for the latest release
+	updateCheckTimeout = 10 * time.Second
+)
+
+// Version, GitCommit and BuildDate describe the build, set by the Makefile and GoReleaser through -ldflags
+// Builds that do not set the commit and date, such as go install, take them from the VCS build info
+var (
+	Version   = "0.1.1"
+	GitCommit = ""
+	BuildDate = ""
+)
+
+// latestReleaseURL is the GitHub API endpoint returning the latest release of the server
+var latestReleaseURL = "https://api.github.com/repos/kamal-hamza/lx-lsp/releases/latest"
+
+func init() {
+	registerCommand(commandVersion, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		return s.versionInfo(), nil
+	})
+	registerCommand(commandCheckUpdate, func(s *LanguageServer, ctx context.Context, args []interface{}) (interface{}, error) {
+		s.inBackground(ctx, commandCheckUpdate, func(ctx context.Context) error {
+			_, err := s.checkUpdate(ctx)
+			return err
+		})
+		return nil, nil
+	})
+}
+
+// VersionInfo is returned by lx.version, for pasting into bug reports
+type VersionInfo struct {
+	Version   string     `json:"version"`
+	GitCommit string     `json:"gitCommit,omitempty"`
+	BuildDate string     `json:"buildDate,omitempty"`
+	Modified  bool       `json:"modified,omitempty"` // built from a tree with uncommitted changes
+	GoVersion string     `json:"goVersion"`
+	Platform  string     `json:"platform"`
+	VaultPath string     `json:"vaultPath"`
+	NotesPath string     `json:"notesPath"`
+	Folders   []string   `json:"folders,omitempty"` // notes directories of workspace folders
+	Indexed   bool       `json:"indexed"`
+	Index     *IndexInfo `json:"index"`
+}
+
+// buildVersion returns the version, commit and build date of the running binary, completed from
+// the build info where the linker flags left them unset
+func buildVersion() (version, commit, date string, modified bool) {
+	version, commit, date = strings.TrimPrefix(Version, "v"), GitCommit, BuildDate
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, commit, date, false
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if commit == "" && len(setting.Value) >= 7 {
+				commit = setting.Value[:7]
+			}
+		case "vcs.time":
+			if date == "" {
+				date = setting.Value
+			}
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	return version, commit, date, modified
+}
+
+// versionInfo describes the build and what the server is serving
+func (s *LanguageServer) versionInfo() *VersionInfo {
+	info := &VersionInfo{
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Folders:   s.workspaceFolders(),
+		Indexed:   s.indexed(),
+		Index:     s.indexInfo(),
+	}
+	info.Version, info.GitCommit, info.BuildDate, info.Modified = buildVersion()
+	if s.vault != nil {
+		info.VaultPath = s.vault.RootPath
+		info.NotesPath = s.vault.NotesPath
+	}
+	return info
+}
+
+// UpdateCheck compares the running version with the latest release
+type UpdateCheck struct {
+	Current         string `json:"current"`
+	Latest          string `json:"latest"`
+	URL             string `json:"url,omitempty"` // release page of the latest version
+	UpdateAvailable bool   `json:"updateAvailable"`
+}
+
+// latestRelease fetches the tag and page of the latest release
+func latestRelease(ctx context.Context) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", "", err
+	}
+	if release.TagName == "" {
+		return "", "", fmt.Errorf("release without a tag")
+	}
+	return strings.TrimPrefix(release.TagName, "v"), release.HTMLURL, nil
+}
+
+// checkUpdate looks up the latest release for lx.checkUpdate
+// The outcome is shown to the user as well as returned; development builds, whose version is not a
+// release number, are never reported as outdated
+func (s *LanguageServer) checkUpdate(ctx context.Context) (*UpdateCheck, error) {
+	current, _, _, _ := buildVersion()
+	latest, url, err := latestRelease(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to look up the latest release: %w", commandCheckUpdate, err)
+	}
+
+	check := &UpdateCheck{Current: current, Latest: latest, URL: url}
+	message := &protocol.ShowMessageParams{Type: protocol.MessageTypeInfo, Message: fmt.Sprintf("lx-lsp %s is the latest release", current)}
+	if newer, ok := compareVersions(latest, current); ok && newer > 0 {
+		check.UpdateAvailable = true
+		message.Type = protocol.MessageTypeWarning
+		message.Message = fmt.Sprintf("lx-lsp %s is available, this is %s: %s", latest, current, url)
+	} else if !ok {
+		message.Message = fmt.Sprintf("lx-lsp %s is a development build; the latest release is %s", current, latest)
+	}
+	if s.conn != nil {
+		s.conn.Notify(ctx, protocol.MethodWindowShowMessage, message)
+	}
+	return check, nil
+}
+
+// compareVersions compares two dotted release numbers such as 0.2.1, ignoring pre-release suffixes
+// Returns a positive number when a is newer than b, and false when either is not a release number
+func compareVersions(a, b string) (int, bool) {
+	parse := func(version string) ([]int, bool) {
+		version, _, _ = strings.Cut(version, "-")
+		var parts []int
+		for _, field := range strings.Split(version, ".") {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, false
+			}
+			parts = append(parts, n)
+		}
+		return parts, true
+	}
+	left, ok := parse(a)
+	if !ok {
+		return 0, false
+	}
+	right, ok := parse(b)
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var x, y int
+		if i < len(left) {
+			x = left[i]
+		}
+		if i < len(right) {
+			y = right[i]
+		}
+		if x != y {
+			return x - y, true
+		}
+	}
+	return 0, true
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestVersionInfo tests reporting the build and the vault served
+func TestVersionInfo(t *testing.T) {
+	tempDir := t.TempDir()
+	ls := &LanguageServer{
+		vault:     &vault.Vault{RootPath: tempDir, NotesPath: tempDir},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+	ls.index.Set("graphs", &NoteHeader{Slug: "graphs", Filename: "20240101-graphs.tex"})
+
+	info := ls.versionInfo()
+	if info.Version != Version || info.VaultPath != tempDir || info.GoVersion == "" || !info.Indexed || info.Index.Notes != 1 {
+		t.Errorf("unexpected version info %+v", info)
+	}
+}
+
+// TestCheckUpdate tests comparing the running version with the latest release
+func TestCheckUpdate(t *testing.T) {
+	tag := "v0.2.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name": %q, "html_url": "https://example.com/releases/%s"}`, tag, tag)
+	}))
+	defer server.Close()
+	defer func(url, version string) { latestReleaseURL, Version = url, version }(latestReleaseURL, Version)
+	latestReleaseURL = server.URL
+	ls := &LanguageServer{index: NewIndex()}
+
+	for _, tc := range []struct {
+		version, tag string
+		available    bool
+	}{
+		{"0.1.1", "v0.2.0", true},
+		{"v0.2.0", "v0.2.0", false},
+		{"0.10.0", "v0.9.3", false},
+		{"dev", "v0.2.0", false},
+	} {
+		Version, tag = tc.version, tc.tag
+		check, err := ls.checkUpdate(context.Background())
+		if err != nil {
+			t.Fatalf("checkUpdate failed: %v", err)
+		}
+		if check.UpdateAvailable != tc.available || check.Latest != tc.tag[1:] {
+			t.Errorf("%s against %s: unexpected result %+v", tc.version, tc.tag, check)
+		}
+	}
+
+	latestReleaseURL = server.URL + "/missing"
+	server.Config.Handler = http.NotFoundHandler()
+	if _, err := ls.checkUpdate(context.Background()); err == nil {
+		t.Error("expected an error when the release cannot be looked up")
+	}
+}
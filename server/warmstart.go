@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// cliIndex mirrors the index.json the lx CLI writes on `lx reindex`
+type cliIndex struct {
+	Version     string                   `json:"version"`
+	LastIndexed time.Time                `json:"last_indexed"`
+	Notes       map[string]cliIndexEntry `json:"notes"`
+}
+
+// cliIndexEntry is the cached metadata of one note in the lx CLI index
+type cliIndexEntry struct {
+	Title    string   `json:"title"`
+	Date     string   `json:"date"`
+	Tags     []string `json:"tags"`
+	Filename string   `json:"filename"`
+}
+
+// warmStart holds the lx CLI index entries that can stand in for parsing a note's metadata
+type warmStart struct {
+	lastIndexed time.Time
+	byFilename  map[string]cliIndexEntry
+}
+
+// loadWarmStart reads the lx CLI index from the vault cache
+// Returns nil when there is no usable index, in which case every note is parsed
+func (s *LanguageServer) loadWarmStart() *warmStart {
+	data, err := os.ReadFile(s.vault.IndexPath())
+	if err != nil {
+		return nil
+	}
+
+	var index cliIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		s.logf(protocol.MessageTypeLog, "Ignoring unreadable lx index %s: %v", s.vault.IndexPath(), err)
+		return nil
+	}
+	if index.LastIndexed.IsZero() {
+		return nil
+	}
+
+	byFilename := make(map[string]cliIndexEntry, len(index.Notes))
+	for _, entry := range index.Notes {
+		if entry.Filename != "" {
+			byFilename[entry.Filename] = entry
+		}
+	}
+
+	return &warmStart{lastIndexed: index.LastIndexed, byFilename: byFilename}
+}
+
+// header returns the cached header of a note, or nil if the note changed since the CLI indexed it
+func (w *warmStart) header(slug, filename string, modified time.Time) *NoteHeader {
+	if w == nil || modified.After(w.lastIndexed) {
+		return nil
+	}
+	entry, ok := w.byFilename[filename]
+	if !ok {
+		return nil
+	}
+
+	header := &NoteHeader{
+		Title:    entry.Title,
+		Date:     entry.Date,
+		Tags:     entry.Tags,
+		Slug:     slug,
+		Filename: filename,
+		Modified: modified,
+	}
+	if header.Tags == nil {
+		header.Tags = []string{}
+	}
+	if header.Title == "" {
+		header.Title = slug
+	}
+	return header
+}
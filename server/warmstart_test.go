@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+)
+
+// TestRebuildIndex_WarmStart tests bootstrapping headers from the lx CLI index
+func TestRebuildIndex_WarmStart(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	cachePath := filepath.Join(tempDir, "cache")
+	os.MkdirAll(notesPath, 0755)
+	os.MkdirAll(cachePath, 0755)
+
+	fresh := filepath.Join(notesPath, "20240101-fresh.tex")
+	stale := filepath.Join(notesPath, "20240102-stale.tex")
+	os.WriteFile(fresh, []byte("%% Metadata\n% title: Fresh On Disk\n"), 0644)
+	os.WriteFile(stale, []byte("%% Metadata\n% title: Edited After Reindex\n"), 0644)
+
+	lastIndexed := time.Now().Add(-time.Hour)
+	os.Chtimes(fresh, lastIndexed.Add(-time.Hour), lastIndexed.Add(-time.Hour))
+	os.Chtimes(stale, time.Now(), time.Now())
+
+	os.WriteFile(filepath.Join(cachePath, "index.json"), []byte(`{
+  "version": "1.0",
+  "last_indexed": "`+lastIndexed.Format(time.RFC3339Nano)+`",
+  "notes": {
+    "fresh": {"title": "Fresh From CLI", "date": "2024-01-01", "tags": ["cli"], "filename": "20240101-fresh.tex"},
+    "stale": {"title": "Stale From CLI", "date": "2024-01-02", "tags": [], "filename": "20240102-stale.tex"}
+  }
+}`), 0644)
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: notesPath, CachePath: cachePath},
+		index: NewIndex(),
+	}
+	if err := ls.RebuildIndex(context.Background()); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+
+	if note, _ := ls.index.Get("fresh"); note == nil || note.Title != "Fresh From CLI" || len(note.Tags) != 1 {
+		t.Errorf("expected unchanged note from CLI index, got %+v", note)
+	}
+	if note, _ := ls.index.Get("stale"); note == nil || note.Title != "Edited After Reindex" {
+		t.Errorf("expected modified note to be re-parsed, got %+v", note)
+	}
+
+	// Without an index every note is parsed
+	os.Remove(filepath.Join(cachePath, "index.json"))
+	ls.index = NewIndex()
+	ls.RebuildIndex(context.Background())
+	if note, _ := ls.index.Get("fresh"); note == nil || note.Title != "Fresh On Disk" {
+		t.Errorf("expected parsed note without CLI index, got %+v", note)
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// indexProgressToken identifies the progress of the initial index build
+const indexProgressToken = "lx-lsp/indexing"
+
+// indexed reports whether the initial index build has finished
+// Servers that never started a background build (e.g. in tests) are always ready
+func (s *LanguageServer) indexed() bool {
+	if s.indexReady == nil {
+		return true
+	}
+	select {
+	case <-s.indexReady:
+		return true
+	default:
+		return false
+	}
+}
+
+// startWarmUp builds the initial index in the background, once per server
+// Called from Initialized, when the client is ready to show progress
+func (s *LanguageServer) startWarmUp(ctx context.Context) {
+	if s.indexReady == nil {
+		return
+	}
+	// The notification context ends with the notification, the index build must not
+	ctx = context.WithoutCancel(ctx)
+	s.warmUpOnce.Do(func() {
+		go s.warmUp(ctx)
+	})
+}
+
+// warmUp builds the initial index and re-checks the documents opened in the meantime
+func (s *LanguageServer) warmUp(ctx context.Context) {
+	ctx, progress := s.createProgress(ctx, indexProgressToken, "Indexing notes")
+	err := s.RebuildIndex(ctx)
+	close(s.indexReady)
+
+	if err != nil {
+		s.logf(protocol.MessageTypeError, "Failed to build initial index: %v", err)
+		progress.End(ctx, "Indexing failed")
+		if s.conn != nil {
+			s.conn.Notify(ctx, protocol.MethodWindowShowMessage, &protocol.ShowMessageParams{
+				Type:    protocol.MessageTypeError,
+				Message: fmt.Sprintf("lx-lsp: failed to build initial index: %v", err),
+			})
+		}
+		return
+	}
+	s.recordActivity(ctx, "index.rebuild", fmt.Sprintf("indexed %d notes", s.index.Count()))
+	s.logf(protocol.MessageTypeInfo, "Indexed %d notes", s.index.Count())
+	s.purgeTrash(time.Now())
+	s.scheduleDoctor()
+	s.scheduleLinkScan()
+	progress.End(ctx, fmt.Sprintf("Indexed %d notes", s.index.Count()))
+
+	// References checked before the index was ready were not reported as broken
+	s.republishOpenDocuments(ctx)
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestWarmUp tests that requests arriving before the initial index is built are answered gracefully
+func TestWarmUp(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-graph-theory.tex"), []byte("%% Metadata\n% title: Graph Theory\n"), 0644)
+
+	content := "See \\ref{graph-theory}."
+	uri := pathToURI(filepath.Join(notesPath, "20240102-trees.tex"))
+	ls := &LanguageServer{
+		vault:      &vault.Vault{NotesPath: notesPath},
+		index:      NewIndex(),
+		documents:  map[protocol.DocumentURI]string{uri: content},
+		indexReady: make(chan struct{}),
+	}
+	hoverParams := &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 0, Character: 12},
+		},
+	}
+
+	// Before indexing: no false broken references, and hover explains itself
+	if ls.indexed() {
+		t.Fatal("expected index not to be ready")
+	}
+	for _, diag := range ls.analyzeDiagnostics(content) {
+		if diag.Code == diagnosticCodeBrokenRef {
+			t.Errorf("unexpected broken reference before indexing: %s", diag.Message)
+		}
+	}
+	hover, err := ls.Hover(context.Background(), hoverParams)
+	if err != nil || hover == nil || !strings.Contains(hover.Contents.Value, "Indexing") {
+		t.Errorf("expected an indexing hover, got %v (%v)", hover, err)
+	}
+
+	ls.startWarmUp(context.Background())
+	ls.startWarmUp(context.Background()) // Only the first call builds
+
+	select {
+	case <-ls.indexReady:
+	case <-time.After(5 * time.Second):
+		t.Fatal("index was not built")
+	}
+
+	hover, err = ls.Hover(context.Background(), hoverParams)
+	if err != nil || hover == nil || !strings.Contains(hover.Contents.Value, "**Graph Theory**") {
+		t.Errorf("expected note hover after indexing, got %v (%v)", hover, err)
+	}
+}
+
+// TestRebuildIndexProgress tests that index builds report how many notes are indexed
+func TestRebuildIndexProgress(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"20240101-a.tex", "20240102-b.tex", "20240103-c.tex"} {
+		os.WriteFile(filepath.Join(tempDir, name), []byte("%% Metadata\n% title: Note\n"), 0644)
+	}
+
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	reports := make(chan protocol.WorkDoneProgressReport, 16)
+	client := jsonrpc2.NewConn(jsonrpc2.NewStream(clientSide))
+	client.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		var params struct {
+			Value protocol.WorkDoneProgressReport `json:"value"`
+		}
+		if err := json.Unmarshal(req.Params(), &params); err == nil && params.Value.Kind == protocol.WorkDoneProgressKindReport {
+			reports <- params.Value
+		} else if params.Value.Kind == protocol.WorkDoneProgressKindEnd {
+			close(reports)
+		}
+		return reply(ctx, nil, nil)
+	})
+	defer client.Close()
+
+	ls := &LanguageServer{
+		vault: &vault.Vault{NotesPath: tempDir},
+		index: NewIndex(),
+		conn:  jsonrpc2.NewConn(jsonrpc2.NewStream(serverSide)),
+	}
+	ctx, progress := ls.beginProgress(context.Background(), protocol.NewProgressToken("index"), "Indexing notes")
+	if err := ls.RebuildIndex(ctx); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	progress.End(ctx, "")
+
+	var messages []string
+	var last protocol.WorkDoneProgressReport
+	for report := range reports {
+		messages = append(messages, report.Message)
+		last = report
+	}
+	if len(messages) != 4 || messages[0] != "Reading notes" {
+		t.Fatalf("unexpected reports %q", messages)
+	}
+	if last.Message != "Indexing vault: 3/3 notes" || last.Percentage != 100 {
+		t.Errorf("unexpected final report %+v", last)
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileEventDebounce is how long watcher events for a note settle before it is parsed again
+// Editors save with bursts of events, writing in parts or through a temporary file, and the
+// note is read once the burst is over rather than half-written, once per event
+const fileEventDebounce = 100 * time.Millisecond
+
+// noteTree returns a notes directory and every folder below it, parents first
+// Hidden folders such as .git are skipped
+func noteTree(root string) []string {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs
+}
+
+// noteDirsTree returns every folder holding managed notes: the notes directories and the folders below them
+func (s *LanguageServer) noteDirsTree() []string {
+	var dirs []string
+	for _, root := range s.notesDirs() {
+		dirs = append(dirs, noteTree(root)...)
+	}
+	return dirs
+}
+
+// inTree reports whether path is dir or inside it
+func inTree(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// watchTree watches a notes directory and every folder below it
+// fsnotify is not recursive, so folders created later are added as they appear, see watchCreatedDir
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	if err := watcher.Add(root); err != nil {
+		return err
+	}
+	for _, dir := range noteTree(root) {
+		if dir != root {
+			watcher.Add(dir)
+		}
+	}
+	return nil
+}
+
+// unwatchTree stops watching a notes directory and the folders below it
+func unwatchTree(watcher *fsnotify.Watcher, root string) {
+	for _, dir := range watcher.WatchList() {
+		if inTree(dir, root) {
+			watcher.Remove(dir)
+		}
+	}
+}
+
+// managedDir reports whether a folder is a notes directory or inside one
+func (s *LanguageServer) managedDir(path string) bool {
+	for _, dir := range s.notesDirs() {
+		if inTree(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchCreatedDir starts watching a folder created in a notes directory, with any folders inside it,
+// and indexes the notes it arrived with, as when a folder is moved into the vault
+func (s *LanguageServer) watchCreatedDir(ctx context.Context, watcher *fsnotify.Watcher, path string) {
+	if strings.HasPrefix(filepath.Base(path), ".") || !s.managedDir(path) {
+		return
+	}
+	for _, dir := range noteTree(path) {
+		watcher.Add(dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".tex") {
+				s.fileChanged(ctx, filepath.Join(dir, entry.Name()), true)
+			}
+		}
+	}
+}
+
+// dropRemovedDir forgets the notes of a folder deleted or moved out of a notes directory
+func (s *LanguageServer) dropRemovedDir(ctx context.Context, watcher *fsnotify.Watcher, path string) {
+	unwatchTree(watcher, path)
+	for _, note := range s.index.AllVariants() {
+		if note.Dir != "" && inTree(note.Dir, path) {
+			s.dropNote(note.Slug, note.Dir, note.Filename)
+			s.publishBacklinkDiagnostics(ctx, note.Slug)
+		}
+	}
+}
+
+// dirEvent follows folders created in or removed from a notes directory
+func (s *LanguageServer) dirEvent(ctx context.Context, watcher *fsnotify.Watcher, event fsnotify.Event) {
+	if strings.HasSuffix(event.Name, ".tex") {
+		return
+	}
+	switch {
+	case event.Has(fsnotify.Create):
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			s.watchCreatedDir(ctx, watcher, event.Name)
+		}
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		s.dropRemovedDir(ctx, watcher, event.Name)
+	}
+}
+
+// pendingFileChange is a burst of watcher events for a note, waiting for fileEventDebounce
+type pendingFileChange struct {
+	timer   *time.Timer
+	created bool // any event of the burst created the file
+}
+
+// scheduleFileChange updates the index for a note once its watcher events stop for fileEventDebounce
+// Permission changes alone leave the note as it was and are ignored
+func (s *LanguageServer) scheduleFileChange(ctx context.Context, event fsnotify.Event) {
+	path := event.Name
+	if !strings.HasSuffix(path, ".tex") || event.Op == fsnotify.Chmod {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fileEvents == nil {
+		s.fileEvents = make(map[string]*pendingFileChange)
+	}
+	created := event.Has(fsnotify.Create)
+	if pending, ok := s.fileEvents[path]; ok {
+		pending.timer.Stop()
+		created = created || pending.created
+	}
+	pending := &pendingFileChange{created: created}
+	pending.timer = time.AfterFunc(fileEventDebounce, func() {
+		s.mu.Lock()
+		// A timer stopped too late to stop it must not drop the burst that replaced it
+		if s.fileEvents[path] == pending {
+			delete(s.fileEvents, path)
+		}
+		s.mu.Unlock()
+		s.fileChanged(ctx, path, created)
+	})
+	s.fileEvents[path] = pending
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestRecursiveWatch tests that notes in folders of the notes directory are indexed and followed as folders come and go
+func TestRecursiveWatch(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "math", "graphs"), 0755)
+	os.MkdirAll(filepath.Join(tempDir, ".git"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "math", "graphs", "20240101-trees.tex"), []byte("%% Metadata\n% title: Trees\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, ".git", "20240101-hidden.tex"), []byte("%% Metadata\n% title: Hidden\n"), 0644)
+
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.RebuildIndex(context.Background())
+	if _, exists := ls.index.Get("trees"); !exists {
+		t.Fatal("expected the note of a nested folder to be indexed")
+	}
+	if _, exists := ls.index.Get("hidden"); exists {
+		t.Error("expected hidden folders to be skipped")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Skipf("no file watching here: %v", err)
+	}
+	defer watcher.Close()
+	if err := watchTree(watcher, tempDir); err != nil {
+		t.Fatalf("watchTree failed: %v", err)
+	}
+	if len(watcher.WatchList()) != 3 {
+		t.Errorf("expected the notes directory and its two folders to be watched, got %v", watcher.WatchList())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ls.handleFileEvents(ctx, watcher)
+
+	eventually := func(what string, check func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if check() {
+				return
+			}
+		}
+		t.Fatalf("timed out waiting for %s", what)
+	}
+
+	// A new folder is watched, so notes written into it later are picked up
+	os.MkdirAll(filepath.Join(tempDir, "cs"), 0755)
+	eventually("the new folder to be watched", func() bool { return len(watcher.WatchList()) == 4 })
+	os.WriteFile(filepath.Join(tempDir, "cs", "20240102-paths.tex"), []byte("%% Metadata\n% title: Paths\n"), 0644)
+	eventually("the note of the new folder", func() bool {
+		_, exists := ls.index.Get("paths")
+		return exists
+	})
+
+	os.RemoveAll(filepath.Join(tempDir, "math"))
+	eventually("the notes of the removed folder to be dropped", func() bool {
+		_, exists := ls.index.Get("trees")
+		return !exists
+	})
+}
+
+// TestFileEventDebounce tests that a burst of watcher events for a note is handled once it settles
+func TestFileEventDebounce(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "20240101-graphs.tex")
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: tempDir}, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+
+	ls.scheduleFileChange(context.Background(), fsnotify.Event{Name: path, Op: fsnotify.Chmod})
+	if len(ls.fileEvents) != 0 {
+		t.Fatal("expected a permission change alone to be ignored")
+	}
+
+	// The file is half-written when the first events arrive
+	os.WriteFile(path, []byte("%% Metadata\n"), 0644)
+	ls.scheduleFileChange(context.Background(), fsnotify.Event{Name: path, Op: fsnotify.Create})
+	ls.scheduleFileChange(context.Background(), fsnotify.Event{Name: path, Op: fsnotify.Write})
+	os.WriteFile(path, []byte("%% Metadata\n% title: Graphs\n"), 0644)
+	ls.scheduleFileChange(context.Background(), fsnotify.Event{Name: path, Op: fsnotify.Write | fsnotify.Chmod})
+
+	ls.mu.RLock()
+	pending, count := ls.fileEvents[path], len(ls.fileEvents)
+	ls.mu.RUnlock()
+	if count != 1 || pending == nil || !pending.created {
+		t.Fatalf("expected one pending change remembering the creation, got %d", count)
+	}
+	if _, exists := ls.index.Get("graphs"); exists {
+		t.Error("expected the note to wait for the burst to settle")
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if note, exists := ls.index.Get("graphs"); exists {
+			if note.Title != "Graphs" {
+				t.Errorf("expected the note as last written, got title %q", note.Title)
+			}
+			return
+		}
+	}
+	t.Fatal("timed out waiting for the note to be indexed")
+}
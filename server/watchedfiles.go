@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+
+	"go.lsp.dev/protocol"
+)
+
+// watchedFilesRegistrationID identifies the dynamic didChangeWatchedFiles registration
+const watchedFilesRegistrationID = "lx-watched-files"
+
+// Handle DidChangeWatchedFiles notification
+// Complements fsnotify, which misses events on network shares and some editors' atomic saves
+// Events already seen through fsnotify are harmless: re-indexing a file is idempotent
+func (s *LanguageServer) DidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	if !s.settings().Features.Watchers {
+		return nil
+	}
+	for _, change := range params.Changes {
+		if !s.IsManaged(change.URI) {
+			continue
+		}
+		s.fileChanged(ctx, uriToPath(change.URI), change.Type == protocol.FileChangeTypeCreated)
+	}
+	return nil
+}
+
+// registerWatchedFiles asks the client to report changes to notes
+func (s *LanguageServer) registerWatchedFiles(ctx context.Context) error {
+	_, err := s.conn.Call(ctx, protocol.MethodClientRegisterCapability, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{
+			{
+				ID:     watchedFilesRegistrationID,
+				Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+				RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+					Watchers: []protocol.FileSystemWatcher{
+						{
+							GlobPattern: "**/*.tex",
+							Kind:        protocol.WatchKindCreate + protocol.WatchKindChange + protocol.WatchKindDelete,
+						},
+					},
+				},
+			},
+		},
+	}, nil)
+	return err
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestDidChangeWatchedFiles tests index updates driven by client file events
+func TestDidChangeWatchedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: make(map[protocol.DocumentURI]string),
+	}
+
+	notify := func(path string, change protocol.FileChangeType) {
+		t.Helper()
+		params := &protocol.DidChangeWatchedFilesParams{
+			Changes: []*protocol.FileEvent{{Type: change, URI: pathToURI(path)}},
+		}
+		if err := ls.DidChangeWatchedFiles(context.Background(), params); err != nil {
+			t.Fatalf("DidChangeWatchedFiles failed: %v", err)
+		}
+	}
+
+	notePath := filepath.Join(notesPath, "20240101-trees.tex")
+	os.WriteFile(notePath, []byte("%% Metadata\n% title: Trees\n"), 0644)
+	notify(notePath, protocol.FileChangeTypeCreated)
+	if _, exists := ls.index.Get("trees"); !exists {
+		t.Fatal("expected created note to be indexed")
+	}
+
+	// Files outside the notes directory are ignored
+	outside := filepath.Join(tempDir, "20240102-outside.tex")
+	os.WriteFile(outside, []byte("%% Metadata\n% title: Outside\n"), 0644)
+	notify(outside, protocol.FileChangeTypeCreated)
+	if _, exists := ls.index.Get("outside"); exists {
+		t.Error("expected file outside the notes directory to be ignored")
+	}
+
+	os.Remove(notePath)
+	notify(notePath, protocol.FileChangeTypeDeleted)
+	if _, exists := ls.index.Get("trees"); exists {
+		t.Error("expected deleted note to leave the index")
+	}
+}
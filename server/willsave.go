@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// Handle WillSaveWaitUntil request
+// Stamps the modified date into the metadata block so notes track their last edit
+func (s *LanguageServer) WillSaveWaitUntil(ctx context.Context, params *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
+	if !s.settings().UpdateModified || !s.IsManaged(params.TextDocument.URI) {
+		return nil, nil
+	}
+
+	content, err := s.GetDocument(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+
+	return s.modifiedDateEdits(content, time.Now().Format("2006-01-02")), nil
+}
+
+// modifiedDateEdits sets the modified field of the metadata block to today
+// Only the modified line is touched: its value is replaced, or the line is inserted after the last
+// field of the block, so comments and directives sharing the block are kept as they are
+func (s *LanguageServer) modifiedDateEdits(content, today string) []protocol.TextEdit {
+	blockStart, blockEnd, found := s.metadataParser().FindBlock(content)
+	if !found {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+
+	lastField := blockStart
+	for lineNum := blockStart + 1; lineNum <= blockEnd; lineNum++ {
+		match := metadataFieldPattern.FindStringSubmatchIndex(lines[lineNum])
+		if match == nil {
+			continue
+		}
+		lastField = lineNum
+		if strings.ToLower(lines[lineNum][match[2]:match[3]]) != "modified" {
+			continue
+		}
+		value := strings.TrimSpace(lines[lineNum][match[4]:match[5]])
+		if value == today {
+			return nil
+		}
+		return []protocol.TextEdit{{Range: lineRange(lineNum, match[4], len(lines[lineNum])), NewText: today}}
+	}
+
+	return []protocol.TextEdit{{
+		Range:   protocol.Range{Start: lineEnd(lines, lastField), End: lineEnd(lines, lastField)},
+		NewText: fmt.Sprintf("\n%%%% modified: %s", today),
+	}}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestModifiedDateEdits tests stamping the modified date before save
+func TestModifiedDateEdits(t *testing.T) {
+	ls := &LanguageServer{vault: &vault.Vault{NotesPath: t.TempDir()}, index: NewIndex()}
+	today := "2024-03-05"
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "adds the field after the last one, leaving the others as they are",
+			content:  "%% Metadata\n%% tags: math\n%% title:  Trees\n%% date: 2024-01-01\n\nBody",
+			expected: "%% Metadata\n%% tags: math\n%% title:  Trees\n%% date: 2024-01-01\n%% modified: 2024-03-05\n\nBody",
+		},
+		{
+			name:     "keeps comments and directives in the block",
+			content:  "%% Metadata\n%% title: Trees\n%% tags: math\n% !TeX program = lualatex\n\nBody",
+			expected: "%% Metadata\n%% title: Trees\n%% tags: math\n%% modified: 2024-03-05\n% !TeX program = lualatex\n\nBody",
+		},
+		{
+			name:     "updates the date next to comments in the block",
+			content:  "%% Metadata\n% keep this comment\n%% title: Trees\n%% modified: 2024-02-01\n% !TeX program = lualatex\n",
+			expected: "%% Metadata\n% keep this comment\n%% title: Trees\n%% modified: 2024-03-05\n% !TeX program = lualatex\n",
+		},
+		{
+			name:     "updates a stale date",
+			content:  "%% Metadata\n%% title: Trees\n%% date: 2024-01-01\n%% modified: 2024-02-01\n%% tags: math\n",
+			expected: "%% Metadata\n%% title: Trees\n%% date: 2024-01-01\n%% modified: 2024-03-05\n%% tags: math\n",
+		},
+		{
+			name:     "replaces only the value next to unknown fields",
+			content:  "%% Metadata\n%% title: Trees\n%% author: Ada\n% modified: 2024-02-01\n\nBody",
+			expected: "%% Metadata\n%% title: Trees\n%% author: Ada\n% modified: 2024-03-05\n\nBody",
+		},
+		{
+			name:     "appends the field next to unknown fields",
+			content:  "%% Metadata\n%% title: Trees\n%% author: Ada\n\nBody",
+			expected: "%% Metadata\n%% title: Trees\n%% author: Ada\n%% modified: 2024-03-05\n\nBody",
+		},
+		{
+			name:     "leaves notes without metadata alone",
+			content:  "Just text",
+			expected: "Just text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyTextEdits(tt.content, ls.modifiedDateEdits(tt.content, today)); got != tt.expected {
+				t.Errorf("unexpected result:\n%q\nwant\n%q", got, tt.expected)
+			}
+		})
+	}
+
+	// An up-to-date block needs no edit
+	current := "%% Metadata\n%% title: Trees\n%% date: 2024-01-01\n%% modified: 2024-03-05\n%% tags: math\n"
+	if edits := ls.modifiedDateEdits(current, today); len(edits) != 0 {
+		t.Errorf("expected no edits, got %v", edits)
+	}
+}
+
+// TestWillSaveWaitUntil tests the setting that disables the save hook
+func TestWillSaveWaitUntil(t *testing.T) {
+	notesPath := t.TempDir()
+	uri := pathToURI(filepath.Join(notesPath, "20240101-trees.tex"))
+
+	config := DefaultConfig()
+	ls := &LanguageServer{
+		vault:     &vault.Vault{NotesPath: notesPath},
+		index:     NewIndex(),
+		documents: map[protocol.DocumentURI]string{uri: "%% Metadata\n%% title: Trees\n"},
+		config:    &config,
+	}
+	params := &protocol.WillSaveTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}}
+
+	edits, err := ls.WillSaveWaitUntil(context.Background(), params)
+	if err != nil || len(edits) != 1 {
+		t.Fatalf("expected 1 edit, got %v (%v)", edits, err)
+	}
+
+	config.UpdateModified = false
+	if edits, _ := ls.WillSaveWaitUntil(context.Background(), params); len(edits) != 0 {
+		t.Errorf("expected no edits when disabled, got %v", edits)
+	}
+}
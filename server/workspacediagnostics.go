@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// LSP 3.17 pull diagnostics, not part of the protocol package this server is built on
+const (
+	MethodTextDocumentDiagnostic = "textDocument/diagnostic"
+	MethodWorkspaceDiagnostic    = "workspace/diagnostic"
+)
+
+// Diagnostic report kinds
+const (
+	diagnosticReportFull      = "full"
+	diagnosticReportUnchanged = "unchanged"
+)
+
+// diagnosticCodeInvalidMetadata is only reported by workspace diagnostics
+const diagnosticCodeInvalidMetadata = "LX010"
+
+// DiagnosticOptions advertises pull diagnostics
+type DiagnosticOptions struct {
+	Identifier            string `json:"identifier,omitempty"`
+	InterFileDependencies bool   `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool   `json:"workspaceDiagnostics"`
+}
+
+// DocumentDiagnosticParams are the parameters of textDocument/diagnostic
+type DocumentDiagnosticParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	Identifier       string                          `json:"identifier,omitempty"`
+	PreviousResultID string                          `json:"previousResultId,omitempty"`
+}
+
+// DocumentDiagnosticReport is a full report for a single document
+type DocumentDiagnosticReport struct {
+	Kind  string                `json:"kind"`
+	Items []protocol.Diagnostic `json:"items"`
+}
+
+// PreviousResultID is the result ID a client holds for a document
+type PreviousResultID struct {
+	URI   protocol.DocumentURI `json:"uri"`
+	Value string               `json:"value"`
+}
+
+// WorkspaceDiagnosticParams are the parameters of workspace/diagnostic
+type WorkspaceDiagnosticParams struct {
+	Identifier        string                  `json:"identifier,omitempty"`
+	PreviousResultIDs []PreviousResultID      `json:"previousResultIds"`
+	WorkDoneToken     *protocol.ProgressToken `json:"workDoneToken,omitempty"`
+}
+
+// WorkspaceDiagnosticReport lists the reports of every note not open in the editor
+// Items are WorkspaceFullDocumentDiagnosticReport or WorkspaceUnchangedDocumentDiagnosticReport
+type WorkspaceDiagnosticReport struct {
+	Items []interface{} `json:"items"`
+}
+
+// WorkspaceFullDocumentDiagnosticReport carries the diagnostics of a note
+type WorkspaceFullDocumentDiagnosticReport struct {
+	Kind     string                `json:"kind"`
+	ResultID string                `json:"resultId"`
+	URI      protocol.DocumentURI  `json:"uri"`
+	Version  *int32                `json:"version"` // always null, the note is not open
+	Items    []protocol.Diagnostic `json:"items"`
+}
+
+// WorkspaceUnchangedDocumentDiagnosticReport tells the client its diagnostics of a note are still current
+type WorkspaceUnchangedDocumentDiagnosticReport struct {
+	Kind     string               `json:"kind"`
+	ResultID string               `json:"resultId"`
+	URI      protocol.DocumentURI `json:"uri"`
+	Version  *int32               `json:"version"`
+}
+
+// supportsPullDiagnostics reports whether the client pulls diagnostics
+// Read from the raw initialize params: protocol v0.12 does not model textDocument.diagnostic
+func supportsPullDiagnostics(raw json.RawMessage) bool {
+	var params struct {
+		Capabilities struct {
+			TextDocument struct {
+				Diagnostic *json.RawMessage `json:"diagnostic"`
+			} `json:"textDocument"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return false
+	}
+	return params.Capabilities.TextDocument.Diagnostic != nil
+}
+
+// Handle DocumentDiagnostic request
+// Open notes get their diagnostics pushed through publishDiagnostics as they are edited,
+// so the pulled report is left empty instead of showing them twice
+func (s *LanguageServer) DocumentDiagnostic(ctx context.Context, params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
+	return &DocumentDiagnosticReport{Kind: diagnosticReportFull, Items: []protocol.Diagnostic{}}, nil
+}
+
+// Handle WorkspaceDiagnostic request
+// Reports broken references, metadata errors and duplicate slugs of the notes that are not open
+func (s *LanguageServer) WorkspaceDiagnostic(ctx context.Context, params *WorkspaceDiagnosticParams) (*WorkspaceDiagnosticReport, error) {
+	report := &WorkspaceDiagnosticReport{Items: []interface{}{}}
+	if config := s.settings(); !config.Diagnostics.Enabled || !config.Features.Diagnostics {
+		return report, nil
+	}
+
+	ctx, progress := s.beginProgress(ctx, params.WorkDoneToken, "Checking notes")
+	defer progress.End(ctx, "")
+
+	previous := make(map[protocol.DocumentURI]string)
+	for _, id := range params.PreviousResultIDs {
+		previous[id.URI] = id.Value
+	}
+
+	paths := s.managedNotePaths()
+	duplicates := s.duplicateSlugs(paths)
+	for i, path := range paths {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		uri := pathToURI(path)
+		s.mu.RLock()
+		_, open := s.documents[uri]
+		s.mu.RUnlock()
+		if open {
+			continue
+		}
+		progress.Report(ctx, filepath.Base(path), uint32(i*100/len(paths)))
+
+		items := s.noteWorkspaceDiagnostics(path, duplicates)
+		resultID := diagnosticsResultID(items)
+		if previous[uri] == resultID {
+			report.Items = append(report.Items, WorkspaceUnchangedDocumentDiagnosticReport{
+				Kind: diagnosticReportUnchanged, ResultID: resultID, URI: uri,
+			})
+			continue
+		}
+		report.Items = append(report.Items, WorkspaceFullDocumentDiagnosticReport{
+			Kind: diagnosticReportFull, ResultID: resultID, URI: uri, Items: items,
+		})
+	}
+
+	return report, nil
+}
+
+// managedNotePaths lists the .tex files of the vault and every managed workspace folder
+func (s *LanguageServer) managedNotePaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, dir := range s.noteDirsTree() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tex") || seen[path] {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// duplicateSlugs maps each slug used by more than one file to those files
+func (s *LanguageServer) duplicateSlugs(paths []string) map[string][]string {
+	bySlug := make(map[string][]string)
+	for _, path := range paths {
+		slug := s.parseFilenameToSlug(filepath.Base(path))
+		bySlug[slug] = append(bySlug[slug], path)
+	}
+	for slug, files := range bySlug {
+		if len(files) < 2 {
+			delete(bySlug, slug)
+		}
+	}
+	return bySlug
+}
+
+// noteWorkspaceDiagnostics computes the diagnostics of a note that is not open
+func (s *LanguageServer) noteWorkspaceDiagnostics(path string, duplicates map[string][]string) []protocol.Diagnostic {
+	content, err := s.GetDocument(pathToURI(path))
+	if err != nil {
+		return []protocol.Diagnostic{}
+	}
+
+	diagnostics := s.analyzeNoteDiagnostics(pathToURI(path), content)
+	if diagnostics == nil {
+		diagnostics = []protocol.Diagnostic{}
+	}
+
+	if result, err := s.metadataParser().Parse(content); err == nil {
+		for _, parseErr := range result.Errors {
+			// Dates and engines have diagnostics of their own
+			if parseErr.Field == "date" || parseErr.Field == "modified" || parseErr.Field == "engine" {
+				continue
+			}
+			line := parseErr.Line - 1
+			if line < 0 {
+				line = 0 // Block-level errors, such as a missing block, have no line of their own
+			}
+			diagnostics = append(diagnostics, protocol.Diagnostic{
+				Range:    lineRange(line, parseErr.Column, parseErr.Column+parseErr.Length),
+				Severity: protocol.DiagnosticSeverityWarning,
+				Code:     diagnosticCodeInvalidMetadata,
+				Message:  parseErr.Message,
+				Source:   "lx-ls",
+			})
+		}
+	}
+
+	// Files of the same directory are reported with the note's own diagnostics
+	slug := s.parseFilenameToSlug(filepath.Base(path))
+	for _, other := range duplicates[slug] {
+		if filepath.Dir(other) == filepath.Dir(path) {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    lineRange(0, 0, 0),
+			Severity: protocol.DiagnosticSeverityError,
+			Code:     diagnosticCodeDuplicateSlug,
+			Message:  fmt.Sprintf("Slug '%s' is also used by %s in %s", slug, other, s.rootName(s.noteDirOf(other))),
+			Source:   "lx-ls",
+		})
+	}
+
+	return describeDiagnostics(s.applySeverities(diagnostics))
+}
+
+// diagnosticsResultID identifies a set of diagnostics, so unchanged reports can be sent
+func diagnosticsResultID(diagnostics []protocol.Diagnostic) string {
+	data, _ := json.Marshal(diagnostics)
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// TestWorkspaceDiagnostic tests reporting problems of notes that are not open
+func TestWorkspaceDiagnostic(t *testing.T) {
+	tempDir := t.TempDir()
+	v := vaultAt(tempDir)
+	os.MkdirAll(v.NotesPath, 0755)
+	folder := filepath.Join(t.TempDir(), "course")
+	os.MkdirAll(folder, 0755)
+
+	notes := map[string]string{
+		filepath.Join(v.NotesPath, "20240101-broken.tex"):   "%% Metadata\n%% title: Broken\n\\ref{gone}",
+		filepath.Join(v.NotesPath, "20240102-untitled.tex"): "%% Metadata\n%% date: 2024-01-02\nbody",
+		filepath.Join(v.NotesPath, "20240103-shared.tex"):   "%% Metadata\n%% title: Shared\n",
+		filepath.Join(folder, "shared.tex"):                 "%% Metadata\n%% title: Shared Too\n",
+		filepath.Join(v.NotesPath, "20240104-open.tex"):     "%% Metadata\n%% title: Open\n\\ref{gone}",
+	}
+	for path, content := range notes {
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	ls := &LanguageServer{vault: v, index: NewIndex(), documents: make(map[protocol.DocumentURI]string)}
+	ls.addWorkspaceFolder(protocol.WorkspaceFolder{URI: string(pathToURI(folder)), Name: "course"})
+	ls.RebuildIndex(context.Background())
+	ls.documents[pathToURI(filepath.Join(v.NotesPath, "20240104-open.tex"))] = notes[filepath.Join(v.NotesPath, "20240104-open.tex")]
+
+	report, err := ls.WorkspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
+	if err != nil {
+		t.Fatalf("WorkspaceDiagnostic failed: %v", err)
+	}
+
+	codes := make(map[string][]string)
+	resultIDs := make(map[protocol.DocumentURI]string)
+	for _, item := range report.Items {
+		full, ok := item.(WorkspaceFullDocumentDiagnosticReport)
+		if !ok {
+			t.Fatalf("expected full reports, got %+v", item)
+		}
+		resultIDs[full.URI] = full.ResultID
+		for _, diag := range full.Items {
+			codes[filepath.Base(uriToPath(full.URI))] = append(codes[filepath.Base(uriToPath(full.URI))], diag.Code.(string))
+		}
+	}
+
+	if len(report.Items) != 4 {
+		t.Errorf("expected reports for the 4 closed notes, got %d", len(report.Items))
+	}
+	if got := strings.Join(codes["20240101-broken.tex"], ","); got != diagnosticCodeBrokenRef {
+		t.Errorf("broken: expected a broken reference, got %q", got)
+	}
+	if got := strings.Join(codes["20240102-untitled.tex"], ","); got != diagnosticCodeInvalidMetadata {
+		t.Errorf("untitled: expected a metadata error, got %q", got)
+	}
+	if got := strings.Join(codes["20240103-shared.tex"], ","); got != diagnosticCodeDuplicateSlug {
+		t.Errorf("shared: expected a duplicate slug, got %q", got)
+	}
+	if got := strings.Join(codes["shared.tex"], ","); got != diagnosticCodeDuplicateSlug {
+		t.Errorf("shared in folder: expected a duplicate slug, got %q", got)
+	}
+
+	// Reports the client already holds come back unchanged
+	var previous []PreviousResultID
+	for uri, id := range resultIDs {
+		previous = append(previous, PreviousResultID{URI: uri, Value: id})
+	}
+	report, err = ls.WorkspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{PreviousResultIDs: previous})
+	if err != nil {
+		t.Fatalf("WorkspaceDiagnostic failed: %v", err)
+	}
+	for _, item := range report.Items {
+		if _, ok := item.(WorkspaceUnchangedDocumentDiagnosticReport); !ok {
+			t.Errorf("expected unchanged report, got %+v", item)
+		}
+	}
+}
+
+// TestPullDiagnosticsCapability tests advertising pull diagnostics to clients supporting them
+func TestPullDiagnosticsCapability(t *testing.T) {
+	if !supportsPullDiagnostics([]byte(`{"capabilities":{"textDocument":{"diagnostic":{"dynamicRegistration":false}}}}`)) {
+		t.Error("expected pull diagnostics support")
+	}
+	if supportsPullDiagnostics([]byte(`{"capabilities":{"textDocument":{}}}`)) {
+		t.Error("expected no pull diagnostics support")
+	}
+
+	ls := &LanguageServer{vault: vaultAt(t.TempDir()), index: NewIndex(), pullDiagnostics: true}
+	result, err := ls.Initialize(context.Background(), &protocol.InitializeParams{})
+	if err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if provider := result.Capabilities.DiagnosticProvider; provider == nil || !provider.WorkspaceDiagnostics {
+		t.Errorf("expected workspace diagnostics to be advertised, got %+v", provider)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// MethodWorkspaceSymbolResolve is the LSP 3.17 request filling in a workspace symbol's range
+// Not part of the protocol package this server is built on
+const MethodWorkspaceSymbolResolve = "workspaceSymbol/resolve"
+
+// WorkspaceSymbol is a note found by workspace/symbol
+// The range is left out until the client resolves the symbol the user picked
+type WorkspaceSymbol struct {
+	Name          string                  `json:"name"`
+	Kind          protocol.SymbolKind     `json:"kind"`
+	ContainerName string                  `json:"containerName,omitempty"`
+	Location      WorkspaceSymbolLocation `json:"location"`
+	Data          *workspaceSymbolData    `json:"data,omitempty"`
+}
+
+// WorkspaceSymbolLocation is a location whose range may be omitted
+type WorkspaceSymbolLocation struct {
+	URI   protocol.DocumentURI `json:"uri"`
+	Range *protocol.Range      `json:"range,omitempty"`
+}
+
+// workspaceSymbolData carries what resolve needs back to the server
+type workspaceSymbolData struct {
+	Slug string `json:"slug"`
+}
+
+// supportsSymbolResolve reports whether the client resolves the range of workspace symbols
+// Read from the raw initialize params: protocol v0.12 does not model resolveSupport
+func supportsSymbolResolve(raw json.RawMessage) bool {
+	var params struct {
+		Capabilities struct {
+			Workspace struct {
+				Symbol struct {
+					ResolveSupport struct {
+						Properties []string `json:"properties"`
+					} `json:"resolveSupport"`
+				} `json:"symbol"`
+			} `json:"workspace"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return false
+	}
+	for _, property := range params.Capabilities.Workspace.Symbol.ResolveSupport.Properties {
+		if property == "location.range" {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle WorkspaceSymbol request
+// Notes match on title or slug, then on their body through the full-text index, best matches first
+// Reading files for ranges is deferred to resolve when the client supports it
+func (s *LanguageServer) WorkspaceSymbol(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]WorkspaceSymbol, error) {
+	query := strings.ToLower(strings.TrimSpace(params.Query))
+
+	symbols := []WorkspaceSymbol{}
+	matched := make(map[string]bool)
+	for _, note := range s.index.All() {
+		if query != "" && !strings.Contains(strings.ToLower(note.Title), query) && !strings.Contains(note.Slug, query) {
+			continue
+		}
+		if symbol, ok := s.workspaceSymbol(ctx, note); ok {
+			symbols = append(symbols, symbol)
+			matched[note.Slug] = true
+		}
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Name != symbols[j].Name {
+			return symbols[i].Name < symbols[j].Name
+		}
+		return symbols[i].ContainerName < symbols[j].ContainerName
+	})
+
+	if query == "" {
+		return symbols, nil
+	}
+	hits, err := s.Search(ctx, &SearchParams{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range hits {
+		if matched[hit.Slug] {
+			continue
+		}
+		if note, exists := s.index.Get(hit.Slug); exists {
+			if symbol, ok := s.workspaceSymbol(ctx, note); ok {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// workspaceSymbol builds the symbol of a note, resolved straight away for clients without resolve support
+func (s *LanguageServer) workspaceSymbol(ctx context.Context, note *NoteHeader) (WorkspaceSymbol, bool) {
+	symbol := WorkspaceSymbol{
+		Name:          note.Title,
+		Kind:          protocol.SymbolKindFile,
+		ContainerName: note.Slug,
+		Location:      WorkspaceSymbolLocation{URI: pathToURI(s.notePath(note.Filename))},
+		Data:          &workspaceSymbolData{Slug: note.Slug},
+	}
+	if !s.lazySymbols {
+		resolved, err := s.WorkspaceSymbolResolve(ctx, &symbol)
+		if err != nil {
+			return WorkspaceSymbol{}, false
+		}
+		symbol = *resolved
+	}
+	return symbol, true
+}
+
+// Handle WorkspaceSymbolResolve request
+// Points the symbol at the note's title field, or its first section when it has none
+func (s *LanguageServer) WorkspaceSymbolResolve(ctx context.Context, symbol *WorkspaceSymbol) (*WorkspaceSymbol, error) {
+	if symbol.Data == nil {
+		return symbol, nil
+	}
+	note, exists := s.index.Get(symbol.Data.Slug)
+	if !exists {
+		return nil, fmt.Errorf("note '%s' not found", symbol.Data.Slug)
+	}
+
+	uri := pathToURI(s.notePath(note.Filename))
+	content, err := s.GetDocument(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read note: %w", err)
+	}
+
+	target, found := s.titleRange(content)
+	if !found {
+		target = protocol.Range{}
+		for lineNum, line := range strings.Split(content, "\n") {
+			if match := sectionPattern.FindStringSubmatchIndex(line); match != nil && !strings.HasPrefix(strings.TrimSpace(line), "%") {
+				target = lineRange(lineNum, match[4], match[5])
+				break
+			}
+		}
+	}
+
+	resolved := *symbol
+	resolved.Location = WorkspaceSymbolLocation{URI: uri, Range: &target}
+	return &resolved, nil
+}
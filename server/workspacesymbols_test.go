@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamal-hamza/lx-cli/pkg/vault"
+	"go.lsp.dev/protocol"
+)
+
+// TestWorkspaceSymbol tests lazy workspace symbols and their resolution
+func TestWorkspaceSymbol(t *testing.T) {
+	tempDir := t.TempDir()
+	notesPath := filepath.Join(tempDir, "notes")
+	os.MkdirAll(notesPath, 0755)
+	os.WriteFile(filepath.Join(notesPath, "20240101-graph-theory.tex"), []byte("%% Metadata\n% date: 2024-01-01\n% title: Graph Theory\n"), 0644)
+	os.WriteFile(filepath.Join(notesPath, "20240102-trees.tex"), []byte("\\documentclass{article}\n% \\section{Old}\n\\section{Trees}\n"), 0644)
+
+	ls := &LanguageServer{
+		vault:       &vault.Vault{NotesPath: notesPath},
+		index:       NewIndex(),
+		documents:   make(map[protocol.DocumentURI]string),
+		lazySymbols: true,
+	}
+	ls.RebuildIndex(context.Background())
+
+	symbols, err := ls.WorkspaceSymbol(context.Background(), &protocol.WorkspaceSymbolParams{Query: "GRAPH"})
+	if err != nil {
+		t.Fatalf("WorkspaceSymbol failed: %v", err)
+	}
+	if len(symbols) != 1 || symbols[0].Name != "Graph Theory" || symbols[0].ContainerName != "graph-theory" {
+		t.Fatalf("expected the graph theory note, got %+v", symbols)
+	}
+	if symbols[0].Location.Range != nil {
+		t.Error("expected no range before resolve")
+	}
+
+	// The symbol survives the round trip through the client
+	data, _ := json.Marshal(symbols[0])
+	var roundTrip WorkspaceSymbol
+	json.Unmarshal(data, &roundTrip)
+
+	resolved, err := ls.WorkspaceSymbolResolve(context.Background(), &roundTrip)
+	if err != nil {
+		t.Fatalf("WorkspaceSymbolResolve failed: %v", err)
+	}
+	if want := lineRange(2, 9, 21); resolved.Location.Range == nil || *resolved.Location.Range != want {
+		t.Errorf("expected title range %v, got %v", want, resolved.Location.Range)
+	}
+
+	// Notes without a title field point at their first section
+	symbols, _ = ls.WorkspaceSymbol(context.Background(), &protocol.WorkspaceSymbolParams{Query: "trees"})
+	resolved, _ = ls.WorkspaceSymbolResolve(context.Background(), &symbols[0])
+	if want := lineRange(2, 9, 14); resolved.Location.Range == nil || *resolved.Location.Range != want {
+		t.Errorf("expected section range %v, got %v", want, resolved.Location.Range)
+	}
+
+	// Notes mentioning the query in their body follow the title matches
+	os.WriteFile(filepath.Join(notesPath, "20240103-forests.tex"), []byte("%% Metadata\n% title: Forests\nMany graph trees.\n"), 0644)
+	ls.updateIndexForFile(filepath.Join(notesPath, "20240103-forests.tex"))
+	symbols, _ = ls.WorkspaceSymbol(context.Background(), &protocol.WorkspaceSymbolParams{Query: "graph"})
+	if len(symbols) != 2 || symbols[0].Name != "Graph Theory" || symbols[1].Name != "Forests" {
+		t.Errorf("expected the title match before the body match, got %+v", symbols)
+	}
+	os.Remove(filepath.Join(notesPath, "20240103-forests.tex"))
+	ls.updateIndexForFile(filepath.Join(notesPath, "20240103-forests.tex"))
+
+	// Clients without resolve support get ranges straight away
+	ls.lazySymbols = false
+	symbols, _ = ls.WorkspaceSymbol(context.Background(), &protocol.WorkspaceSymbolParams{})
+	if len(symbols) != 2 || symbols[0].Location.Range == nil || symbols[1].Location.Range == nil {
+		t.Errorf("expected 2 resolved symbols, got %+v", symbols)
+	}
+}
+
+// TestSupportsSymbolResolve tests reading resolve support from raw initialize params
+func TestSupportsSymbolResolve(t *testing.T) {
+	supported := `{"capabilities": {"workspace": {"symbol": {"resolveSupport": {"properties": ["location.range"]}}}}}`
+	if !supportsSymbolResolve(json.RawMessage(supported)) {
+		t.Error("expected resolve support")
+	}
+	if supportsSymbolResolve(json.RawMessage(`{"capabilities": {}}`)) {
+		t.Error("expected no resolve support")
+	}
+}